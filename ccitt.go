@@ -0,0 +1,884 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CCITTFaxDecode (§7.4.6)
+//
+// Group 3 one-dimensional (K = 0), Group 3 two-dimensional (K > 0)
+// and Group 4 (K < 0) encodings from ITU-T Recommendations T.4 and T.6.
+// Each row is a list of changing elements: the positions where
+// the colour changes, starting from white.
+
+// CCITTFaxDecode filter parameters (§7.4.6 Table 11)
+type ccittParameters struct {
+	k                      int
+	endOfLine              bool
+	encodedByteAlign       bool
+	columns                int
+	rows                   int
+	endOfBlock             bool
+	blackIs1               bool
+	damagedRowsBeforeError int
+}
+
+func newCCITTParameters(dict Dictionary) (ccittParameters, error) {
+	p := ccittParameters{
+		columns:    1728,
+		endOfBlock: true,
+	}
+
+	for key, value := range map[Name]*int{
+		"K":                      &p.k,
+		"Columns":                &p.columns,
+		"Rows":                   &p.rows,
+		"DamagedRowsBeforeError": &p.damagedRowsBeforeError,
+	} {
+		obj, ok := dict[key]
+		if !ok {
+			continue
+		}
+		integer, ok := obj.(Integer)
+		if !ok {
+			return p, fmt.Errorf("%s must be an integer, not %T", key, obj)
+		}
+		*value = int(integer)
+	}
+
+	for key, value := range map[Name]*bool{
+		"EndOfLine":        &p.endOfLine,
+		"EncodedByteAlign": &p.encodedByteAlign,
+		"EndOfBlock":       &p.endOfBlock,
+		"BlackIs1":         &p.blackIs1,
+	} {
+		obj, ok := dict[key]
+		if !ok {
+			continue
+		}
+		boolean, ok := obj.(Boolean)
+		if !ok {
+			return p, fmt.Errorf("%s must be a boolean, not %T", key, obj)
+		}
+		*value = bool(boolean)
+	}
+
+	if p.columns < 1 || p.columns > 1<<20 {
+		return p, fmt.Errorf("invalid number of columns %d", p.columns)
+	}
+	if p.rows < 0 {
+		return p, fmt.Errorf("invalid number of rows %d", p.rows)
+	}
+
+	return p, nil
+}
+
+func (p ccittParameters) rowLength() int {
+	return (p.columns + 7) / 8
+}
+
+// the code for the end of a line (T.4 §4.1.2)
+const (
+	ccittEOL       = 1
+	ccittEOLLength = 12
+)
+
+// two-dimensional coding modes (T.4 Table 4)
+const (
+	ccittPass = iota
+	ccittHorizontal
+	ccittV0
+	ccittVR1
+	ccittVR2
+	ccittVR3
+	ccittVL1
+	ccittVL2
+	ccittVL3
+	ccittExtension
+)
+
+var ccittModeCodes = map[int]string{
+	ccittPass:       "0001",
+	ccittHorizontal: "001",
+	ccittV0:         "1",
+	ccittVR1:        "011",
+	ccittVR2:        "000011",
+	ccittVR3:        "0000011",
+	ccittVL1:        "010",
+	ccittVL2:        "000010",
+	ccittVL3:        "0000010",
+	ccittExtension:  "0000001",
+}
+
+// a1 - b1 for each vertical mode
+var ccittVerticalOffsets = map[int]int{
+	ccittV0:  0,
+	ccittVR1: 1,
+	ccittVR2: 2,
+	ccittVR3: 3,
+	ccittVL1: -1,
+	ccittVL2: -2,
+	ccittVL3: -3,
+}
+
+// a variable length code
+type ccittCode struct {
+	bits   uint32
+	length uint
+}
+
+// a code table in both directions
+type ccittTable struct {
+	decode map[ccittCode]int
+	encode map[int]ccittCode
+}
+
+func newCCITTTable(codes map[int]string) ccittTable {
+	table := ccittTable{
+		decode: map[ccittCode]int{},
+		encode: map[int]ccittCode{},
+	}
+	for value, s := range codes {
+		code := ccittCode{length: uint(len(s))}
+		for _, char := range s {
+			code.bits = code.bits<<1 | uint32(char-'0')
+		}
+		table.decode[code] = value
+		table.encode[value] = code
+	}
+	return table
+}
+
+var (
+	ccittModes = newCCITTTable(ccittModeCodes)
+	ccittWhite = newCCITTTable(ccittWhiteCodes)
+	ccittBlack = newCCITTTable(ccittBlackCodes)
+)
+
+// longest run length code
+const ccittMaxCodeLength = 13
+
+func ccittRunTable(white bool) ccittTable {
+	if white {
+		return ccittWhite
+	}
+	return ccittBlack
+}
+
+// reads bits most significant first
+type ccittReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (r *ccittReader) bit() (uint32, bool) {
+	if r.pos >= len(r.data)*8 {
+		return 0, false
+	}
+	bit := uint32(r.data[r.pos/8]>>(7-uint(r.pos%8))) & 1
+	r.pos++
+	return bit, true
+}
+
+func (r *ccittReader) align() {
+	r.pos = (r.pos + 7) &^ 7
+}
+
+// reports if only zero (fill) bits are left
+func (r *ccittReader) atEnd() bool {
+	for pos := r.pos; pos < len(r.data)*8; pos++ {
+		if r.data[pos/8]>>(7-uint(pos%8))&1 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// consumes fill bits and an EOL if there is one
+func (r *ccittReader) eol() bool {
+	start := r.pos
+	zeros := 0
+	for {
+		bit, ok := r.bit()
+		if !ok {
+			break
+		}
+		if bit == 1 {
+			if zeros >= ccittEOLLength-1 {
+				return true
+			}
+			break
+		}
+		zeros++
+	}
+	r.pos = start
+	return false
+}
+
+// skips to just after the next EOL
+func (r *ccittReader) skipToEOL() bool {
+	for r.pos < len(r.data)*8 {
+		if r.eol() {
+			return true
+		}
+		r.pos++
+	}
+	return false
+}
+
+func (r *ccittReader) code(table ccittTable) (int, error) {
+	code := ccittCode{}
+	for code.length < ccittMaxCodeLength {
+		bit, ok := r.bit()
+		if !ok {
+			return 0, errors.New("unexpected end of data")
+		}
+		code.bits = code.bits<<1 | bit
+		code.length++
+
+		if value, ok := table.decode[code]; ok {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid code at bit %d", r.pos)
+}
+
+// a run is zero or more make-up codes followed by a terminating code
+func (r *ccittReader) run(white bool) (int, error) {
+	table := ccittRunTable(white)
+	total := 0
+	for {
+		run, err := r.code(table)
+		if err != nil {
+			return 0, err
+		}
+		total += run
+		if run < 64 {
+			return total, nil
+		}
+	}
+}
+
+// T.4 §4.1
+func (r *ccittReader) line1D(columns int) ([]int, error) {
+	changes := []int{}
+	a0, white := 0, true
+	for a0 < columns {
+		run, err := r.run(white)
+		if err != nil {
+			return nil, err
+		}
+
+		a0 += run
+		if a0 > columns {
+			return nil, errors.New("run past the end of the line")
+		}
+		changes = append(changes, a0)
+		white = !white
+	}
+	return changes, nil
+}
+
+// T.4 §4.2
+func (r *ccittReader) line2D(reference []int, columns int) ([]int, error) {
+	changes := []int{}
+	ref := ccittReferenceLine(reference, columns)
+
+	a0, white := -1, true
+	i := 0 // index of b1 in ref
+	for a0 < columns {
+		b1, b2 := ccittB1B2(ref, &i, a0, white)
+
+		mode, err := r.code(ccittModes)
+		if err != nil {
+			return nil, err
+		}
+
+		switch mode {
+		case ccittPass:
+			a0 = b2
+		case ccittHorizontal:
+			start := a0
+			if start < 0 {
+				start = 0
+			}
+			run1, err := r.run(white)
+			if err != nil {
+				return nil, err
+			}
+			run2, err := r.run(!white)
+			if err != nil {
+				return nil, err
+			}
+
+			a1 := start + run1
+			a2 := a1 + run2
+			if a2 > columns {
+				return nil, errors.New("run past the end of the line")
+			}
+			changes = append(changes, a1, a2)
+			a0 = a2
+		case ccittExtension:
+			return nil, errors.New("uncompressed mode is not supported")
+		default:
+			a1 := b1 + ccittVerticalOffsets[mode]
+			if a1 < a0 || a1 > columns {
+				return nil, errors.New("vertical mode past the line")
+			}
+			changes = append(changes, a1)
+			a0 = a1
+			white = !white
+		}
+	}
+	return changes, nil
+}
+
+// adds changing elements at the end of the line for b1 and b2
+func ccittReferenceLine(reference []int, columns int) []int {
+	ref := make([]int, len(reference), len(reference)+3)
+	copy(ref, reference)
+	return append(ref, columns, columns, columns)
+}
+
+// b1 is the first changing element on the reference line to the right
+// of a0 and of opposite colour to a0's colour, b2 is the next one.
+// Changing elements at even indexes change to black.
+func ccittB1B2(ref []int, i *int, a0 int, white bool) (int, int) {
+	for *i > 0 && ref[*i-1] > a0 {
+		*i--
+	}
+	for *i < len(ref)-2 && ref[*i] <= a0 {
+		*i++
+	}
+	if (*i%2 == 0) != white && *i < len(ref)-2 {
+		*i++
+	}
+	return ref[*i], ref[*i+1]
+}
+
+func ccittDecode(encoded []byte, dict Dictionary) ([]byte, error) {
+	p, err := newCCITTParameters(dict)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ccittReader{data: encoded}
+	decoded := []byte{}
+	reference := []int{} // all white
+	damaged := 0
+
+	for row := 0; p.rows == 0 || row < p.rows; row++ {
+		twoDimensional := p.k < 0
+
+		if p.k < 0 {
+			if p.encodedByteAlign {
+				r.align()
+			}
+			// EOFB
+			if r.atEnd() || (p.endOfBlock && r.eol()) {
+				break
+			}
+		} else {
+			if p.encodedByteAlign && !p.endOfLine {
+				r.align()
+			}
+			if r.eol() && p.endOfBlock && r.rtc(p.k > 0) {
+				break
+			}
+			if r.atEnd() {
+				break
+			}
+			if p.k > 0 {
+				tag, _ := r.bit()
+				twoDimensional = tag == 0
+			}
+		}
+
+		var changes []int
+		if twoDimensional {
+			changes, err = r.line2D(reference, p.columns)
+		} else {
+			changes, err = r.line1D(p.columns)
+		}
+
+		if err != nil {
+			// with EOLs, damaged rows can be replaced by the
+			// previous row until the next EOL
+			damaged++
+			if !p.endOfLine || damaged > p.damagedRowsBeforeError || !r.skipToEOL() {
+				return nil, fmt.Errorf("row %d: %v", row, err)
+			}
+			r.pos -= ccittEOLLength
+			changes = reference
+		}
+
+		changes = ccittNormalize(changes, p.columns)
+		decoded = append(decoded, p.pack(changes)...)
+		reference = changes
+	}
+
+	return decoded, nil
+}
+
+// removes zero length runs and changes at the end of the line so
+// the reference line is the same as the one used by the encoder
+func ccittNormalize(changes []int, columns int) []int {
+	normalized := make([]int, 0, len(changes))
+	for _, change := range changes {
+		if change >= columns {
+			break
+		}
+		if n := len(normalized); n > 0 && normalized[n-1] == change {
+			normalized = normalized[:n-1]
+			continue
+		}
+		normalized = append(normalized, change)
+	}
+	return normalized
+}
+
+// checks for the rest of a return to control (six EOLs, T.4 §4.1.4)
+// after its first EOL, consuming it if it is there
+func (r *ccittReader) rtc(tagged bool) bool {
+	start := r.pos
+	for i := 0; i < 5; i++ {
+		if tagged {
+			r.bit()
+		}
+		if !r.eol() {
+			if i == 0 {
+				r.pos = start
+				return false
+			}
+			break
+		}
+	}
+	return true
+}
+
+// converts changing elements to a row of samples
+func (p ccittParameters) pack(changes []int) []byte {
+	row := make([]byte, p.rowLength())
+
+	// black spans are between a change to black and the next change
+	for i := 0; i < len(changes); i += 2 {
+		start := changes[i]
+		end := p.columns
+		if i+1 < len(changes) {
+			end = changes[i+1]
+		}
+		for x := start; x < end && x < p.columns; x++ {
+			row[x/8] |= 0x80 >> uint(x%8)
+		}
+	}
+
+	if !p.blackIs1 {
+		for i := range row {
+			row[i] = ^row[i]
+		}
+	}
+	return row
+}
+
+// converts a row of samples to changing elements
+func (p ccittParameters) changes(row []byte) []int {
+	changes := []int{}
+	white := true
+	for x := 0; x < p.columns; x++ {
+		black := row[x/8]&(0x80>>uint(x%8)) != 0
+		if !p.blackIs1 {
+			black = !black
+		}
+		if black == white {
+			changes = append(changes, x)
+			white = !white
+		}
+	}
+	return changes
+}
+
+// writes bits most significant first
+type ccittWriter struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (w *ccittWriter) write(code ccittCode) {
+	for i := code.length; i > 0; i-- {
+		if w.pos%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		bit := byte(code.bits>>(i-1)) & 1
+		w.data[w.pos/8] |= bit << (7 - uint(w.pos%8))
+		w.pos++
+	}
+}
+
+func (w *ccittWriter) align() {
+	w.pos = (w.pos + 7) &^ 7
+}
+
+func (w *ccittWriter) eol() {
+	w.write(ccittCode{ccittEOL, ccittEOLLength})
+}
+
+// pads with zeros so the EOL ends on a byte boundary
+func (w *ccittWriter) alignedEOL() {
+	for (w.pos+ccittEOLLength)%8 != 0 {
+		w.write(ccittCode{0, 1})
+	}
+	w.eol()
+}
+
+func (w *ccittWriter) run(run int, white bool) {
+	table := ccittRunTable(white)
+	for run > 2560 {
+		w.write(table.encode[2560])
+		run -= 2560
+	}
+	if run >= 64 {
+		w.write(table.encode[run/64*64])
+		run %= 64
+	}
+	w.write(table.encode[run])
+}
+
+func (w *ccittWriter) line1D(changes []int, columns int) {
+	a0, white := 0, true
+	for _, change := range append(changes, columns) {
+		w.run(change-a0, white)
+		a0 = change
+		white = !white
+	}
+}
+
+// T.4 §4.2.1.3.4 Figure 7
+func (w *ccittWriter) line2D(changes, reference []int, columns int) {
+	coding := ccittReferenceLine(changes, columns)
+	ref := ccittReferenceLine(reference, columns)
+
+	a0, white := -1, true
+	i, j := 0, 0 // indexes of b1 in ref and a1 in coding
+	for a0 < columns {
+		for j < len(coding)-2 && coding[j] <= a0 {
+			j++
+		}
+		a1, a2 := coding[j], coding[j+1]
+		b1, b2 := ccittB1B2(ref, &i, a0, white)
+
+		switch {
+		case b2 < a1:
+			w.write(ccittModes.encode[ccittPass])
+			a0 = b2
+		case a1-b1 >= -3 && a1-b1 <= 3:
+			for mode, offset := range ccittVerticalOffsets {
+				if offset == a1-b1 {
+					w.write(ccittModes.encode[mode])
+				}
+			}
+			a0 = a1
+			white = !white
+		default:
+			start := a0
+			if start < 0 {
+				start = 0
+			}
+			w.write(ccittModes.encode[ccittHorizontal])
+			w.run(a1-start, white)
+			w.run(a2-a1, !white)
+			a0 = a2
+		}
+	}
+}
+
+func ccittEncode(decoded []byte, dict Dictionary) ([]byte, error) {
+	p, err := newCCITTParameters(dict)
+	if err != nil {
+		return nil, err
+	}
+
+	rowLength := p.rowLength()
+	rows := len(decoded) / rowLength
+	if p.rows > 0 && p.rows < rows {
+		rows = p.rows
+	}
+
+	w := &ccittWriter{}
+	eol := func() {
+		if p.encodedByteAlign {
+			w.alignedEOL()
+		} else {
+			w.eol()
+		}
+	}
+
+	reference := []int{}
+	for row := 0; row < rows; row++ {
+		changes := p.changes(decoded[row*rowLength : (row+1)*rowLength])
+
+		if p.encodedByteAlign && (p.k < 0 || !p.endOfLine) {
+			w.align()
+		}
+		if p.k >= 0 && p.endOfLine {
+			eol()
+		}
+
+		oneDimensional := p.k == 0 || (p.k > 0 && row%p.k == 0)
+		if p.k > 0 {
+			tag := uint32(0)
+			if oneDimensional {
+				tag = 1
+			}
+			w.write(ccittCode{tag, 1})
+		}
+
+		if oneDimensional {
+			w.line1D(changes, p.columns)
+		} else {
+			w.line2D(changes, reference, p.columns)
+		}
+		reference = changes
+	}
+
+	if p.endOfBlock {
+		switch {
+		case p.k < 0:
+			// EOFB (T.6 §2.4)
+			if p.encodedByteAlign {
+				w.align()
+			}
+			eol()
+			eol()
+		default:
+			// RTC (T.4 §4.1.4)
+			if p.encodedByteAlign && !p.endOfLine {
+				w.align()
+			}
+			for i := 0; i < 6; i++ {
+				eol()
+				if p.k > 0 {
+					w.write(ccittCode{1, 1})
+				}
+			}
+		}
+	}
+
+	return w.data, nil
+}
+
+// white run lengths (T.4 Tables 2 and 3)
+var ccittWhiteCodes = map[int]string{
+	0:    "00110101",
+	1:    "000111",
+	2:    "0111",
+	3:    "1000",
+	4:    "1011",
+	5:    "1100",
+	6:    "1110",
+	7:    "1111",
+	8:    "10011",
+	9:    "10100",
+	10:   "00111",
+	11:   "01000",
+	12:   "001000",
+	13:   "000011",
+	14:   "110100",
+	15:   "110101",
+	16:   "101010",
+	17:   "101011",
+	18:   "0100111",
+	19:   "0001100",
+	20:   "0001000",
+	21:   "0010111",
+	22:   "0000011",
+	23:   "0000100",
+	24:   "0101000",
+	25:   "0101011",
+	26:   "0010011",
+	27:   "0100100",
+	28:   "0011000",
+	29:   "00000010",
+	30:   "00000011",
+	31:   "00011010",
+	32:   "00011011",
+	33:   "00010010",
+	34:   "00010011",
+	35:   "00010100",
+	36:   "00010101",
+	37:   "00010110",
+	38:   "00010111",
+	39:   "00101000",
+	40:   "00101001",
+	41:   "00101010",
+	42:   "00101011",
+	43:   "00101100",
+	44:   "00101101",
+	45:   "00000100",
+	46:   "00000101",
+	47:   "00001010",
+	48:   "00001011",
+	49:   "01010010",
+	50:   "01010011",
+	51:   "01010100",
+	52:   "01010101",
+	53:   "00100100",
+	54:   "00100101",
+	55:   "01011000",
+	56:   "01011001",
+	57:   "01011010",
+	58:   "01011011",
+	59:   "01001010",
+	60:   "01001011",
+	61:   "00110010",
+	62:   "00110011",
+	63:   "00110100",
+	64:   "11011",
+	128:  "10010",
+	192:  "010111",
+	256:  "0110111",
+	320:  "00110110",
+	384:  "00110111",
+	448:  "01100100",
+	512:  "01100101",
+	576:  "01101000",
+	640:  "01100111",
+	704:  "011001100",
+	768:  "011001101",
+	832:  "011010010",
+	896:  "011010011",
+	960:  "011010100",
+	1024: "011010101",
+	1088: "011010110",
+	1152: "011010111",
+	1216: "011011000",
+	1280: "011011001",
+	1344: "011011010",
+	1408: "011011011",
+	1472: "010011000",
+	1536: "010011001",
+	1600: "010011010",
+	1664: "011000",
+	1728: "010011011",
+	1792: "00000001000",
+	1856: "00000001100",
+	1920: "00000001101",
+	1984: "000000010010",
+	2048: "000000010011",
+	2112: "000000010100",
+	2176: "000000010101",
+	2240: "000000010110",
+	2304: "000000010111",
+	2368: "000000011100",
+	2432: "000000011101",
+	2496: "000000011110",
+	2560: "000000011111",
+}
+
+// black run lengths (T.4 Tables 2 and 3)
+var ccittBlackCodes = map[int]string{
+	0:    "0000110111",
+	1:    "010",
+	2:    "11",
+	3:    "10",
+	4:    "011",
+	5:    "0011",
+	6:    "0010",
+	7:    "00011",
+	8:    "000101",
+	9:    "000100",
+	10:   "0000100",
+	11:   "0000101",
+	12:   "0000111",
+	13:   "00000100",
+	14:   "00000111",
+	15:   "000011000",
+	16:   "0000010111",
+	17:   "0000011000",
+	18:   "0000001000",
+	19:   "00001100111",
+	20:   "00001101000",
+	21:   "00001101100",
+	22:   "00000110111",
+	23:   "00000101000",
+	24:   "00000010111",
+	25:   "00000011000",
+	26:   "000011001010",
+	27:   "000011001011",
+	28:   "000011001100",
+	29:   "000011001101",
+	30:   "000001101000",
+	31:   "000001101001",
+	32:   "000001101010",
+	33:   "000001101011",
+	34:   "000011010010",
+	35:   "000011010011",
+	36:   "000011010100",
+	37:   "000011010101",
+	38:   "000011010110",
+	39:   "000011010111",
+	40:   "000001101100",
+	41:   "000001101101",
+	42:   "000011011010",
+	43:   "000011011011",
+	44:   "000001010100",
+	45:   "000001010101",
+	46:   "000001010110",
+	47:   "000001010111",
+	48:   "000001100100",
+	49:   "000001100101",
+	50:   "000001010010",
+	51:   "000001010011",
+	52:   "000000100100",
+	53:   "000000110111",
+	54:   "000000111000",
+	55:   "000000100111",
+	56:   "000000101000",
+	57:   "000001011000",
+	58:   "000001011001",
+	59:   "000000101011",
+	60:   "000000101100",
+	61:   "000001011010",
+	62:   "000001100110",
+	63:   "000001100111",
+	64:   "0000001111",
+	128:  "000011001000",
+	192:  "000011001001",
+	256:  "000001011011",
+	320:  "000000110011",
+	384:  "000000110100",
+	448:  "000000110101",
+	512:  "0000001101100",
+	576:  "0000001101101",
+	640:  "0000001001010",
+	704:  "0000001001011",
+	768:  "0000001001100",
+	832:  "0000001001101",
+	896:  "0000001110010",
+	960:  "0000001110011",
+	1024: "0000001110100",
+	1088: "0000001110101",
+	1152: "0000001110110",
+	1216: "0000001110111",
+	1280: "0000001010010",
+	1344: "0000001010011",
+	1408: "0000001010100",
+	1472: "0000001010101",
+	1536: "0000001011010",
+	1600: "0000001011011",
+	1664: "0000001100100",
+	1728: "0000001100101",
+	1792: "00000001000",
+	1856: "00000001100",
+	1920: "00000001101",
+	1984: "000000010010",
+	2048: "000000010011",
+	2112: "000000010100",
+	2176: "000000010101",
+	2240: "000000010110",
+	2304: "000000010111",
+	2368: "000000011100",
+	2432: "000000011101",
+	2496: "000000011110",
+	2560: "000000011111",
+}
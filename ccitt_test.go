@@ -0,0 +1,212 @@
+package pdf
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// a bilevel test image with runs of every length, shapes that
+// exercise each coding mode, and noise
+func ccittTestImage(columns, rows int, blackIs1 bool) []byte {
+	random := rand.New(rand.NewSource(int64(columns * rows)))
+	rowLength := (columns + 7) / 8
+	image := make([]byte, rowLength*rows)
+
+	set := func(x, y int) {
+		if x >= 0 && x < columns {
+			image[y*rowLength+x/8] |= 0x80 >> uint(x%8)
+		}
+	}
+
+	for y := 0; y < rows; y++ {
+		switch y % 4 {
+		case 0: // a slanted bar
+			for x := y; x < y+10; x++ {
+				set(x, y)
+			}
+		case 1: // noise
+			for x := 0; x < columns; x++ {
+				if random.Intn(3) == 0 {
+					set(x, y)
+				}
+			}
+		case 2: // long runs
+			for x := columns / 3; x < columns; x++ {
+				set(x, y)
+			}
+		case 3: // the previous row shifted by up to 3
+			shift := y%7 - 3
+			for x := 0; x < columns; x++ {
+				previous := (y-1)*rowLength + x/8
+				if image[previous]&(0x80>>uint(x%8)) != 0 {
+					set(x+shift, y)
+				}
+			}
+		}
+	}
+
+	if !blackIs1 {
+		for i := range image {
+			image[i] = ^image[i]
+		}
+	}
+	return image
+}
+
+// compares rows ignoring the padding bits at the end of each row
+func ccittEqual(a, b []byte, columns int) bool {
+	rowLength := (columns + 7) / 8
+	if len(a) != len(b) {
+		return false
+	}
+	mask := byte(0xff << uint((8-columns%8)%8))
+	for i := range a {
+		if i%rowLength == rowLength-1 {
+			if a[i]&mask != b[i]&mask {
+				return false
+			}
+			continue
+		}
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCCITTRoundTrip(t *testing.T) {
+	for _, k := range []Integer{-1, 0, 1, 4} {
+		for _, columns := range []int{1, 61, 1728, 3000} {
+			for _, flags := range []int{0, 1, 2, 3, 4, 5, 6, 7, 8} {
+				params := Dictionary{
+					"K":                k,
+					"Columns":          Integer(columns),
+					"EndOfLine":        Boolean(flags&1 != 0),
+					"EncodedByteAlign": Boolean(flags&2 != 0),
+					"BlackIs1":         Boolean(flags&4 != 0),
+					"EndOfBlock":       Boolean(flags&8 == 0),
+				}
+				rows := 23
+				if flags&8 != 0 {
+					params["Rows"] = Integer(rows)
+				}
+
+				image := ccittTestImage(columns, rows, flags&4 != 0)
+				encoded, err := ccittEncode(image, params)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				decoded, err := ccittDecode(encoded, params)
+				if err != nil {
+					t.Fatalf("%v: %v", params, err)
+				}
+				if !ccittEqual(image, decoded, columns) {
+					t.Errorf("%v: round trip failed", params)
+				}
+			}
+		}
+	}
+}
+
+func TestCCITTCompression(t *testing.T) {
+	// a white page with a few black rectangles
+	columns, rows := 1728, 200
+	rowLength := columns / 8
+	image := bytes.Repeat([]byte{0xff}, rowLength*rows)
+	for y := 20; y < 180; y++ {
+		for x := 10 + y%3; x < 100; x++ {
+			image[y*rowLength+x] = 0
+		}
+		image[y*rowLength+150] = 0xf0
+	}
+
+	for _, k := range []Integer{-1, 0, 4} {
+		encoded, err := ccittEncode(image, Dictionary{"K": k})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(encoded) > len(image)/20 {
+			t.Errorf("K %d: %d bytes encoded to %d", k, len(image), len(encoded))
+		}
+	}
+}
+
+func TestCCITTDecode(t *testing.T) {
+	type test struct {
+		params  Dictionary
+		encoded []byte
+		decoded []byte
+	}
+	tests := []test{
+		// Group 4: white rows are V0 (1), then EOFB
+		test{
+			Dictionary{"K": Integer(-1), "Columns": Integer(16)},
+			[]byte{0xe0, 0x01, 0x00, 0x10},
+			[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		// Group 3 1D with EOLs: EOL, white 4 (1011), black 4 (011), RTC
+		test{
+			Dictionary{"K": Integer(0), "Columns": Integer(8), "EndOfLine": Boolean(true), "BlackIs1": Boolean(true)},
+			[]byte{
+				0x00, 0x1b, 0x60, // EOL 1011 011 0000
+				0x01, 0x00, 0x10, 0x01, 0x00, 0x10, 0x01, 0x00, 0x10, // RTC
+			},
+			[]byte{0x0f},
+		},
+		// Group 3 1D with EncodedByteAlign and no EOLs,
+		// white 0 (00110101) black 8 (000101), white 8 (10011)
+		test{
+			Dictionary{"K": Integer(0), "Columns": Integer(8), "EncodedByteAlign": Boolean(true), "EndOfBlock": Boolean(false)},
+			[]byte{0x35, 0x14, 0x98},
+			[]byte{0x00, 0xff},
+		},
+	}
+
+	for i, test := range tests {
+		decoded, err := ccittDecode(test.encoded, test.params)
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(decoded, test.decoded) {
+			t.Errorf("%d: expected % x, got % x", i, test.decoded, decoded)
+		}
+	}
+}
+
+func TestCCITTDamagedRows(t *testing.T) {
+	columns, rows := 64, 10
+	image := ccittTestImage(columns, rows, false)
+	params := Dictionary{
+		"K":         Integer(0),
+		"Columns":   Integer(columns),
+		"EndOfLine": Boolean(true),
+	}
+
+	encoded, err := ccittEncode(image, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// damage the middle of the data
+	damaged := append([]byte{}, encoded...)
+	for i := len(damaged) / 2; i < len(damaged)/2+2; i++ {
+		damaged[i] = 0xff
+	}
+
+	_, err = ccittDecode(damaged, params)
+	if err == nil {
+		t.Error("expected an error for damaged rows")
+	}
+
+	params["DamagedRowsBeforeError"] = Integer(3)
+	decoded, err := ccittDecode(damaged, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(image) {
+		t.Errorf("expected %d rows, got %d", rows, len(decoded)/8)
+	}
+}
@@ -0,0 +1,624 @@
+package content
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/nathankerr/pdf"
+)
+
+// A ContentBuilder creates a content stream from method calls,
+// one method per operator (§8.2 and Table A.1).
+//
+// The zero value is ready to use. Misuse, such as a Q without a
+// matching q or showing text outside of BT and ET, is stored until
+// Bytes or Stream is called, after which the other calls are ignored.
+type ContentBuilder struct {
+	operations []Operation
+	err        error
+
+	saves         int  // q without Q
+	text          bool // inside BT and ET
+	markedContent int  // BMC or BDC without EMC
+	compatibility int  // BX without EX
+}
+
+// Append adds operations, such as those from Parse, to the content stream.
+func (cb *ContentBuilder) Append(operations ...Operation) {
+	for _, operation := range operations {
+		cb.op(operation.Operator, operation.Operands...)
+	}
+}
+
+// checks that the operator is allowed and keeps track of nesting
+func (cb *ContentBuilder) op(operator Operator, operands ...pdf.Object) {
+	if cb.err != nil {
+		return
+	}
+
+	fail := func(format string, a ...interface{}) {
+		cb.err = fmt.Errorf("operation %d (%s): %s", len(cb.operations), operator, fmt.Sprintf(format, a...))
+	}
+
+	switch operator {
+	case "q":
+		if cb.text {
+			fail("not allowed in a text object")
+			return
+		}
+		cb.saves++
+	case "Q":
+		if cb.saves == 0 {
+			fail("without a matching q")
+			return
+		}
+		if cb.text {
+			fail("not allowed in a text object")
+			return
+		}
+		cb.saves--
+	case "BT":
+		if cb.text {
+			fail("text objects cannot be nested")
+			return
+		}
+		cb.text = true
+	case "ET":
+		if !cb.text {
+			fail("without a matching BT")
+			return
+		}
+		cb.text = false
+	case "Tj", "TJ", "'", "\"", "Td", "TD", "Tm", "T*":
+		if !cb.text {
+			fail("only allowed in a text object")
+			return
+		}
+	case "m", "l", "c", "v", "y", "h", "re",
+		"S", "s", "f", "F", "f*", "B", "B*", "b", "b*", "n",
+		"W", "W*", "cm", "Do", "sh", "BI", "d0", "d1":
+		if cb.text {
+			fail("not allowed in a text object")
+			return
+		}
+	case "BMC", "BDC":
+		cb.markedContent++
+	case "EMC":
+		if cb.markedContent == 0 {
+			fail("without a matching BMC or BDC")
+			return
+		}
+		cb.markedContent--
+	case "BX":
+		cb.compatibility++
+	case "EX":
+		if cb.compatibility == 0 {
+			fail("without a matching BX")
+			return
+		}
+		cb.compatibility--
+	default:
+		if !operator.Known() && cb.compatibility == 0 {
+			fail("unknown operator outside of a compatibility section")
+			return
+		}
+	}
+
+	if operands == nil {
+		operands = []pdf.Object{}
+	}
+	cb.operations = append(cb.operations, Operation{
+		Operator: operator,
+		Operands: operands,
+	})
+}
+
+// Bytes returns the content stream, or the first error.
+func (cb *ContentBuilder) Bytes() ([]byte, error) {
+	if cb.err != nil {
+		return nil, cb.err
+	}
+
+	switch {
+	case cb.saves != 0:
+		return nil, fmt.Errorf("%d q without a matching Q", cb.saves)
+	case cb.text:
+		return nil, fmt.Errorf("BT without a matching ET")
+	case cb.markedContent != 0:
+		return nil, fmt.Errorf("%d BMC or BDC without a matching EMC", cb.markedContent)
+	case cb.compatibility != 0:
+		return nil, fmt.Errorf("%d BX without a matching EX", cb.compatibility)
+	}
+
+	buf := &bytes.Buffer{}
+	_, err := Write(buf, cb.operations)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Stream returns the content stream as a Stream that can be added to a File.
+func (cb *ContentBuilder) Stream() (pdf.Stream, error) {
+	data, err := cb.Bytes()
+	if err != nil {
+		return pdf.Stream{}, err
+	}
+
+	return pdf.Stream{
+		Dictionary: pdf.Dictionary{},
+		Stream:     data,
+	}, nil
+}
+
+// Integral values are written as Integers, everything else as Reals.
+func number(value float64) pdf.Object {
+	if value == math.Trunc(value) && math.Abs(value) < 1<<31 {
+		return pdf.Integer(value)
+	}
+	return pdf.Real(value)
+}
+
+func numbers(values ...float64) []pdf.Object {
+	objects := make([]pdf.Object, len(values))
+	for i, value := range values {
+		objects[i] = number(value)
+	}
+	return objects
+}
+
+// Graphics state operators §8.4.4
+
+// Save pushes a copy of the graphics state (q).
+func (cb *ContentBuilder) Save() {
+	cb.op("q")
+}
+
+// Restore pops the graphics state saved by the matching Save (Q).
+func (cb *ContentBuilder) Restore() {
+	cb.op("Q")
+}
+
+// Transform concatenates [a b c d e f] to the current transformation matrix (cm).
+func (cb *ContentBuilder) Transform(a, b, c, d, e, f float64) {
+	cb.op("cm", numbers(a, b, c, d, e, f)...)
+}
+
+// Translate moves the origin to (x, y).
+func (cb *ContentBuilder) Translate(x, y float64) {
+	cb.Transform(1, 0, 0, 1, x, y)
+}
+
+// Scale scales the x and y axes.
+func (cb *ContentBuilder) Scale(x, y float64) {
+	cb.Transform(x, 0, 0, y, 0, 0)
+}
+
+// Rotate rotates the axes counterclockwise by degrees.
+func (cb *ContentBuilder) Rotate(degrees float64) {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+
+	// exact values for multiples of 90 degrees
+	sin, cos = math.Round(sin*1e12)/1e12, math.Round(cos*1e12)/1e12
+
+	cb.Transform(cos, sin, -sin, cos, 0, 0)
+}
+
+// SetLineWidth sets the line width (w).
+func (cb *ContentBuilder) SetLineWidth(width float64) {
+	cb.op("w", number(width))
+}
+
+// SetLineCap sets the line cap style (J).
+func (cb *ContentBuilder) SetLineCap(style int) {
+	cb.op("J", pdf.Integer(style))
+}
+
+// SetLineJoin sets the line join style (j).
+func (cb *ContentBuilder) SetLineJoin(style int) {
+	cb.op("j", pdf.Integer(style))
+}
+
+// SetMiterLimit sets the miter limit (M).
+func (cb *ContentBuilder) SetMiterLimit(limit float64) {
+	cb.op("M", number(limit))
+}
+
+// SetDash sets the line dash pattern (d).
+func (cb *ContentBuilder) SetDash(dashes []float64, phase float64) {
+	cb.op("d", pdf.Array(numbers(dashes...)), number(phase))
+}
+
+// SetRenderingIntent sets the colour rendering intent (ri).
+func (cb *ContentBuilder) SetRenderingIntent(intent pdf.Name) {
+	cb.op("ri", intent)
+}
+
+// SetFlatness sets the flatness tolerance (i).
+func (cb *ContentBuilder) SetFlatness(flatness float64) {
+	cb.op("i", number(flatness))
+}
+
+// SetExtGState sets the parameters from the named
+// graphics state parameter dictionary in the resources (gs).
+func (cb *ContentBuilder) SetExtGState(name pdf.Name) {
+	cb.op("gs", name)
+}
+
+// Path construction operators §8.5.2
+
+// MoveTo begins a new subpath at (x, y) (m).
+func (cb *ContentBuilder) MoveTo(x, y float64) {
+	cb.op("m", numbers(x, y)...)
+}
+
+// LineTo appends a straight line to (x, y) (l).
+func (cb *ContentBuilder) LineTo(x, y float64) {
+	cb.op("l", numbers(x, y)...)
+}
+
+// CurveTo appends a Bézier curve to (x3, y3) using
+// (x1, y1) and (x2, y2) as control points (c).
+func (cb *ContentBuilder) CurveTo(x1, y1, x2, y2, x3, y3 float64) {
+	cb.op("c", numbers(x1, y1, x2, y2, x3, y3)...)
+}
+
+// CurveToV appends a Bézier curve to (x3, y3) using the current
+// point and (x2, y2) as control points (v).
+func (cb *ContentBuilder) CurveToV(x2, y2, x3, y3 float64) {
+	cb.op("v", numbers(x2, y2, x3, y3)...)
+}
+
+// CurveToY appends a Bézier curve to (x3, y3) using
+// (x1, y1) and (x3, y3) as control points (y).
+func (cb *ContentBuilder) CurveToY(x1, y1, x3, y3 float64) {
+	cb.op("y", numbers(x1, y1, x3, y3)...)
+}
+
+// ClosePath closes the current subpath (h).
+func (cb *ContentBuilder) ClosePath() {
+	cb.op("h")
+}
+
+// Rectangle appends a rectangle as a complete subpath (re).
+func (cb *ContentBuilder) Rectangle(x, y, width, height float64) {
+	cb.op("re", numbers(x, y, width, height)...)
+}
+
+// Path painting operators §8.5.3
+
+// Stroke strokes the path (S).
+func (cb *ContentBuilder) Stroke() {
+	cb.op("S")
+}
+
+// CloseStroke closes and strokes the path (s).
+func (cb *ContentBuilder) CloseStroke() {
+	cb.op("s")
+}
+
+// Fill fills the path using the non-zero winding number rule (f).
+func (cb *ContentBuilder) Fill() {
+	cb.op("f")
+}
+
+// FillEvenOdd fills the path using the even-odd rule (f*).
+func (cb *ContentBuilder) FillEvenOdd() {
+	cb.op("f*")
+}
+
+// FillStroke fills, using the non-zero winding number rule,
+// and then strokes the path (B).
+func (cb *ContentBuilder) FillStroke() {
+	cb.op("B")
+}
+
+// FillStrokeEvenOdd fills, using the even-odd rule,
+// and then strokes the path (B*).
+func (cb *ContentBuilder) FillStrokeEvenOdd() {
+	cb.op("B*")
+}
+
+// CloseFillStroke closes, fills, using the non-zero winding number rule,
+// and then strokes the path (b).
+func (cb *ContentBuilder) CloseFillStroke() {
+	cb.op("b")
+}
+
+// CloseFillStrokeEvenOdd closes, fills, using the even-odd rule,
+// and then strokes the path (b*).
+func (cb *ContentBuilder) CloseFillStrokeEvenOdd() {
+	cb.op("b*")
+}
+
+// EndPath ends the path without painting it (n).
+func (cb *ContentBuilder) EndPath() {
+	cb.op("n")
+}
+
+// Clipping path operators §8.5.4
+
+// Clip intersects the clipping path with the current path,
+// using the non-zero winding number rule (W).
+// The path still needs to be painted or ended.
+func (cb *ContentBuilder) Clip() {
+	cb.op("W")
+}
+
+// ClipEvenOdd intersects the clipping path with the current path,
+// using the even-odd rule (W*).
+// The path still needs to be painted or ended.
+func (cb *ContentBuilder) ClipEvenOdd() {
+	cb.op("W*")
+}
+
+// Colour operators §8.6.8
+
+// SetStrokeColorSpace sets the colour space used for stroking (CS).
+func (cb *ContentBuilder) SetStrokeColorSpace(name pdf.Name) {
+	cb.op("CS", name)
+}
+
+// SetFillColorSpace sets the colour space used for filling (cs).
+func (cb *ContentBuilder) SetFillColorSpace(name pdf.Name) {
+	cb.op("cs", name)
+}
+
+// SetStrokeColor sets the colour used for stroking (SC).
+func (cb *ContentBuilder) SetStrokeColor(components ...float64) {
+	cb.op("SC", numbers(components...)...)
+}
+
+// SetFillColor sets the colour used for filling (sc).
+func (cb *ContentBuilder) SetFillColor(components ...float64) {
+	cb.op("sc", numbers(components...)...)
+}
+
+// SetStrokeColorN sets the colour used for stroking in Pattern,
+// Separation, DeviceN and ICCBased colour spaces (SCN).
+// The pattern name is omitted when empty.
+func (cb *ContentBuilder) SetStrokeColorN(pattern pdf.Name, components ...float64) {
+	operands := numbers(components...)
+	if pattern != "" {
+		operands = append(operands, pattern)
+	}
+	cb.op("SCN", operands...)
+}
+
+// SetFillColorN sets the colour used for filling in Pattern,
+// Separation, DeviceN and ICCBased colour spaces (scn).
+// The pattern name is omitted when empty.
+func (cb *ContentBuilder) SetFillColorN(pattern pdf.Name, components ...float64) {
+	operands := numbers(components...)
+	if pattern != "" {
+		operands = append(operands, pattern)
+	}
+	cb.op("scn", operands...)
+}
+
+// SetStrokeGray sets DeviceGray as the stroking colour space
+// and the gray level to use (G).
+func (cb *ContentBuilder) SetStrokeGray(gray float64) {
+	cb.op("G", number(gray))
+}
+
+// SetFillGray sets DeviceGray as the filling colour space
+// and the gray level to use (g).
+func (cb *ContentBuilder) SetFillGray(gray float64) {
+	cb.op("g", number(gray))
+}
+
+// SetStrokeRGB sets DeviceRGB as the stroking colour space
+// and the colour to use (RG).
+func (cb *ContentBuilder) SetStrokeRGB(r, g, b float64) {
+	cb.op("RG", numbers(r, g, b)...)
+}
+
+// SetFillRGB sets DeviceRGB as the filling colour space
+// and the colour to use (rg).
+func (cb *ContentBuilder) SetFillRGB(r, g, b float64) {
+	cb.op("rg", numbers(r, g, b)...)
+}
+
+// SetStrokeCMYK sets DeviceCMYK as the stroking colour space
+// and the colour to use (K).
+func (cb *ContentBuilder) SetStrokeCMYK(c, m, y, k float64) {
+	cb.op("K", numbers(c, m, y, k)...)
+}
+
+// SetFillCMYK sets DeviceCMYK as the filling colour space
+// and the colour to use (k).
+func (cb *ContentBuilder) SetFillCMYK(c, m, y, k float64) {
+	cb.op("k", numbers(c, m, y, k)...)
+}
+
+// Shading operator §8.7.4.2
+
+// Shade paints the named shading over the current clipping area (sh).
+func (cb *ContentBuilder) Shade(name pdf.Name) {
+	cb.op("sh", name)
+}
+
+// External objects §8.8 and inline images §8.9.7
+
+// DrawXObject paints the named XObject from the resources (Do).
+func (cb *ContentBuilder) DrawXObject(name pdf.Name) {
+	cb.op("Do", name)
+}
+
+// InlineImage paints an image with the (abbreviated) parameters
+// and data (BI, ID and EI).
+func (cb *ContentBuilder) InlineImage(params pdf.Dictionary, data []byte) {
+	cb.op("BI", pdf.Stream{Dictionary: params, Stream: data})
+}
+
+// Text objects §9.4
+
+// BeginText begins a text object (BT).
+func (cb *ContentBuilder) BeginText() {
+	cb.op("BT")
+}
+
+// EndText ends a text object (ET).
+func (cb *ContentBuilder) EndText() {
+	cb.op("ET")
+}
+
+// Text state operators §9.3
+
+// SetCharacterSpacing sets the character spacing (Tc).
+func (cb *ContentBuilder) SetCharacterSpacing(spacing float64) {
+	cb.op("Tc", number(spacing))
+}
+
+// SetWordSpacing sets the word spacing (Tw).
+func (cb *ContentBuilder) SetWordSpacing(spacing float64) {
+	cb.op("Tw", number(spacing))
+}
+
+// SetHorizontalScaling sets the horizontal scaling in percent (Tz).
+func (cb *ContentBuilder) SetHorizontalScaling(scale float64) {
+	cb.op("Tz", number(scale))
+}
+
+// SetLeading sets the text leading (TL).
+func (cb *ContentBuilder) SetLeading(leading float64) {
+	cb.op("TL", number(leading))
+}
+
+// SetFont sets the named font from the resources and its size (Tf).
+func (cb *ContentBuilder) SetFont(name pdf.Name, size float64) {
+	cb.op("Tf", name, number(size))
+}
+
+// SetTextRenderingMode sets the text rendering mode (Tr).
+func (cb *ContentBuilder) SetTextRenderingMode(mode int) {
+	cb.op("Tr", pdf.Integer(mode))
+}
+
+// SetTextRise sets the text rise (Ts).
+func (cb *ContentBuilder) SetTextRise(rise float64) {
+	cb.op("Ts", number(rise))
+}
+
+// Text positioning operators §9.4.2
+
+// MoveText moves to the start of the next line, offset by (x, y) (Td).
+func (cb *ContentBuilder) MoveText(x, y float64) {
+	cb.op("Td", numbers(x, y)...)
+}
+
+// MoveTextSetLeading moves to the start of the next line,
+// offset by (x, y), and sets the leading to -y (TD).
+func (cb *ContentBuilder) MoveTextSetLeading(x, y float64) {
+	cb.op("TD", numbers(x, y)...)
+}
+
+// SetTextMatrix sets the text matrix and the text line matrix (Tm).
+func (cb *ContentBuilder) SetTextMatrix(a, b, c, d, e, f float64) {
+	cb.op("Tm", numbers(a, b, c, d, e, f)...)
+}
+
+// NextLine moves to the start of the next line (T*).
+func (cb *ContentBuilder) NextLine() {
+	cb.op("T*")
+}
+
+// Text showing operators §9.4.3
+
+// ShowText shows a string (Tj).
+func (cb *ContentBuilder) ShowText(text pdf.String) {
+	cb.op("Tj", text)
+}
+
+// ShowTextAdjusted shows strings, adjusting the position
+// between them by numbers in thousandths of a unit of text space (TJ).
+// Elements must be pdf.Strings, pdf.Integers, pdf.Reals or float64s.
+func (cb *ContentBuilder) ShowTextAdjusted(elements ...interface{}) {
+	array := make(pdf.Array, 0, len(elements))
+	for _, element := range elements {
+		switch typed := element.(type) {
+		case pdf.String, pdf.Integer, pdf.Real:
+			array = append(array, typed.(pdf.Object))
+		case float64:
+			array = append(array, number(typed))
+		case int:
+			array = append(array, pdf.Integer(typed))
+		default:
+			if cb.err == nil {
+				cb.err = fmt.Errorf("operation %d (TJ): unexpected %T", len(cb.operations), element)
+			}
+			return
+		}
+	}
+	cb.op("TJ", array)
+}
+
+// NextLineShowText moves to the next line and shows a string (').
+func (cb *ContentBuilder) NextLineShowText(text pdf.String) {
+	cb.op("'", text)
+}
+
+// NextLineShowTextSpacing sets the word and character spacing,
+// moves to the next line and shows a string (").
+func (cb *ContentBuilder) NextLineShowTextSpacing(wordSpacing, characterSpacing float64, text pdf.String) {
+	cb.op("\"", number(wordSpacing), number(characterSpacing), text)
+}
+
+// Type 3 fonts §9.6.5
+
+// SetGlyphWidth sets the width of a Type 3 glyph (d0).
+func (cb *ContentBuilder) SetGlyphWidth(wx, wy float64) {
+	cb.op("d0", numbers(wx, wy)...)
+}
+
+// SetGlyphWidthAndBoundingBox sets the width and bounding box
+// of a Type 3 glyph that does not specify colour (d1).
+func (cb *ContentBuilder) SetGlyphWidthAndBoundingBox(wx, wy, llx, lly, urx, ury float64) {
+	cb.op("d1", numbers(wx, wy, llx, lly, urx, ury)...)
+}
+
+// Marked content operators §14.6
+
+// MarkPoint designates a marked-content point (MP).
+func (cb *ContentBuilder) MarkPoint(tag pdf.Name) {
+	cb.op("MP", tag)
+}
+
+// MarkPointWithProperties designates a marked-content point
+// with a property list, either a pdf.Dictionary or the name
+// of one in the Properties resources (DP).
+func (cb *ContentBuilder) MarkPointWithProperties(tag pdf.Name, properties pdf.Object) {
+	cb.op("DP", tag, properties)
+}
+
+// BeginMarkedContent begins a marked-content sequence (BMC).
+func (cb *ContentBuilder) BeginMarkedContent(tag pdf.Name) {
+	cb.op("BMC", tag)
+}
+
+// BeginMarkedContentWithProperties begins a marked-content
+// sequence with a property list, either a pdf.Dictionary or
+// the name of one in the Properties resources (BDC).
+func (cb *ContentBuilder) BeginMarkedContentWithProperties(tag pdf.Name, properties pdf.Object) {
+	cb.op("BDC", tag, properties)
+}
+
+// EndMarkedContent ends a marked-content sequence (EMC).
+func (cb *ContentBuilder) EndMarkedContent() {
+	cb.op("EMC")
+}
+
+// Compatibility operators §7.8.2
+
+// BeginCompatibility begins a compatibility section (BX).
+func (cb *ContentBuilder) BeginCompatibility() {
+	cb.op("BX")
+}
+
+// EndCompatibility ends a compatibility section (EX).
+func (cb *ContentBuilder) EndCompatibility() {
+	cb.op("EX")
+}
@@ -0,0 +1,113 @@
+package content
+
+import (
+	"testing"
+
+	"github.com/nathankerr/pdf"
+)
+
+func TestContentBuilder(t *testing.T) {
+	cb := &ContentBuilder{}
+	cb.Save()
+	cb.Translate(72, 0.5)
+	cb.Rotate(90)
+	cb.SetExtGState("GS1")
+	cb.SetFillRGB(1, 0, 0)
+	cb.Rectangle(0, 0, 100, 50)
+	cb.Clip()
+	cb.EndPath()
+	cb.BeginMarkedContentWithProperties("Span", pdf.Dictionary{"ActualText": pdf.String("Hi")})
+	cb.BeginText()
+	cb.SetFont("F1", 12)
+	cb.MoveText(10, 20)
+	cb.ShowText(pdf.String("Hello"))
+	cb.ShowTextAdjusted(pdf.String("W"), 120, pdf.String("orld"), -0.5)
+	cb.EndText()
+	cb.EndMarkedContent()
+	cb.DrawXObject("Im1")
+	cb.Restore()
+
+	data, err := cb.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `q
+1 0 0 1 72 0.5 cm
+0 1 -1 0 0 0 cm
+/GS1 gs
+1 0 0 rg
+0 0 100 50 re
+W
+n
+/Span <</ActualText (Hi)>> BDC
+BT
+/F1 12 Tf
+10 20 Td
+(Hello) Tj
+[(W) 120 (orld) -0.5] TJ
+ET
+EMC
+/Im1 Do
+Q
+`
+	if string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+	}
+
+	// the output is a content stream
+	operations, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 18 {
+		t.Errorf("expected 18 operations, got %d", len(operations))
+	}
+
+	stream, err := cb.Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stream.Stream) != expected {
+		t.Errorf("expected the stream to contain the content stream")
+	}
+}
+
+func TestContentBuilderBalance(t *testing.T) {
+	tests := []func(cb *ContentBuilder){
+		func(cb *ContentBuilder) { cb.Save() },
+		func(cb *ContentBuilder) { cb.Restore() },
+		func(cb *ContentBuilder) { cb.BeginText() },
+		func(cb *ContentBuilder) { cb.EndText() },
+		func(cb *ContentBuilder) { cb.BeginText(); cb.BeginText() },
+		func(cb *ContentBuilder) { cb.ShowText(pdf.String("outside of BT")) },
+		func(cb *ContentBuilder) { cb.BeginText(); cb.Save() },
+		func(cb *ContentBuilder) { cb.BeginText(); cb.Rectangle(0, 0, 1, 1) },
+		func(cb *ContentBuilder) { cb.Save(); cb.BeginText(); cb.Restore() },
+		func(cb *ContentBuilder) { cb.BeginMarkedContent("Artifact") },
+		func(cb *ContentBuilder) { cb.EndMarkedContent() },
+		func(cb *ContentBuilder) { cb.BeginCompatibility() },
+		func(cb *ContentBuilder) { cb.Append(Operation{Operator: "unknown"}) },
+		func(cb *ContentBuilder) { cb.BeginText(); cb.ShowTextAdjusted("not a pdf.String"); cb.EndText() },
+	}
+
+	for i, test := range tests {
+		cb := &ContentBuilder{}
+		test(cb)
+
+		_, err := cb.Bytes()
+		if err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+
+	// unknown operators are allowed in compatibility sections
+	cb := &ContentBuilder{}
+	cb.BeginCompatibility()
+	cb.Append(Operation{Operator: "unknown"})
+	cb.EndCompatibility()
+	_, err := cb.Bytes()
+	if err != nil {
+		t.Error(err)
+	}
+}
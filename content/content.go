@@ -0,0 +1,51 @@
+/*
+Package content reads and writes PDF content streams.
+
+A content stream is a sequence of operations, each of which is
+an operator preceded by its operands (§7.8.2). Operands are the
+same objects used in the rest of a PDF and are parsed and written
+by package pdf.
+*/
+package content
+
+import (
+	"github.com/nathankerr/pdf"
+)
+
+// An Operator is the keyword that ends an operation.
+type Operator string
+
+// An Operation is an Operator and the operands that precede it.
+//
+// Inline images (§8.9.7) are a single Operation with the BI
+// Operator and a pdf.Stream operand. The Stream's Dictionary
+// holds the image parameters (as they appear between BI and ID)
+// and its Stream holds the image data (between ID and EI).
+type Operation struct {
+	Operator Operator
+	Operands []pdf.Object
+}
+
+// operators defined in Table A.1
+var operators = map[Operator]bool{
+	"b": true, "B": true, "b*": true, "B*": true, "BDC": true,
+	"BI": true, "BMC": true, "BT": true, "BX": true, "c": true,
+	"cm": true, "CS": true, "cs": true, "d": true, "d0": true,
+	"d1": true, "Do": true, "DP": true, "EI": true, "EMC": true,
+	"ET": true, "EX": true, "f": true, "F": true, "f*": true,
+	"G": true, "g": true, "gs": true, "h": true, "i": true,
+	"ID": true, "j": true, "J": true, "K": true, "k": true,
+	"l": true, "m": true, "M": true, "MP": true, "n": true,
+	"q": true, "Q": true, "re": true, "RG": true, "rg": true,
+	"ri": true, "s": true, "S": true, "SC": true, "sc": true,
+	"SCN": true, "scn": true, "sh": true, "T*": true, "Tc": true,
+	"Td": true, "TD": true, "Tf": true, "Tj": true, "TJ": true,
+	"TL": true, "Tm": true, "Tr": true, "Ts": true, "Tw": true,
+	"Tz": true, "v": true, "w": true, "W": true, "W*": true,
+	"y": true, "'": true, "\"": true,
+}
+
+// Known reports if the Operator is defined in Table A.1.
+func (op Operator) Known() bool {
+	return operators[op]
+}
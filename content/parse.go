@@ -0,0 +1,289 @@
+package content
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/nathankerr/pdf"
+	"github.com/nathankerr/pdf/internal/lexer"
+)
+
+// Parse splits a (decoded) content stream into Operations.
+//
+// Operators that are not defined in Table A.1 are errors, except
+// within compatibility sections (BX/EX §7.8.2) where they are kept
+// as they are. On error, the Operations parsed so far are returned.
+func Parse(data []byte) ([]Operation, error) {
+	return parse(data, false)
+}
+
+// ParseCompatible is like Parse, except that unknown operators are
+// kept wherever they are, as conforming readers ignore them (§7.8.2).
+func ParseCompatible(data []byte) ([]Operation, error) {
+	return parse(data, true)
+}
+
+func parse(data []byte, compatible bool) ([]Operation, error) {
+	operations := []Operation{}
+	operands := []pdf.Object{}
+	compatibility := 0 // nesting level of BX/EX
+
+	i := 0
+	for {
+		begin, ok := nextNonWhitespace(data[i:])
+		i += begin
+		if !ok {
+			break
+		}
+
+		// operands
+		if startsObject(data[i:]) {
+			object, n, err := pdf.ParseObject(data[i:])
+			if err != nil {
+				return operations, fmt.Errorf("offset %d: %v", i+n, err)
+			}
+			i += n
+
+			operands = append(operands, object)
+			continue
+		}
+
+		// operators
+		token := regularToken(data[i:])
+		if len(token) == 0 {
+			return operations, fmt.Errorf("offset %d: unexpected %q", i, data[i])
+		}
+		i += len(token)
+		operation := Operation{
+			Operator: Operator(token),
+			Operands: operands,
+		}
+		operands = []pdf.Object{}
+
+		switch operation.Operator {
+		case "BX":
+			compatibility++
+		case "EX":
+			if compatibility == 0 {
+				return operations, fmt.Errorf("offset %d: EX without BX", i)
+			}
+			compatibility--
+		case "BI":
+			image, n, err := parseInlineImage(data[i:])
+			if err != nil {
+				return operations, fmt.Errorf("offset %d: %v", i+n, err)
+			}
+			i += n
+			operation.Operands = append(operation.Operands, image)
+		case "ID", "EI":
+			return operations, fmt.Errorf("offset %d: %s outside of an inline image", i, operation.Operator)
+		default:
+			if !operation.Operator.Known() && compatibility == 0 && !compatible {
+				return operations, fmt.Errorf("offset %d: unknown operator %q", i, operation.Operator)
+			}
+		}
+
+		operations = append(operations, operation)
+	}
+
+	if len(operands) != 0 {
+		return operations, errors.New("operands without an operator at end of content stream")
+	}
+	if compatibility != 0 {
+		return operations, errors.New("BX without EX")
+	}
+
+	return operations, nil
+}
+
+// parses the part of an inline image after BI (§8.9.7)
+// returns the image as a Stream and the bytes consumed (including EI)
+func parseInlineImage(data []byte) (pdf.Stream, int, error) {
+	image := pdf.Stream{Dictionary: pdf.Dictionary{}}
+
+	// key-value pairs until ID
+	i := 0
+	for {
+		begin, ok := nextNonWhitespace(data[i:])
+		i += begin
+		if !ok {
+			return image, i, errors.New("expected ID")
+		}
+
+		if token := regularToken(data[i:]); string(token) == "ID" {
+			i += len(token)
+			break
+		}
+
+		key, n, err := pdf.ParseObject(data[i:])
+		if err != nil {
+			return image, i + n, err
+		}
+		i += n
+
+		name, ok := key.(pdf.Name)
+		if !ok {
+			return image, i, fmt.Errorf("expected a name, got %T", key)
+		}
+
+		value, n, err := pdf.ParseObject(data[i:])
+		if err != nil {
+			return image, i + n, err
+		}
+		i += n
+
+		image.Dictionary[name] = value
+	}
+
+	// a single white-space character follows ID
+	if i >= len(data) || !lexer.IsWhitespace(data[i]) {
+		return image, i, errors.New("expected white-space after ID")
+	}
+	i++
+	start := i
+
+	// when the size of the data is known, it can contain anything
+	if length, ok := inlineImageLength(image.Dictionary); ok && start+length <= len(data) {
+		end := start + length
+		begin, _ := nextNonWhitespace(data[end:])
+		if n, ok := matchEI(data[end+begin:]); ok {
+			image.Stream = data[start:end]
+			return image, end + begin + n, nil
+		}
+	}
+
+	// otherwise the data ends at the first EI surrounded by white-space
+	for j := start; j+2 <= len(data); j++ {
+		if j > start && !lexer.IsWhitespace(data[j-1]) {
+			continue
+		}
+
+		n, ok := matchEI(data[j:])
+		if !ok {
+			continue
+		}
+
+		end := j
+		if end > start {
+			end-- // the white-space before EI
+		}
+		image.Stream = data[start:end]
+		return image, j + n, nil
+	}
+
+	return image, len(data), errors.New("expected EI")
+}
+
+func matchEI(data []byte) (int, bool) {
+	if !bytes.HasPrefix(data, []byte("EI")) {
+		return 0, false
+	}
+	if len(data) > 2 && !lexer.IsWhitespace(data[2]) && !lexer.IsDelimiter(data[2]) {
+		return 0, false
+	}
+	return 2, true
+}
+
+// abbreviations used in inline images (§8.9.7 Table 91 and 92)
+var colorComponents = map[pdf.Name]int{
+	"G": 1, "DeviceGray": 1, "CalGray": 1,
+	"RGB": 3, "DeviceRGB": 3, "CalRGB": 3,
+	"CMYK": 4, "DeviceCMYK": 4,
+	"I": 1, "Indexed": 1,
+}
+
+// determines the length of unfiltered image data from the image parameters
+func inlineImageLength(params pdf.Dictionary) (int, bool) {
+	lookup := func(abbreviation, name pdf.Name) pdf.Object {
+		if value, ok := params[abbreviation]; ok {
+			return value
+		}
+		return params[name]
+	}
+
+	// PDF 2.0 gives the length of the (possibly filtered) data
+	if length, ok := lookup("L", "Length").(pdf.Integer); ok {
+		return int(length), true
+	}
+
+	if filter := lookup("F", "Filter"); filter != nil {
+		if array, ok := filter.(pdf.Array); !ok || len(array) != 0 {
+			return 0, false
+		}
+	}
+
+	width, ok1 := lookup("W", "Width").(pdf.Integer)
+	height, ok2 := lookup("H", "Height").(pdf.Integer)
+	if !ok1 || !ok2 || width < 0 || height < 0 {
+		return 0, false
+	}
+
+	components, bitsPerComponent := 1, 1
+	if mask, _ := lookup("IM", "ImageMask").(pdf.Boolean); !mask {
+		bpc, ok := lookup("BPC", "BitsPerComponent").(pdf.Integer)
+		if !ok {
+			return 0, false
+		}
+		bitsPerComponent = int(bpc)
+
+		switch colorSpace := lookup("CS", "ColorSpace").(type) {
+		case pdf.Name:
+			components, ok = colorComponents[colorSpace]
+		case pdf.Array:
+			// [/Indexed base hival lookup]
+			var family pdf.Name
+			if len(colorSpace) > 0 {
+				family, _ = colorSpace[0].(pdf.Name)
+			}
+			ok = family == "I" || family == "Indexed"
+		default:
+			ok = false
+		}
+		if !ok {
+			return 0, false
+		}
+	}
+
+	bytesPerRow := (int(width)*components*bitsPerComponent + 7) / 8
+	return int(height) * bytesPerRow, true
+}
+
+// the characters that can start an operand
+func startsObject(data []byte) bool {
+	switch data[0] {
+	case '/', '(', '<', '[', '+', '-', '.',
+		'0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	case 't', 'f', 'n':
+		switch string(regularToken(data)) {
+		case "true", "false", "null":
+			return true
+		}
+	}
+	return false
+}
+
+// skips white-space and comments
+func nextNonWhitespace(data []byte) (int, bool) {
+	for i := 0; i < len(data); i++ {
+		switch {
+		case data[i] == '%':
+			for i < len(data) && data[i] != '\r' && data[i] != '\n' {
+				i++
+			}
+		case !lexer.IsWhitespace(data[i]):
+			return i, true
+		}
+	}
+	return len(data), false
+}
+
+// the run of regular characters at the start of data
+func regularToken(data []byte) []byte {
+	end := 0
+	for end < len(data) && !lexer.IsWhitespace(data[end]) && !lexer.IsDelimiter(data[end]) {
+		end++
+	}
+	return data[:end]
+}
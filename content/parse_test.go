@@ -0,0 +1,159 @@
+package content
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/nathankerr/pdf"
+)
+
+func TestParse(t *testing.T) {
+	type test struct {
+		stream     string
+		operations []Operation
+	}
+	tests := []test{
+		// §7.8.2 Example
+		test{
+			stream: "0.0 G\n1.0 1.0 1.0 rg % a comment\n[(Hello) -120 (World)] TJ",
+			operations: []Operation{
+				Operation{"G", []pdf.Object{pdf.Real(0)}},
+				Operation{"rg", []pdf.Object{pdf.Real(1), pdf.Real(1), pdf.Real(1)}},
+				Operation{"TJ", []pdf.Object{pdf.Array{pdf.String("Hello"), pdf.Integer(-120), pdf.String("World")}}},
+			},
+		},
+		test{
+			stream: "BT/F1 12 Tf 72 712 Td(a)' 1 2(b)\" ET",
+			operations: []Operation{
+				Operation{"BT", []pdf.Object{}},
+				Operation{"Tf", []pdf.Object{pdf.Name("F1"), pdf.Integer(12)}},
+				Operation{"Td", []pdf.Object{pdf.Integer(72), pdf.Integer(712)}},
+				Operation{"'", []pdf.Object{pdf.String("a")}},
+				Operation{"\"", []pdf.Object{pdf.Integer(1), pdf.Integer(2), pdf.String("b")}},
+				Operation{"ET", []pdf.Object{}},
+			},
+		},
+		// §14.6.2 marked content with an inline property list
+		test{
+			stream: "/Span <</ActualText (x) /MCID 0>> BDC EMC",
+			operations: []Operation{
+				Operation{"BDC", []pdf.Object{pdf.Name("Span"), pdf.Dictionary{"ActualText": pdf.String("x"), "MCID": pdf.Integer(0)}}},
+				Operation{"EMC", []pdf.Object{}},
+			},
+		},
+		// §7.8.2 compatibility sections
+		test{
+			stream: "BX 1 true /X unknown BX EX null Unknown EX q",
+			operations: []Operation{
+				Operation{"BX", []pdf.Object{}},
+				Operation{"unknown", []pdf.Object{pdf.Integer(1), pdf.Boolean(true), pdf.Name("X")}},
+				Operation{"BX", []pdf.Object{}},
+				Operation{"EX", []pdf.Object{}},
+				Operation{"Unknown", []pdf.Object{pdf.Null{}}},
+				Operation{"EX", []pdf.Object{}},
+				Operation{"q", []pdf.Object{}},
+			},
+		},
+		// §8.9.7 inline image where the data contains "EI"
+		test{
+			stream: "q BI /W 2 /H 2 /CS /G /BPC 8 ID  EI \nEI Q",
+			operations: []Operation{
+				Operation{"q", []pdf.Object{}},
+				Operation{"BI", []pdf.Object{pdf.Stream{
+					Dictionary: pdf.Dictionary{"W": pdf.Integer(2), "H": pdf.Integer(2), "CS": pdf.Name("G"), "BPC": pdf.Integer(8)},
+					Stream:     []byte(" EI "),
+				}}},
+				Operation{"Q", []pdf.Object{}},
+			},
+		},
+		// filtered inline image data ends at EI
+		test{
+			stream: "BI /W 1 /H 1 /F /AHx /CS /RGB /BPC 8 ID\nFF00FF>\nEI",
+			operations: []Operation{
+				Operation{"BI", []pdf.Object{pdf.Stream{
+					Dictionary: pdf.Dictionary{"W": pdf.Integer(1), "H": pdf.Integer(1), "F": pdf.Name("AHx"), "CS": pdf.Name("RGB"), "BPC": pdf.Integer(8)},
+					Stream:     []byte("FF00FF>"),
+				}}},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		operations, err := Parse([]byte(test.stream))
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(operations, test.operations) {
+			t.Errorf("%d:\nexpected %#v\ngot      %#v", i, test.operations, operations)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for i, stream := range []string{
+		"1 0 0 unknown",
+		"BX q",
+		"EX",
+		"1 2",
+		"BI /W 1 /H 1 ID",
+		"ID",
+		") Tj",
+	} {
+		_, err := Parse([]byte(stream))
+		if err == nil {
+			t.Errorf("%d: expected an error for %q", i, stream)
+		}
+	}
+}
+
+func TestParseCompatible(t *testing.T) {
+	operations, err := ParseCompatible([]byte("q 1 0 0 unknown Q"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Operation{
+		{"q", []pdf.Object{}},
+		{"unknown", []pdf.Object{pdf.Integer(1), pdf.Integer(0), pdf.Integer(0)}},
+		{"Q", []pdf.Object{}},
+	}
+	if !reflect.DeepEqual(operations, expected) {
+		t.Errorf("expected %v, got %v", expected, operations)
+	}
+
+	// other errors are still reported
+	_, err = ParseCompatible([]byte("1 2"))
+	if err == nil {
+		t.Error("expected an error for missing operator")
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	stream := []byte(`q 1 0 0 1 72.5 -0.25 cm
+BT /F1 12 Tf [(Unbalanced \( paren) -250 <00ff>] TJ ET
+/OC /oc1 BDC BX 1 2 3 sh2 EX EMC
+BI /W 4 /H 1 /BPC 8 /CS /G /D [1 0] ID  EI
+EI
+/Im1 Do Q`)
+
+	operations, err := Parse(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = Write(buf, operations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reparsed, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("%v\n%s", err, buf.Bytes())
+	}
+
+	if !reflect.DeepEqual(operations, reparsed) {
+		t.Errorf("expected %v\ngot %v\n%s", operations, reparsed, buf.Bytes())
+	}
+}
@@ -0,0 +1,95 @@
+package content
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/nathankerr/pdf"
+)
+
+// Write serializes the operations as a content stream,
+// one operation per line.
+// Parsing the result gives the same operations.
+func Write(w io.Writer, operations []Operation) (int64, error) {
+	buf := &bytes.Buffer{}
+
+	for _, operation := range operations {
+		err := operation.write(buf)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return buf.WriteTo(w)
+}
+
+func (operation Operation) write(buf *bytes.Buffer) error {
+	operands := operation.Operands
+
+	// inline images are the parameters and data between BI and EI
+	var image pdf.Stream
+	if operation.Operator == "BI" {
+		if len(operands) == 0 {
+			return errors.New("BI without an inline image")
+		}
+
+		var ok bool
+		image, ok = operands[len(operands)-1].(pdf.Stream)
+		if !ok {
+			return errors.New("BI without an inline image")
+		}
+		operands = operands[:len(operands)-1]
+	}
+
+	for _, operand := range operands {
+		_, err := pdf.WriteObject(buf, operand)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(string(operation.Operator))
+
+	if operation.Operator == "BI" {
+		params, err := writeDictionaryEntries(image.Dictionary)
+		if err != nil {
+			return err
+		}
+		buf.Write(params)
+
+		buf.WriteString(" ID ")
+		buf.Write(image.Stream)
+		buf.WriteString("\nEI")
+	}
+
+	buf.WriteByte('\n')
+	return nil
+}
+
+// writes the entries of a dictionary without the << and >>
+func writeDictionaryEntries(dict pdf.Dictionary) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	_, err := pdf.WriteObject(buf, dict)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := buf.Bytes()
+	entries = entries[2 : len(entries)-2]
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	return append([]byte{' '}, entries...), nil
+}
+
+// String returns the operation as it appears in a content stream.
+func (operation Operation) String() string {
+	buf := &bytes.Buffer{}
+	err := operation.write(buf)
+	if err != nil {
+		return "<" + err.Error() + ">"
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+}
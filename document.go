@@ -0,0 +1,270 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Document is the document structure (§7.7) of a file: the
+// catalog and the page tree it refers to.
+type Document struct {
+	File    *File
+	Catalog Catalog
+}
+
+// Catalog is the document catalog (§7.7.2), the root of the
+// document structure.
+type Catalog struct {
+	ObjectReference
+	Dictionary
+}
+
+// Pages returns the root of the page tree.
+func (c Catalog) Pages() (ObjectReference, bool) {
+	ref, ok := c.Dictionary["Pages"].(ObjectReference)
+	return ref, ok
+}
+
+// NewDocument returns the document structure of the file. When the
+// file does not have a catalog, one with an empty page tree is added.
+func NewDocument(file *File) (*Document, error) {
+	d := &Document{File: file}
+
+	if file.Root.ObjectNumber == 0 {
+		pagesRef, err := file.Add(Dictionary{
+			"Type":  Name("Pages"),
+			"Kids":  Array{},
+			"Count": Integer(0),
+		})
+		if err != nil {
+			return nil, err
+		}
+		file.Root, err = file.Add(Dictionary{
+			"Type":  Name("Catalog"),
+			"Pages": pagesRef,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	catalog, ok := file.Get(file.Root).(Dictionary)
+	if !ok {
+		return nil, errors.New("document catalog is not a dictionary")
+	}
+	d.Catalog = Catalog{ObjectReference: file.Root, Dictionary: catalog}
+	if _, ok := d.Catalog.Pages(); !ok {
+		return nil, errors.New("document catalog does not have a page tree")
+	}
+	return d, nil
+}
+
+// Page is a page object (§7.7.3.3) in a document's page tree.
+type Page struct {
+	ObjectReference
+	Dictionary
+
+	// the page tree nodes from the page's parent to the root,
+	// from which attributes are inherited
+	parents []Dictionary
+	file    *File
+}
+
+// Pages returns the pages of the document in order.
+func (d *Document) Pages() ([]Page, error) {
+	root, _ := d.Catalog.Pages()
+	pages := []Page{}
+	err := d.pages(root, nil, map[ObjectReference]bool{}, &pages)
+	if err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// appends the pages under the page tree node (§7.7.3.2), whose
+// ancestors are parents
+func (d *Document) pages(ref ObjectReference, parents []Dictionary, visited map[ObjectReference]bool, pages *[]Page) error {
+	if visited[ref] {
+		return fmt.Errorf("page tree node %v is in the page tree more than once", ref)
+	}
+	visited[ref] = true
+
+	node, ok := d.File.Get(ref).(Dictionary)
+	if !ok {
+		return fmt.Errorf("page tree node %v is not a dictionary", ref)
+	}
+
+	// Type is sometimes missing, so page tree nodes are told
+	// apart from pages by their Kids
+	kids, isNode := d.File.resolve(node["Kids"]).(Array)
+	if node["Type"] == Name("Page") || (!isNode && node["Type"] != Name("Pages")) {
+		*pages = append(*pages, Page{
+			ObjectReference: ref,
+			Dictionary:      node,
+			parents:         parents,
+			file:            d.File,
+		})
+		return nil
+	}
+
+	// the nearest ancestor is first
+	parents = append([]Dictionary{node}, parents...)
+	for _, kid := range kids {
+		kidRef, ok := kid.(ObjectReference)
+		if !ok {
+			return fmt.Errorf("kid of page tree node %v is not an indirect reference", ref)
+		}
+		err := d.pages(kidRef, parents, visited, pages)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Inherited returns the resolved value of the key from the page or,
+// when the page does not have it, from the nearest page tree node
+// that does (§7.7.3.4). Nil is returned when none have the key.
+func (p Page) Inherited(key Name) Object {
+	if value, ok := p.Dictionary[key]; ok {
+		return p.file.resolve(value)
+	}
+	for _, parent := range p.parents {
+		if value, ok := parent[key]; ok {
+			return p.file.resolve(value)
+		}
+	}
+	return nil
+}
+
+// MediaBox returns the boundaries of the physical medium the page
+// is displayed or printed on (§14.11.2).
+func (p Page) MediaBox() (Rectangle, error) {
+	obj := p.Inherited("MediaBox")
+	if obj == nil {
+		return Rectangle{}, fmt.Errorf("page %v does not have a MediaBox", p.ObjectReference)
+	}
+	return p.rectangle(obj)
+}
+
+// CropBox returns the region the page is clipped to when displayed
+// or printed. It defaults to the MediaBox and is limited to the
+// MediaBox (§14.11.2).
+func (p Page) CropBox() (Rectangle, error) {
+	mediaBox, err := p.MediaBox()
+	if err != nil {
+		return Rectangle{}, err
+	}
+	obj := p.Inherited("CropBox")
+	if obj == nil {
+		return mediaBox, nil
+	}
+	cropBox, err := p.rectangle(obj)
+	if err != nil {
+		return Rectangle{}, err
+	}
+	return cropBox.Intersect(mediaBox), nil
+}
+
+// Resources returns the page's resource dictionary (§7.8.3),
+// which is nil when the page has no resources.
+func (p Page) Resources() Dictionary {
+	resources, _ := p.Inherited("Resources").(Dictionary)
+	return resources
+}
+
+// Rotate returns the number of degrees, one of 0, 90, 180 or 270,
+// the page is rotated clockwise by when displayed or printed.
+func (p Page) Rotate() int {
+	rotate, _ := p.Inherited("Rotate").(Integer)
+	// multiples of 90 that are negative or larger than 270
+	// are the same as those in 0 to 270
+	return ((int(rotate)/90)%4 + 4) % 4 * 90
+}
+
+func (p Page) rectangle(obj Object) (Rectangle, error) {
+	array, ok := obj.(Array)
+	if !ok || len(array) != 4 {
+		return Rectangle{}, fmt.Errorf("page %v rectangle %v is not an array of 4 numbers", p.ObjectReference, obj)
+	}
+	values := [4]float64{}
+	for i, value := range array {
+		switch n := p.file.resolve(value).(type) {
+		case Integer:
+			values[i] = float64(n)
+		case Real:
+			values[i] = float64(n)
+		default:
+			return Rectangle{}, fmt.Errorf("page %v rectangle %v is not an array of 4 numbers", p.ObjectReference, obj)
+		}
+	}
+	return NewRectangle(values[0], values[1], values[2], values[3]), nil
+}
+
+// Rectangle is a rectangle (§7.9.5) given by its lower-left
+// and upper-right corners.
+type Rectangle struct {
+	LLX, LLY, URX, URY float64
+}
+
+// NewRectangle returns the rectangle with the diagonally opposite
+// corners (x1, y1) and (x2, y2).
+func NewRectangle(x1, y1, x2, y2 float64) Rectangle {
+	r := Rectangle{x1, y1, x2, y2}
+	if r.LLX > r.URX {
+		r.LLX, r.URX = r.URX, r.LLX
+	}
+	if r.LLY > r.URY {
+		r.LLY, r.URY = r.URY, r.LLY
+	}
+	return r
+}
+
+// Width returns the horizontal size of the rectangle.
+func (r Rectangle) Width() float64 {
+	return r.URX - r.LLX
+}
+
+// Height returns the vertical size of the rectangle.
+func (r Rectangle) Height() float64 {
+	return r.URY - r.LLY
+}
+
+// Intersect returns the largest rectangle contained by both. It is
+// empty when they do not overlap.
+func (r Rectangle) Intersect(s Rectangle) Rectangle {
+	if s.LLX > r.LLX {
+		r.LLX = s.LLX
+	}
+	if s.LLY > r.LLY {
+		r.LLY = s.LLY
+	}
+	if s.URX < r.URX {
+		r.URX = s.URX
+	}
+	if s.URY < r.URY {
+		r.URY = s.URY
+	}
+	if r.LLX > r.URX || r.LLY > r.URY {
+		return Rectangle{}
+	}
+	return r
+}
+
+// Array returns the rectangle as an array of numbers.
+func (r Rectangle) Array() Array {
+	return numbers(r.LLX, r.LLY, r.URX, r.URY)
+}
+
+// an array of the values, which are integers when they are whole
+func numbers(values ...float64) Array {
+	array := Array{}
+	for _, value := range values {
+		if value == float64(int64(value)) {
+			array = append(array, Integer(value))
+		} else {
+			array = append(array, Real(value))
+		}
+	}
+	return array
+}
@@ -0,0 +1,149 @@
+package pdf
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDocumentPages(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "document.pdf")
+	file, err := Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	add := func(obj Object) ObjectReference {
+		ref, err := file.Add(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ref
+	}
+	set := func(ref ObjectReference, obj Object) {
+		_, err := file.Add(IndirectObject{ref, obj})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// root
+	//   page 1 (own MediaBox, Rotate -90)
+	//   node (no Type, CropBox, Rotate 180)
+	//     page 2
+	//     page 3 (own Resources)
+	rootRef := add(Null{})
+	nodeRef := add(Null{})
+	resourcesRef := add(Dictionary{"Font": Dictionary{}})
+	mediaBoxRef := add(Array{Integer(0), Integer(0), Integer(612), Integer(792)})
+	page1 := add(Dictionary{
+		"Type":     Name("Page"),
+		"Parent":   rootRef,
+		"MediaBox": Array{Integer(0), Integer(0), Real(595.5), Integer(842)},
+		"Rotate":   Integer(-90),
+	})
+	page2 := add(Dictionary{"Type": Name("Page"), "Parent": nodeRef})
+	page3 := add(Dictionary{"Type": Name("Page"), "Parent": nodeRef, "Resources": Dictionary{}})
+	set(nodeRef, Dictionary{
+		"Parent":  rootRef,
+		"Kids":    Array{page2, page3},
+		"Count":   Integer(2),
+		"CropBox": Array{Integer(-10), Integer(700), Integer(100), Integer(10)},
+		"Rotate":  Integer(180),
+	})
+	set(rootRef, Dictionary{
+		"Type":      Name("Pages"),
+		"Kids":      Array{page1, nodeRef},
+		"Count":     Integer(3),
+		"MediaBox":  mediaBoxRef,
+		"Resources": resourcesRef,
+	})
+	file.Root = add(Dictionary{"Type": Name("Catalog"), "Pages": rootRef})
+
+	document, err := NewDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := document.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := []ObjectReference{}
+	for _, page := range pages {
+		refs = append(refs, page.ObjectReference)
+	}
+	if !reflect.DeepEqual(refs, []ObjectReference{page1, page2, page3}) {
+		t.Fatalf("expected pages %v, got %v", []ObjectReference{page1, page2, page3}, refs)
+	}
+
+	for i, expected := range []struct {
+		mediaBox  Rectangle
+		cropBox   Rectangle
+		rotate    int
+		resources Dictionary
+	}{
+		{Rectangle{0, 0, 595.5, 842}, Rectangle{0, 0, 595.5, 842}, 270, Dictionary{"Font": Dictionary{}}},
+		{Rectangle{0, 0, 612, 792}, Rectangle{0, 10, 100, 700}, 180, Dictionary{"Font": Dictionary{}}},
+		{Rectangle{0, 0, 612, 792}, Rectangle{0, 10, 100, 700}, 180, Dictionary{}},
+	} {
+		page := pages[i]
+		mediaBox, err := page.MediaBox()
+		if err != nil || mediaBox != expected.mediaBox {
+			t.Errorf("page %d: expected MediaBox %v, got %v %v", i+1, expected.mediaBox, mediaBox, err)
+		}
+		cropBox, err := page.CropBox()
+		if err != nil || cropBox != expected.cropBox {
+			t.Errorf("page %d: expected CropBox %v, got %v %v", i+1, expected.cropBox, cropBox, err)
+		}
+		if page.Rotate() != expected.rotate {
+			t.Errorf("page %d: expected Rotate %d, got %d", i+1, expected.rotate, page.Rotate())
+		}
+		if !reflect.DeepEqual(page.Resources(), expected.resources) {
+			t.Errorf("page %d: expected Resources %v, got %v", i+1, expected.resources, page.Resources())
+		}
+	}
+
+	// a page tree node reached twice
+	set(nodeRef, Dictionary{"Kids": Array{page2, nodeRef}})
+	_, err = document.Pages()
+	if err == nil {
+		t.Error("expected an error for a page tree cycle")
+	}
+}
+
+func TestNewDocumentCreatesCatalog(t *testing.T) {
+	file, err := Create(filepath.Join(t.TempDir(), "new.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	document, err := NewDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Root != document.Catalog.ObjectReference || document.Catalog.Dictionary["Type"] != Name("Catalog") {
+		t.Errorf("unexpected catalog %v", document.Catalog)
+	}
+	pages, err := document.Pages()
+	if err != nil || len(pages) != 0 {
+		t.Errorf("expected no pages, got %v %v", pages, err)
+	}
+}
+
+func TestRectangle(t *testing.T) {
+	r := NewRectangle(100, 200, 0, 50)
+	if r != (Rectangle{0, 50, 100, 200}) || r.Width() != 100 || r.Height() != 150 {
+		t.Errorf("unexpected normalized rectangle %v", r)
+	}
+	if i := r.Intersect(Rectangle{50, 0, 300, 100}); i != (Rectangle{50, 50, 100, 100}) {
+		t.Errorf("unexpected intersection %v", i)
+	}
+	if i := r.Intersect(Rectangle{200, 0, 300, 100}); i != (Rectangle{}) {
+		t.Errorf("expected an empty intersection, got %v", i)
+	}
+	if a := (Rectangle{0, 0, 595.5, 842}).Array(); !reflect.DeepEqual(a, Array{Integer(0), Integer(0), Real(595.5), Integer(842)}) {
+		t.Errorf("unexpected array %v", a)
+	}
+}
@@ -0,0 +1,1000 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Permissions are the operations allowed to users that open an
+// encrypted file with its user password.
+// - §7.6.4.2 Table 22
+type Permissions uint32
+
+const (
+	PermissionPrint            Permissions = 1 << 2
+	PermissionModify           Permissions = 1 << 3
+	PermissionCopy             Permissions = 1 << 4
+	PermissionAnnotate         Permissions = 1 << 5
+	PermissionFillForms        Permissions = 1 << 8
+	PermissionExtract          Permissions = 1 << 9 // for accessibility
+	PermissionAssemble         Permissions = 1 << 10
+	PermissionPrintHighQuality Permissions = 1 << 11
+)
+
+// An EncryptionAlgorithm is the cipher used to encrypt strings and streams.
+type EncryptionAlgorithm int
+
+const (
+	AES256 EncryptionAlgorithm = iota // revision 6 of the standard security handler
+	AES128                            // revision 4 of the standard security handler
+)
+
+// Encryption describes how a File is encrypted with the standard
+// security handler, or with the public-key security handler
+// when it has Recipients.
+type Encryption struct {
+	// The password needed to open the file, which may be empty.
+	UserPassword string
+
+	// The password that grants all permissions.
+	// The user password is used when it is empty.
+	OwnerPassword string
+
+	// Permissions granted to users who open the file with the user password.
+	Permissions Permissions
+
+	Algorithm EncryptionAlgorithm
+
+	// When UnencryptedMetadata is set, metadata streams
+	// are left readable, such as for search engines.
+	UnencryptedMetadata bool
+
+	// Certificates of the recipients who can open the file with
+	// their private keys, see OpenWithCertificate.
+	// The passwords are not used when there are recipients.
+	Recipients []*x509.Certificate
+}
+
+// ErrPassword is returned when an encrypted file is opened with
+// a password that is neither its user nor its owner password.
+var ErrPassword = errors.New("incorrect password")
+
+// pads passwords for revisions 2 to 4 (§7.6.4.3.2 Algorithm 2)
+var passwordPadding = []byte{
+	0x28, 0xbf, 0x4e, 0x5e, 0x4e, 0x75, 0x8a, 0x41,
+	0x64, 0x00, 0x4e, 0x56, 0xff, 0xfa, 0x01, 0x08,
+	0x2e, 0x2e, 0x00, 0xb6, 0xd0, 0x68, 0x3e, 0x80,
+	0x2f, 0x0c, 0xa9, 0xfe, 0x64, 0x53, 0x69, 0x7a,
+}
+
+// the standard security handler (§7.6.4)
+type securityHandler struct {
+	v, r            int
+	key             []byte // file encryption key
+	owner           bool   // opened with the owner password
+	encryptMetadata bool
+
+	o, u []byte
+	p    uint32
+	id   []byte // first element of the file identifier
+
+	// crypt filter methods (§7.6.6 Table 25) for
+	// streams, strings and embedded files
+	stmF, strF, eff Name
+	filters         map[Name]Name
+}
+
+// creates the security handler for an encryption
+// dictionary and authenticates the password
+func newSecurityHandler(encrypt Dictionary, id Array, password string) (*securityHandler, error) {
+	if filter, _ := encrypt["Filter"].(Name); filter != "Standard" {
+		return nil, fmt.Errorf("the %s security handler is not supported", filter)
+	}
+
+	h, n, err := parseEncryption(encrypt)
+	if err != nil {
+		return nil, err
+	}
+	r, _ := encrypt["R"].(Integer)
+	h.r = int(r)
+	o, _ := encrypt["O"].(String)
+	u, _ := encrypt["U"].(String)
+	h.o, h.u = []byte(o), []byte(u)
+	p, _ := encrypt["P"].(Integer)
+	h.p = uint32(p)
+	if len(id) > 0 {
+		if first, ok := id[0].(String); ok {
+			h.id = []byte(first)
+		}
+	}
+
+	switch h.r {
+	case 2, 3, 4:
+		if len(h.o) < 32 || len(h.u) < 32 {
+			return nil, errors.New("O and U must be 32 bytes long")
+		}
+		if h.authenticateOwner(password, n) || h.authenticateUser(passwordBytes(password), n) {
+			return h, nil
+		}
+	case 5, 6:
+		if len(h.o) < 48 || len(h.u) < 48 {
+			return nil, errors.New("O and U must be 48 bytes long")
+		}
+		oe, _ := encrypt["OE"].(String)
+		ue, _ := encrypt["UE"].(String)
+		ok, err := h.authenticateAES256(password, []byte(oe), []byte(ue))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return h, nil
+		}
+	default:
+		return nil, fmt.Errorf("standard security handler revision %d is not supported", h.r)
+	}
+
+	return nil, ErrPassword
+}
+
+// the parts of an encryption dictionary (§7.6.2 Table 20) common
+// to the security handlers, and the key length in bytes
+func parseEncryption(encrypt Dictionary) (*securityHandler, int, error) {
+	h := &securityHandler{
+		encryptMetadata: true,
+		filters:         map[Name]Name{"Identity": "None"},
+	}
+	v, _ := encrypt["V"].(Integer)
+	h.v = int(v)
+	if encryptMetadata, ok := encrypt["EncryptMetadata"].(Boolean); ok {
+		h.encryptMetadata = bool(encryptMetadata)
+	}
+
+	// key length in bytes, the crypt filter's
+	// length is used when the dictionary has none
+	n := 5
+	length, hasLength := encrypt["Length"].(Integer)
+	switch {
+	case hasLength && h.v > 1:
+		n = int(length) / 8
+	case h.v == 4:
+		n = 16
+	}
+
+	switch h.v {
+	case 1, 2:
+		h.stmF, h.strF, h.eff = "StdCF", "StdCF", "StdCF"
+		h.filters["StdCF"] = "V2"
+	case 4, 5:
+		cf, _ := encrypt["CF"].(Dictionary)
+		for name, filter := range cf {
+			filter, ok := filter.(Dictionary)
+			if !ok {
+				continue
+			}
+			method, _ := filter["CFM"].(Name)
+			switch method {
+			case "", "None":
+				method = "None"
+			case "V2", "AESV2", "AESV3":
+			default:
+				return nil, 0, fmt.Errorf("the %s crypt filter method is not supported", method)
+			}
+			h.filters[name] = method
+
+			// in bytes, though some writers use bits
+			if length, ok := filter["Length"].(Integer); ok && h.v == 4 && !hasLength {
+				n = int(length)
+				if n >= 40 {
+					n /= 8
+				}
+			}
+		}
+
+		h.stmF, h.strF = "Identity", "Identity"
+		if stmF, ok := encrypt["StmF"].(Name); ok {
+			h.stmF = stmF
+		}
+		if strF, ok := encrypt["StrF"].(Name); ok {
+			h.strF = strF
+		}
+		h.eff = h.stmF
+		if eff, ok := encrypt["EFF"].(Name); ok {
+			h.eff = eff
+		}
+		for _, name := range []Name{h.stmF, h.strF, h.eff} {
+			if _, ok := h.filters[name]; !ok {
+				return nil, 0, fmt.Errorf("crypt filter %s is not defined", name)
+			}
+		}
+	default:
+		return nil, 0, fmt.Errorf("encryption algorithm %d is not supported", h.v)
+	}
+	if h.v < 5 && (n < 5 || n > 16) {
+		return nil, 0, fmt.Errorf("%d bit encryption keys are not supported", n*8)
+	}
+
+	return h, n, nil
+}
+
+// creates the security handler and encryption dictionary that
+// encrypt a file with the file identifier id
+func newEncryption(e *Encryption, id []byte) (*securityHandler, Dictionary, error) {
+	if len(e.Recipients) > 0 {
+		return newPublicKeyEncryption(e)
+	}
+
+	h := &securityHandler{
+		encryptMetadata: !e.UnencryptedMetadata,
+		id:              id,
+		stmF:            "StdCF",
+		strF:            "StdCF",
+		eff:             "StdCF",
+		filters:         map[Name]Name{"Identity": "None"},
+	}
+	// the reserved bits are set (§7.6.4.2 Table 22)
+	h.p = uint32(e.Permissions)&0xf3c | 0xfffff0c0
+
+	owner := e.OwnerPassword
+	if owner == "" {
+		owner = e.UserPassword
+	}
+
+	encrypt := Dictionary{
+		"Filter": Name("Standard"),
+		"P":      Integer(int32(h.p)),
+		"StmF":   Name("StdCF"),
+		"StrF":   Name("StdCF"),
+	}
+	if !h.encryptMetadata {
+		encrypt["EncryptMetadata"] = Boolean(false)
+	}
+
+	switch e.Algorithm {
+	case AES128:
+		h.v, h.r = 4, 4
+		h.filters["StdCF"] = "AESV2"
+		h.o = h.ownerEntry(passwordBytes(e.UserPassword), passwordBytes(owner), 16)
+		h.key = h.fileKey(passwordBytes(e.UserPassword), 16)
+		h.u = h.userEntry(h.key)
+
+		encrypt["V"] = Integer(4)
+		encrypt["R"] = Integer(4)
+		encrypt["Length"] = Integer(128)
+		encrypt["CF"] = Dictionary{"StdCF": Dictionary{
+			"CFM":       Name("AESV2"),
+			"AuthEvent": Name("DocOpen"),
+			"Length":    Integer(16),
+		}}
+	case AES256:
+		h.v, h.r = 5, 6
+		h.filters["StdCF"] = "AESV3"
+		h.key = make([]byte, 32)
+		_, err := rand.Read(h.key)
+		if err != nil {
+			return nil, nil, err
+		}
+		ue, oe, err := h.aes256Entries(e.UserPassword, owner)
+		if err != nil {
+			return nil, nil, err
+		}
+		perms, err := h.perms()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		encrypt["V"] = Integer(5)
+		encrypt["R"] = Integer(6)
+		encrypt["Length"] = Integer(256)
+		encrypt["CF"] = Dictionary{"StdCF": Dictionary{
+			"CFM":       Name("AESV3"),
+			"AuthEvent": Name("DocOpen"),
+			"Length":    Integer(32),
+		}}
+		encrypt["UE"] = String(ue)
+		encrypt["OE"] = String(oe)
+		encrypt["Perms"] = String(perms)
+	default:
+		return nil, nil, fmt.Errorf("unknown encryption algorithm %d", e.Algorithm)
+	}
+	encrypt["O"] = String(h.o)
+	encrypt["U"] = String(h.u)
+
+	return h, encrypt, nil
+}
+
+// passwords for revisions 2 to 4 are in PDFDocEncoding,
+// which matches Latin-1 for most characters
+func passwordBytes(password string) []byte {
+	encoded := []byte{}
+	for _, r := range password {
+		if r < 256 {
+			encoded = append(encoded, byte(r))
+		}
+	}
+	return encoded
+}
+
+func padPassword(password []byte) []byte {
+	padded := append([]byte{}, password...)
+	if len(padded) > 32 {
+		padded = padded[:32]
+	}
+	return append(padded, passwordPadding[:32-len(padded)]...)
+}
+
+// the file encryption key for a user password (§7.6.4.3.2 Algorithm 2)
+func (h *securityHandler) fileKey(password []byte, n int) []byte {
+	digest := md5.New()
+	digest.Write(padPassword(password))
+	digest.Write(h.o[:32])
+	binary.Write(digest, binary.LittleEndian, h.p)
+	digest.Write(h.id)
+	if h.r >= 4 && !h.encryptMetadata {
+		digest.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	key := digest.Sum(nil)
+
+	if h.r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum := md5.Sum(key[:n])
+			key = sum[:]
+		}
+	}
+	if h.r == 2 {
+		n = 5
+	}
+	return key[:n]
+}
+
+// the U entry for a file encryption key
+// (§7.6.4.4.3 Algorithm 4 and §7.6.4.4.4 Algorithm 5)
+func (h *securityHandler) userEntry(key []byte) []byte {
+	if h.r == 2 {
+		return rc4Crypt(key, passwordPadding)
+	}
+
+	digest := md5.New()
+	digest.Write(passwordPadding)
+	digest.Write(h.id)
+	u := rc4Iterations(key, digest.Sum(nil), false)
+
+	// arbitrary padding to 32 bytes
+	return append(u, make([]byte, 16)...)
+}
+
+// §7.6.4.4.5 Algorithm 6
+func (h *securityHandler) authenticateUser(password []byte, n int) bool {
+	key := h.fileKey(password, n)
+	u := h.userEntry(key)
+
+	length := 32
+	if h.r >= 3 {
+		length = 16
+	}
+	if !bytes.Equal(u[:length], h.u[:length]) {
+		return false
+	}
+
+	h.key = key
+	return true
+}
+
+// §7.6.4.4.6 Algorithm 7
+func (h *securityHandler) authenticateOwner(password string, n int) bool {
+	key := h.ownerKey(passwordBytes(password), n)
+	var user []byte
+	if h.r == 2 {
+		user = rc4Crypt(key, h.o[:32])
+	} else {
+		user = rc4Iterations(key, h.o[:32], true)
+	}
+
+	if !h.authenticateUser(user, n) {
+		return false
+	}
+	h.owner = true
+	return true
+}
+
+// the O entry for revisions 2 to 4 (§7.6.4.4.2 Algorithm 3)
+func (h *securityHandler) ownerEntry(user, owner []byte, n int) []byte {
+	key := h.ownerKey(owner, n)
+	if h.r == 2 {
+		return rc4Crypt(key, padPassword(user))
+	}
+	return rc4Iterations(key, padPassword(user), false)
+}
+
+// the RC4 key used for the O entry (§7.6.4.4.2 Algorithm 3 steps a to d)
+func (h *securityHandler) ownerKey(password []byte, n int) []byte {
+	sum := md5.Sum(padPassword(password))
+	key := sum[:]
+	if h.r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(key)
+			key = sum[:]
+		}
+	}
+	if h.r == 2 {
+		n = 5
+	}
+	return key[:n]
+}
+
+// encrypts or decrypts data with RC4 20 times, the nth time
+// with the key's bytes xored with n
+func rc4Iterations(key, data []byte, decrypt bool) []byte {
+	xored := make([]byte, len(key))
+	for i := 0; i < 20; i++ {
+		n := i
+		if decrypt {
+			n = 19 - i
+		}
+		for j := range key {
+			xored[j] = key[j] ^ byte(n)
+		}
+		data = rc4Crypt(xored, data)
+	}
+	return data
+}
+
+func rc4Crypt(key, data []byte) []byte {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		panic(err) // keys are between 5 and 16 bytes long
+	}
+	crypted := make([]byte, len(data))
+	c.XORKeyStream(crypted, data)
+	return crypted
+}
+
+// authenticates revisions 5 and 6 (§7.6.4.3.3 Algorithm 2.A)
+func (h *securityHandler) authenticateAES256(password string, oe, ue []byte) (bool, error) {
+	pw := aes256Password(password)
+
+	var encryptedKey, intermediate []byte
+	switch {
+	case bytes.Equal(h.hash(pw, h.o[32:40], h.u[:48]), h.o[:32]):
+		h.owner = true
+		intermediate = h.hash(pw, h.o[40:48], h.u[:48])
+		encryptedKey = oe
+	case bytes.Equal(h.hash(pw, h.u[32:40], nil), h.u[:32]):
+		intermediate = h.hash(pw, h.u[40:48], nil)
+		encryptedKey = ue
+	default:
+		return false, nil
+	}
+	if len(encryptedKey) != 32 {
+		return false, errors.New("OE and UE must be 32 bytes long")
+	}
+
+	// AES-256 without padding and with a zero initialization vector
+	block, err := aes.NewCipher(intermediate)
+	if err != nil {
+		return false, err
+	}
+	h.key = make([]byte, 32)
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(h.key, encryptedKey)
+	return true, nil
+}
+
+// sets U and O for the file encryption key, returning UE and OE
+// (§7.6.4.4.7 Algorithm 8 and §7.6.4.4.8 Algorithm 9)
+func (h *securityHandler) aes256Entries(user, owner string) (ue, oe []byte, err error) {
+	// validation and key salts for the user and owner passwords
+	salts := make([]byte, 32)
+	_, err = rand.Read(salts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pw := aes256Password(user)
+	h.u = append(h.hash(pw, salts[0:8], nil), salts[0:16]...)
+	ue = aes256EncryptKey(h.hash(pw, salts[8:16], nil), h.key)
+
+	pw = aes256Password(owner)
+	h.o = append(h.hash(pw, salts[16:24], h.u), salts[16:32]...)
+	oe = aes256EncryptKey(h.hash(pw, salts[24:32], h.u), h.key)
+
+	return ue, oe, nil
+}
+
+// encrypts the file encryption key with AES-256 without
+// padding and with a zero initialization vector
+func aes256EncryptKey(intermediate, key []byte) []byte {
+	block, err := aes.NewCipher(intermediate)
+	if err != nil {
+		panic(err) // the intermediate key is 32 bytes long
+	}
+	encrypted := make([]byte, len(key))
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(encrypted, key)
+	return encrypted
+}
+
+// the Perms entry (§7.6.4.4.9 Algorithm 10)
+func (h *securityHandler) perms() ([]byte, error) {
+	perms := make([]byte, 16)
+	binary.LittleEndian.PutUint32(perms, h.p)
+	copy(perms[4:], []byte{0xff, 0xff, 0xff, 0xff, 'F', 'a', 'd', 'b'})
+	if h.encryptMetadata {
+		perms[8] = 'T'
+	}
+	_, err := rand.Read(perms[12:])
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(h.key)
+	if err != nil {
+		return nil, err
+	}
+	block.Encrypt(perms, perms)
+	return perms, nil
+}
+
+// passwords for revisions 5 and 6 are UTF-8 limited to 127 bytes
+func aes256Password(password string) []byte {
+	pw := []byte(password)
+	if len(pw) > 127 {
+		pw = pw[:127]
+	}
+	return pw
+}
+
+// the password hash for revisions 5 and 6 (§7.6.4.3.4 Algorithm 2.B)
+func (h *securityHandler) hash(password, salt, userKey []byte) []byte {
+	digest := sha256.New()
+	digest.Write(password)
+	digest.Write(salt)
+	digest.Write(userKey)
+	k := digest.Sum(nil)
+	if h.r == 5 {
+		return k
+	}
+
+	for round := 0; ; round++ {
+		sequence := append(append(append([]byte{}, password...), k...), userKey...)
+		k1 := bytes.Repeat(sequence, 64)
+
+		block, err := aes.NewCipher(k[:16])
+		if err != nil {
+			panic(err) // k is at least 32 bytes long
+		}
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+
+		// the first 16 bytes of e as a number modulo 3
+		sum := 0
+		for _, b := range e[:16] {
+			sum += int(b)
+		}
+		var next hash.Hash
+		switch sum % 3 {
+		case 0:
+			next = sha256.New()
+		case 1:
+			next = sha512.New384()
+		case 2:
+			next = sha512.New()
+		}
+		next.Write(e)
+		k = next.Sum(nil)
+
+		if round >= 63 && int(e[len(e)-1]) <= round-31 {
+			break
+		}
+	}
+	return k[:32]
+}
+
+// the key for an object with a crypt filter method (§7.6.3.1 Algorithm 1)
+func (h *securityHandler) objectKey(ref ObjectReference, method Name) []byte {
+	if method == "AESV3" {
+		return h.key
+	}
+
+	digest := md5.New()
+	digest.Write(h.key)
+	digest.Write([]byte{
+		byte(ref.ObjectNumber), byte(ref.ObjectNumber >> 8), byte(ref.ObjectNumber >> 16),
+		byte(ref.GenerationNumber), byte(ref.GenerationNumber >> 8),
+	})
+	if method == "AESV2" {
+		digest.Write([]byte("sAlT"))
+	}
+
+	n := len(h.key) + 5
+	if n > 16 {
+		n = 16
+	}
+	return digest.Sum(nil)[:n]
+}
+
+// decrypts the data of the referenced object with a crypt filter
+func (h *securityHandler) decrypt(filter Name, ref ObjectReference, data []byte) ([]byte, error) {
+	method, ok := h.filters[filter]
+	if !ok {
+		return nil, fmt.Errorf("crypt filter %s is not defined", filter)
+	}
+
+	key := h.objectKey(ref, method)
+	switch method {
+	case "None":
+		return data, nil
+	case "V2":
+		return rc4Crypt(key, data), nil
+	}
+
+	// AES with the initialization vector before the data
+	// and PKCS#5 padding (§7.6.3.1), though some writers
+	// encrypt empty strings as only the initialization vector
+	if len(data) == 0 || len(data) == aes.BlockSize {
+		return []byte{}, nil
+	}
+	if len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("AES encrypted data must be a multiple of 16 bytes long")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(decrypted, data[aes.BlockSize:])
+
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errors.New("invalid AES padding")
+	}
+	return decrypted[:len(decrypted)-padding], nil
+}
+
+// encrypts the data of the referenced object with a crypt filter
+func (h *securityHandler) encrypt(filter Name, ref ObjectReference, data []byte) ([]byte, error) {
+	method, ok := h.filters[filter]
+	if !ok {
+		return nil, fmt.Errorf("crypt filter %s is not defined", filter)
+	}
+
+	key := h.objectKey(ref, method)
+	switch method {
+	case "None":
+		return data, nil
+	case "V2":
+		return rc4Crypt(key, data), nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(data)%aes.BlockSize
+	encrypted := make([]byte, aes.BlockSize+len(data)+padding)
+	_, err = rand.Read(encrypted[:aes.BlockSize])
+	if err != nil {
+		return nil, err
+	}
+	copy(encrypted[aes.BlockSize:], data)
+	for i := len(encrypted) - padding; i < len(encrypted); i++ {
+		encrypted[i] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, encrypted[:aes.BlockSize]).CryptBlocks(encrypted[aes.BlockSize:], encrypted[aes.BlockSize:])
+	return encrypted, nil
+}
+
+// decrypts the strings and streams in an object read from the file
+func (h *securityHandler) decryptObject(obj Object, ref ObjectReference) (Object, error) {
+	return h.cryptObject(obj, ref, false)
+}
+
+// encrypts the strings and streams in an object to be saved
+func (h *securityHandler) encryptObject(obj Object, ref ObjectReference) (Object, error) {
+	return h.cryptObject(obj, ref, true)
+}
+
+func (h *securityHandler) cryptObject(obj Object, ref ObjectReference, encrypt bool) (Object, error) {
+	crypt := h.decrypt
+	if encrypt {
+		crypt = h.encrypt
+	}
+
+	switch typed := obj.(type) {
+	case String:
+		crypted, err := crypt(h.strF, ref, []byte(typed))
+		if err != nil && !encrypt {
+			// only the string is lost, not the object it is in
+			return Null{fmt.Errorf("could not decrypt string in %v: %v", ref, err)}, nil
+		}
+		return String(crypted), err
+	case Array:
+		crypted := make(Array, len(typed))
+		for i := range typed {
+			var err error
+			crypted[i], err = h.cryptObject(typed[i], ref, encrypt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return crypted, nil
+	case Dictionary:
+		crypted := Dictionary{}
+		for key, value := range typed {
+			// signature values are not encrypted (§7.6.2 of PDF 2.0)
+			if key == "Contents" && isSignature(typed) {
+				crypted[key] = value
+				continue
+			}
+
+			var err error
+			crypted[key], err = h.cryptObject(value, ref, encrypt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return crypted, nil
+	case Stream:
+		return h.cryptStream(typed, ref, encrypt)
+	}
+	return obj, nil
+}
+
+func isSignature(dict Dictionary) bool {
+	switch dict["Type"] {
+	case Name("Sig"), Name("DocTimeStamp"):
+		return true
+	}
+	_, ok := dict["ByteRange"]
+	return ok
+}
+
+func (h *securityHandler) cryptStream(stream Stream, ref ObjectReference, encrypt bool) (Object, error) {
+	// cross-reference streams are not encrypted (§7.5.8.2)
+	if stream.Dictionary["Type"] == Name("XRef") {
+		return stream, nil
+	}
+
+	dict, err := h.cryptObject(stream.Dictionary, ref, encrypt)
+	if err != nil {
+		return nil, err
+	}
+	stream.Dictionary = dict.(Dictionary)
+
+	if stream.Dictionary["Type"] == Name("Metadata") && !h.encryptMetadata {
+		return stream, nil
+	}
+
+	filter := h.stmF
+	if stream.Dictionary["Type"] == Name("EmbeddedFile") {
+		filter = h.eff
+	}
+
+	// the Crypt filter is only needed to read the stream
+	if name, ok := cryptFilter(stream.Dictionary); ok {
+		filter = name
+		if !encrypt {
+			removeCryptFilter(stream.Dictionary)
+		}
+	}
+
+	if encrypt {
+		stream.Stream, err = h.encrypt(filter, ref, stream.Stream)
+	} else {
+		stream.Stream, err = h.decrypt(filter, ref, stream.Stream)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// the name of the crypt filter used by a Crypt filter (§7.4.10),
+// which must be the stream's first filter
+func cryptFilter(dict Dictionary) (Name, bool) {
+	var params Dictionary
+	switch filter := dict["Filter"].(type) {
+	case Name:
+		if filter != "Crypt" {
+			return "", false
+		}
+		params, _ = dict["DecodeParms"].(Dictionary)
+	case Array:
+		if len(filter) == 0 || filter[0] != Name("Crypt") {
+			return "", false
+		}
+		if decodeParms, ok := dict["DecodeParms"].(Array); ok && len(decodeParms) > 0 {
+			params, _ = decodeParms[0].(Dictionary)
+		}
+	default:
+		return "", false
+	}
+
+	name, ok := params["Name"].(Name)
+	if !ok {
+		name = "Identity"
+	}
+	return name, true
+}
+
+func removeCryptFilter(dict Dictionary) {
+	switch filter := dict["Filter"].(type) {
+	case Name:
+		delete(dict, "Filter")
+		delete(dict, "DecodeParms")
+	case Array:
+		dict["Filter"] = filter[1:]
+		if decodeParms, ok := dict["DecodeParms"].(Array); ok && len(decodeParms) > 0 {
+			dict["DecodeParms"] = decodeParms[1:]
+		}
+	}
+}
+
+// SetEncryption encrypts the file with the standard security handler
+// from the next Save, or removes its encryption when e is nil.
+// Files opened with the user password cannot be changed.
+//
+// When the file already has objects on disk, the next Save replaces
+// the whole file, so its earlier revisions (§7.5.6) are lost. As that
+// would invalidate their signatures, the encryption of signed files
+// cannot be changed.
+func (f *File) SetEncryption(e *Encryption) error {
+	if f.security != nil && !f.security.owner {
+		return errors.New("the owner password is needed to change the encryption")
+	}
+	if f.signed() {
+		return errors.New("changing the encryption would invalidate the file's signatures")
+	}
+
+	f.encryptionChanged = true
+	if e == nil {
+		f.saveSecurity = nil
+		f.Encrypt = nil
+		return nil
+	}
+
+	// the file identifier is part of the encryption key (§14.4)
+	if len(f.ID) != 2 {
+		id := make([]byte, 16)
+		_, err := rand.Read(id)
+		if err != nil {
+			return err
+		}
+		f.ID = Array{String(id), String(id)}
+	}
+	id, _ := f.ID[0].(String)
+
+	var err error
+	f.saveSecurity, f.Encrypt, err = newEncryption(e, []byte(id))
+	return err
+}
+
+// whether the file has signed signature fields or a certification signature
+func (f *File) signed() bool {
+	fields, _ := f.signatureFields()
+	_, certified := f.certification()
+	return len(fields) != 0 || certified
+}
+
+// the object as it is saved, encrypted when the file is
+func (f *File) saveObject(iobj IndirectObject) (IndirectObject, error) {
+	if f.saveSecurity == nil {
+		return iobj, nil
+	}
+
+	encrypted, err := f.saveSecurity.encryptObject(iobj.Object, iobj.ObjectReference)
+	if err != nil {
+		return iobj, err
+	}
+	iobj.Object = encrypted
+	return iobj, nil
+}
+
+// writes every object to a new file that replaces the one on disk,
+// so that the objects are saved with the current encryption
+func (f *File) rewrite() error {
+	objects := map[uint]interface{}{}
+	for objectNumber, object := range f.objects {
+		var iobj IndirectObject
+		switch typed := object.(type) {
+		case crossReference:
+			if typed[0] == 0 {
+				continue
+			}
+			ref := ObjectReference{ObjectNumber: objectNumber}
+			if typed[0] == 1 {
+				ref.GenerationNumber = typed[2]
+			}
+			if ref == f.encryptRef {
+				continue
+			}
+
+			obj := f.Get(ref)
+			if null, ok := obj.(Null); ok && null.Error != nil {
+				return null.Error
+			}
+			iobj = IndirectObject{ref, obj}
+		case IndirectObject:
+			iobj = typed
+		default:
+			continue
+		}
+
+		// replaced by the new cross-reference stream
+		if stream, ok := iobj.Object.(Stream); ok {
+			switch stream.Dictionary["Type"] {
+			case Name("ObjStm"), Name("XRef"):
+				continue
+			}
+		}
+		objects[objectNumber] = iobj
+	}
+
+	info, err := os.Stat(f.filename)
+	if err != nil {
+		return err
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(f.filename), filepath.Base(f.filename)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = temp.WriteString("%PDF-" + string(f.version))
+	if err == nil {
+		err = temp.Chmod(info.Mode())
+	}
+	err2 := temp.Close()
+	if err == nil {
+		err = err2
+	}
+
+	rewritten := &File{
+		filename:        temp.Name(),
+		objects:         objects,
+		size:            f.size,
+		version:         f.version,
+		saveSecurity:    f.saveSecurity,
+		Root:            f.Root,
+		Encrypt:         f.Encrypt,
+		Info:            f.Info,
+		ID:              f.ID,
+		CompressStreams: f.CompressStreams,
+	}
+	if err == nil {
+		err = rewritten.saveUsingXrefStream()
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+
+	// objects read from the old file are no longer needed
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(temp.Name(), f.filename)
+	if err != nil {
+		return err
+	}
+
+	f.created = false
+	f.encryptRef = ObjectReference{}
+	err = f.open()
+	if err != nil {
+		return err
+	}
+	f.security = f.saveSecurity
+	f.encryptionChanged = false
+	return nil
+}
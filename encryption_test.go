@@ -0,0 +1,599 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// writes a PDF file with a cross-reference table,
+// objects are numbered from 1
+func writeTestPDF(t *testing.T, filename string, objects []Object, trailer Dictionary) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.7\n")
+
+	offsets := []int{}
+	for i, obj := range objects {
+		offsets = append(offsets, buf.Len())
+		iobj := IndirectObject{ObjectReference{ObjectNumber: uint(i + 1)}, obj}
+		_, err := iobj.writeTo(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.WriteString("\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	trailer["Size"] = Integer(len(objects) + 1)
+	buf.WriteString("trailer\n")
+	trailer.writeTo(buf)
+	fmt.Fprintf(buf, "\nstartxref\n%d\n%%%%EOF\n", xref)
+
+	err := ioutil.WriteFile(filename, buf.Bytes(), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// adds O and U, and for AES-256 OE and UE, to an encryption
+// dictionary and returns the handler for the user password
+func testSecurityHandler(t *testing.T, encrypt Dictionary, id Array, user, owner string) *securityHandler {
+	h := &securityHandler{r: int(encrypt["R"].(Integer))}
+	if h.r <= 4 {
+		n := 5
+		if length, ok := encrypt["Length"].(Integer); ok {
+			n = int(length) / 8
+		} else if encrypt["V"] == Integer(4) {
+			n = 16
+		}
+		h.encryptMetadata = encrypt["EncryptMetadata"] != Boolean(false)
+		h.id = []byte(id[0].(String))
+		h.p = uint32(encrypt["P"].(Integer))
+
+		h.o = h.ownerEntry(passwordBytes(user), passwordBytes(owner), n)
+		encrypt["O"] = String(h.o)
+		encrypt["U"] = String(h.userEntry(h.fileKey(passwordBytes(user), n)))
+	} else {
+		h.key = randomBytes(t, 32)
+		ue, oe, err := h.aes256Entries(user, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypt["O"] = String(h.o)
+		encrypt["U"] = String(h.u)
+		encrypt["OE"] = String(oe)
+		encrypt["UE"] = String(ue)
+	}
+
+	h, err := newSecurityHandler(encrypt, id, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// encrypts data as decrypt expects it
+func testEncrypt(t *testing.T, h *securityHandler, filter Name, ref ObjectReference, data []byte) []byte {
+	encrypted, err := h.encrypt(filter, ref, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encrypted
+}
+
+// the contents of a String, for comparisons
+func str(obj Object) string {
+	s, _ := obj.(String)
+	return string(s)
+}
+
+func TestOpenEncrypted(t *testing.T) {
+	stdCF := func(method Name, length Integer) Dictionary {
+		return Dictionary{"StdCF": Dictionary{"CFM": method, "Length": length}}
+	}
+
+	for _, test := range []struct {
+		name    string
+		encrypt Dictionary
+	}{
+		{"RC4 40", Dictionary{"V": Integer(1), "R": Integer(2)}},
+		{"RC4 128", Dictionary{"V": Integer(2), "R": Integer(3), "Length": Integer(128)}},
+		{"RC4 crypt filter", Dictionary{"V": Integer(4), "R": Integer(4), "Length": Integer(128), "CF": stdCF("V2", 16)}},
+		{"AES-128", Dictionary{"V": Integer(4), "R": Integer(4), "Length": Integer(128), "CF": stdCF("AESV2", 16)}},
+		{"AES-128 unencrypted metadata", Dictionary{"V": Integer(4), "R": Integer(4), "CF": stdCF("AESV2", 16), "EncryptMetadata": Boolean(false)}},
+		{"AES-256 R5", Dictionary{"V": Integer(5), "R": Integer(5), "Length": Integer(256), "CF": stdCF("AESV3", 32)}},
+		{"AES-256", Dictionary{"V": Integer(5), "R": Integer(6), "Length": Integer(256), "CF": stdCF("AESV3", 32)}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			encrypt := test.encrypt
+			encrypt["Filter"] = Name("Standard")
+			encrypt["P"] = Integer(-3904)
+			if _, ok := encrypt["CF"]; ok {
+				encrypt["StmF"] = Name("StdCF")
+				encrypt["StrF"] = Name("StdCF")
+			}
+			id := Array{String(randomBytes(t, 16)), String(randomBytes(t, 16))}
+			h := testSecurityHandler(t, encrypt, id, "user", "owner")
+
+			ref := func(n uint) ObjectReference {
+				return ObjectReference{ObjectNumber: n}
+			}
+			encryptString := func(n uint, s string) String {
+				return String(testEncrypt(t, h, h.strF, ref(n), []byte(s)))
+			}
+
+			content := []byte("BT /F1 12 Tf (Secret) Tj ET")
+			compressed, err := encode("FlateDecode", content, Dictionary{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			metadata := []byte("<x:xmpmeta/>")
+			if h.encryptMetadata {
+				metadata = testEncrypt(t, h, h.stmF, ref(5), metadata)
+			}
+
+			objects := []Object{
+				Dictionary{
+					"Type":  Name("Catalog"),
+					"Title": encryptString(1, "Secret title"),
+					"Kids":  Array{encryptString(1, "nested"), Integer(1)},
+				},
+				Stream{
+					Dictionary: Dictionary{"Filter": Name("FlateDecode")},
+					Stream:     testEncrypt(t, h, h.stmF, ref(2), compressed),
+				},
+				encrypt,
+				// streams with a crypt filter are not encrypted by StmF
+				Stream{
+					Dictionary: Dictionary{
+						"Filter":      Array{Name("Crypt"), Name("FlateDecode")},
+						"DecodeParms": Array{Dictionary{"Name": Name("Identity")}, Null{}},
+					},
+					Stream: compressed,
+				},
+				Stream{
+					Dictionary: Dictionary{"Type": Name("Metadata"), "Subtype": Name("XML")},
+					Stream:     metadata,
+				},
+				// signature values are not encrypted
+				Dictionary{
+					"Type":     Name("Sig"),
+					"Contents": String("signature"),
+					"Name":     encryptString(6, "signer"),
+				},
+			}
+			filename := filepath.Join(t.TempDir(), "encrypted.pdf")
+			writeTestPDF(t, filename, objects, Dictionary{
+				"Root":    ref(1),
+				"Encrypt": ref(3),
+				"ID":      id,
+			})
+
+			_, err = Open(filename)
+			if err != ErrPassword {
+				t.Errorf("expected ErrPassword for the empty password, got %v", err)
+			}
+			_, err = OpenWithPassword(filename, "wrong")
+			if err != ErrPassword {
+				t.Errorf("expected ErrPassword for a wrong password, got %v", err)
+			}
+
+			for _, password := range []string{"user", "owner"} {
+				file, err := OpenWithPassword(filename, password)
+				if err != nil {
+					t.Fatal(password, err)
+				}
+				defer file.Close()
+				if file.security.owner != (password == "owner") {
+					t.Errorf("%s: owner is %v", password, file.security.owner)
+				}
+
+				catalog := file.Get(ref(1)).(Dictionary)
+				if str(catalog["Title"]) != "Secret title" {
+					t.Errorf("%s: title decrypted to %q", password, catalog["Title"])
+				}
+				if str(catalog["Kids"].(Array)[0]) != "nested" {
+					t.Errorf("%s: nested string decrypted to %q", password, catalog["Kids"].(Array)[0])
+				}
+
+				for _, n := range []uint{2, 4} {
+					decoded, err := file.Get(ref(n)).(Stream).Decode()
+					if err != nil {
+						t.Fatal(password, n, err)
+					}
+					if !bytes.Equal(decoded, content) {
+						t.Errorf("%s: stream %d decoded to %q", password, n, decoded)
+					}
+				}
+
+				decoded, err := file.Get(ref(5)).(Stream).Decode()
+				if err != nil {
+					t.Fatal(password, err)
+				}
+				if string(decoded) != "<x:xmpmeta/>" {
+					t.Errorf("%s: metadata decoded to %q", password, decoded)
+				}
+
+				sig := file.Get(ref(6)).(Dictionary)
+				if str(sig["Contents"]) != "signature" || str(sig["Name"]) != "signer" {
+					t.Errorf("%s: signature decrypted to %v", password, sig)
+				}
+
+				if _, ok := file.Encrypt["O"]; !ok {
+					t.Errorf("%s: Encrypt was not loaded", password)
+				}
+				// the encryption dictionary's strings are not decrypted
+				got := file.Get(ref(3)).(Dictionary)
+				for _, key := range []Name{"O", "U"} {
+					if str(got[key]) != str(encrypt[key]) {
+						t.Errorf("%s: %s is %q instead of %q", password, key, got[key], encrypt[key])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOpenEncryptedEmptyUserPassword(t *testing.T) {
+	encrypt := Dictionary{
+		"Filter": Name("Standard"),
+		"V":      Integer(2),
+		"R":      Integer(3),
+		"Length": Integer(128),
+		"P":      Integer(-4),
+	}
+	id := Array{String("0123456789abcdef"), String("0123456789abcdef")}
+	h := testSecurityHandler(t, encrypt, id, "", "owner")
+
+	ref := ObjectReference{ObjectNumber: 1}
+	filename := filepath.Join(t.TempDir(), "encrypted.pdf")
+	writeTestPDF(t, filename, []Object{
+		Dictionary{
+			"Type":  Name("Catalog"),
+			"Title": String(testEncrypt(t, h, h.strF, ref, []byte("title"))),
+		},
+	}, Dictionary{"Root": ref, "Encrypt": encrypt, "ID": id})
+
+	file, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	catalog := file.Get(ref).(Dictionary)
+	if str(catalog["Title"]) != "title" {
+		t.Errorf("title decrypted to %q", catalog["Title"])
+	}
+
+	// added objects are encrypted
+	added, err := file.Add(String("added"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	file, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str(file.Get(added)) != "added" {
+		t.Errorf("added object decrypted to %v", file.Get(added))
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("added")) {
+		t.Error("added object was saved unencrypted")
+	}
+}
+
+func TestSetEncryption(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		encryption Encryption
+	}{
+		{"AES-128", Encryption{Algorithm: AES128}},
+		{"AES-256", Encryption{Algorithm: AES256}},
+		{"AES-128 unencrypted metadata", Encryption{Algorithm: AES128, UnencryptedMetadata: true}},
+		{"AES-256 unencrypted metadata", Encryption{Algorithm: AES256, UnencryptedMetadata: true}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "encrypted.pdf")
+			file, err := Create(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content := []byte("BT (Secret content) Tj ET")
+			contentRef, err := file.Add(Stream{Dictionary: Dictionary{}, Stream: content})
+			if err != nil {
+				t.Fatal(err)
+			}
+			metadataRef, err := file.Add(Stream{
+				Dictionary: Dictionary{"Type": Name("Metadata"), "Subtype": Name("XML")},
+				Stream:     []byte("<x:xmpmeta>Secret metadata</x:xmpmeta>"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Root, err = file.Add(Dictionary{
+				"Type":     Name("Catalog"),
+				"Title":    String("Secret title"),
+				"Metadata": metadataRef,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			encryption := test.encryption
+			encryption.UserPassword = "user"
+			encryption.OwnerPassword = "owner"
+			encryption.Permissions = PermissionPrint | PermissionCopy
+			err = file.SetEncryption(&encryption)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.CompressStreams = true
+			err = file.Save()
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Close()
+
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("Secret title")) {
+				t.Error("strings were saved unencrypted")
+			}
+			if bytes.Contains(data, []byte("Secret metadata")) != encryption.UnencryptedMetadata {
+				t.Error("metadata encryption does not match UnencryptedMetadata")
+			}
+
+			_, err = OpenWithPassword(filename, "wrong")
+			if err != ErrPassword {
+				t.Errorf("expected ErrPassword, got %v", err)
+			}
+
+			for _, password := range []string{"user", "owner"} {
+				file, err := OpenWithPassword(filename, password)
+				if err != nil {
+					t.Fatal(password, err)
+				}
+				defer file.Close()
+
+				if len(file.ID) != 2 {
+					t.Errorf("%s: ID is %v", password, file.ID)
+				}
+				p := uint32(file.Encrypt["P"].(Integer))
+				if p&0xf3c != uint32(PermissionPrint|PermissionCopy) || p&0xfffff0c0 != 0xfffff0c0 {
+					t.Errorf("%s: P is %#x", password, p)
+				}
+
+				catalog := file.Get(file.Root).(Dictionary)
+				if str(catalog["Title"]) != "Secret title" {
+					t.Errorf("%s: title decrypted to %q", password, catalog["Title"])
+				}
+				decoded, err := file.Get(contentRef).(Stream).Decode()
+				if err != nil {
+					t.Fatal(password, err)
+				}
+				if !bytes.Equal(decoded, content) {
+					t.Errorf("%s: content decoded to %q", password, decoded)
+				}
+				metadata, err := file.Get(metadataRef).(Stream).Decode()
+				if err != nil {
+					t.Fatal(password, err)
+				}
+				if !bytes.Contains(metadata, []byte("Secret metadata")) {
+					t.Errorf("%s: metadata decoded to %q", password, metadata)
+				}
+			}
+		})
+	}
+}
+
+// one string that cannot be decrypted does not lose the object it is in
+func TestDecryptStrings(t *testing.T) {
+	stdCF := Dictionary{"StdCF": Dictionary{"CFM": Name("AESV2"), "Length": Integer(16)}}
+	encrypt := Dictionary{"Filter": Name("Standard"), "V": Integer(4), "R": Integer(4), "CF": stdCF,
+		"StmF": Name("StdCF"), "StrF": Name("StdCF"), "P": Integer(-4)}
+	id := String(randomBytes(t, 16))
+	h := testSecurityHandler(t, encrypt, Array{id, id}, "user", "owner")
+
+	ref := ObjectReference{ObjectNumber: 3}
+	decrypted, err := h.decryptObject(Dictionary{
+		"Title":  String(testEncrypt(t, h, "StdCF", ref, []byte("title"))),
+		"Empty":  String(randomBytes(t, 16)), // only the initialization vector
+		"Broken": String(randomBytes(t, 20)),
+	}, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict := decrypted.(Dictionary)
+	if str(dict["Title"]) != "title" {
+		t.Errorf("Title decrypted to %v", dict["Title"])
+	}
+	if empty, ok := dict["Empty"].(String); !ok || len(empty) != 0 {
+		t.Errorf("Empty decrypted to %v", dict["Empty"])
+	}
+	if null, ok := dict["Broken"].(Null); !ok || null.Error == nil {
+		t.Errorf("Broken decrypted to %v", dict["Broken"])
+	}
+}
+
+// values computed from the algorithms in §7.6.4.3 and §7.6.4.4 with
+// an implementation that uses Python's hashlib and OpenSSL's RC4 and AES
+func TestEncryptionKnownAnswers(t *testing.T) {
+	id := String("\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19\x1a\x1b\x1c\x1d\x1e\x1f")
+	stdCF := Dictionary{"StdCF": Dictionary{"CFM": Name("AESV3"), "AuthEvent": Name("DocOpen"), "Length": Integer(32)}}
+	for _, test := range []struct {
+		name      string
+		encrypt   Dictionary
+		key       string
+		encrypted string
+	}{
+		{
+			"RC4 128 R3",
+			Dictionary{
+				"Filter": Name("Standard"), "V": Integer(2), "R": Integer(3), "Length": Integer(128), "P": Integer(-3904),
+				"O": String("\x0b\xa3\x83\x5f\x88\xf9\x03\x88\xe7\x4e\x54\x58\x41\x25\xce\x14\x2b\xe0\xde\x24\xc6\xb0\xd3\x77\x46\xe0\x75\xb8\x91\x75\x66\x71"),
+				"U": String("\x01\x34\xea\x83\x38\x2f\x5f\x5d\xaa\xed\x65\x09\x6a\x5f\xdb\xba\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"),
+			},
+			"\xaa\xb2\x48\xf7\x00\xca\xac\x15\xba\xb4\xd2\x83\x9f\x7b\x07\x61",
+			"\xc4\x03\x89\x3a\x4b\x3b\xfa\xae\x9f\x7a\x48\x44",
+		},
+		{
+			"AES-256 R6",
+			Dictionary{
+				"Filter": Name("Standard"), "V": Integer(5), "R": Integer(6), "Length": Integer(256), "P": Integer(-3904),
+				"CF": stdCF, "StmF": Name("StdCF"), "StrF": Name("StdCF"),
+				"O":     String("\xf1\x94\xfa\x80\xf3\xb3\x00\x80\x18\xff\x43\xd0\xc7\x48\x8c\x75\xf5\x4e\xdd\x25\x76\x7a\xca\x7c\xf5\x10\x1f\x04\x2e\x13\x59\xc8\x4f\x56\x53\x41\x4c\x54\x30\x33\x4f\x4b\x53\x41\x4c\x54\x30\x34"),
+				"U":     String("\x27\x67\xbf\xdc\xbf\x33\xc9\x7e\x78\x01\xa8\x9c\xb7\x52\x52\x50\x84\x9f\x86\x43\xc4\x78\x3c\xa2\x19\x67\x49\x1d\x65\xb0\xa9\xde\x55\x56\x53\x41\x4c\x54\x30\x31\x55\x4b\x53\x41\x4c\x54\x30\x32"),
+				"OE":    String("\x2a\x4d\xdb\xdd\x96\x2d\x1c\x80\x93\xed\xb9\x28\xe6\xc1\x96\x35\x89\xed\x2f\x90\x0f\x01\x82\x3f\x93\x48\x6a\x07\x85\x93\xd5\x12"),
+				"UE":    String("\x87\x25\xa9\x04\xd8\x51\xc7\x50\x85\xb8\xc8\x3a\x07\xe5\xf6\x7a\x2a\x63\x20\xf2\x89\x9f\x2a\x90\xf8\x65\x29\x44\x59\x99\x2a\xee"),
+				"Perms": String("\xad\x9b\x66\xa3\x70\x19\x8d\xcd\xc7\xa6\xc6\xbf\x95\xb6\x50\x66"),
+			},
+			"\x40\x41\x42\x43\x44\x45\x46\x47\x48\x49\x4a\x4b\x4c\x4d\x4e\x4f\x50\x51\x52\x53\x54\x55\x56\x57\x58\x59\x5a\x5b\x5c\x5d\x5e\x5f",
+			"\xa0\xa1\xa2\xa3\xa4\xa5\xa6\xa7\xa8\xa9\xaa\xab\xac\xad\xae\xaf\x9f\x14\x2e\x1e\x1b\x72\xcb\xaa\x0d\xe3\xe3\xe3\xfd\x79\x41\x5c",
+		},
+	} {
+		for _, password := range []string{"user", "owner"} {
+			h, err := newSecurityHandler(test.encrypt, Array{id, id}, password)
+			if err != nil {
+				t.Errorf("%s: %s: %v", test.name, password, err)
+				continue
+			}
+			if string(h.key) != test.key {
+				t.Errorf("%s: %s: file key is % x", test.name, password, h.key)
+			}
+			decrypted, err := h.decrypt("StdCF", ObjectReference{ObjectNumber: 7}, []byte(test.encrypted))
+			if err != nil || string(decrypted) != "Known answer" {
+				t.Errorf("%s: %s: decrypted to %q %v", test.name, password, decrypted, err)
+			}
+		}
+
+		_, err := newSecurityHandler(test.encrypt, Array{id, id}, "wrong")
+		if err != ErrPassword {
+			t.Errorf("%s: expected ErrPassword, got %v", test.name, err)
+		}
+	}
+}
+
+// rewriting signed files would invalidate their signatures
+func TestSetEncryptionSigned(t *testing.T) {
+	cert, key := testCertificate(t, "signer", 1)
+	file := testDocument(t, filepath.Join(t.TempDir(), "signed.pdf"))
+	err := file.Sign(&Signature{Signer: key, Certificate: cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	err = file.SetEncryption(&Encryption{UserPassword: "user"})
+	if err == nil {
+		t.Error("expected an error encrypting a signed file")
+	}
+}
+
+// encrypting, and decrypting, existing files rewrites them
+func TestSetEncryptionRewrite(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "rewritten.pdf")
+	file, err := Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Root, err = file.Add(Dictionary{"Type": Name("Catalog"), "Title": String("Secret title")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	file, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.SetEncryption(&Encryption{UserPassword: "user", OwnerPassword: "owner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str(file.Get(file.Root).(Dictionary)["Title"]) != "Secret title" {
+		t.Error("file is not usable after being rewritten")
+	}
+	file.Close()
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("Secret title")) {
+		t.Error("the rewritten file has unencrypted strings")
+	}
+
+	// the owner password is needed to change the encryption
+	file, err = OpenWithPassword(filename, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.SetEncryption(nil)
+	if err == nil {
+		t.Error("expected an error removing the encryption with the user password")
+	}
+	file.Close()
+
+	file, err = OpenWithPassword(filename, "owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.SetEncryption(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	file, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if len(file.Encrypt) != 0 {
+		t.Error("file is still encrypted")
+	}
+	if str(file.Get(file.Root).(Dictionary)["Title"]) != "Secret title" {
+		t.Error("title was not decrypted")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nathankerr/pdf"
+	"github.com/nathankerr/pdf/impose"
+)
+
+var sheets = map[string]impose.Size{
+	"a3":      impose.A3,
+	"a4":      impose.A4,
+	"a5":      impose.A5,
+	"letter":  impose.Letter,
+	"legal":   impose.Legal,
+	"tabloid": impose.Tabloid,
+}
+
+var orders = map[string]impose.Order{
+	"rows":        impose.RowsLeftToRight,
+	"rows-rtl":    impose.RowsRightToLeft,
+	"columns":     impose.ColumnsLeftToRight,
+	"columns-rtl": impose.ColumnsRightToLeft,
+}
+
+var scalings = map[string]impose.Scaling{
+	"fit":     impose.Fit,
+	"shrink":  impose.ShrinkToFit,
+	"actual":  impose.ActualSize,
+	"uniform": impose.Uniform,
+}
+
+func usage() {
+	fmt.Printf("Usage: nup [options] <file.pdf>\n\nOptions:\n")
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func main() {
+	log.SetFlags(log.Lshortfile)
+
+	n := flag.Int("n", 2, "pages per sheet, placed in the grid closest to square")
+	columns := flag.Int("columns", 0, "columns of pages, used with -rows instead of -n")
+	rows := flag.Int("rows", 0, "rows of pages, used with -columns instead of -n")
+	sheetName := flag.String("sheet", "a4", "sheet size {a3, a4, a5, letter, legal, tabloid}")
+	landscape := flag.Bool("landscape", false, "turn the sheet to landscape when using -columns and -rows")
+	margin := flag.Float64("margin", 0, "margin around the sheet's edges in points")
+	gutter := flag.Float64("gutter", 0, "space between the pages in points")
+	orderName := flag.String("order", "rows", "reading order {rows, rows-rtl, columns, columns-rtl}")
+	scalingName := flag.String("scaling", "fit", "page scaling {fit, shrink, actual, uniform}")
+	rotate := flag.Bool("rotate", false, "turn pages when that makes them larger")
+	frames := flag.Bool("frames", false, "draw lines around the pages")
+	output := flag.String("o", "nup.pdf", ".pdf to output the sheets to")
+	flag.Parse()
+
+	sheet, ok := sheets[*sheetName]
+	order, orderOk := orders[*orderName]
+	scaling, scalingOk := scalings[*scalingName]
+	if !ok || !orderOk || !scalingOk || flag.NArg() != 1 {
+		usage()
+	}
+
+	var layout impose.Layout
+	if *columns > 0 || *rows > 0 {
+		if *landscape {
+			sheet = sheet.Landscape()
+		}
+		layout = impose.Layout{Columns: *columns, Rows: *rows, Sheet: sheet}
+	} else {
+		var err error
+		layout, err = impose.Grid(*n, sheet)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	layout.Margins = impose.Margins{Top: *margin, Right: *margin, Bottom: *margin, Left: *margin}
+	layout.ColumnGutter = *gutter
+	layout.RowGutter = *gutter
+	layout.Order = order
+	layout.Scaling = scaling
+	layout.AutoRotate = *rotate
+	layout.Frames = *frames
+
+	// the sheets are added to a copy of the document
+	file, err := pdf.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer file.Close()
+	source, err := pdf.NewDocument(file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	imposed, err := pdf.Create(*output)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	document, err := pdf.NewDocument(imposed)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = pdf.Merge(document, source)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = layout.Impose(document)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	imposed.CompressStreams = true
+	err = imposed.Save()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
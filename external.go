@@ -0,0 +1,182 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// external stream data (§7.3.8.2) in files named by
+// file specifications (§7.11)
+
+// reads the data of a stream whose F entry is spec
+func (s Stream) readExternal(spec Object) ([]byte, error) {
+	if s.file == nil {
+		return nil, errors.New("external stream data can only be read from streams in a File")
+	}
+	if !s.file.ExternalStreams {
+		return nil, errors.New("external stream data is not enabled, see File.ExternalStreams")
+	}
+
+	path, err := s.file.externalPath(spec)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(path)
+}
+
+// writes the data of the added streams with an F entry to their
+// external files, leaving the streams in the PDF file empty
+func (f *File) writeExternalStreams() error {
+	for i, object := range f.objects {
+		iobj, ok := object.(IndirectObject)
+		if !ok {
+			continue
+		}
+		stream, ok := iobj.Object.(Stream)
+		if !ok {
+			continue
+		}
+		spec, ok := stream.Dictionary["F"]
+		if !ok || len(stream.Stream) == 0 {
+			continue
+		}
+
+		if !f.ExternalStreams {
+			return fmt.Errorf("%v has external stream data, but File.ExternalStreams is not set", iobj.ObjectReference)
+		}
+		path, err := f.externalPath(spec)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(path, stream.Stream, 0666)
+		if err != nil {
+			return err
+		}
+
+		stream.Stream = nil
+		iobj.Object = stream
+		f.objects[i] = iobj
+	}
+	return nil
+}
+
+// the path of the file specified by spec, which must be
+// relative to the PDF file and within its directory
+func (f *File) externalPath(spec Object) (string, error) {
+	name, err := f.fileSpecName(spec)
+	if err != nil {
+		return "", err
+	}
+
+	// absolute file specifications start with the volume (§7.11.2.1)
+	if name == "" || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("file specification %q is not relative to the PDF file", name)
+	}
+	if strings.Contains(name, `\/`) {
+		return "", fmt.Errorf("file specification %q has a slash within a file name", name)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(f.filename))
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if !withinDir(dir, path) {
+		return "", fmt.Errorf("file specification %q is outside of %s", name, dir)
+	}
+
+	// symbolic links may also lead outside of the directory,
+	// files that do not exist yet are checked by their directory
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		realPath, err = filepath.EvalSymlinks(filepath.Dir(path))
+	}
+	if err != nil {
+		return "", err
+	}
+	if !withinDir(realDir, realPath) {
+		return "", fmt.Errorf("file specification %q links outside of %s", name, dir)
+	}
+
+	return path, nil
+}
+
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// the file name from a file specification string or dictionary (§7.11)
+func (f *File) fileSpecName(spec Object) (string, error) {
+	if ref, ok := spec.(ObjectReference); ok {
+		spec = f.Get(ref)
+	}
+
+	switch spec := spec.(type) {
+	case String:
+		return string(spec), nil
+	case Dictionary:
+		if fs, ok := spec["FS"].(Name); ok {
+			return "", fmt.Errorf("%s file systems are not supported", fs)
+		}
+
+		// the unicode file name is preferred (§7.11.3 Table 44)
+		for _, key := range []Name{"UF", "F", "Unix"} {
+			value := spec[key]
+			if ref, ok := value.(ObjectReference); ok {
+				value = f.Get(ref)
+			}
+			if name, ok := value.(String); ok {
+				if key == "UF" {
+					return textString(name), nil
+				}
+				return string(name), nil
+			}
+		}
+		return "", errors.New("file specification dictionary does not have a file name")
+	case Null:
+		if spec.Error != nil {
+			return "", spec.Error
+		}
+	}
+	return "", fmt.Errorf("file specification must be a string or a dictionary, not %T", spec)
+}
+
+// decodes UTF-16BE text strings, others are treated as UTF-8 (§7.9.2.2)
+func textString(s String) string {
+	if len(s) < 2 || s[0] != 0xfe || s[1] != 0xff {
+		return string(s)
+	}
+
+	units := make([]uint16, 0, len(s)/2-1)
+	for i := 2; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// encodes text that is not ASCII as UTF-16BE (§7.9.2.2)
+func newTextString(text string) String {
+	for _, r := range text {
+		if r >= 0x80 {
+			s := String{0xfe, 0xff}
+			for _, unit := range utf16.Encode([]rune(text)) {
+				s = append(s, byte(unit>>8), byte(unit))
+			}
+			return s
+		}
+	}
+	return String(text)
+}
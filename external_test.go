@@ -0,0 +1,137 @@
+package pdf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExternalStreams(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "external.pdf")
+	file, err := Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("0 0 m 100 100 l S")
+	encoded, err := encode("FlateDecode", content, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := file.Add(Stream{
+		Dictionary: Dictionary{
+			"F":       String("content.z"),
+			"FFilter": Name("FlateDecode"),
+		},
+		Stream: encoded,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// unsaved streams hold their external data
+	decoded, err := file.Get(ref).(Stream).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, content) {
+		t.Errorf("expected %q, got %q", content, decoded)
+	}
+
+	err = file.Save()
+	if err == nil {
+		t.Error("expected an error when saving without ExternalStreams")
+	}
+
+	file.ExternalStreams = true
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := ioutil.ReadFile(filepath.Join(dir, "content.z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, encoded) {
+		t.Error("external file does not have the encoded data")
+	}
+
+	file, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stream := file.Get(ref).(Stream)
+	if len(stream.Stream) != 0 {
+		t.Errorf("stream in the PDF file has %d bytes", len(stream.Stream))
+	}
+	_, err = stream.Decode()
+	if err == nil {
+		t.Error("expected an error when ExternalStreams is not set")
+	}
+
+	file.ExternalStreams = true
+	decoded, err = stream.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, content) {
+		t.Errorf("expected %q, got %q", content, decoded)
+	}
+}
+
+func TestExternalPath(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	err := os.Mkdir(sub, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Symlink(os.TempDir(), filepath.Join(sub, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &File{filename: filepath.Join(sub, "test.pdf")}
+
+	for i, test := range []struct {
+		spec Object
+		path string // empty when the spec is not allowed
+	}{
+		{String("data.bin"), filepath.Join(sub, "data.bin")},
+		{String("./a/../data.bin"), filepath.Join(sub, "data.bin")},
+		{Dictionary{"F": String("f.bin"), "UF": String("\xfe\xff\x00u\x00f\x00.\x00b\x00i\x00n")}, filepath.Join(sub, "uf.bin")},
+		{Dictionary{"Unix": String("unix.bin")}, filepath.Join(sub, "unix.bin")},
+		{String("../data.bin"), ""},
+		{String("a/../../data.bin"), ""},
+		{String("/etc/passwd"), ""},
+		{String("link/data.bin"), ""},
+		{String(`a\/b`), ""},
+		{Dictionary{"FS": Name("URL"), "F": String("http://example.com/data")}, ""},
+		{Dictionary{}, ""},
+		{Integer(1), ""},
+	} {
+		path, err := file.externalPath(test.spec)
+		if test.path == "" {
+			if err == nil {
+				t.Errorf("%d: expected an error for %v, got %s", i, test.spec, path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		if path != test.path {
+			t.Errorf("%d: expected %s, got %s", i, test.path, path)
+		}
+	}
+}
@@ -1,9 +1,5 @@
 package pdf
 
-// NOTE for encryption:
-// encrypt during Add
-// decrypt during Get
-
 import (
 	"bytes"
 	"errors"
@@ -34,6 +30,16 @@ type File struct {
 
 	prev Integer
 
+	// from the file header
+	version Version
+
+	// decrypts the objects read from the file and encrypts
+	// those that are saved, which differ after SetEncryption
+	security          *securityHandler
+	saveSecurity      *securityHandler
+	encryptionChanged bool
+	encryptRef        ObjectReference // when Encrypt is an indirect object
+
 	// The catalog dictionary for the PDF document contained in the file.
 	Root ObjectReference
 
@@ -45,49 +51,95 @@ type File struct {
 
 	// An array of two byte-strings constituting a file identifier for the file.
 	ID Array
+
+	// When CompressStreams is set, Save compresses the streams
+	// without filters, and the cross-reference stream, with FlateDecode.
+	CompressStreams bool
+
+	// When CheckLimits is set, Save returns a *LimitError, and
+	// writes nothing, when an added object exceeds the Limits
+	// of the file's Version.
+	CheckLimits bool
+
+	// When ExternalStreams is set, the data of streams with a file
+	// specification in their F entry is read from, and saved to,
+	// external files. The files must be in the PDF file's directory,
+	// or below it.
+	ExternalStreams bool
 }
 
 // Open opens a PDF file for manipulation of its objects.
+// Encrypted files are opened with an empty password,
+// which is often the user password.
 func Open(filename string) (*File, error) {
-	file := &File{
-		filename: filename,
-		objects:  map[uint]interface{}{},
+	return OpenWithPassword(filename, "")
+}
+
+// OpenWithPassword opens a PDF file that may be encrypted with the
+// standard security handler, using either its user or owner password.
+// Strings and streams read with Get are decrypted.
+// ErrPassword is returned when the password is incorrect.
+func OpenWithPassword(filename, password string) (*File, error) {
+	file := &File{filename: filename}
+	err := file.open()
+	if err != nil {
+		return nil, err
 	}
 
+	if len(file.Encrypt) != 0 {
+		file.security, err = newSecurityHandler(file.Encrypt, file.ID, password)
+		if err != nil {
+			err2 := file.Close()
+			if err2 != nil {
+				return nil, fmt.Errorf("%v %v", err, err2)
+			}
+			return nil, err
+		}
+		file.saveSecurity = file.security
+	}
+
+	return file, nil
+}
+
+// opens the file on disk and loads its cross references
+func (f *File) open() error {
+	f.objects = map[uint]interface{}{}
+
 	var err error
-	file.file, err = os.Open(filename)
+	f.file, err = os.Open(f.filename)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	file.mmap, err = mmap.Map(file.file, mmap.RDONLY, 0)
+	f.mmap, err = mmap.Map(f.file, mmap.RDONLY, 0)
 	if err != nil {
-		err2 := file.Close()
+		err2 := f.Close()
 		if err2 != nil {
-			return nil, fmt.Errorf("%v %v", err, err2)
+			return fmt.Errorf("%v %v", err, err2)
 		}
-		return nil, err
+		return err
 	}
 
 	// check pdf file header
-	if !bytes.Equal(file.mmap[:7], []byte("%PDF-1.")) {
-		err := file.Close()
+	if !isHeader(f.mmap) {
+		err := f.Close()
 		if err != nil {
-			return nil, errors.New("file does not have PDF header; " + err.Error())
+			return errors.New("file does not have PDF header; " + err.Error())
 		}
-		return nil, errors.New("file does not have PDF header")
+		return errors.New("file does not have PDF header")
 	}
+	f.version = Version(f.mmap[5:8])
 
-	err = file.loadReferences()
+	err = f.loadReferences()
 	if err != nil {
-		err2 := file.Close()
+		err2 := f.Close()
 		if err2 != nil {
-			return nil, fmt.Errorf("%v %v", err, err2)
+			return fmt.Errorf("%v %v", err, err2)
 		}
-		return nil, err
+		return err
 	}
 
-	return file, nil
+	return nil
 }
 
 // Create creates a new PDF file with no objects.
@@ -97,6 +149,7 @@ func Create(filename string) (*File, error) {
 		objects:  map[uint]interface{}{},
 		created:  true,
 		size:     1,
+		version:  "1.7",
 	}
 
 	// create enough of the pdf so that
@@ -112,7 +165,7 @@ func Create(filename string) (*File, error) {
 		}
 	}()
 
-	_, err = f.Write([]byte("%PDF-1.7"))
+	_, err = f.Write([]byte("%PDF-" + file.version))
 	if err != nil {
 		return nil, err
 	}
@@ -120,6 +173,30 @@ func Create(filename string) (*File, error) {
 	return file, nil
 }
 
+// header is %PDF-M.m (§7.5.2)
+func isHeader(data []byte) bool {
+	return len(data) >= 8 &&
+		bytes.Equal(data[:5], []byte("%PDF-")) &&
+		'1' <= data[5] && data[5] <= '9' &&
+		data[6] == '.' &&
+		'0' <= data[7] && data[7] <= '9'
+}
+
+// Version returns the version of PDF the file conforms to.
+// The catalog's Version entry is used when it is later
+// than the version in the file header (§7.7.2).
+func (f *File) Version() Version {
+	version := f.version
+
+	if catalog, ok := f.Get(f.Root).(Dictionary); ok {
+		if catalogVersion, ok := catalog[Name("Version")].(Name); ok && Version(catalogVersion) > version {
+			version = Version(catalogVersion)
+		}
+	}
+
+	return version
+}
+
 // Get returns the referenced object.
 // When the object does not exist, Null is returned.
 func (f *File) Get(ref ObjectReference) Object {
@@ -130,6 +207,7 @@ func (f *File) Get(ref ObjectReference) Object {
 	}
 
 	var object Object
+	encrypted := false // objects in object streams are not encrypted
 
 	switch typed := objectRaw.(type) {
 	case crossReference: // existing object
@@ -152,6 +230,9 @@ func (f *File) Get(ref ObjectReference) Object {
 				return Null{fmt.Errorf("%v's object is nil", ref)}
 			}
 			object = iobj.Object
+			ref = iobj.ObjectReference
+			// the encryption dictionary's strings are not encrypted (§7.6.2)
+			encrypted = f.security != nil && ref != f.encryptRef
 		case 2: // in object stream
 			// get the object stream
 			objectStreamRef := ObjectReference{ObjectNumber: typed[1]}
@@ -223,9 +304,18 @@ func (f *File) Get(ref ObjectReference) Object {
 			streamObj.Dictionary["Length"] = length
 			streamObj.Stream = streamObj.Stream[:int(length)]
 		}
+		streamObj.file = f
 		object = streamObj
 	}
 
+	if encrypted {
+		decrypted, err := f.security.decryptObject(object, ref)
+		if err != nil {
+			return Null{fmt.Errorf("could not decrypt %v: %v", ref, err)}
+		}
+		object = decrypted
+	}
+
 	return object
 }
 
@@ -311,11 +401,89 @@ func writeLineBreakTo(w io.Writer) (int64, error) {
 //
 // NOTE: A new object index will be written on each save,
 // taking space in the file on disk
+//
+// After SetEncryption, the whole file is rewritten, without its
+// earlier revisions, when it already has objects on disk.
 func (f *File) Save() error {
+	if f.CompressStreams {
+		err := f.compressStreams()
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.CheckLimits {
+		limits := LimitsFor(f.Version())
+		for _, object := range f.objects {
+			if iobj, ok := object.(IndirectObject); ok {
+				err := limits.Check(iobj)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	err := f.writeExternalStreams()
+	if err != nil {
+		return err
+	}
+
+	if f.encryptionChanged {
+		// objects already on disk have to be encrypted again
+		info, err := os.Stat(f.filename)
+		if err != nil {
+			return err
+		}
+		if info.Size() > int64(len("%PDF-"+f.version)) {
+			return f.rewrite()
+		}
+	}
+
 	// return f.saveUsingXrefTable()
-	return f.saveUsingXrefStream()
+	err = f.saveUsingXrefStream()
+	if err != nil {
+		return err
+	}
+	f.security = f.saveSecurity
+	f.encryptionChanged = false
+	return nil
 }
 
+// replaces added streams without filters with FlateDecode
+// compressed ones, unless compression would make them larger
+func (f *File) compressStreams() error {
+	for i, object := range f.objects {
+		iobj, ok := object.(IndirectObject)
+		if !ok {
+			continue
+		}
+		stream, ok := iobj.Object.(Stream)
+		if !ok {
+			continue
+		}
+		if _, ok := stream.Dictionary["Filter"]; ok {
+			continue
+		}
+		if _, ok := stream.Dictionary["F"]; ok {
+			continue
+		}
+
+		compressed, err := stream.Encode("FlateDecode")
+		if err != nil {
+			return err
+		}
+		if len(compressed.Stream) < len(stream.Stream) {
+			iobj.Object = compressed
+			f.objects[i] = iobj
+		}
+	}
+	return nil
+}
+
+// cross-reference table entries have 10 digit offsets (§7.5.4)
+const maxXrefTableOffset = 9999999999
+
 func (f *File) saveUsingXrefTable() error {
 	info, err := os.Stat(f.filename)
 	if err != nil {
@@ -357,7 +525,11 @@ func (f *File) saveUsingXrefTable() error {
 			}
 		case IndirectObject:
 			xrefs[Integer(i)] = crossReference{1, uint(offset - 1), typed.GenerationNumber}
-			n, err = typed.writeTo(file)
+			saved, err := f.saveObject(typed)
+			if err != nil {
+				return err
+			}
+			n, err = saved.writeTo(file)
 			if err != nil {
 				return err
 			}
@@ -412,6 +584,9 @@ func (f *File) saveUsingXrefTable() error {
 		fmt.Fprintf(file, "%d %d\n", group[0], len(group))
 		for _, objectNumber := range group {
 			xref := xrefs[Integer(objectNumber)]
+			if xref[1] > maxXrefTableOffset {
+				return fmt.Errorf("offset %d of object %d does not fit in a cross-reference table", xref[1], objectNumber)
+			}
 			fmt.Fprintf(file, "%010d %05d ", xref[1], xref[2])
 			switch xref[0] {
 			case 0:
@@ -516,7 +691,11 @@ func (f *File) saveUsingXrefStream() error {
 			}
 		case IndirectObject:
 			xrefs[Integer(i)] = crossReference{1, uint(offset - 1), typed.GenerationNumber}
-			n, err = typed.writeTo(file)
+			saved, err := f.saveObject(typed)
+			if err != nil {
+				return err
+			}
+			n, err = saved.writeTo(file)
 			if err != nil {
 				return err
 			}
@@ -647,14 +826,26 @@ func (f *File) saveUsingXrefStream() error {
 		}
 	}
 
+	xrefstreamObject := Stream{
+		Dictionary: trailer,
+		Stream:     stream.Bytes(),
+	}
+	if f.CompressStreams {
+		// rows of entries compress best with the PNG Up predictor
+		columns := nBytes[0] + nBytes[1] + nBytes[2]
+		xrefstreamObject, err = EncodeStream(trailer, stream.Bytes(), []Name{"FlateDecode"}, []Dictionary{
+			{"Predictor": Integer(12), "Columns": Integer(columns)},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	xrefstream := IndirectObject{
 		ObjectReference: ObjectReference{
 			ObjectNumber: xrefstreamObjectNumber,
 		},
-		Object: Stream{
-			Dictionary: trailer,
-			Stream:     stream.Bytes(),
-		},
+		Object: xrefstreamObject,
 	}
 	_, err = f.Add(xrefstream)
 	if err != nil {
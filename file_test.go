@@ -0,0 +1,98 @@
+package pdf
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveCompressStreams(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "compressed.pdf")
+	file, err := Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := bytes.Repeat([]byte("0 0 m 100 100 l S\n"), 100)
+	contentRef, err := file.Add(Stream{Dictionary: Dictionary{}, Stream: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// compression would make this one larger
+	tinyRef, err := file.Add(Stream{Dictionary: Dictionary{}, Stream: []byte("q")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Root, err = file.Add(Dictionary{"Type": Name("Catalog")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file.CompressStreams = true
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stream := file.Get(contentRef).(Stream)
+	if stream.Dictionary["Filter"] != Name("FlateDecode") {
+		t.Errorf("expected FlateDecode, got %v", stream.Dictionary["Filter"])
+	}
+	if len(stream.Stream) >= len(content) {
+		t.Errorf("%d bytes compressed to %d", len(content), len(stream.Stream))
+	}
+	decoded, err := stream.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, content) {
+		t.Error("decoded content does not match")
+	}
+
+	tiny := file.Get(tinyRef).(Stream)
+	if _, ok := tiny.Dictionary["Filter"]; ok || string(tiny.Stream) != "q" {
+		t.Errorf("tiny stream was changed to %v %q", tiny.Dictionary, tiny.Stream)
+	}
+}
+
+func TestSaveCheckLimits(t *testing.T) {
+	file, err := Create(filepath.Join(t.TempDir(), "limits.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if file.Version() >= "2.0" {
+		t.Fatalf("expected a version with limits, got %s", file.Version())
+	}
+
+	file.Root, err = file.Add(Dictionary{
+		"Type":   Name("Catalog"),
+		"JS":     String(bytes.Repeat([]byte("a"), 40000)),
+		"Offset": Integer(5 << 30),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// values beyond Annex C are written unless checked
+	file.CheckLimits = true
+	err = file.Save()
+	if _, ok := err.(*LimitError); !ok {
+		t.Fatalf("expected a *LimitError, got %v", err)
+	}
+	file.CheckLimits = false
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
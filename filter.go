@@ -0,0 +1,180 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// A Filter decodes and encodes stream data.
+// The parameters are the filter's entry in the stream's DecodeParms,
+// or an empty Dictionary when there is none.
+// - §7.4
+type Filter interface {
+	// NewDecoder returns a reader of the data decoded from r.
+	// Errors, including those in params, are returned by Read.
+	NewDecoder(r io.Reader, params Dictionary) io.Reader
+
+	// NewEncoder returns a writer that encodes data to w.
+	// Close flushes the encoded data, it does not close w.
+	// Errors, including those in params, are returned by Write and Close.
+	NewEncoder(w io.Writer, params Dictionary) io.WriteCloser
+}
+
+var (
+	filtersMutex sync.RWMutex
+	filters      = map[Name]Filter{}
+)
+
+// the standard filters (§7.4.1 Table 6),
+// registered here because JBIG2Decode uses Decode for its globals
+func init() {
+	for name, filter := range map[Name]Filter{
+		Name("ASCIIHexDecode"):  asciiHexFilter{},
+		Name("ASCII85Decode"):   ascii85Filter{},
+		Name("LZWDecode"):       lzwFilter{},
+		Name("FlateDecode"):     flateFilter{},
+		Name("RunLengthDecode"): runLengthFilter{},
+		Name("CCITTFaxDecode"):  bufferFilter{ccittDecode, ccittEncode},
+		Name("JBIG2Decode"):     bufferFilter{decode: jbig2Decode},
+	} {
+		RegisterFilter(name, filter)
+	}
+}
+
+// RegisterFilter makes a filter available to streams with name
+// in their Filter entry. A filter already registered with the
+// name, including the standard filters, is replaced.
+func RegisterFilter(name Name, filter Filter) {
+	filtersMutex.Lock()
+	defer filtersMutex.Unlock()
+	filters[name] = filter
+}
+
+func lookupFilter(name Name) (Filter, bool) {
+	filtersMutex.RLock()
+	defer filtersMutex.RUnlock()
+	filter, ok := filters[name]
+	return filter, ok
+}
+
+// bufferFilter adapts filters that need all of their data at once
+type bufferFilter struct {
+	decode, encode func([]byte, Dictionary) ([]byte, error)
+}
+
+func (f bufferFilter) NewDecoder(r io.Reader, params Dictionary) io.Reader {
+	return &bufferReader{r: r, params: params, decode: f.decode}
+}
+
+func (f bufferFilter) NewEncoder(w io.Writer, params Dictionary) io.WriteCloser {
+	if f.encode == nil {
+		return errorWriter{errors.New("encoding is not supported")}
+	}
+	return &bufferWriter{w: w, params: params, encode: f.encode}
+}
+
+// decodes all of the data on the first Read
+type bufferReader struct {
+	r       io.Reader
+	params  Dictionary
+	decode  func([]byte, Dictionary) ([]byte, error)
+	decoded io.Reader
+}
+
+func (b *bufferReader) Read(p []byte) (int, error) {
+	if b.decoded == nil {
+		encoded, err := ioutil.ReadAll(b.r)
+		if err != nil {
+			b.decoded = errorReader{err}
+			return 0, err
+		}
+
+		decoded, err := b.decode(encoded, b.params)
+		if err != nil {
+			b.decoded = errorReader{err}
+			return 0, err
+		}
+		b.decoded = bytes.NewReader(decoded)
+	}
+	return b.decoded.Read(p)
+}
+
+// encodes all of the data on Close
+type bufferWriter struct {
+	w      io.Writer
+	params Dictionary
+	encode func([]byte, Dictionary) ([]byte, error)
+	buf    bytes.Buffer
+}
+
+func (b *bufferWriter) Write(p []byte) (int, error) {
+	return b.buf.Write(p)
+}
+
+func (b *bufferWriter) Close() error {
+	encoded, err := b.encode(b.buf.Bytes(), b.params)
+	if err != nil {
+		return err
+	}
+	_, err = b.w.Write(encoded)
+	return err
+}
+
+// prefixes errors with the name of the filter that caused them
+type filterReader struct {
+	name Name
+	r    io.Reader
+}
+
+type filterError struct {
+	name Name
+	err  error
+}
+
+func (e filterError) Error() string {
+	return string(e.name) + ": " + e.err.Error()
+}
+
+func (f filterReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err != nil && err != io.EOF {
+		if _, ok := err.(filterError); !ok {
+			err = filterError{f.name, err}
+		}
+	}
+	return n, err
+}
+
+// errorReader and errorWriter report errors found
+// before any data is read or written
+type errorReader struct {
+	err error
+}
+
+func (e errorReader) Read(p []byte) (int, error) {
+	return 0, e.err
+}
+
+type errorWriter struct {
+	err error
+}
+
+func (e errorWriter) Write(p []byte) (int, error) {
+	return 0, e.err
+}
+
+func (e errorWriter) Close() error {
+	return e.err
+}
+
+// adds a Close that does nothing
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,219 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// decodes data with a single registered filter
+func decode(name Name, data []byte, params Dictionary) ([]byte, error) {
+	filter, _ := lookupFilter(name)
+	return ioutil.ReadAll(filter.NewDecoder(bytes.NewReader(data), params))
+}
+
+// encodes data with a single registered filter
+func encode(name Name, data []byte, params Dictionary) ([]byte, error) {
+	filter, _ := lookupFilter(name)
+	buf := &bytes.Buffer{}
+	w := filter.NewEncoder(buf, params)
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	return buf.Bytes(), err
+}
+
+// xors each byte with the Key parameter
+type xorFilter struct{}
+
+type xorReader struct {
+	r   io.Reader
+	key byte
+}
+
+func (x xorReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	for i := range p[:n] {
+		p[i] ^= x.key
+	}
+	return n, err
+}
+
+type xorWriter struct {
+	w   io.Writer
+	key byte
+}
+
+func (x xorWriter) Write(p []byte) (int, error) {
+	xored := make([]byte, len(p))
+	for i := range p {
+		xored[i] = p[i] ^ x.key
+	}
+	return x.w.Write(xored)
+}
+
+func (x xorWriter) Close() error {
+	return nil
+}
+
+func (xorFilter) NewDecoder(r io.Reader, params Dictionary) io.Reader {
+	key, _ := params["Key"].(Integer)
+	return xorReader{r, byte(key)}
+}
+
+func (xorFilter) NewEncoder(w io.Writer, params Dictionary) io.WriteCloser {
+	key, _ := params["Key"].(Integer)
+	return xorWriter{w, byte(key)}
+}
+
+func TestRegisterFilter(t *testing.T) {
+	RegisterFilter("XORDecode", xorFilter{})
+	defer func() {
+		filtersMutex.Lock()
+		delete(filters, "XORDecode")
+		filtersMutex.Unlock()
+	}()
+
+	params := Dictionary{"Key": Integer(0x20)}
+	xored, err := encode("XORDecode", []byte("hello"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hex, err := encode("ASCIIHexDecode", xored, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := Stream{
+		Dictionary: Dictionary{
+			"Filter":      Array{Name("ASCIIHexDecode"), Name("XORDecode")},
+			"DecodeParms": Array{Null{}, params},
+		},
+		Stream: hex,
+	}
+	decoded, err := stream.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != "hello" {
+		t.Errorf("expected %q, got %q", "hello", decoded)
+	}
+}
+
+func TestStreamReader(t *testing.T) {
+	// rows of 100 bytes with the PNG Up predictor
+	params := Dictionary{"Predictor": Integer(12), "Columns": Integer(100)}
+	data := make([]byte, 100*1000)
+	for i := range data {
+		data[i] = byte(i / 100 * (i % 100))
+	}
+	encoded, err := encode("FlateDecode", data, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := Stream{
+		Dictionary: Dictionary{
+			"Filter":      Array{Name("ASCII85Decode"), Name("FlateDecode")},
+			"DecodeParms": Array{Null{}, params},
+		},
+	}
+	stream.Stream, err = encode("ASCII85Decode", encoded, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := stream.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// read in pieces smaller than a row
+	decoded := []byte{}
+	buf := make([]byte, 37)
+	for {
+		n, err := r.Read(buf)
+		decoded = append(decoded, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("decoded data does not match")
+	}
+}
+
+// data is only decoded when it is read
+func TestStreamReaderErrors(t *testing.T) {
+	stream := Stream{
+		Dictionary: Dictionary{"Filter": Array{Name("ASCIIHexDecode"), Name("FlateDecode")}},
+		Stream:     []byte("78 9c zz>"),
+	}
+	r, err := stream.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ioutil.ReadAll(r)
+	if err == nil || !strings.HasPrefix(err.Error(), "ASCIIHexDecode: ") {
+		t.Errorf("expected an ASCIIHexDecode error, got %v", err)
+	}
+
+	for i, dict := range []Dictionary{
+		{"Filter": Name("NoSuchDecode")},
+		{"Filter": Integer(1)},
+		{"Filter": Name("FlateDecode"), "DecodeParms": Integer(1)},
+	} {
+		_, err := Stream{Dictionary: dict}.Reader()
+		if err == nil {
+			t.Errorf("%d: expected an error for %v", i, dict)
+		}
+	}
+}
+
+// LZW data is decoded as it is read, not after all of it has been
+func TestLZWReader(t *testing.T) {
+	data := bytes.Repeat([]byte("abcdefgh"), 10000)
+	encoded, err := encode("LZWDecode", data, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("read failed")
+	r := lzwFilter{}.NewDecoder(io.MultiReader(bytes.NewReader(encoded[:len(encoded)/2]), errorReader{failure}), Dictionary{})
+	decoded, err := ioutil.ReadAll(r)
+	if err != failure {
+		t.Errorf("expected %v, got %v", failure, err)
+	}
+	if len(decoded) == 0 || !bytes.Equal(decoded, data[:len(decoded)]) {
+		t.Errorf("decoded %d bytes before the error", len(decoded))
+	}
+}
+
+// zlib checksums are often wrong in PDF files
+func TestFlateChecksum(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := zlib.NewWriter(buf)
+	w.Write([]byte("content"))
+	w.Close()
+
+	encoded := buf.Bytes()
+	encoded[len(encoded)-1] ^= 0xff
+
+	decoded, err := decode("FlateDecode", encoded, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != "content" {
+		t.Errorf("expected %q, got %q", "content", decoded)
+	}
+}
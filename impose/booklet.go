@@ -0,0 +1,188 @@
+package impose
+
+import (
+	"fmt"
+
+	"github.com/nathankerr/pdf"
+)
+
+// Sides is the order the fronts and backs of sheets are in.
+type Sides int
+
+// The orders of the sides.
+const (
+	// the front and then the back of each sheet,
+	// for printing on both sides at once
+	Duplex Sides = iota
+	// the fronts of the sheets and then their backs, for printing
+	// the backs after turning over the printed fronts
+	FrontsThenBacks
+	// the fronts of the sheets and then their backs in reverse,
+	// for printers that reverse the order of the printed fronts
+	FrontsThenReversedBacks
+)
+
+// A Booklet places two pages on each side of sheets that are folded in
+// half and bound along the fold. The sheets of a signature are nested
+// inside each other, outermost first.
+type Booklet struct {
+	// The size of the sheets, which are folded vertically in their
+	// middle. The zero value is twice the width of the first page.
+	Sheet Size
+
+	// The number of pages, a multiple of 4 such as 8, 16 or 32, in each
+	// signature, the sheets that are folded together. The signatures
+	// are stacked to be perfect bound. With 0 pages, the booklet is
+	// one signature of all the pages that is saddle stitched.
+	Signature int
+
+	Scaling Scaling
+	Sides   Sides
+
+	// turns the backs of the sheets upside down,
+	// for duplex printing that flips sheets on their short edge
+	RotateBacks bool
+
+	// The distance in points the pages of the innermost sheet of a
+	// signature are moved toward the fold, which compensates for the
+	// inner sheets sticking out further when folded (creep). The pages
+	// of the other sheets are moved proportionally less.
+	Creep float64
+}
+
+// Impose replaces the document's pages with the sheets of the booklet.
+func (b Booklet) Impose(document *pdf.Document) error {
+	pages, err := document.Pages()
+	if err != nil {
+		return err
+	}
+	sheets, err := b.Sheets(pages)
+	if err != nil {
+		return err
+	}
+	return Impose(document, sheets)
+}
+
+// Sheets returns the sides of the sheets the pages are placed on. Each
+// signature is padded with blank pages at its end to a multiple of 4
+// pages; the last signature may be smaller than the others.
+func (b Booklet) Sheets(pages []pdf.Page) ([]Sheet, error) {
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages to impose")
+	}
+	if b.Signature < 0 || b.Signature%4 != 0 {
+		return nil, fmt.Errorf("signature of %d pages is not a multiple of 4", b.Signature)
+	}
+
+	size := b.Sheet
+	if size == (Size{}) {
+		width, height, err := pages[0].DisplaySize()
+		if err != nil {
+			return nil, err
+		}
+		size = Size{2 * width, height}
+	}
+	if size.Width <= 0 || size.Height <= 0 {
+		return nil, fmt.Errorf("sheet has size %v", size)
+	}
+	left := pdf.NewRectangle(0, 0, size.Width/2, size.Height)
+	right := pdf.NewRectangle(size.Width/2, 0, size.Width, size.Height)
+
+	// every page fits in either half
+	placements := make([]Placement, len(pages))
+	for i, page := range pages {
+		width, height, err := page.DisplaySize()
+		if err != nil {
+			return nil, err
+		}
+		if width <= 0 || height <= 0 {
+			return nil, fmt.Errorf("page %d is empty", i)
+		}
+		placements[i] = Placement{Page: i, Scale: fit(width, height, left)}
+	}
+	err := scale(placements, b.Scaling)
+	if err != nil {
+		return nil, err
+	}
+
+	// places the page, which is blank past the last page,
+	// moved by creep toward the fold
+	place := func(sheet *Sheet, index int, box pdf.Rectangle, creep float64) {
+		if index >= len(placements) {
+			return
+		}
+		placement := placements[index]
+		placement.Box = box
+		placement.OffsetX = creep
+		if box == right {
+			placement.OffsetX = -creep
+		}
+		sheet.Placements = append(sheet.Placements, placement)
+	}
+
+	total := (len(pages) + 3) / 4 * 4
+	signature := b.Signature
+	if signature == 0 {
+		signature = total
+	}
+	fronts, backs := []Sheet{}, []Sheet{}
+	for start := 0; start < total; start += signature {
+		n := signature
+		if n > total-start {
+			n = total - start
+		}
+		last := start + n - 1
+
+		// the first and last pages are on the outermost sheet
+		nested := n / 4
+		for i := 0; i < nested; i++ {
+			creep := 0.0
+			if nested > 1 {
+				creep = b.Creep * float64(i) / float64(nested-1)
+			}
+
+			front := Sheet{Size: size}
+			place(&front, last-2*i, left, creep)
+			place(&front, start+2*i, right, creep)
+			back := Sheet{Size: size}
+			place(&back, start+2*i+1, left, creep)
+			place(&back, last-2*i-1, right, creep)
+			if b.RotateBacks {
+				back = back.turned()
+			}
+			fronts = append(fronts, front)
+			backs = append(backs, back)
+		}
+	}
+
+	sheets := []Sheet{}
+	switch b.Sides {
+	case Duplex:
+		for i := range fronts {
+			sheets = append(sheets, fronts[i], backs[i])
+		}
+	case FrontsThenBacks:
+		sheets = append(fronts, backs...)
+	case FrontsThenReversedBacks:
+		sheets = fronts
+		for i := len(backs) - 1; i >= 0; i-- {
+			sheets = append(sheets, backs[i])
+		}
+	default:
+		return nil, fmt.Errorf("unknown sides %d", b.Sides)
+	}
+	return sheets, nil
+}
+
+// the sheet turned upside down
+func (s Sheet) turned() Sheet {
+	turned := Sheet{Size: s.Size, Frames: s.Frames}
+	for _, placement := range s.Placements {
+		box := placement.Box
+		placement.Box = pdf.NewRectangle(s.Size.Width-box.LLX, s.Size.Height-box.LLY, s.Size.Width-box.URX, s.Size.Height-box.URY)
+		placement.Rotate = (placement.Rotate + 180) % 360
+		placement.OffsetX, placement.OffsetY = -placement.OffsetX, -placement.OffsetY
+		turned.Placements = append(turned.Placements, placement)
+	}
+	return turned
+}
@@ -0,0 +1,184 @@
+package impose
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/nathankerr/pdf"
+	"github.com/nathankerr/pdf/content"
+)
+
+// the pages on the halves of each side, with - for a blank half
+// and ^ marking pages that are upside down
+func sides(sheets []Sheet) [][2]string {
+	result := [][2]string{}
+	for _, sheet := range sheets {
+		side := [2]string{"-", "-"}
+		for _, placement := range sheet.Placements {
+			half := 0
+			if placement.Box.LLX >= sheet.Size.Width/2 {
+				half = 1
+			}
+			side[half] = fmt.Sprint(placement.Page)
+			if placement.Rotate == 180 {
+				side[half] += "^"
+			}
+		}
+		result = append(result, side)
+	}
+	return result
+}
+
+func TestBookletSheets(t *testing.T) {
+	sizes := make([]Size, 10)
+	rotations := make([]int, len(sizes))
+	for i := range sizes {
+		sizes[i] = Size{100, 200}
+	}
+	document := testDocument(t, sizes, rotations)
+	pages, err := document.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		pages    int
+		booklet  Booklet
+		expected [][2]string
+	}{
+		// saddle stitched, with blank pages at the end
+		{6, Booklet{}, [][2]string{
+			{"-", "0"}, {"1", "-"},
+			{"5", "2"}, {"3", "4"},
+		}},
+		{6, Booklet{Sides: FrontsThenBacks}, [][2]string{
+			{"-", "0"}, {"5", "2"},
+			{"1", "-"}, {"3", "4"},
+		}},
+		{6, Booklet{Sides: FrontsThenReversedBacks}, [][2]string{
+			{"-", "0"}, {"5", "2"},
+			{"3", "4"}, {"1", "-"},
+		}},
+		{4, Booklet{RotateBacks: true}, [][2]string{
+			{"3", "0"}, {"2^", "1^"},
+		}},
+		// signatures of 8 pages, the last padded to 4
+		{10, Booklet{Signature: 8}, [][2]string{
+			{"7", "0"}, {"1", "6"},
+			{"5", "2"}, {"3", "4"},
+			{"-", "8"}, {"9", "-"},
+		}},
+		{8, Booklet{Signature: 4}, [][2]string{
+			{"3", "0"}, {"1", "2"},
+			{"7", "4"}, {"5", "6"},
+		}},
+	} {
+		sheets, err := test.booklet.Sheets(pages[:test.pages])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sides(sheets); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%d pages in %+v are on %v instead of %v", test.pages, test.booklet, got, test.expected)
+		}
+		for _, sheet := range sheets {
+			if sheet.Size != (Size{200, 200}) {
+				t.Errorf("sheet has size %v", sheet.Size)
+			}
+		}
+	}
+
+	_, err = Booklet{Signature: 6}.Sheets(pages)
+	if err == nil {
+		t.Error("expected an error for a signature of 6 pages")
+	}
+}
+
+func TestBookletCreep(t *testing.T) {
+	sizes := make([]Size, 12)
+	for i := range sizes {
+		sizes[i] = Size{100, 200}
+	}
+	document := testDocument(t, sizes, make([]int, len(sizes)))
+	pages, err := document.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	booklet := Booklet{Sheet: Size{400, 300}, Creep: 4, RotateBacks: true}
+	sheets, err := booklet.Sheets(pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 6 {
+		t.Fatalf("expected 6 sides, got %d", len(sheets))
+	}
+
+	// the innermost sheet's pages are moved the furthest toward the fold
+	for i, sheet := range sheets {
+		creep := []float64{0, 2, 4}[i/2]
+		for _, placement := range sheet.Placements {
+			_, placed, err := placement.transform(100, 200)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the pages are scaled to the height of the sheet
+			if !closeTo(placement.Scale, 1.5) {
+				t.Errorf("page %d has scale %v", placement.Page, placement.Scale)
+			}
+			fold := placed.URX
+			if placement.Box.LLX >= 200 {
+				fold = 400 - placed.LLX
+			}
+			if !closeTo(fold, 200-25+creep) {
+				t.Errorf("side %d page %d is at %v", i, placement.Page, placed)
+			}
+		}
+	}
+}
+
+func TestBookletImpose(t *testing.T) {
+	document := testDocument(t, []Size{A5, A5, A5}, []int{0, 0, 90})
+	err := Booklet{Sheet: A4.Landscape(), RotateBacks: true}.Impose(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheets, err := document.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 2 {
+		t.Fatalf("expected 2 sides, got %d", len(sheets))
+	}
+	for i, sheet := range sheets {
+		mediaBox, err := sheet.MediaBox()
+		if err != nil || !closeTo(mediaBox.Width(), A4.Height) || !closeTo(mediaBox.Height(), A4.Width) {
+			t.Errorf("side %d has MediaBox %v %v", i, mediaBox, err)
+		}
+		if xobjects := sheet.Resources()["XObject"].(pdf.Dictionary); len(xobjects) != i+1 {
+			t.Errorf("side %d has XObjects %v", i, xobjects)
+		}
+
+		// the pages on the back are upside down
+		stream := document.File.Get(sheet.Dictionary["Contents"].(pdf.ObjectReference)).(pdf.Stream)
+		operations, err := content.Parse(stream.Stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, operation := range operations {
+			if operation.Operator != "cm" {
+				continue
+			}
+			var a float64
+			switch n := operation.Operands[0].(type) {
+			case pdf.Integer:
+				a = float64(n)
+			case pdf.Real:
+				a = float64(n)
+			}
+			if (a < 0) != (i == 1) {
+				t.Errorf("side %d has %v", i, operation)
+			}
+		}
+	}
+}
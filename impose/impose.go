@@ -0,0 +1,182 @@
+/*
+Package impose arranges the pages of a document on sheets, such as
+several pages on each side of a sheet of paper or the folded sheets
+of a booklet.
+
+Each page is drawn as a form XObject (§8.10), see pdf.Page.AsFormXObject,
+so its crop box and rotation are kept. The sheets replace the pages
+in the document's page tree. Links and outline items that go to the
+original pages are not moved to the sheets.
+*/
+package impose
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nathankerr/pdf"
+	"github.com/nathankerr/pdf/content"
+)
+
+// A Sheet is one side of a sheet with pages placed on it.
+type Sheet struct {
+	Size       Size
+	Placements []Placement
+
+	// draws a line around each placed page
+	Frames bool
+}
+
+// A Placement draws a page in a box on a sheet. The page is rotated,
+// scaled, centered in the box and clipped to it.
+type Placement struct {
+	Page int // the index of the page in the document
+
+	// the region of the sheet, in default user space (§8.3.2.3)
+	Box pdf.Rectangle
+
+	// the number of degrees, a multiple of 90, the page
+	// is turned counterclockwise by
+	Rotate int
+
+	// the factor the page's size is multiplied by
+	Scale float64
+
+	// moves the page from the center of the box,
+	// which it is still clipped to
+	OffsetX, OffsetY float64
+}
+
+// Impose replaces the document's pages with the sheets.
+func Impose(document *pdf.Document, sheets []Sheet) error {
+	pages, err := document.Pages()
+	if err != nil {
+		return err
+	}
+
+	// each page is added as an XObject once,
+	// however many times it is placed
+	xobjects := map[int]pdf.ObjectReference{}
+	xobject := func(index int) (pdf.ObjectReference, error) {
+		if ref, ok := xobjects[index]; ok {
+			return ref, nil
+		}
+		if index < 0 || index >= len(pages) {
+			return pdf.ObjectReference{}, fmt.Errorf("page index %d is out of range", index)
+		}
+		stream, err := pages[index].AsFormXObject()
+		if err != nil {
+			return pdf.ObjectReference{}, err
+		}
+		ref, err := document.File.Add(stream)
+		if err != nil {
+			return pdf.ObjectReference{}, err
+		}
+		xobjects[index] = ref
+		return ref, nil
+	}
+
+	refs := []pdf.ObjectReference{}
+	for i, sheet := range sheets {
+		if sheet.Size.Width <= 0 || sheet.Size.Height <= 0 {
+			return fmt.Errorf("sheet %d has size %v", i, sheet.Size)
+		}
+
+		names := pdf.Dictionary{}
+		stream := &content.ContentBuilder{}
+		if sheet.Frames {
+			stream.SetLineWidth(0.5)
+		}
+		for _, placement := range sheet.Placements {
+			ref, err := xobject(placement.Page)
+			if err != nil {
+				return err
+			}
+			name := pdf.Name(fmt.Sprintf("Page%d", placement.Page))
+			names[name] = ref
+
+			width, height, err := pages[placement.Page].DisplaySize()
+			if err != nil {
+				return err
+			}
+			matrix, placed, err := placement.transform(width, height)
+			if err != nil {
+				return fmt.Errorf("sheet %d: %v", i, err)
+			}
+
+			box := placement.Box
+			stream.Save()
+			stream.Rectangle(box.LLX, box.LLY, box.Width(), box.Height())
+			stream.Clip()
+			stream.EndPath()
+			stream.Transform(matrix[0], matrix[1], matrix[2], matrix[3], matrix[4], matrix[5])
+			stream.DrawXObject(name)
+			stream.Restore()
+
+			if sheet.Frames {
+				frame := placed.Intersect(box)
+				stream.Rectangle(frame.LLX, frame.LLY, frame.Width(), frame.Height())
+				stream.Stroke()
+			}
+		}
+
+		contents, err := stream.Stream()
+		if err != nil {
+			return err
+		}
+		contentsRef, err := document.File.Add(contents)
+		if err != nil {
+			return err
+		}
+		ref, err := document.File.Add(pdf.Dictionary{
+			"Type":      pdf.Name("Page"),
+			"MediaBox":  pdf.NewRectangle(0, 0, sheet.Size.Width, sheet.Size.Height).Array(),
+			"Resources": pdf.Dictionary{"XObject": names},
+			"Contents":  contentsRef,
+		})
+		if err != nil {
+			return err
+		}
+		refs = append(refs, ref)
+	}
+
+	indexes := make([]int, len(pages))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	err = document.DeletePages(indexes...)
+	if err != nil {
+		return err
+	}
+	return document.InsertPages(0, refs...)
+}
+
+// the transformation matrix that draws a page of the width and height
+// at the placement and the rectangle the page is drawn in
+func (p Placement) transform(width, height float64) ([6]float64, pdf.Rectangle, error) {
+	if p.Rotate%90 != 0 {
+		return [6]float64{}, pdf.Rectangle{}, fmt.Errorf("page %d rotation %d is not a multiple of 90", p.Page, p.Rotate)
+	}
+	if p.Scale <= 0 {
+		return [6]float64{}, pdf.Rectangle{}, fmt.Errorf("page %d has scale %v", p.Page, p.Scale)
+	}
+	sin, cos := math.Sincos(float64(p.Rotate) * math.Pi / 180)
+	sin, cos = math.Round(sin), math.Round(cos)
+	a, b, c, d := p.Scale*cos, p.Scale*sin, -p.Scale*sin, p.Scale*cos
+
+	// the page's corners after it is rotated and scaled
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{0, 0}, {width, 0}, {0, height}, {width, height}} {
+		x := a*corner[0] + c*corner[1]
+		y := b*corner[0] + d*corner[1]
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+
+	// centered in the box
+	left := p.Box.LLX + (p.Box.Width()-(maxX-minX))/2 + p.OffsetX
+	bottom := p.Box.LLY + (p.Box.Height()-(maxY-minY))/2 + p.OffsetY
+	placed := pdf.NewRectangle(left, bottom, left+maxX-minX, bottom+maxY-minY)
+	return [6]float64{a, b, c, d, left - minX, bottom - minY}, placed, nil
+}
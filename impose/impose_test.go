@@ -0,0 +1,252 @@
+package impose
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nathankerr/pdf"
+	"github.com/nathankerr/pdf/content"
+)
+
+// a document with pages of the sizes and rotations
+func testDocument(t *testing.T, sizes []Size, rotations []int) *pdf.Document {
+	file, err := pdf.Create(filepath.Join(t.TempDir(), "impose.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	document, err := pdf.NewDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refs := []pdf.ObjectReference{}
+	for i, size := range sizes {
+		contents, err := file.Add(pdf.Stream{Dictionary: pdf.Dictionary{}, Stream: []byte("0 0 m 10 10 l S")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := file.Add(pdf.Dictionary{
+			"Type":     pdf.Name("Page"),
+			"MediaBox": pdf.NewRectangle(0, 0, size.Width, size.Height).Array(),
+			"Rotate":   pdf.Integer(rotations[i]),
+			"Contents": contents,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs = append(refs, ref)
+	}
+	err = document.InsertPages(0, refs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestGrid(t *testing.T) {
+	for _, test := range []struct {
+		n             int
+		sheet         Size
+		columns, rows int
+		landscape     bool
+	}{
+		{1, A4, 1, 1, false},
+		{2, A4, 2, 1, true},
+		{4, A4, 2, 2, false},
+		{8, A4, 4, 2, true},
+		{9, A4, 3, 3, false},
+		{2, A4.Landscape(), 1, 2, false},
+	} {
+		layout, err := Grid(test.n, test.sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		landscape := layout.Sheet.Width > layout.Sheet.Height
+		if layout.Columns != test.columns || layout.Rows != test.rows || landscape != test.landscape {
+			t.Errorf("%d-up is %dx%d, landscape %v", test.n, layout.Columns, layout.Rows, landscape)
+		}
+	}
+
+	_, err := Grid(0, A4)
+	if err == nil {
+		t.Error("expected an error for 0-up")
+	}
+}
+
+func TestCells(t *testing.T) {
+	layout := Layout{
+		Columns:      2,
+		Rows:         2,
+		Sheet:        Size{220, 120},
+		Margins:      Margins{Top: 10, Right: 5, Bottom: 0, Left: 5},
+		ColumnGutter: 10,
+		RowGutter:    10,
+	}
+	topLeft := pdf.NewRectangle(5, 60, 105, 110)
+	topRight := pdf.NewRectangle(115, 60, 215, 110)
+	bottomLeft := pdf.NewRectangle(5, 0, 105, 50)
+	bottomRight := pdf.NewRectangle(115, 0, 215, 50)
+
+	for order, expected := range map[Order][]pdf.Rectangle{
+		RowsLeftToRight:    {topLeft, topRight, bottomLeft, bottomRight},
+		RowsRightToLeft:    {topRight, topLeft, bottomRight, bottomLeft},
+		ColumnsLeftToRight: {topLeft, bottomLeft, topRight, bottomRight},
+		ColumnsRightToLeft: {topRight, bottomRight, topLeft, bottomLeft},
+	} {
+		layout.Order = order
+		cells, err := layout.cells()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cells, expected) {
+			t.Errorf("order %d has cells %v", order, cells)
+		}
+	}
+
+	layout.ColumnGutter = 300
+	_, err := layout.cells()
+	if err == nil {
+		t.Error("expected an error when the gutter is wider than the sheet")
+	}
+}
+
+func TestPlacementTransform(t *testing.T) {
+	box := pdf.NewRectangle(10, 10, 210, 110)
+	for _, rotate := range []int{0, 90, 180, 270, -90} {
+		placement := Placement{Box: box, Rotate: rotate, Scale: 0.5}
+		m, placed, err := placement.transform(100, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		width, height := 50.0, 25.0
+		if rotate%180 != 0 {
+			width, height = height, width
+		}
+		if !closeTo(placed.Width(), width) || !closeTo(placed.Height(), height) ||
+			!closeTo(placed.LLX+placed.URX, box.LLX+box.URX) || !closeTo(placed.LLY+placed.URY, box.LLY+box.URY) {
+			t.Errorf("rotation %d placed the page in %v", rotate, placed)
+		}
+
+		// the page's corners are the placed rectangle's corners
+		for _, corner := range [][2]float64{{0, 0}, {100, 0}, {0, 50}, {100, 50}} {
+			x := m[0]*corner[0] + m[2]*corner[1] + m[4]
+			y := m[1]*corner[0] + m[3]*corner[1] + m[5]
+			if !(closeTo(x, placed.LLX) || closeTo(x, placed.URX)) || !(closeTo(y, placed.LLY) || closeTo(y, placed.URY)) {
+				t.Errorf("rotation %d matrix %v maps %v to (%v, %v)", rotate, m, corner, x, y)
+			}
+		}
+	}
+
+	// the page's origin is at the lower-left of the box when it is not rotated
+	m, _, _ := Placement{Box: box, Scale: 2}.transform(100, 50)
+	if m != [6]float64{2, 0, 0, 2, 10, 10} {
+		t.Errorf("unexpected matrix %v", m)
+	}
+
+	_, _, err := Placement{Box: box, Rotate: 45, Scale: 1}.transform(100, 50)
+	if err == nil {
+		t.Error("expected an error for a rotation of 45 degrees")
+	}
+}
+
+func TestSheets(t *testing.T) {
+	document := testDocument(t,
+		[]Size{{100, 200}, {200, 100}, {50, 100}},
+		[]int{0, 0, 90},
+	)
+	pages, err := document.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		scaling    Scaling
+		autoRotate bool
+		scales     []float64
+		rotations  []int
+	}{
+		{Fit, false, []float64{1, 0.5, 1}, []int{0, 0, 0}},
+		{Fit, true, []float64{1, 1, 2}, []int{0, 90, 90}},
+		{ShrinkToFit, true, []float64{1, 1, 1}, []int{0, 90, 90}},
+		{ActualSize, false, []float64{1, 1, 1}, []int{0, 0, 0}},
+		{Uniform, false, []float64{0.5, 0.5, 0.5}, []int{0, 0, 0}},
+	} {
+		layout := Layout{Columns: 2, Rows: 1, Sheet: Size{200, 200}, Scaling: test.scaling, AutoRotate: test.autoRotate}
+		sheets, err := layout.Sheets(pages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sheets) != 2 || len(sheets[0].Placements) != 2 || len(sheets[1].Placements) != 1 {
+			t.Fatalf("unexpected sheets %v", sheets)
+		}
+		placements := append(sheets[0].Placements, sheets[1].Placements...)
+		for i, placement := range placements {
+			if placement.Page != i || !closeTo(placement.Scale, test.scales[i]) || placement.Rotate != test.rotations[i] {
+				t.Errorf("scaling %d, auto rotate %v: page %d has scale %v and rotation %d",
+					test.scaling, test.autoRotate, i, placement.Scale, placement.Rotate)
+			}
+		}
+	}
+}
+
+func TestImpose(t *testing.T) {
+	document := testDocument(t,
+		[]Size{A4, A4, A5, Letter, A4},
+		[]int{0, 90, 0, 180, 270},
+	)
+	layout, err := Grid(2, A4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout.Frames = true
+	err = layout.Impose(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheets, err := document.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 3 {
+		t.Fatalf("expected 3 sheets, got %d", len(sheets))
+	}
+	for i, sheet := range sheets {
+		mediaBox, err := sheet.MediaBox()
+		if err != nil || !closeTo(mediaBox.Width(), A4.Height) || !closeTo(mediaBox.Height(), A4.Width) {
+			t.Errorf("sheet %d has MediaBox %v %v", i, mediaBox, err)
+		}
+
+		xobjects := sheet.Resources()["XObject"].(pdf.Dictionary)
+		if len(xobjects) != []int{2, 2, 1}[i] {
+			t.Errorf("sheet %d has XObjects %v", i, xobjects)
+		}
+		for name, ref := range xobjects {
+			xobject := document.File.Get(ref.(pdf.ObjectReference)).(pdf.Stream)
+			if xobject.Dictionary["Subtype"] != pdf.Name("Form") {
+				t.Errorf("sheet %d XObject %s is %v", i, name, xobject.Dictionary)
+			}
+		}
+
+		stream := document.File.Get(sheet.Dictionary["Contents"].(pdf.ObjectReference)).(pdf.Stream)
+		operations, err := content.Parse(stream.Stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		operators := map[content.Operator]int{}
+		for _, operation := range operations {
+			operators[operation.Operator]++
+		}
+		if operators["Do"] != len(xobjects) || operators["S"] != len(xobjects) || operators["W"] != len(xobjects) {
+			t.Errorf("sheet %d has operators %v", i, operators)
+		}
+	}
+}
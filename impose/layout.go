@@ -0,0 +1,249 @@
+package impose
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nathankerr/pdf"
+)
+
+// A Size is the width and height of a sheet in points.
+type Size struct {
+	Width, Height float64
+}
+
+// the number of points in a millimetre
+const mm = 72 / 25.4
+
+// Common paper sizes (ISO 216 and ANSI), in portrait orientation.
+var (
+	A3      = Size{297 * mm, 420 * mm}
+	A4      = Size{210 * mm, 297 * mm}
+	A5      = Size{148 * mm, 210 * mm}
+	Letter  = Size{612, 792}
+	Legal   = Size{612, 1008}
+	Tabloid = Size{792, 1224}
+)
+
+// Landscape returns the size with its longer side horizontal.
+func (s Size) Landscape() Size {
+	if s.Width < s.Height {
+		return Size{s.Height, s.Width}
+	}
+	return s
+}
+
+// Portrait returns the size with its longer side vertical.
+func (s Size) Portrait() Size {
+	if s.Width > s.Height {
+		return Size{s.Height, s.Width}
+	}
+	return s
+}
+
+// Margins are the distances in points from the edges of the sheet.
+type Margins struct {
+	Top, Right, Bottom, Left float64
+}
+
+// Order is the order the cells of a grid are filled in.
+type Order int
+
+// The reading orders.
+const (
+	// rows from top to bottom, each from left to right
+	RowsLeftToRight Order = iota
+	// rows from top to bottom, each from right to left
+	RowsRightToLeft
+	// columns from left to right, each from top to bottom
+	ColumnsLeftToRight
+	// columns from right to left, each from top to bottom
+	ColumnsRightToLeft
+)
+
+// Scaling is how pages are sized to fit their cells.
+type Scaling int
+
+// The scaling policies.
+const (
+	// each page is as large as fits in its cell
+	Fit Scaling = iota
+	// pages that do not fit in their cells are made smaller
+	ShrinkToFit
+	// pages keep their size and are clipped to their cells
+	ActualSize
+	// all pages are scaled by the factor that fits the largest
+	Uniform
+)
+
+// A Layout places pages in a grid of cells on each sheet.
+type Layout struct {
+	Columns, Rows int
+	Sheet         Size
+	Margins       Margins
+
+	// the space between columns and between rows
+	ColumnGutter, RowGutter float64
+
+	Order   Order
+	Scaling Scaling
+
+	// turns pages by 90 degrees when that makes them larger
+	AutoRotate bool
+
+	// draws a line around each page
+	Frames bool
+}
+
+// Grid returns a layout of n pages on each sheet, in the grid closest
+// to square. The sheet is turned when that gives cells shaped more like
+// the sheet, so that 2-up is on a landscape sheet of two portrait cells.
+func Grid(n int, sheet Size) (Layout, error) {
+	if n < 1 {
+		return Layout{}, fmt.Errorf("%d pages per sheet", n)
+	}
+	rows := int(math.Sqrt(float64(n)))
+	for n%rows != 0 {
+		rows--
+	}
+	columns := n / rows
+
+	// how far the cells are from the sheet's shape
+	distance := func(l Layout) float64 {
+		cell := (l.Sheet.Width / float64(l.Columns)) / (l.Sheet.Height / float64(l.Rows))
+		return math.Abs(math.Log(cell / (sheet.Width / sheet.Height)))
+	}
+	turned := Size{sheet.Height, sheet.Width}
+	layout := Layout{Columns: columns, Rows: rows, Sheet: sheet}
+	for _, l := range []Layout{
+		{Columns: rows, Rows: columns, Sheet: sheet},
+		{Columns: columns, Rows: rows, Sheet: turned},
+		{Columns: rows, Rows: columns, Sheet: turned},
+	} {
+		if distance(l) < distance(layout)-1e-9 {
+			layout = l
+		}
+	}
+	return layout, nil
+}
+
+// Impose replaces the document's pages with the sheets of the layout.
+func (l Layout) Impose(document *pdf.Document) error {
+	pages, err := document.Pages()
+	if err != nil {
+		return err
+	}
+	sheets, err := l.Sheets(pages)
+	if err != nil {
+		return err
+	}
+	return Impose(document, sheets)
+}
+
+// Sheets returns the sheets the pages are placed on. The last sheet's
+// remaining cells are left empty.
+func (l Layout) Sheets(pages []pdf.Page) ([]Sheet, error) {
+	cells, err := l.cells()
+	if err != nil {
+		return nil, err
+	}
+
+	placements := make([]Placement, len(pages))
+	for i, page := range pages {
+		width, height, err := page.DisplaySize()
+		if err != nil {
+			return nil, err
+		}
+		if width <= 0 || height <= 0 {
+			return nil, fmt.Errorf("page %d is empty", i)
+		}
+		box := cells[i%len(cells)]
+		placements[i] = Placement{Page: i, Box: box, Scale: fit(width, height, box)}
+		if l.AutoRotate {
+			if turned := fit(height, width, box); turned > placements[i].Scale {
+				placements[i].Rotate = 90
+				placements[i].Scale = turned
+			}
+		}
+	}
+
+	err = scale(placements, l.Scaling)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets := []Sheet{}
+	for len(placements) > 0 {
+		n := len(cells)
+		if n > len(placements) {
+			n = len(placements)
+		}
+		sheets = append(sheets, Sheet{Size: l.Sheet, Placements: placements[:n:n], Frames: l.Frames})
+		placements = placements[n:]
+	}
+	return sheets, nil
+}
+
+// the boxes of the grid's cells in reading order
+func (l Layout) cells() ([]pdf.Rectangle, error) {
+	if l.Columns < 1 || l.Rows < 1 {
+		return nil, fmt.Errorf("grid of %d columns and %d rows", l.Columns, l.Rows)
+	}
+	width := (l.Sheet.Width - l.Margins.Left - l.Margins.Right - float64(l.Columns-1)*l.ColumnGutter) / float64(l.Columns)
+	height := (l.Sheet.Height - l.Margins.Top - l.Margins.Bottom - float64(l.Rows-1)*l.RowGutter) / float64(l.Rows)
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("no room for %d columns and %d rows on a %vx%v sheet", l.Columns, l.Rows, l.Sheet.Width, l.Sheet.Height)
+	}
+
+	n := l.Columns * l.Rows
+	cells := make([]pdf.Rectangle, n)
+	for i := range cells {
+		var column, row int
+		switch l.Order {
+		case RowsLeftToRight:
+			column, row = i%l.Columns, i/l.Columns
+		case RowsRightToLeft:
+			column, row = l.Columns-1-i%l.Columns, i/l.Columns
+		case ColumnsLeftToRight:
+			column, row = i/l.Rows, i%l.Rows
+		case ColumnsRightToLeft:
+			column, row = l.Columns-1-i/l.Rows, i%l.Rows
+		default:
+			return nil, fmt.Errorf("unknown order %d", l.Order)
+		}
+
+		// rows are counted from the top of the sheet
+		x := l.Margins.Left + float64(column)*(width+l.ColumnGutter)
+		y := l.Sheet.Height - l.Margins.Top - float64(row)*(height+l.RowGutter) - height
+		cells[i] = pdf.NewRectangle(x, y, x+width, y+height)
+	}
+	return cells, nil
+}
+
+// applies the scaling to placements that are scaled to fit their boxes
+func scale(placements []Placement, scaling Scaling) error {
+	// the largest page is the one that has to be made the smallest
+	uniform := math.Inf(1)
+	for _, placement := range placements {
+		uniform = math.Min(uniform, placement.Scale)
+	}
+	for i := range placements {
+		switch scaling {
+		case Fit:
+		case ShrinkToFit:
+			placements[i].Scale = math.Min(placements[i].Scale, 1)
+		case ActualSize:
+			placements[i].Scale = 1
+		case Uniform:
+			placements[i].Scale = uniform
+		default:
+			return fmt.Errorf("unknown scaling %d", scaling)
+		}
+	}
+	return nil
+}
+
+// the largest scale a page of the width and height fits in the box at
+func fit(width, height float64, box pdf.Rectangle) float64 {
+	return math.Min(box.Width()/width, box.Height()/height)
+}
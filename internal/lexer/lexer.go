@@ -0,0 +1,22 @@
+// Package lexer holds the character classes shared by the packages
+// that read PDF syntax outside of package pdf, such as content streams
+// and CMaps.
+package lexer
+
+// IsWhitespace reports whether char is white-space (§7.2.2 Table 1).
+func IsWhitespace(char byte) bool {
+	switch char {
+	case 0, 9, 10, 12, 13, 32:
+		return true
+	}
+	return false
+}
+
+// IsDelimiter reports whether char is a delimiter (§7.2.2 Table 2).
+func IsDelimiter(char byte) bool {
+	switch char {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
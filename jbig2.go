@@ -0,0 +1,1429 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// JBIG2Decode (§7.4.7)
+//
+// The embedded stream organisation of ITU-T T.88 Annex D.3: a sequence
+// of segments without a file header. Segments shared by several pages,
+// such as symbol dictionaries, are in the stream referred to by the
+// JBIG2Globals filter parameter.
+//
+// Arithmetic coded generic, generic refinement, symbol dictionary and
+// text region segments are supported, as are MMR coded generic regions.
+// Huffman coded symbol dictionaries and text regions, pattern
+// dictionaries and halftone regions are not.
+
+// segment types (T.88 §7.3)
+const (
+	jbig2SymbolDictionary                  = 0
+	jbig2IntermediateTextRegion            = 4
+	jbig2ImmediateTextRegion               = 6
+	jbig2ImmediateLosslessTextRegion       = 7
+	jbig2PatternDictionary                 = 16
+	jbig2IntermediateHalftoneRegion        = 20
+	jbig2ImmediateHalftoneRegion           = 22
+	jbig2ImmediateLosslessHalftoneRegion   = 23
+	jbig2IntermediateGenericRegion         = 36
+	jbig2ImmediateGenericRegion            = 38
+	jbig2ImmediateLosslessGenericRegion    = 39
+	jbig2IntermediateRefinementRegion      = 40
+	jbig2ImmediateRefinementRegion         = 42
+	jbig2ImmediateLosslessRefinementRegion = 43
+	jbig2PageInformation                   = 48
+	jbig2EndOfPage                         = 49
+	jbig2EndOfStripe                       = 50
+	jbig2EndOfFile                         = 51
+	jbig2Profiles                          = 52
+	jbig2Tables                            = 53
+	jbig2Extension                         = 62
+)
+
+// combination operators (T.88 §7.4.1.5)
+const (
+	jbig2Or = iota
+	jbig2And
+	jbig2Xor
+	jbig2Xnor
+	jbig2Replace
+)
+
+// text region reference corners (T.88 §7.4.3.1.1)
+const (
+	jbig2BottomLeft = iota
+	jbig2TopLeft
+	jbig2BottomRight
+	jbig2TopRight
+)
+
+// limits the memory used by corrupt or malicious bitmap sizes to
+// 32 MB per bitmap, enough for a letter page at 1600 dpi
+const jbig2MaxPixels = 1 << 28
+
+func jbig2Decode(encoded []byte, dict Dictionary) ([]byte, error) {
+	j := &jbig2Decoder{
+		symbols:  map[uint32][]*jbig2Bitmap{},
+		contexts: map[uint32]*jbig2Contexts{},
+		regions:  map[uint32]jbig2Region{},
+	}
+
+	if obj, ok := dict["JBIG2Globals"]; ok {
+		globals, ok := obj.(Stream)
+		if !ok {
+			// references are resolved by streams from a File
+			return nil, fmt.Errorf("JBIG2Globals must be a stream, not %T", obj)
+		}
+		data, err := globals.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("JBIG2Globals: %v", err)
+		}
+		err = j.decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("JBIG2Globals: %v", err)
+		}
+	}
+
+	err := j.decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if j.page == nil {
+		return nil, errors.New("no page information segment")
+	}
+
+	// JBIG2 uses 1 for black, the decoded image uses 0 like
+	// a DeviceGray image with 1 bit per component
+	decoded := make([]byte, len(j.page.data))
+	for i, b := range j.page.data {
+		decoded[i] = ^b
+	}
+	return decoded, nil
+}
+
+// the state of decoding a page and the segments it refers to
+type jbig2Decoder struct {
+	page              *jbig2Bitmap
+	pageDefaultPixel  int
+	pageUnknownHeight bool
+	symbols           map[uint32][]*jbig2Bitmap // exported by symbol dictionaries
+	contexts          map[uint32]*jbig2Contexts // retained by symbol dictionaries
+	regions           map[uint32]jbig2Region    // intermediate regions
+}
+
+// an intermediate region waiting to be refined
+type jbig2Region struct {
+	info   jbig2RegionInfo
+	bitmap *jbig2Bitmap
+}
+
+func (j *jbig2Decoder) decode(data []byte) error {
+	segments, err := jbig2ParseSegments(data)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range segments {
+		err := j.segment(s)
+		if err != nil {
+			return fmt.Errorf("segment %d: %v", s.number, err)
+		}
+	}
+	return nil
+}
+
+func (j *jbig2Decoder) segment(s jbig2Segment) error {
+	f := &jbig2Fields{data: s.data}
+
+	switch s.kind {
+	case jbig2PageInformation:
+		return j.pageInformation(f)
+	case jbig2SymbolDictionary:
+		return j.symbolDictionary(s, f)
+	case jbig2IntermediateTextRegion, jbig2ImmediateTextRegion, jbig2ImmediateLosslessTextRegion:
+		info, bitmap, err := j.textRegion(s, f)
+		if err != nil {
+			return err
+		}
+		return j.region(s, info, bitmap)
+	case jbig2IntermediateGenericRegion, jbig2ImmediateGenericRegion, jbig2ImmediateLosslessGenericRegion:
+		info, bitmap, err := j.genericRegion(f)
+		if err != nil {
+			return err
+		}
+		return j.region(s, info, bitmap)
+	case jbig2IntermediateRefinementRegion, jbig2ImmediateRefinementRegion, jbig2ImmediateLosslessRefinementRegion:
+		info, bitmap, err := j.refinementRegion(s, f)
+		if err != nil {
+			return err
+		}
+		return j.region(s, info, bitmap)
+	case jbig2EndOfStripe:
+		// the last row of the stripe
+		row := int(f.u32())
+		if f.err != nil {
+			return f.err
+		}
+		if j.page != nil && j.pageUnknownHeight {
+			return j.page.grow(row+1, j.pageDefaultPixel)
+		}
+		return nil
+	case jbig2EndOfPage, jbig2EndOfFile, jbig2Profiles, jbig2Extension:
+		return nil
+	case jbig2Tables:
+		// only used by Huffman coded segments
+		return nil
+	case jbig2PatternDictionary, jbig2IntermediateHalftoneRegion, jbig2ImmediateHalftoneRegion, jbig2ImmediateLosslessHalftoneRegion:
+		return errors.New("pattern dictionaries and halftone regions are not supported")
+	}
+	return fmt.Errorf("unknown segment type %d", s.kind)
+}
+
+// page information segment (T.88 §7.4.8)
+func (j *jbig2Decoder) pageInformation(f *jbig2Fields) error {
+	width := f.u32()
+	height := f.u32()
+	f.u32() // x resolution
+	f.u32() // y resolution
+	flags := f.u8()
+	f.u16() // striping
+	if f.err != nil {
+		return f.err
+	}
+
+	if j.page != nil {
+		return errors.New("more than one page")
+	}
+
+	if height == 0xffffffff {
+		// the height is set by end of stripe segments
+		j.pageUnknownHeight = true
+		height = 0
+	}
+
+	page, err := newJBIG2Bitmap(int(width), int(height))
+	if err != nil {
+		return err
+	}
+	j.pageDefaultPixel = flags >> 2 & 1
+	page.fill(j.pageDefaultPixel)
+	j.page = page
+	return nil
+}
+
+// places an immediate region on the page, intermediate regions
+// are kept until a refinement region refers to them
+func (j *jbig2Decoder) region(s jbig2Segment, info jbig2RegionInfo, bitmap *jbig2Bitmap) error {
+	switch s.kind {
+	case jbig2IntermediateTextRegion, jbig2IntermediateGenericRegion, jbig2IntermediateRefinementRegion:
+		j.regions[s.number] = jbig2Region{info, bitmap}
+		return nil
+	}
+
+	if j.page == nil {
+		return errors.New("region before the page information")
+	}
+	if j.pageUnknownHeight && info.y+info.height > j.page.height {
+		err := j.page.grow(info.y+info.height, j.pageDefaultPixel)
+		if err != nil {
+			return err
+		}
+	}
+	j.page.compose(bitmap, info.x, info.y, info.combination)
+	return nil
+}
+
+// symbols exported by the symbol dictionaries a segment refers to
+func (j *jbig2Decoder) referredSymbols(s jbig2Segment) []*jbig2Bitmap {
+	symbols := []*jbig2Bitmap{}
+	for _, number := range s.referred {
+		symbols = append(symbols, j.symbols[number]...)
+	}
+	return symbols
+}
+
+// region segment information field (T.88 §7.4.1)
+type jbig2RegionInfo struct {
+	width, height int
+	x, y          int
+	combination   int
+}
+
+func (f *jbig2Fields) regionInfo() jbig2RegionInfo {
+	info := jbig2RegionInfo{
+		width:  int(f.u32()),
+		height: int(f.u32()),
+		x:      int(int32(f.u32())),
+		y:      int(int32(f.u32())),
+	}
+	info.combination = f.u8() & 7
+	return info
+}
+
+const jbig2RegionInfoLength = 17
+
+// adaptive template pixels (T.88 §6.2.5.3)
+type jbig2Offset struct {
+	x, y int
+}
+
+func (f *jbig2Fields) offsets(n int) []jbig2Offset {
+	offsets := make([]jbig2Offset, n)
+	for i := range offsets {
+		offsets[i].x = f.s8()
+		offsets[i].y = f.s8()
+	}
+	return offsets
+}
+
+// generic region segment (T.88 §7.4.6)
+func (j *jbig2Decoder) genericRegion(f *jbig2Fields) (jbig2RegionInfo, *jbig2Bitmap, error) {
+	info := f.regionInfo()
+	flags := f.u8()
+	g := jbig2Generic{
+		width:    info.width,
+		height:   info.height,
+		mmr:      flags&1 != 0,
+		template: flags >> 1 & 3,
+		tpgdon:   flags&8 != 0,
+	}
+	if flags&0x10 != 0 {
+		return info, nil, errors.New("extended reference templates are not supported")
+	}
+	if !g.mmr {
+		n := 1
+		if g.template == 0 {
+			n = 4
+		}
+		g.at = f.offsets(n)
+	}
+	if f.err != nil {
+		return info, nil, f.err
+	}
+
+	var bitmap *jbig2Bitmap
+	var err error
+	if g.mmr {
+		bitmap, err = g.decodeMMR(f.data)
+	} else {
+		d := newJBIG2ArithDecoder(f.data)
+		bitmap, err = g.decode(d, make([]jbig2Context, jbig2GenericContexts(g.template)))
+	}
+	return info, bitmap, err
+}
+
+// generic refinement region segment (T.88 §7.4.7)
+func (j *jbig2Decoder) refinementRegion(s jbig2Segment, f *jbig2Fields) (jbig2RegionInfo, *jbig2Bitmap, error) {
+	info := f.regionInfo()
+	flags := f.u8()
+	r := jbig2Refinement{
+		width:    info.width,
+		height:   info.height,
+		template: flags & 1,
+		tpgron:   flags&2 != 0,
+	}
+	if r.template == 0 {
+		r.at = f.offsets(2)
+	}
+	if f.err != nil {
+		return info, nil, f.err
+	}
+
+	// refines an intermediate region, or the page itself
+	for _, number := range s.referred {
+		region, ok := j.regions[number]
+		if ok {
+			r.reference = region.bitmap
+			delete(j.regions, number)
+			break
+		}
+	}
+	if r.reference == nil {
+		if j.page == nil {
+			return info, nil, errors.New("region before the page information")
+		}
+		reference, err := j.page.crop(info.x, info.y, info.width, info.height)
+		if err != nil {
+			return info, nil, err
+		}
+		r.reference = reference
+	}
+
+	d := newJBIG2ArithDecoder(f.data)
+	bitmap, err := r.decode(d, make([]jbig2Context, jbig2RefinementContexts(r.template)))
+	return info, bitmap, err
+}
+
+// symbol dictionary segment (T.88 §7.4.2)
+func (j *jbig2Decoder) symbolDictionary(s jbig2Segment, f *jbig2Fields) error {
+	flags := f.u16()
+	sd := jbig2Symbols{
+		template:            flags >> 10 & 3,
+		refinementAggregate: flags&2 != 0,
+		refinementTemplate:  flags >> 12 & 1,
+		input:               j.referredSymbols(s),
+	}
+	if flags&1 != 0 {
+		return errors.New("Huffman coded symbol dictionaries are not supported")
+	}
+
+	n := 1
+	if sd.template == 0 {
+		n = 4
+	}
+	sd.at = f.offsets(n)
+	if sd.refinementAggregate && sd.refinementTemplate == 0 {
+		sd.refinementAT = f.offsets(2)
+	}
+	sd.exported = int(f.u32())
+	sd.new = int(f.u32())
+	if f.err != nil {
+		return f.err
+	}
+
+	// the contexts may continue from the last symbol dictionary referred to
+	var cx *jbig2Contexts
+	if flags&0x100 != 0 {
+		for _, number := range s.referred {
+			if retained, ok := j.contexts[number]; ok {
+				cx = retained
+			}
+		}
+		if cx == nil {
+			return errors.New("no retained contexts to use")
+		}
+	} else {
+		cx = newJBIG2Contexts()
+		cx.generic = make([]jbig2Context, jbig2GenericContexts(sd.template))
+		cx.refinement = make([]jbig2Context, jbig2RefinementContexts(sd.refinementTemplate))
+	}
+
+	d := newJBIG2ArithDecoder(f.data)
+	symbols, err := sd.decode(d, cx)
+	if err != nil {
+		return err
+	}
+
+	j.symbols[s.number] = symbols
+	if flags&0x200 != 0 {
+		j.contexts[s.number] = cx
+	}
+	return nil
+}
+
+// text region segment (T.88 §7.4.3)
+func (j *jbig2Decoder) textRegion(s jbig2Segment, f *jbig2Fields) (jbig2RegionInfo, *jbig2Bitmap, error) {
+	info := f.regionInfo()
+	flags := f.u16()
+	t := jbig2Text{
+		width:              info.width,
+		height:             info.height,
+		refine:             flags&2 != 0,
+		strips:             1 << uint(flags>>2&3),
+		refCorner:          flags >> 4 & 3,
+		transposed:         flags&0x40 != 0,
+		combination:        flags >> 7 & 3,
+		defaultPixel:       flags >> 9 & 1,
+		dsOffset:           flags >> 10 & 0x1f,
+		refinementTemplate: flags >> 15 & 1,
+		symbols:            j.referredSymbols(s),
+	}
+	if flags&1 != 0 {
+		return info, nil, errors.New("Huffman coded text regions are not supported")
+	}
+	// a 5 bit two's complement number
+	if t.dsOffset >= 16 {
+		t.dsOffset -= 32
+	}
+	if t.refine && t.refinementTemplate == 0 {
+		t.refinementAT = f.offsets(2)
+	}
+	t.instances = int(f.u32())
+	if f.err != nil {
+		return info, nil, f.err
+	}
+
+	for t.codeLength = 0; 1<<t.codeLength < len(t.symbols); t.codeLength++ {
+	}
+
+	cx := newJBIG2Contexts()
+	cx.refinement = make([]jbig2Context, jbig2RefinementContexts(t.refinementTemplate))
+	d := newJBIG2ArithDecoder(f.data)
+	bitmap, err := t.decode(d, cx)
+	return info, bitmap, err
+}
+
+// a segment header (T.88 §7.2) and its data
+type jbig2Segment struct {
+	number   uint32
+	kind     int
+	referred []uint32
+	data     []byte
+}
+
+func jbig2ParseSegments(data []byte) ([]jbig2Segment, error) {
+	segments := []jbig2Segment{}
+
+	for len(data) > 0 {
+		f := &jbig2Fields{data: data}
+		s := jbig2Segment{number: f.u32()}
+		flags := f.u8()
+		s.kind = flags & 0x3f
+
+		// the count of referred to segments is in the top 3 bits
+		// of the short form or the top 3 bits are all set and the
+		// count is in the rest of the long form
+		first := f.u8()
+		count := first >> 5
+		if count == 7 {
+			rest := f.next(3)
+			count = (first&0x1f)<<24 | int(rest[0])<<16 | int(rest[1])<<8 | int(rest[2])
+			if count > len(data) {
+				return nil, fmt.Errorf("segment %d refers to %d segments", s.number, count)
+			}
+			f.next((count + 8) / 8) // retention flags
+		} else if count > 4 {
+			return nil, fmt.Errorf("segment %d has an invalid referred to segment count", s.number)
+		}
+
+		size := 4
+		switch {
+		case s.number <= 256:
+			size = 1
+		case s.number <= 65536:
+			size = 2
+		}
+		for i := 0; i < count; i++ {
+			number := uint32(0)
+			for _, b := range f.next(size) {
+				number = number<<8 | uint32(b)
+			}
+			s.referred = append(s.referred, number)
+		}
+
+		// page association
+		if flags&0x40 != 0 {
+			f.u32()
+		} else {
+			f.u8()
+		}
+
+		length := f.u32()
+		if f.err != nil {
+			return nil, f.err
+		}
+
+		n := int(length)
+		if length == 0xffffffff {
+			var err error
+			n, err = jbig2UnknownLength(s.kind, f.data)
+			if err != nil {
+				return nil, fmt.Errorf("segment %d: %v", s.number, err)
+			}
+		}
+		if n > len(f.data) {
+			return nil, fmt.Errorf("segment %d is truncated", s.number)
+		}
+
+		s.data = f.data[:n]
+		data = f.data[n:]
+		segments = append(segments, s)
+	}
+
+	return segments, nil
+}
+
+// Only immediate generic regions can have an unknown length (T.88 §7.2.7).
+// Their data ends with a marker followed by the number of rows,
+// which is looked for as the height of the region.
+func jbig2UnknownLength(kind int, data []byte) (int, error) {
+	if kind != jbig2ImmediateGenericRegion || len(data) <= jbig2RegionInfoLength {
+		return 0, errors.New("unknown data length")
+	}
+
+	end := []byte{0xff, 0xac}
+	if data[jbig2RegionInfoLength]&1 != 0 { // MMR
+		end = []byte{0x00, 0x00}
+	}
+	end = append(end, data[4:8]...) // height
+
+	i := bytes.Index(data[jbig2RegionInfoLength+1:], end)
+	if i < 0 {
+		return 0, errors.New("end of data with unknown length not found")
+	}
+	return jbig2RegionInfoLength + 1 + i + len(end), nil
+}
+
+// reads big-endian fields from segment data,
+// remembering the first error
+type jbig2Fields struct {
+	data []byte
+	err  error
+}
+
+func (f *jbig2Fields) next(n int) []byte {
+	if f.err == nil && len(f.data) < n {
+		f.err = errors.New("segment data is truncated")
+	}
+	if f.err != nil {
+		return make([]byte, n)
+	}
+	b := f.data[:n]
+	f.data = f.data[n:]
+	return b
+}
+
+func (f *jbig2Fields) u8() int {
+	return int(f.next(1)[0])
+}
+
+func (f *jbig2Fields) s8() int {
+	return int(int8(f.next(1)[0]))
+}
+
+func (f *jbig2Fields) u16() int {
+	return int(binary.BigEndian.Uint16(f.next(2)))
+}
+
+func (f *jbig2Fields) u32() uint32 {
+	return binary.BigEndian.Uint32(f.next(4))
+}
+
+// a bilevel image with 1 for black and rows padded to whole bytes
+type jbig2Bitmap struct {
+	width, height int
+	stride        int
+	data          []byte
+}
+
+func newJBIG2Bitmap(width, height int) (*jbig2Bitmap, error) {
+	if width < 0 || height < 0 || int64(width)*int64(height) > jbig2MaxPixels {
+		return nil, fmt.Errorf("invalid bitmap size %dx%d", width, height)
+	}
+	stride := (width + 7) / 8
+	return &jbig2Bitmap{
+		width:  width,
+		height: height,
+		stride: stride,
+		data:   make([]byte, stride*height),
+	}, nil
+}
+
+// pixels outside of the bitmap are 0
+func (b *jbig2Bitmap) get(x, y int) int {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return 0
+	}
+	return int(b.data[y*b.stride+x/8] >> uint(7-x%8) & 1)
+}
+
+func (b *jbig2Bitmap) set(x, y, value int) {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return
+	}
+	mask := byte(0x80) >> uint(x%8)
+	if value != 0 {
+		b.data[y*b.stride+x/8] |= mask
+	} else {
+		b.data[y*b.stride+x/8] &^= mask
+	}
+}
+
+func (b *jbig2Bitmap) row(y int) []byte {
+	return b.data[y*b.stride : (y+1)*b.stride]
+}
+
+func (b *jbig2Bitmap) fill(value int) {
+	fill := byte(0)
+	if value != 0 {
+		fill = 0xff
+	}
+	for i := range b.data {
+		b.data[i] = fill
+	}
+}
+
+// adds rows of value to the bottom of the bitmap
+func (b *jbig2Bitmap) grow(height int, value int) error {
+	if height <= b.height {
+		return nil
+	}
+	if int64(b.width)*int64(height) > jbig2MaxPixels {
+		return fmt.Errorf("invalid bitmap size %dx%d", b.width, height)
+	}
+
+	fill := byte(0)
+	if value != 0 {
+		fill = 0xff
+	}
+	for i := b.height * b.stride; i < height*b.stride; i++ {
+		b.data = append(b.data, fill)
+	}
+	b.height = height
+	return nil
+}
+
+// a copy of part of the bitmap
+func (b *jbig2Bitmap) crop(x, y, width, height int) (*jbig2Bitmap, error) {
+	cropped, err := newJBIG2Bitmap(width, height)
+	if err != nil {
+		return nil, err
+	}
+	for j := 0; j < height; j++ {
+		for i := 0; i < width; i++ {
+			cropped.set(i, j, b.get(x+i, y+j))
+		}
+	}
+	return cropped, nil
+}
+
+// combines src into the bitmap with its top left corner at x, y
+func (b *jbig2Bitmap) compose(src *jbig2Bitmap, x, y, op int) {
+	for j := 0; j < src.height; j++ {
+		if y+j < 0 || y+j >= b.height {
+			continue
+		}
+		for i := 0; i < src.width; i++ {
+			if x+i < 0 || x+i >= b.width {
+				continue
+			}
+
+			s, d := src.get(i, j), b.get(x+i, y+j)
+			switch op {
+			case jbig2Or:
+				d |= s
+			case jbig2And:
+				d &= s
+			case jbig2Xor:
+				d ^= s
+			case jbig2Xnor:
+				d = 1 ^ d ^ s
+			case jbig2Replace:
+				d = s
+			}
+			b.set(x+i, y+j, d)
+		}
+	}
+}
+
+// the state of an arithmetic decoding context (T.88 §E.2.3)
+type jbig2Context struct {
+	index uint8
+	mps   int
+}
+
+// the arithmetic decoder (T.88 Annex E.3) with the
+// software conventions of Figures E.15 to E.19
+type jbig2ArithDecoder struct {
+	data []byte
+	pos  int
+	c    uint32
+	a    uint32
+	ct   int
+}
+
+// probability estimation (T.88 Table E.1)
+var jbig2Qe = [47]struct {
+	qe         uint32
+	nmps, nlps uint8
+	swtch      bool
+}{
+	{0x5601, 1, 1, true},
+	{0x3401, 2, 6, false},
+	{0x1801, 3, 9, false},
+	{0x0ac1, 4, 12, false},
+	{0x0521, 5, 29, false},
+	{0x0221, 38, 33, false},
+	{0x5601, 7, 6, true},
+	{0x5401, 8, 14, false},
+	{0x4801, 9, 14, false},
+	{0x3801, 10, 14, false},
+	{0x3001, 11, 17, false},
+	{0x2401, 12, 18, false},
+	{0x1c01, 13, 20, false},
+	{0x1601, 29, 21, false},
+	{0x5601, 15, 14, true},
+	{0x5401, 16, 14, false},
+	{0x5101, 17, 15, false},
+	{0x4801, 18, 16, false},
+	{0x3801, 19, 17, false},
+	{0x3401, 20, 18, false},
+	{0x3001, 21, 19, false},
+	{0x2801, 22, 19, false},
+	{0x2401, 23, 20, false},
+	{0x2201, 24, 21, false},
+	{0x1c01, 25, 22, false},
+	{0x1801, 26, 23, false},
+	{0x1601, 27, 24, false},
+	{0x1401, 28, 25, false},
+	{0x1201, 29, 26, false},
+	{0x1101, 30, 27, false},
+	{0x0ac1, 31, 28, false},
+	{0x09c1, 32, 29, false},
+	{0x08a1, 33, 30, false},
+	{0x0521, 34, 31, false},
+	{0x0441, 35, 32, false},
+	{0x02a1, 36, 33, false},
+	{0x0221, 37, 34, false},
+	{0x0141, 38, 35, false},
+	{0x0111, 39, 36, false},
+	{0x0085, 40, 37, false},
+	{0x0049, 41, 38, false},
+	{0x0025, 42, 39, false},
+	{0x0015, 43, 40, false},
+	{0x0009, 44, 41, false},
+	{0x0005, 45, 42, false},
+	{0x0001, 45, 43, false},
+	{0x5601, 46, 46, false},
+}
+
+func newJBIG2ArithDecoder(data []byte) *jbig2ArithDecoder {
+	d := &jbig2ArithDecoder{data: data}
+	d.c = uint32(d.at(0)) << 16
+	d.byteIn()
+	d.c <<= 7
+	d.ct -= 7
+	d.a = 0x8000
+	return d
+}
+
+// data past the end reads as 0xff
+func (d *jbig2ArithDecoder) at(i int) byte {
+	if i < len(d.data) {
+		return d.data[i]
+	}
+	return 0xff
+}
+
+func (d *jbig2ArithDecoder) byteIn() {
+	if d.at(d.pos) == 0xff {
+		if d.at(d.pos+1) > 0x8f {
+			// a marker, feed 1 bits
+			d.c += 0xff00
+			d.ct = 8
+			return
+		}
+		// a stuffed bit
+		d.pos++
+		d.c += uint32(d.at(d.pos)) << 9
+		d.ct = 7
+		return
+	}
+	d.pos++
+	d.c += uint32(d.at(d.pos)) << 8
+	d.ct = 8
+}
+
+func (d *jbig2ArithDecoder) decode(cx *jbig2Context) int {
+	q := jbig2Qe[cx.index]
+	d.a -= q.qe
+
+	var bit int
+	if d.c>>16 < q.qe {
+		// LPS exchange
+		if d.a < q.qe {
+			bit = cx.mps
+			cx.index = q.nmps
+		} else {
+			bit = 1 - cx.mps
+			if q.swtch {
+				cx.mps = bit
+			}
+			cx.index = q.nlps
+		}
+		d.a = q.qe
+	} else {
+		d.c -= q.qe << 16
+		if d.a&0x8000 != 0 {
+			return cx.mps
+		}
+		// MPS exchange
+		if d.a < q.qe {
+			bit = 1 - cx.mps
+			if q.swtch {
+				cx.mps = bit
+			}
+			cx.index = q.nlps
+		} else {
+			bit = cx.mps
+			cx.index = q.nmps
+		}
+	}
+
+	// renormalize
+	for {
+		if d.ct == 0 {
+			d.byteIn()
+		}
+		d.a <<= 1
+		d.c <<= 1
+		d.ct--
+		if d.a&0x8000 != 0 {
+			break
+		}
+	}
+	return bit
+}
+
+// the integer arithmetic decoding procedure (T.88 Annex A.2),
+// ok is false for the out-of-band value
+func (d *jbig2ArithDecoder) integer(cx []jbig2Context) (value int, ok bool) {
+	prev := 1
+	bit := func() int {
+		b := d.decode(&cx[prev])
+		if prev < 256 {
+			prev = prev<<1 | b
+		} else {
+			prev = (prev<<1|b)&511 | 256
+		}
+		return b
+	}
+	bits := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | bit()
+		}
+		return v
+	}
+
+	sign := bit()
+	switch {
+	case bit() == 0:
+		value = bits(2)
+	case bit() == 0:
+		value = bits(4) + 4
+	case bit() == 0:
+		value = bits(6) + 20
+	case bit() == 0:
+		value = bits(8) + 84
+	case bit() == 0:
+		value = bits(12) + 340
+	default:
+		value = bits(32) + 4436
+	}
+
+	if sign == 1 {
+		if value == 0 {
+			return 0, false
+		}
+		value = -value
+	}
+	return value, true
+}
+
+var errJBIG2OOB = errors.New("unexpected out-of-band value")
+
+// an integer that cannot be the out-of-band value
+func (d *jbig2ArithDecoder) value(cx []jbig2Context) (int, error) {
+	value, ok := d.integer(cx)
+	if !ok {
+		return 0, errJBIG2OOB
+	}
+	return value, nil
+}
+
+// the IAID decoding procedure (T.88 Annex A.3)
+func (d *jbig2ArithDecoder) id(cx []jbig2Context, length int) int {
+	prev := 1
+	for i := 0; i < length; i++ {
+		prev = prev<<1 | d.decode(&cx[prev])
+	}
+	return prev - 1<<uint(length)
+}
+
+// the contexts used by the integer decoders of a segment (T.88 Table A.1)
+// and the bitmaps its regions decode
+type jbig2Contexts struct {
+	dh, dw, ex, ai []jbig2Context // symbol dictionaries
+	dt, fs, ds, it []jbig2Context // text regions
+	ri, rdw, rdh   []jbig2Context
+	rdx, rdy       []jbig2Context
+	id             []jbig2Context
+
+	generic, refinement []jbig2Context
+}
+
+func newJBIG2Contexts() *jbig2Contexts {
+	cx := &jbig2Contexts{}
+	for _, integer := range []*[]jbig2Context{
+		&cx.dh, &cx.dw, &cx.ex, &cx.ai,
+		&cx.dt, &cx.fs, &cx.ds, &cx.it,
+		&cx.ri, &cx.rdw, &cx.rdh, &cx.rdx, &cx.rdy,
+	} {
+		*integer = make([]jbig2Context, 512)
+	}
+	return cx
+}
+
+// generic region decoding parameters (T.88 §6.2.2)
+type jbig2Generic struct {
+	width, height int
+	mmr           bool
+	template      int
+	tpgdon        bool
+	at            []jbig2Offset
+}
+
+func jbig2GenericContexts(template int) int {
+	return [4]int{1 << 16, 1 << 13, 1 << 10, 1 << 10}[template]
+}
+
+// the contexts used to decode whether a row is the same as
+// the one above when TPGDON is used (T.88 §6.2.5.7)
+var jbig2TypicalContexts = [4]int{0x9b25, 0x0795, 0x00e5, 0x0195}
+
+func (g jbig2Generic) decode(d *jbig2ArithDecoder, cx []jbig2Context) (*jbig2Bitmap, error) {
+	b, err := newJBIG2Bitmap(g.width, g.height)
+	if err != nil {
+		return nil, err
+	}
+
+	ltp := 0
+	for y := 0; y < g.height; y++ {
+		if g.tpgdon {
+			ltp ^= d.decode(&cx[jbig2TypicalContexts[g.template]])
+			if ltp == 1 {
+				if y > 0 {
+					copy(b.row(y), b.row(y-1))
+				}
+				continue
+			}
+		}
+
+		for x := 0; x < g.width; x++ {
+			if d.decode(&cx[g.context(b, x, y)]) == 1 {
+				b.set(x, y, 1)
+			}
+		}
+	}
+
+	return b, nil
+}
+
+// the context of a pixel from the pixels above and to its
+// left (T.88 Figures 3 to 6), the bit order matters because
+// jbig2TypicalContexts shares the same contexts
+func (g jbig2Generic) context(b *jbig2Bitmap, x, y int) int {
+	at := func(i int) int {
+		return b.get(x+g.at[i].x, y+g.at[i].y)
+	}
+
+	switch g.template {
+	case 0:
+		return b.get(x-1, y) | b.get(x-2, y)<<1 | b.get(x-3, y)<<2 | b.get(x-4, y)<<3 |
+			at(0)<<4 |
+			b.get(x+2, y-1)<<5 | b.get(x+1, y-1)<<6 | b.get(x, y-1)<<7 | b.get(x-1, y-1)<<8 | b.get(x-2, y-1)<<9 |
+			at(1)<<10 | at(2)<<11 |
+			b.get(x+1, y-2)<<12 | b.get(x, y-2)<<13 | b.get(x-1, y-2)<<14 |
+			at(3)<<15
+	case 1:
+		return b.get(x-1, y) | b.get(x-2, y)<<1 | b.get(x-3, y)<<2 |
+			at(0)<<3 |
+			b.get(x+2, y-1)<<4 | b.get(x+1, y-1)<<5 | b.get(x, y-1)<<6 | b.get(x-1, y-1)<<7 | b.get(x-2, y-1)<<8 |
+			b.get(x+2, y-2)<<9 | b.get(x+1, y-2)<<10 | b.get(x, y-2)<<11 | b.get(x-1, y-2)<<12
+	case 2:
+		return b.get(x-1, y) | b.get(x-2, y)<<1 |
+			at(0)<<2 |
+			b.get(x+1, y-1)<<3 | b.get(x, y-1)<<4 | b.get(x-1, y-1)<<5 | b.get(x-2, y-1)<<6 |
+			b.get(x+1, y-2)<<7 | b.get(x, y-2)<<8 | b.get(x-1, y-2)<<9
+	}
+	return b.get(x-1, y) | b.get(x-2, y)<<1 | b.get(x-3, y)<<2 | b.get(x-4, y)<<3 |
+		at(0)<<4 |
+		b.get(x+1, y-1)<<5 | b.get(x, y-1)<<6 | b.get(x-1, y-1)<<7 | b.get(x-2, y-1)<<8 | b.get(x-3, y-1)<<9
+}
+
+// MMR coded generic regions are T.6 without EOFB (T.88 §6.2.6)
+func (g jbig2Generic) decodeMMR(data []byte) (*jbig2Bitmap, error) {
+	b, err := newJBIG2Bitmap(g.width, g.height)
+	if err != nil || g.width == 0 || g.height == 0 {
+		return b, err
+	}
+
+	decoded, err := ccittDecode(data, Dictionary{
+		"K":        Integer(-1),
+		"Columns":  Integer(g.width),
+		"Rows":     Integer(g.height),
+		"BlackIs1": Boolean(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	copy(b.data, decoded)
+	return b, nil
+}
+
+// generic refinement region decoding parameters (T.88 §6.3.2)
+type jbig2Refinement struct {
+	width, height int
+	template      int
+	tpgron        bool
+	at            []jbig2Offset
+	reference     *jbig2Bitmap
+	dx, dy        int
+}
+
+func jbig2RefinementContexts(template int) int {
+	return [2]int{1 << 13, 1 << 10}[template]
+}
+
+func (r jbig2Refinement) decode(d *jbig2ArithDecoder, cx []jbig2Context) (*jbig2Bitmap, error) {
+	b, err := newJBIG2Bitmap(r.width, r.height)
+	if err != nil {
+		return nil, err
+	}
+
+	// the context with only the reference pixel set decodes
+	// whether the row is typical (T.88 §6.3.5.6)
+	typical := 1 << 8
+	if r.template == 1 {
+		typical = 1 << 7
+	}
+
+	ltp := 0
+	for y := 0; y < r.height; y++ {
+		if r.tpgron {
+			ltp ^= d.decode(&cx[typical])
+		}
+
+		for x := 0; x < r.width; x++ {
+			// pixels with a uniform 3x3 neighbourhood in the reference
+			// are copied from the reference in typical rows
+			if ltp == 1 {
+				if value, ok := r.uniform(x-r.dx, y-r.dy); ok {
+					b.set(x, y, value)
+					continue
+				}
+			}
+
+			if d.decode(&cx[r.context(b, x, y)]) == 1 {
+				b.set(x, y, 1)
+			}
+		}
+	}
+
+	return b, nil
+}
+
+func (r jbig2Refinement) uniform(x, y int) (int, bool) {
+	value := r.reference.get(x, y)
+	for j := -1; j <= 1; j++ {
+		for i := -1; i <= 1; i++ {
+			if r.reference.get(x+i, y+j) != value {
+				return 0, false
+			}
+		}
+	}
+	return value, true
+}
+
+// the context of a pixel from the pixels decoded so far and
+// those around it in the reference (T.88 Figures 12 and 13)
+func (r jbig2Refinement) context(b *jbig2Bitmap, x, y int) int {
+	ref := func(i, j int) int {
+		return r.reference.get(x-r.dx+i, y-r.dy+j)
+	}
+
+	if r.template == 0 {
+		return b.get(x-1, y) | b.get(x+1, y-1)<<1 | b.get(x, y-1)<<2 |
+			b.get(x+r.at[0].x, y+r.at[0].y)<<3 |
+			ref(1, 1)<<4 | ref(0, 1)<<5 | ref(-1, 1)<<6 |
+			ref(1, 0)<<7 | ref(0, 0)<<8 | ref(-1, 0)<<9 |
+			ref(1, -1)<<10 | ref(0, -1)<<11 |
+			ref(r.at[1].x, r.at[1].y)<<12
+	}
+	return b.get(x-1, y) | b.get(x+1, y-1)<<1 | b.get(x, y-1)<<2 | b.get(x-1, y-1)<<3 |
+		ref(1, 1)<<4 | ref(0, 1)<<5 |
+		ref(1, 0)<<6 | ref(0, 0)<<7 | ref(-1, 0)<<8 |
+		ref(0, -1)<<9
+}
+
+// symbol dictionary decoding parameters (T.88 §6.5.2)
+type jbig2Symbols struct {
+	template            int
+	at                  []jbig2Offset
+	refinementAggregate bool
+	refinementTemplate  int
+	refinementAT        []jbig2Offset
+	exported, new       int
+	input               []*jbig2Bitmap
+}
+
+// returns the exported symbols (T.88 §6.5.5)
+func (sd jbig2Symbols) decode(d *jbig2ArithDecoder, cx *jbig2Contexts) ([]*jbig2Bitmap, error) {
+	codeLength := 0
+	for 1<<uint(codeLength) < len(sd.input)+sd.new {
+		codeLength++
+	}
+	if len(cx.id) != 1<<uint(codeLength+1) {
+		cx.id = make([]jbig2Context, 1<<uint(codeLength+1))
+	}
+
+	symbols := append([]*jbig2Bitmap{}, sd.input...)
+	height := 0
+	for len(symbols) < len(sd.input)+sd.new {
+		// a height class of symbols
+		dh, err := d.value(cx.dh)
+		if err != nil {
+			return nil, err
+		}
+		height += dh
+		if height < 0 {
+			return nil, fmt.Errorf("invalid symbol height %d", height)
+		}
+
+		width := 0
+		for {
+			dw, ok := d.integer(cx.dw)
+			if !ok {
+				break
+			}
+			width += dw
+			if width < 0 {
+				return nil, fmt.Errorf("invalid symbol width %d", width)
+			}
+			if len(symbols) >= len(sd.input)+sd.new {
+				return nil, errors.New("too many symbols")
+			}
+
+			symbol, err := sd.symbol(d, cx, symbols, width, height, codeLength)
+			if err != nil {
+				return nil, err
+			}
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	// runs of symbols alternate between not exported and exported
+	exported := []*jbig2Bitmap{}
+	export := false
+	for i, runs := 0, 0; i < len(symbols); runs++ {
+		run, err := d.value(cx.ex)
+		if err != nil {
+			return nil, err
+		}
+		if run < 0 || i+run > len(symbols) || runs > len(symbols)+1 {
+			return nil, errors.New("invalid exported symbols")
+		}
+		if export {
+			exported = append(exported, symbols[i:i+run]...)
+		}
+		i += run
+		export = !export
+	}
+	if len(exported) != sd.exported {
+		return nil, fmt.Errorf("%d symbols exported instead of %d", len(exported), sd.exported)
+	}
+
+	return exported, nil
+}
+
+// a symbol's bitmap is a generic region or, with refinement
+// and aggregation, a refined symbol or a text region of symbols
+func (sd jbig2Symbols) symbol(d *jbig2ArithDecoder, cx *jbig2Contexts, symbols []*jbig2Bitmap, width, height, codeLength int) (*jbig2Bitmap, error) {
+	if !sd.refinementAggregate {
+		g := jbig2Generic{
+			width:    width,
+			height:   height,
+			template: sd.template,
+			at:       sd.at,
+		}
+		return g.decode(d, cx.generic)
+	}
+
+	instances, err := d.value(cx.ai)
+	if err != nil {
+		return nil, err
+	}
+
+	if instances != 1 {
+		t := jbig2Text{
+			width:              width,
+			height:             height,
+			instances:          instances,
+			strips:             1,
+			symbols:            symbols,
+			codeLength:         codeLength,
+			refCorner:          jbig2TopLeft,
+			refine:             true,
+			refinementTemplate: sd.refinementTemplate,
+			refinementAT:       sd.refinementAT,
+		}
+		return t.decode(d, cx)
+	}
+
+	id := d.id(cx.id, codeLength)
+	if id >= len(symbols) {
+		return nil, fmt.Errorf("symbol %d is not defined", id)
+	}
+	rdx, err := d.value(cx.rdx)
+	if err != nil {
+		return nil, err
+	}
+	rdy, err := d.value(cx.rdy)
+	if err != nil {
+		return nil, err
+	}
+
+	r := jbig2Refinement{
+		width:     width,
+		height:    height,
+		template:  sd.refinementTemplate,
+		at:        sd.refinementAT,
+		reference: symbols[id],
+		dx:        rdx,
+		dy:        rdy,
+	}
+	return r.decode(d, cx.refinement)
+}
+
+// text region decoding parameters (T.88 §6.4.2)
+type jbig2Text struct {
+	width, height      int
+	instances          int
+	strips             int
+	symbols            []*jbig2Bitmap
+	codeLength         int
+	defaultPixel       int
+	combination        int
+	transposed         bool
+	refCorner          int
+	dsOffset           int
+	refine             bool
+	refinementTemplate int
+	refinementAT       []jbig2Offset
+}
+
+// places symbol instances in strips (T.88 §6.4.5)
+func (t jbig2Text) decode(d *jbig2ArithDecoder, cx *jbig2Contexts) (*jbig2Bitmap, error) {
+	b, err := newJBIG2Bitmap(t.width, t.height)
+	if err != nil {
+		return nil, err
+	}
+	b.fill(t.defaultPixel)
+
+	if len(cx.id) != 1<<uint(t.codeLength+1) {
+		cx.id = make([]jbig2Context, 1<<uint(t.codeLength+1))
+	}
+
+	stripT, err := d.value(cx.dt)
+	if err != nil {
+		return nil, err
+	}
+	stripT *= -t.strips
+
+	firstS := 0
+	for n := 0; n < t.instances; {
+		dt, err := d.value(cx.dt)
+		if err != nil {
+			return nil, err
+		}
+		stripT += dt * t.strips
+
+		// the symbols in the strip
+		curS := 0
+		for first := true; n < t.instances; first = false {
+			if first {
+				dfs, err := d.value(cx.fs)
+				if err != nil {
+					return nil, err
+				}
+				firstS += dfs
+				curS = firstS
+			} else {
+				ds, ok := d.integer(cx.ds)
+				if !ok {
+					break
+				}
+				curS += ds + t.dsOffset
+			}
+
+			curT := 0
+			if t.strips > 1 {
+				curT, err = d.value(cx.it)
+				if err != nil {
+					return nil, err
+				}
+			}
+			symbolT := stripT + curT
+
+			symbol, err := t.symbol(d, cx)
+			if err != nil {
+				return nil, err
+			}
+			w, h := symbol.width, symbol.height
+
+			// S is the position along the strip of the
+			// reference corner, T is across the strip
+			switch {
+			case !t.transposed && (t.refCorner == jbig2TopRight || t.refCorner == jbig2BottomRight):
+				curS += w - 1
+			case t.transposed && (t.refCorner == jbig2BottomLeft || t.refCorner == jbig2BottomRight):
+				curS += h - 1
+			}
+
+			x, y := curS, symbolT
+			if t.transposed {
+				x, y = symbolT, curS
+			}
+			if t.refCorner == jbig2TopRight || t.refCorner == jbig2BottomRight {
+				x -= w - 1
+			}
+			if t.refCorner == jbig2BottomLeft || t.refCorner == jbig2BottomRight {
+				y -= h - 1
+			}
+			b.compose(symbol, x, y, t.combination)
+
+			switch {
+			case !t.transposed && (t.refCorner == jbig2TopLeft || t.refCorner == jbig2BottomLeft):
+				curS += w - 1
+			case t.transposed && (t.refCorner == jbig2TopLeft || t.refCorner == jbig2TopRight):
+				curS += h - 1
+			}
+			n++
+		}
+	}
+
+	return b, nil
+}
+
+// the bitmap of the next symbol instance, refined if
+// its refinement flag is set (T.88 §6.4.11)
+func (t jbig2Text) symbol(d *jbig2ArithDecoder, cx *jbig2Contexts) (*jbig2Bitmap, error) {
+	id := d.id(cx.id, t.codeLength)
+	if id >= len(t.symbols) {
+		return nil, fmt.Errorf("symbol %d is not defined", id)
+	}
+	symbol := t.symbols[id]
+
+	if !t.refine {
+		return symbol, nil
+	}
+	refine, err := d.value(cx.ri)
+	if err != nil || refine == 0 {
+		return symbol, err
+	}
+
+	var rd [4]int
+	for i, contexts := range [][]jbig2Context{cx.rdw, cx.rdh, cx.rdx, cx.rdy} {
+		rd[i], err = d.value(contexts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rdw, rdh, rdx, rdy := rd[0], rd[1], rd[2], rd[3]
+
+	r := jbig2Refinement{
+		width:     symbol.width + rdw,
+		height:    symbol.height + rdh,
+		template:  t.refinementTemplate,
+		at:        t.refinementAT,
+		reference: symbol,
+		dx:        rdw>>1 + rdx,
+		dy:        rdh>>1 + rdy,
+	}
+	return r.decode(d, cx.refinement)
+}
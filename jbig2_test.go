@@ -0,0 +1,684 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// the arithmetic coder test sequence from T.88 Annex H.2
+var (
+	jbig2TestDecoded = []byte{
+		0x00, 0x02, 0x00, 0x51, 0x00, 0x00, 0x00, 0xc0, 0x03, 0x52, 0x87, 0x2a, 0xaa, 0xaa, 0xaa, 0xaa,
+		0x82, 0xc0, 0x20, 0x00, 0xfc, 0xd7, 0x9e, 0xf6, 0xbf, 0x7f, 0xed, 0x90, 0x4f, 0x46, 0xa3, 0xbf,
+	}
+	jbig2TestEncoded = []byte{
+		0x84, 0xc7, 0x3b, 0xfc, 0xe1, 0xa1, 0x43, 0x04, 0x02, 0x20, 0x00, 0x00, 0x41, 0x0d, 0xbb, 0x86,
+		0xf4, 0x31, 0x7f, 0xff, 0x88, 0xff, 0x37, 0x47, 0x1a, 0xdb, 0x6a, 0xdf, 0xff, 0xac,
+	}
+)
+
+func TestJBIG2ArithDecoder(t *testing.T) {
+	d := newJBIG2ArithDecoder(jbig2TestEncoded)
+	cx := &jbig2Context{}
+
+	decoded := make([]byte, len(jbig2TestDecoded))
+	for i := range decoded {
+		for bit := 7; bit >= 0; bit-- {
+			decoded[i] |= byte(d.decode(cx) << uint(bit))
+		}
+	}
+	if !bytes.Equal(decoded, jbig2TestDecoded) {
+		t.Errorf("expected % x, got % x", jbig2TestDecoded, decoded)
+	}
+
+	// the encoder used to write the test streams
+	e := newJBIG2ArithEncoder()
+	cx = &jbig2Context{}
+	for _, b := range jbig2TestDecoded {
+		for bit := 7; bit >= 0; bit-- {
+			e.encode(cx, int(b>>uint(bit)&1))
+		}
+	}
+	encoded := e.flush()
+	if !bytes.Equal(encoded, jbig2TestEncoded) {
+		t.Errorf("expected % x, got % x", jbig2TestEncoded, encoded)
+	}
+}
+
+// the arithmetic encoder (T.88 Annex E.2) with the
+// software conventions of Figures E.3 to E.11
+type jbig2ArithEncoder struct {
+	out []byte // starts with the byte before the data
+	c   uint32
+	a   uint32
+	ct  int
+}
+
+func newJBIG2ArithEncoder() *jbig2ArithEncoder {
+	return &jbig2ArithEncoder{out: []byte{0}, a: 0x8000, ct: 12}
+}
+
+func (e *jbig2ArithEncoder) encode(cx *jbig2Context, bit int) {
+	q := jbig2Qe[cx.index]
+	e.a -= q.qe
+
+	if bit == cx.mps {
+		if e.a&0x8000 != 0 {
+			e.c += q.qe
+			return
+		}
+		if e.a < q.qe {
+			e.a = q.qe
+		} else {
+			e.c += q.qe
+		}
+		cx.index = q.nmps
+	} else {
+		if e.a < q.qe {
+			e.c += q.qe
+		} else {
+			e.a = q.qe
+		}
+		if q.swtch {
+			cx.mps = 1 - cx.mps
+		}
+		cx.index = q.nlps
+	}
+
+	for {
+		e.a <<= 1
+		e.c <<= 1
+		e.ct--
+		if e.ct == 0 {
+			e.byteOut()
+		}
+		if e.a&0x8000 != 0 {
+			break
+		}
+	}
+}
+
+func (e *jbig2ArithEncoder) byteOut() {
+	last := len(e.out) - 1
+	if e.out[last] == 0xff {
+		e.out = append(e.out, byte(e.c>>20))
+		e.c &= 0xfffff
+		e.ct = 7
+		return
+	}
+	if e.c >= 0x8000000 {
+		// carry
+		e.out[last]++
+		e.c &= 0x7ffffff
+		if e.out[last] == 0xff {
+			e.out = append(e.out, byte(e.c>>20))
+			e.c &= 0xfffff
+			e.ct = 7
+			return
+		}
+	}
+	e.out = append(e.out, byte(e.c>>19))
+	e.c &= 0x7ffff
+	e.ct = 8
+}
+
+func (e *jbig2ArithEncoder) flush() []byte {
+	temp := e.c + e.a
+	e.c |= 0xffff
+	if e.c >= temp {
+		e.c -= 0x8000
+	}
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	e.c <<= uint(e.ct)
+	e.byteOut()
+	if e.out[len(e.out)-1] != 0xff {
+		e.out = append(e.out, 0xff)
+	}
+	e.out = append(e.out, 0xac)
+	return e.out[1:]
+}
+
+// the inverse of jbig2ArithDecoder.integer
+func (e *jbig2ArithEncoder) integer(cx []jbig2Context, value int, oob bool) {
+	prev := 1
+	bit := func(b int) {
+		e.encode(&cx[prev], b)
+		if prev < 256 {
+			prev = prev<<1 | b
+		} else {
+			prev = (prev<<1|b)&511 | 256
+		}
+	}
+	bits := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bit(v >> uint(i) & 1)
+		}
+	}
+
+	if oob {
+		bit(1)
+		bit(0)
+		bits(0, 2)
+		return
+	}
+
+	sign := 0
+	if value < 0 {
+		sign, value = 1, -value
+	}
+	bit(sign)
+	for _, r := range []struct{ prefix, prefixLength, length, offset int }{
+		{0, 1, 2, 0}, {2, 2, 4, 4}, {6, 3, 6, 20}, {14, 4, 8, 84}, {30, 5, 12, 340},
+	} {
+		if value < r.offset+1<<uint(r.length) {
+			bits(r.prefix, r.prefixLength)
+			bits(value-r.offset, r.length)
+			return
+		}
+	}
+	bits(31, 5)
+	bits(value-4436, 32)
+}
+
+func (e *jbig2ArithEncoder) id(cx []jbig2Context, id, length int) {
+	prev := 1
+	for i := length - 1; i >= 0; i-- {
+		b := id >> uint(i) & 1
+		e.encode(&cx[prev], b)
+		prev = prev<<1 | b
+	}
+}
+
+func (e *jbig2ArithEncoder) generic(g jbig2Generic, b *jbig2Bitmap, cx []jbig2Context) {
+	ltp := 0
+	for y := 0; y < b.height; y++ {
+		if g.tpgdon {
+			typical := 1
+			if y > 0 && !bytes.Equal(b.row(y), b.row(y-1)) {
+				typical = 0
+			}
+			if y == 0 && !bytes.Equal(b.row(0), make([]byte, b.stride)) {
+				typical = 0
+			}
+			e.encode(&cx[jbig2TypicalContexts[g.template]], typical^ltp)
+			ltp = typical
+			if ltp == 1 {
+				continue
+			}
+		}
+
+		for x := 0; x < b.width; x++ {
+			e.encode(&cx[g.context(b, x, y)], b.get(x, y))
+		}
+	}
+}
+
+func (e *jbig2ArithEncoder) refinement(r jbig2Refinement, b *jbig2Bitmap, cx []jbig2Context) {
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			e.encode(&cx[r.context(b, x, y)], b.get(x, y))
+		}
+	}
+}
+
+// builds a bitmap from rows of '#' and '.'
+func jbig2TestBitmap(rows ...string) *jbig2Bitmap {
+	b, _ := newJBIG2Bitmap(len(rows[0]), len(rows))
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '#' {
+				b.set(x, y, 1)
+			}
+		}
+	}
+	return b
+}
+
+func jbig2TestSegment(number uint32, kind int, referred []uint32, data []byte) []byte {
+	segment := make([]byte, 4)
+	binary.BigEndian.PutUint32(segment, number)
+	segment = append(segment, byte(kind), byte(len(referred)<<5))
+	for _, r := range referred {
+		segment = append(segment, byte(r))
+	}
+	segment = append(segment, 1) // page
+	segment = binary.BigEndian.AppendUint32(segment, uint32(len(data)))
+	return append(segment, data...)
+}
+
+func jbig2TestPage(width, height int) []byte {
+	data := binary.BigEndian.AppendUint32(nil, uint32(width))
+	data = binary.BigEndian.AppendUint32(data, uint32(height))
+	data = append(data, make([]byte, 8)...) // resolution
+	data = append(data, 0, 0, 0)            // flags and striping
+	return jbig2TestSegment(1, jbig2PageInformation, nil, data)
+}
+
+func jbig2TestRegionInfo(width, height, x, y int) []byte {
+	data := []byte{}
+	for _, v := range []int{width, height, x, y} {
+		data = binary.BigEndian.AppendUint32(data, uint32(v))
+	}
+	return append(data, jbig2Or)
+}
+
+// the page as decoded by JBIG2Decode, with 0 for black
+func jbig2Inverted(b *jbig2Bitmap) []byte {
+	inverted := make([]byte, len(b.data))
+	for i := range b.data {
+		inverted[i] = ^b.data[i]
+	}
+	return inverted
+}
+
+func TestJBIG2GenericRegion(t *testing.T) {
+	image := jbig2TestBitmap(
+		"....................................",
+		"..######..........##########........",
+		"..######..........##########........",
+		"..##..##..............##............",
+		"..##..##..............##............",
+		"..######..............##............",
+		"..######..............##......#.#.#.",
+		"..............................#.#.#.",
+		"....................................",
+		"....................................",
+	)
+
+	for template := 0; template < 4; template++ {
+		for _, tpgdon := range []bool{false, true} {
+			g := jbig2Generic{
+				width:    image.width,
+				height:   image.height,
+				template: template,
+				tpgdon:   tpgdon,
+				at:       []jbig2Offset{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}},
+			}
+			if template != 0 {
+				g.at = []jbig2Offset{{2, -1}}
+			}
+
+			data := jbig2TestRegionInfo(image.width, image.height, 0, 0)
+			flags := byte(template << 1)
+			if tpgdon {
+				flags |= 8
+			}
+			data = append(data, flags)
+			for _, at := range g.at {
+				data = append(data, byte(at.x), byte(at.y))
+			}
+			e := newJBIG2ArithEncoder()
+			e.generic(g, image, make([]jbig2Context, jbig2GenericContexts(template)))
+			data = append(data, e.flush()...)
+
+			encoded := jbig2TestPage(image.width, image.height)
+			encoded = append(encoded, jbig2TestSegment(2, jbig2ImmediateLosslessGenericRegion, nil, data)...)
+
+			decoded, err := jbig2Decode(encoded, Dictionary{})
+			if err != nil {
+				t.Fatalf("template %d, TPGDON %v: %v", template, tpgdon, err)
+			}
+			if !bytes.Equal(decoded, jbig2Inverted(image)) {
+				t.Errorf("template %d, TPGDON %v: expected % x, got % x", template, tpgdon, jbig2Inverted(image), decoded)
+			}
+		}
+	}
+}
+
+func TestJBIG2MMR(t *testing.T) {
+	image := jbig2TestBitmap(
+		"..........",
+		".########.",
+		".#......#.",
+		".########.",
+	)
+
+	mmr, err := ccittEncode(image.data, Dictionary{
+		"K":        Integer(-1),
+		"Columns":  Integer(image.width),
+		"BlackIs1": Boolean(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := jbig2TestRegionInfo(image.width, image.height, 0, 0)
+	data = append(data, 1) // MMR
+	data = append(data, mmr...)
+
+	encoded := jbig2TestPage(image.width, image.height)
+	encoded = append(encoded, jbig2TestSegment(2, jbig2ImmediateGenericRegion, nil, data)...)
+
+	decoded, err := jbig2Decode(encoded, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, jbig2Inverted(image)) {
+		t.Errorf("expected % x, got % x", jbig2Inverted(image), decoded)
+	}
+}
+
+// a 153x55 image written by libtiff as T.6 and as PNG, from the
+// golang.org/x/image/ccitt test data
+var (
+	jbig2TestGopherMMR = []byte{
+		0x3b, 0x5a, 0x70, 0x40, 0xc8, 0xe7, 0x08, 0xee, 0x7b, 0x48, 0xf1, 0x84, 0x12, 0x4c, 0x22, 0x3c,
+		0xca, 0x4e, 0x82, 0xa5, 0x7e, 0x12, 0x49, 0x36, 0x1d, 0x3f, 0x7a, 0x0d, 0x95, 0xd6, 0xe8, 0xd8,
+		0x0b, 0x69, 0xe9, 0x5b, 0x2b, 0x2c, 0xaa, 0xd0, 0x22, 0x3a, 0x0a, 0xc2, 0xff, 0x84, 0x13, 0x2a,
+		0xa0, 0xbc, 0x7d, 0x02, 0x0b, 0x83, 0x04, 0x61, 0x75, 0x56, 0x57, 0x04, 0xac, 0xad, 0x7d, 0x82,
+		0x23, 0xa0, 0x4b, 0xbe, 0x18, 0x51, 0x09, 0x95, 0xa6, 0xe2, 0x1a, 0x23, 0xd7, 0x76, 0x29, 0xe1,
+		0xd8, 0x50, 0x7c, 0x58, 0x45, 0xd5, 0x94, 0x36, 0x3f, 0x0a, 0xca, 0x1a, 0xce, 0xc6, 0xd7, 0xce,
+		0xd2, 0x08, 0xca, 0x10, 0x69, 0xe7, 0x65, 0xc1, 0x34, 0x08, 0xc2, 0xe7, 0x61, 0x87, 0x65, 0x27,
+		0x1f, 0x3b, 0x80, 0x76, 0x55, 0x45, 0x42, 0xe4, 0xd0, 0x2e, 0x23, 0xca, 0xc8, 0x56, 0x51, 0x8c,
+		0xa9, 0x82, 0x7c, 0x96, 0x82, 0x11, 0x21, 0xb2, 0x92, 0x64, 0x58, 0x1c, 0x89, 0x82, 0x7c, 0x11,
+		0x08, 0x2a, 0x17, 0xd9, 0xc4, 0x88, 0x6c, 0x07, 0xe2, 0x54, 0x03, 0x7b, 0xe5, 0x54, 0x1b, 0xfc,
+		0x85, 0x83, 0x1a, 0x59, 0x11, 0x67, 0xc7, 0xfd, 0xf4, 0x11, 0x75, 0xfe, 0xe9, 0x05, 0xe9, 0x0f,
+		0x09, 0x6d, 0x28, 0x65, 0x6a, 0x15, 0x94, 0x80, 0xac, 0xbb, 0x05, 0x82, 0x2e, 0x7e, 0x50, 0xd6,
+		0x96, 0x38, 0x00, 0x80, 0x08,
+	}
+	jbig2TestGopherPNG = []byte{
+		0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+		0x00, 0x00, 0x00, 0x99, 0x00, 0x00, 0x00, 0x37, 0x08, 0x00, 0x00, 0x00, 0x00, 0x3a, 0x21, 0xb7,
+		0xf8, 0x00, 0x00, 0x01, 0xe9, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0xcc, 0x99, 0x8d, 0x6e, 0x84,
+		0x20, 0x10, 0x84, 0x99, 0xe6, 0xde, 0xff, 0x95, 0xa7, 0x39, 0x04, 0xe4, 0x67, 0x41, 0xe0, 0x74,
+		0xd7, 0x4d, 0xda, 0xbb, 0xa6, 0x1a, 0xbf, 0xcc, 0xfe, 0x0c, 0xe0, 0x87, 0xce, 0x28, 0xe0, 0x9c,
+		0x1b, 0x3d, 0xfc, 0xa3, 0xc8, 0x92, 0x05, 0xd2, 0x07, 0xfd, 0x57, 0x01, 0xd1, 0x84, 0x0c, 0x8e,
+		0xfe, 0xc7, 0x39, 0xd0, 0x7f, 0x42, 0xba, 0x48, 0x3f, 0x9b, 0x81, 0x85, 0xe1, 0xf9, 0x5f, 0xdd,
+		0x24, 0x8c, 0x3f, 0x7d, 0x30, 0x7a, 0x8a, 0x00, 0xd6, 0xaf, 0x35, 0x6d, 0xcd, 0x82, 0x56, 0x48,
+		0x60, 0x3e, 0xb1, 0x12, 0x9e, 0xb2, 0x66, 0x90, 0x6a, 0x4a, 0x16, 0x47, 0x97, 0xcc, 0x53, 0x21,
+		0x75, 0x64, 0x64, 0xa2, 0xd4, 0x02, 0xaa, 0xbd, 0x19, 0xd3, 0x76, 0x96, 0x10, 0x8f, 0xaf, 0x14,
+		0xc8, 0x34, 0x35, 0x3b, 0xeb, 0x09, 0x91, 0x25, 0x12, 0x09, 0x68, 0x8a, 0x9a, 0x21, 0x08, 0x84,
+		0x8c, 0x65, 0xd0, 0x7f, 0x46, 0x1e, 0xe0, 0x6a, 0x2e, 0x36, 0x43, 0x42, 0x8d, 0x0c, 0x83, 0xbf,
+		0xc4, 0xd0, 0x22, 0xc3, 0xd9, 0x7f, 0x4c, 0x6e, 0x3e, 0x1c, 0xa6, 0x4a, 0x1d, 0x80, 0xcc, 0xbd,
+		0x8f, 0xaf, 0xb5, 0x5b, 0x36, 0x3d, 0xa0, 0x5b, 0x67, 0x71, 0x8c, 0x05, 0x05, 0x87, 0xfe, 0xa3,
+		0x44, 0x16, 0x15, 0x49, 0x6b, 0x8c, 0x96, 0xab, 0xee, 0x01, 0xbd, 0x79, 0x16, 0xe7, 0x3d, 0xc3,
+		0x2f, 0x02, 0x55, 0x02, 0x61, 0x42, 0xc6, 0x0a, 0x43, 0xf0, 0xf1, 0x4a, 0x43, 0xb5, 0x3a, 0xab,
+		0xbd, 0xb1, 0x1d, 0x60, 0xd5, 0x9a, 0x48, 0x7f, 0x7d, 0x16, 0xa3, 0x05, 0xa3, 0x63, 0xd6, 0xa1,
+		0x8a, 0x64, 0x92, 0x6d, 0x57, 0x91, 0xa7, 0xdc, 0xca, 0x9d, 0x3a, 0x91, 0x09, 0xa9, 0x99, 0xcd,
+		0x09, 0xd1, 0xb2, 0x78, 0x9c, 0x6c, 0x89, 0xa6, 0xb8, 0xf1, 0xc1, 0x7d, 0x40, 0x82, 0xe2, 0xf0,
+		0x69, 0x59, 0x47, 0xe6, 0xff, 0x7f, 0xa6, 0xce, 0x4a, 0x47, 0xbc, 0xb8, 0xb6, 0x63, 0xed, 0xf7,
+		0x92, 0xe1, 0x02, 0x48, 0x1a, 0x62, 0xbd, 0x7c, 0xdf, 0x41, 0x56, 0x4f, 0xf7, 0x41, 0xb4, 0x68,
+		0x3d, 0xc9, 0x7e, 0x26, 0xc3, 0x24, 0x52, 0x37, 0xd8, 0xbb, 0x73, 0x9b, 0x6c, 0x13, 0x49, 0xcc,
+		0xa7, 0x18, 0x1b, 0x64, 0x2b, 0xe5, 0xfd, 0x43, 0x2c, 0x91, 0xad, 0x31, 0x41, 0xbc, 0x6e, 0x5a,
+		0xb4, 0x39, 0xb2, 0x85, 0x1a, 0x6f, 0xae, 0xdf, 0x9d, 0x98, 0x13, 0x64, 0x58, 0x40, 0x6a, 0x67,
+		0xc0, 0x76, 0xba, 0x27, 0xc8, 0x88, 0xe9, 0xcc, 0x55, 0x37, 0xee, 0x21, 0x85, 0x98, 0xc9, 0x66,
+		0xff, 0x09, 0xf2, 0x90, 0x9c, 0x18, 0xfa, 0x13, 0xb1, 0x35, 0x35, 0xb6, 0x80, 0x56, 0x63, 0x99,
+		0xac, 0xa1, 0xba, 0x11, 0xa8, 0x50, 0x73, 0xdf, 0x03, 0x9e, 0x3e, 0xac, 0x5c, 0x26, 0x53, 0x3b,
+		0x3d, 0xb5, 0xdb, 0xa1, 0x48, 0x91, 0x97, 0xca, 0x9b, 0xc8, 0x90, 0x6f, 0x9d, 0x5e, 0x45, 0x56,
+		0x86, 0x3e, 0x59, 0xb7, 0x50, 0x51, 0xee, 0xe3, 0xdf, 0xb3, 0xab, 0x03, 0x4b, 0xe8, 0xb7, 0x90,
+		0xb5, 0x0e, 0x68, 0x51, 0x67, 0x82, 0x85, 0x08, 0xd6, 0x6c, 0xa0, 0x59, 0xfb, 0xfe, 0x4b, 0x5c,
+		0x32, 0x98, 0x64, 0xb3, 0x58, 0x3d, 0xf6, 0x5e, 0x8a, 0xd9, 0xd4, 0x19, 0xe3, 0x99, 0xe3, 0xe0,
+		0x5d, 0x9d, 0x55, 0x07, 0xf0, 0x38, 0xf7, 0x79, 0xe1, 0x9b, 0x8a, 0x6b, 0x07, 0x36, 0xf3, 0x80,
+		0xcb, 0xf5, 0xa3, 0x15, 0xd9, 0xf5, 0x11, 0xd1, 0x7f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x74, 0xc4,
+		0x90, 0xa2, 0x83, 0x09, 0x6a, 0xa6, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae, 0x42,
+		0x60, 0x82,
+	}
+)
+
+// an MMR coded generic region not written by ccittEncode
+func TestJBIG2MMRGopher(t *testing.T) {
+	img, err := png.Decode(bytes.NewReader(jbig2TestGopherPNG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := img.Bounds().Size()
+	expected, err := newJBIG2Bitmap(size.X, size.Y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			if img.(*image.Gray).GrayAt(x, y).Y < 0x80 {
+				expected.set(x, y, 1)
+			}
+		}
+	}
+
+	data := jbig2TestRegionInfo(size.X, size.Y, 0, 0)
+	data = append(data, 1) // MMR
+	data = append(data, jbig2TestGopherMMR...)
+
+	encoded := jbig2TestPage(size.X, size.Y)
+	encoded = append(encoded, jbig2TestSegment(2, jbig2ImmediateGenericRegion, nil, data)...)
+
+	decoded, err := jbig2Decode(encoded, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, jbig2Inverted(expected)) {
+		t.Errorf("expected % x, got % x", jbig2Inverted(expected), decoded)
+	}
+}
+
+// a symbol dictionary in JBIG2Globals used by a text region on the page
+func TestJBIG2SymbolsAndText(t *testing.T) {
+	symbols := []*jbig2Bitmap{
+		jbig2TestBitmap(
+			".##.",
+			"#..#",
+			"####",
+			"#..#",
+		),
+		jbig2TestBitmap(
+			"###.",
+			"#..#",
+			"###.",
+			"#..#",
+			"###.",
+		),
+		jbig2TestBitmap(
+			"#",
+			"#",
+			"#",
+			"#",
+			"#",
+		),
+	}
+
+	// symbol dictionary: heights 4 and 5, all exported
+	cx := newJBIG2Contexts()
+	cx.generic = make([]jbig2Context, jbig2GenericContexts(0))
+	g := jbig2Generic{template: 0, at: []jbig2Offset{{3, -1}, {-3, -1}, {2, -2}, {-2, -2}}}
+	e := newJBIG2ArithEncoder()
+	height, width := 0, 0
+	for i, symbol := range symbols {
+		if symbol.height != height {
+			if i > 0 {
+				e.integer(cx.dw, 0, true)
+			}
+			e.integer(cx.dh, symbol.height-height, false)
+			height, width = symbol.height, 0
+		}
+		e.integer(cx.dw, symbol.width-width, false)
+		width = symbol.width
+		e.generic(g, symbol, cx.generic)
+	}
+	e.integer(cx.dw, 0, true)
+	e.integer(cx.ex, 0, false)
+	e.integer(cx.ex, len(symbols), false)
+
+	dictionary := []byte{0, 0} // flags
+	for _, at := range g.at {
+		dictionary = append(dictionary, byte(at.x), byte(at.y))
+	}
+	dictionary = binary.BigEndian.AppendUint32(dictionary, uint32(len(symbols)))
+	dictionary = binary.BigEndian.AppendUint32(dictionary, uint32(len(symbols)))
+	dictionary = append(dictionary, e.flush()...)
+	globals := jbig2TestSegment(0, jbig2SymbolDictionary, nil, dictionary)
+
+	// text region: "AB|" on the first strip, "|BA" refined on the second
+	type instance struct {
+		id, s, t int
+		refined  *jbig2Bitmap
+	}
+	refined := jbig2TestBitmap(
+		".##.",
+		"#..#",
+		"####",
+		"#..#",
+		"#..#",
+	)
+	instances := []instance{
+		{0, 1, 1, nil},
+		{1, 6, 1, nil},
+		{2, 12, 1, nil},
+		{2, 2, 8, nil},
+		{1, 4, 8, nil},
+		{0, 10, 8, refined},
+	}
+
+	cx = newJBIG2Contexts()
+	cx.id = make([]jbig2Context, 1<<3)
+	cx.refinement = make([]jbig2Context, jbig2RefinementContexts(0))
+	at := []jbig2Offset{{-1, -1}, {-1, -1}}
+	e = newJBIG2ArithEncoder()
+	e.integer(cx.dt, 0, false)
+	stripT, firstS, curS := 0, 0, 0
+	for i, instance := range instances {
+		symbol := symbols[instance.id]
+		if i == 0 || instance.t != stripT {
+			if i > 0 {
+				e.integer(cx.ds, 0, true)
+			}
+			e.integer(cx.dt, instance.t-stripT, false)
+			stripT = instance.t
+			e.integer(cx.fs, instance.s-firstS, false)
+			firstS = instance.s
+		} else {
+			e.integer(cx.ds, instance.s-curS, false)
+		}
+		curS = instance.s
+
+		e.id(cx.id, instance.id, 2)
+		if instance.refined == nil {
+			e.integer(cx.ri, 0, false)
+		} else {
+			e.integer(cx.ri, 1, false)
+			rdw := instance.refined.width - symbol.width
+			rdh := instance.refined.height - symbol.height
+			for _, v := range []struct {
+				cx    []jbig2Context
+				value int
+			}{{cx.rdw, rdw}, {cx.rdh, rdh}, {cx.rdx, 0}, {cx.rdy, 0}} {
+				e.integer(v.cx, v.value, false)
+			}
+			r := jbig2Refinement{
+				template:  0,
+				at:        at,
+				reference: symbol,
+				dx:        rdw >> 1,
+				dy:        rdh >> 1,
+			}
+			e.refinement(r, instance.refined, cx.refinement)
+			symbol = instance.refined
+		}
+		curS += symbol.width - 1
+	}
+	e.integer(cx.ds, 0, true)
+
+	page, _ := newJBIG2Bitmap(20, 14)
+	for _, instance := range instances {
+		symbol := symbols[instance.id]
+		if instance.refined != nil {
+			symbol = instance.refined
+		}
+		page.compose(symbol, instance.s, instance.t, jbig2Or)
+	}
+
+	text := jbig2TestRegionInfo(page.width, page.height, 0, 0)
+	flags := 2 | jbig2TopLeft<<4 // refinement
+	text = append(text, byte(flags>>8), byte(flags))
+	for _, offset := range at {
+		text = append(text, byte(offset.x), byte(offset.y))
+	}
+	text = binary.BigEndian.AppendUint32(text, uint32(len(instances)))
+	text = append(text, e.flush()...)
+
+	encoded := jbig2TestPage(page.width, page.height)
+	encoded = append(encoded, jbig2TestSegment(2, jbig2ImmediateTextRegion, []uint32{0}, text)...)
+
+	// the globals are resolved through the file
+	file, err := Create(filepath.Join(t.TempDir(), "jbig2.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	globalsRef, err := file.Add(Stream{Dictionary: Dictionary{}, Stream: globals})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imageRef, err := file.Add(Stream{
+		Dictionary: Dictionary{
+			"Filter":      Name("JBIG2Decode"),
+			"DecodeParms": Dictionary{"JBIG2Globals": globalsRef},
+		},
+		Stream: encoded,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := file.Get(imageRef).(Stream).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, jbig2Inverted(page)) {
+		t.Errorf("expected % x, got % x", jbig2Inverted(page), decoded)
+	}
+
+	// without the file the reference cannot be resolved
+	_, err = Stream{
+		Dictionary: Dictionary{
+			"Filter":      Name("JBIG2Decode"),
+			"DecodeParms": Dictionary{"JBIG2Globals": globalsRef},
+		},
+		Stream: encoded,
+	}.Decode()
+	if err == nil {
+		t.Error("expected an error for unresolved JBIG2Globals")
+	}
+}
+
+func TestJBIG2Errors(t *testing.T) {
+	for i, encoded := range [][]byte{
+		// truncated segment header
+		{0, 0, 0, 1, jbig2PageInformation},
+		// a region without a page
+		jbig2TestSegment(1, jbig2ImmediateGenericRegion, nil, append(jbig2TestRegionInfo(1, 1, 0, 0), 1)),
+		// a page larger than jbig2MaxPixels
+		jbig2TestPage(1<<16, 1<<16),
+		// no page
+		{},
+	} {
+		_, err := jbig2Decode(encoded, Dictionary{})
+		if err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+}
+
+func TestJBIG2NotSupported(t *testing.T) {
+	huffmanText := append(jbig2TestRegionInfo(8, 8, 0, 0), 0, 1)
+	huffmanText = append(huffmanText, 0, 0, 0, 0) // number of instances
+
+	for _, test := range []struct {
+		name    string
+		encoded []byte
+	}{
+		{"Huffman coded symbol dictionary", jbig2TestSegment(2, jbig2SymbolDictionary, nil, []byte{0, 1})},
+		{"Huffman coded text region", jbig2TestSegment(2, jbig2ImmediateTextRegion, nil, huffmanText)},
+		{"pattern dictionary", jbig2TestSegment(2, jbig2PatternDictionary, nil, []byte{0, 8, 8, 0, 0, 0, 0})},
+		{"halftone region", jbig2TestSegment(2, jbig2ImmediateHalftoneRegion, nil, nil)},
+	} {
+		encoded := append(jbig2TestPage(8, 8), test.encoded...)
+		_, err := jbig2Decode(encoded, Dictionary{})
+		if err == nil || !strings.Contains(err.Error(), "not supported") {
+			t.Errorf("%s: expected a not supported error, got %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+)
+
+// Version is a PDF version number, such as "1.7" or "2.0".
+// - §7.5.2
+type Version string
+
+// Limits are the ranges of values that readers of a PDF version
+// are expected to handle. Zero values mean there is no limit.
+// - Annex C
+type Limits struct {
+	Version         Version
+	MinInteger      Integer
+	MaxInteger      Integer
+	MaxReal         Real // largest magnitude
+	MinReal         Real // smallest non-zero magnitude
+	MaxStringLength int  // in bytes, of strings in content streams
+	MaxNameLength   int  // in bytes
+	MaxObjectNumber uint // largest number of indirect objects
+}
+
+// LimitsFor returns the Limits for a PDF version.
+//
+// Versions before 2.0 use the 32-bit integers and single-precision
+// reals described in Annex C of ISO 32000-1. PDF 2.0 does not impose
+// architectural limits on numbers.
+func LimitsFor(version Version) Limits {
+	if version >= "2.0" {
+		return Limits{Version: version}
+	}
+
+	return Limits{
+		Version:         version,
+		MinInteger:      math.MinInt32,
+		MaxInteger:      math.MaxInt32,
+		MaxReal:         3.403e+38,
+		MinReal:         1.175e-38,
+		MaxStringLength: 32767,
+		MaxNameLength:   127,
+		MaxObjectNumber: 8388607,
+	}
+}
+
+// A LimitError reports an Object with a value
+// that exceeds the Limits of a PDF version.
+type LimitError struct {
+	Version Version
+	Object  Object
+	Limit   string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s exceeds the %s limit of PDF-%s", describe(e.Object), e.Limit, e.Version)
+}
+
+// a short description of a value that exceeds a limit,
+// as strings and names can be long
+func describe(obj Object) string {
+	switch typed := obj.(type) {
+	case String:
+		return fmt.Sprintf("string of %d bytes", len(typed))
+	case Name:
+		return fmt.Sprintf("name of %d bytes", len(typed))
+	case ObjectReference:
+		return fmt.Sprintf("object number %d", typed.ObjectNumber)
+	}
+	return fmt.Sprint(obj)
+}
+
+// Check returns a *LimitError for the first value in obj
+// (including the contents of Arrays, Dictionaries and Streams)
+// that exceeds the limits. The length of strings is not checked,
+// as its limit only applies to strings in content streams.
+func (l Limits) Check(obj Object) error {
+	err := l.check(obj)
+	if err != nil {
+		err.Version = l.Version
+		return err
+	}
+	return nil
+}
+
+func (l Limits) check(obj Object) *LimitError {
+	switch typed := obj.(type) {
+	case Integer:
+		if l.MinInteger != 0 && typed < l.MinInteger {
+			return &LimitError{Object: typed, Limit: "smallest integer"}
+		}
+		if l.MaxInteger != 0 && typed > l.MaxInteger {
+			return &LimitError{Object: typed, Limit: "largest integer"}
+		}
+	case Real:
+		magnitude := Real(math.Abs(float64(typed)))
+		if l.MaxReal != 0 && magnitude > l.MaxReal {
+			return &LimitError{Object: typed, Limit: "largest real"}
+		}
+		if l.MinReal != 0 && magnitude != 0 && magnitude < l.MinReal {
+			return &LimitError{Object: typed, Limit: "smallest real"}
+		}
+	case Name:
+		if l.MaxNameLength != 0 && len(typed) > l.MaxNameLength {
+			return &LimitError{Object: typed, Limit: "name length"}
+		}
+	case Array:
+		for _, element := range typed {
+			if err := l.check(element); err != nil {
+				return err
+			}
+		}
+	case Dictionary:
+		for key, value := range typed {
+			if err := l.check(key); err != nil {
+				return err
+			}
+			if err := l.check(value); err != nil {
+				return err
+			}
+		}
+	case Stream:
+		return l.check(typed.Dictionary)
+	case ObjectReference:
+		if l.MaxObjectNumber != 0 && typed.ObjectNumber > l.MaxObjectNumber {
+			return &LimitError{Object: typed, Limit: "number of indirect objects"}
+		}
+	case IndirectObject:
+		if err := l.check(typed.ObjectReference); err != nil {
+			return err
+		}
+		return l.check(typed.Object)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package pdf
+
+import "testing"
+
+func TestLimits(t *testing.T) {
+	type test struct {
+		version Version
+		object  Object
+		exceeds bool
+	}
+	tests := []test{
+		test{"1.7", Integer(1<<31 - 1), false},
+		test{"1.7", Integer(1 << 31), true},
+		test{"1.7", Integer(-1<<31 - 1), true},
+		test{"2.0", Integer(1 << 31), false},
+		test{"1.4", Real(3.5e38), true},
+		test{"1.4", Real(-1e-39), true},
+		test{"1.4", Real(0), false},
+		test{"2.0", Real(3.5e38), false},
+		test{"1.7", Array{Integer(0), Dictionary{"Prev": Integer(3 << 30)}}, true},
+		test{"1.7", Stream{Dictionary: Dictionary{"Length": Integer(5 << 30)}}, true},
+		test{"1.7", Name(make([]byte, 128)), true},
+		test{"1.7", ObjectReference{ObjectNumber: 8388608}, true},
+		test{"1.7", IndirectObject{ObjectReference{ObjectNumber: 1}, String("ok")}, false},
+		// the string length limit is only for content streams
+		test{"1.7", Dictionary{"JS": String(make([]byte, 40000))}, false},
+	}
+
+	for i, test := range tests {
+		err := LimitsFor(test.version).Check(test.object)
+		if test.exceeds != (err != nil) {
+			t.Errorf("%d: expected exceeds to be %v, got %v", i, test.exceeds, err)
+		}
+
+		if err != nil {
+			limitErr, ok := err.(*LimitError)
+			if !ok {
+				t.Errorf("%d: expected a *LimitError, got %T", i, err)
+			} else if limitErr.Version != test.version {
+				t.Errorf("%d: expected version %v, got %v", i, test.version, limitErr.Version)
+			}
+		}
+	}
+}
+
+func TestLimitErrorDescribesValue(t *testing.T) {
+	err := LimitsFor("1.7").Check(Name(make([]byte, 40000)))
+	if err == nil || err.Error() != "name of 40000 bytes exceeds the name length limit of PDF-1.7" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// LZWDecode (§7.4.4.2)
+//
+// compress/lzw is not used because it does not support EarlyChange
+// and its code widths are one code late compared to PDF's default.
+
+const (
+	lzwClear    = 256
+	lzwEOD      = 257
+	lzwFirst    = 258 // first code added to the table
+	lzwMaxCode  = 4096
+	lzwMinWidth = 9
+	lzwMaxWidth = 12
+)
+
+// the EarlyChange entry of the filter parameters (§7.4.4.3 Table 8)
+func lzwEarlyChange(dict Dictionary) (uint, error) {
+	obj, ok := dict["EarlyChange"]
+	if !ok {
+		return 1, nil
+	}
+
+	earlyChange, ok := obj.(Integer)
+	if !ok || (earlyChange != 0 && earlyChange != 1) {
+		return 0, fmt.Errorf("EarlyChange must be 0 or 1, not %v", obj)
+	}
+	return uint(earlyChange), nil
+}
+
+// LZWDecode as a Filter, which decodes and encodes as data is read and written
+type lzwFilter struct{}
+
+func (lzwFilter) NewDecoder(r io.Reader, params Dictionary) io.Reader {
+	earlyChange, err := lzwEarlyChange(params)
+	if err != nil {
+		return errorReader{err}
+	}
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	lr := &lzwReader{r: br, earlyChange: earlyChange}
+	lr.clear()
+	return newPredictorReader(lr, params)
+}
+
+func (lzwFilter) NewEncoder(w io.Writer, params Dictionary) io.WriteCloser {
+	earlyChange, err := lzwEarlyChange(params)
+	if err != nil {
+		return errorWriter{err}
+	}
+	lw := &lzwWriter{w: bufio.NewWriter(w), earlyChange: earlyChange, width: lzwMinWidth, prefix: -1}
+	lw.emit(lzwClear)
+	return newPredictorWriter(lw, params)
+}
+
+func lzwDecode(encoded []byte, dict Dictionary) ([]byte, error) {
+	return ioutil.ReadAll(lzwFilter{}.NewDecoder(bytes.NewReader(encoded), dict))
+}
+
+// a table entry is the bytes of its prefix code followed by suffix
+type lzwEntry struct {
+	prefix int // -1 for single bytes
+	suffix byte
+	length int
+}
+
+// lzwReader decodes a code at a time
+type lzwReader struct {
+	r           io.ByteReader
+	earlyChange uint
+	table       [lzwMaxCode]lzwEntry
+
+	bits     uint32 // buffered bits
+	nBits    uint   // number of buffered bits
+	width    uint
+	next     int
+	previous int // the previous code, -1 after a clear code
+
+	decoded []byte // decoded bytes that have not been read
+	buf     []byte
+	err     error
+}
+
+func (lr *lzwReader) clear() {
+	for code := 0; code < 256; code++ {
+		lr.table[code] = lzwEntry{-1, byte(code), 1}
+	}
+	lr.width = lzwMinWidth
+	lr.next = lzwFirst
+	lr.previous = -1
+}
+
+func (lr *lzwReader) Read(p []byte) (int, error) {
+	for len(lr.decoded) == 0 {
+		if lr.err != nil {
+			return 0, lr.err
+		}
+		lr.decoded, lr.err = lr.code()
+	}
+
+	n := copy(p, lr.decoded)
+	lr.decoded = lr.decoded[n:]
+	return n, nil
+}
+
+// the bytes of the next code
+func (lr *lzwReader) code() ([]byte, error) {
+	for lr.nBits < lr.width {
+		b, err := lr.r.ReadByte()
+		if err != nil {
+			// data without an EOD marker ends with the data
+			return nil, err
+		}
+		lr.bits = lr.bits<<8 | uint32(b)
+		lr.nBits += 8
+	}
+
+	lr.nBits -= lr.width
+	code := int(lr.bits>>lr.nBits) & (1<<lr.width - 1)
+	lr.bits &= 1<<lr.nBits - 1
+
+	switch {
+	case code == lzwClear:
+		lr.clear()
+		return nil, nil
+	case code == lzwEOD:
+		return nil, io.EOF
+	case code < lr.next:
+	case code == lr.next && lr.previous >= 0:
+		// the code being defined: the previous output
+		// followed by its own first byte
+	default:
+		return nil, fmt.Errorf("LZW code %d is not defined", code)
+	}
+
+	// the previous output followed by the first byte of this one
+	if lr.previous >= 0 && lr.next < lzwMaxCode {
+		first := code
+		if code == lr.next {
+			first = lr.previous
+		}
+		for lr.table[first].prefix >= 0 {
+			first = lr.table[first].prefix
+		}
+		lr.table[lr.next] = lzwEntry{lr.previous, lr.table[first].suffix, lr.table[lr.previous].length + 1}
+		lr.next++
+
+		if uint(lr.next)+lr.earlyChange >= 1<<lr.width && lr.width < lzwMaxWidth {
+			lr.width++
+		}
+	}
+	lr.previous = code
+
+	// the entry's bytes are found from last to first
+	length := lr.table[code].length
+	if cap(lr.buf) < length {
+		lr.buf = make([]byte, length)
+	}
+	decoded := lr.buf[:length]
+	for i := length - 1; i >= 0; i-- {
+		decoded[i] = lr.table[code].suffix
+		code = lr.table[code].prefix
+	}
+	return decoded, nil
+}
+
+func lzwEncode(decoded []byte, dict Dictionary) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := lzwFilter{}.NewEncoder(buf, dict)
+	_, err := w.Write(decoded)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// lzwWriter encodes data as it is written
+type lzwWriter struct {
+	w           *bufio.Writer
+	earlyChange uint
+
+	bits  uint32
+	nBits uint
+
+	// code widths must match those used by the decoder,
+	// which adds each table entry one code later
+	width       uint
+	decoderNext int
+	first       bool
+
+	// prefix code and next byte to code
+	table  map[int]int
+	next   int
+	prefix int // the code of the bytes that have not been emitted, or -1
+}
+
+func (lw *lzwWriter) emit(code int) {
+	lw.bits = lw.bits<<lw.width | uint32(code)
+	lw.nBits += lw.width
+	for lw.nBits >= 8 {
+		lw.nBits -= 8
+		lw.w.WriteByte(byte(lw.bits >> lw.nBits))
+	}
+	lw.bits &= 1<<lw.nBits - 1
+
+	switch {
+	case code == lzwClear:
+		lw.width = lzwMinWidth
+		lw.decoderNext = lzwFirst
+		lw.first = true
+		lw.table = map[int]int{}
+		lw.next = lzwFirst
+		return
+	case lw.first:
+		lw.first = false
+		return
+	case lw.decoderNext < lzwMaxCode:
+		lw.decoderNext++
+		if uint(lw.decoderNext)+lw.earlyChange >= 1<<lw.width && lw.width < lzwMaxWidth {
+			lw.width++
+		}
+	}
+}
+
+func (lw *lzwWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if lw.prefix < 0 {
+			lw.prefix = int(b)
+			continue
+		}
+
+		key := lw.prefix<<8 | int(b)
+		if code, ok := lw.table[key]; ok {
+			lw.prefix = code
+			continue
+		}
+
+		lw.emit(lw.prefix)
+		lw.table[key] = lw.next
+		lw.next++
+
+		// start over before the table is full
+		if lw.next == lzwMaxCode {
+			lw.emit(lzwClear)
+		}
+
+		lw.prefix = int(b)
+	}
+	// write errors are kept by the bufio.Writer and returned by Close
+	return len(p), nil
+}
+
+// Close emits the remaining bytes and EOD, it does not close the
+// underlying writer
+func (lw *lzwWriter) Close() error {
+	if lw.prefix >= 0 {
+		lw.emit(lw.prefix)
+		lw.prefix = -1
+	}
+	lw.emit(lzwEOD)
+
+	// pad the last byte
+	if lw.nBits > 0 {
+		lw.w.WriteByte(byte(lw.bits << (8 - lw.nBits)))
+		lw.nBits = 0
+	}
+	return lw.w.Flush()
+}
@@ -0,0 +1,881 @@
+package pdf
+
+import (
+	"sort"
+	"strconv"
+)
+
+// the catalog entries taken from the first source merged into a
+// document without pages, when the document does not have them
+var documentCatalogEntries = []Name{"Lang", "ViewerPreferences", "PageLayout", "PageMode", "Metadata"}
+
+// Merge appends the pages of the sources to the document, combining
+// their outlines (§12.3.3), named destinations (§12.3.2.3), other name
+// trees, interactive forms (§12.7), page labels (§12.4.2), logical
+// structure (§14.7) and optional content (§8.11.4) with the document's.
+//
+// Named destinations and top-level fields whose names are already used
+// are renamed, and links, actions and outline items are rewritten to
+// use the new names and the merged pages. XFA forms are removed, as
+// they no longer match the merged fields. The sources must remain
+// open until the document is saved.
+func Merge(dst *Document, sources ...*Document) error {
+	for _, src := range sources {
+		err := dst.merge(src)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Document) merge(src *Document) error {
+	current, err := d.Pages()
+	if err != nil {
+		return err
+	}
+	pages, err := src.Pages()
+	if err != nil {
+		return err
+	}
+
+	c := &copier{
+		dst:          d.File,
+		src:          src.File,
+		refs:         map[ObjectReference]ObjectReference{},
+		catalog:      d.Catalog.Dictionary,
+		srcCatalog:   src.Catalog.Dictionary,
+		firstContent: len(current) == 0,
+	}
+
+	// the merged catalog and page tree are used for the source's
+	srcPagesRef, _ := src.Catalog.Pages()
+	dstPagesRef, _ := d.Catalog.Pages()
+	c.refs[src.Catalog.ObjectReference] = d.Catalog.ObjectReference
+	c.refs[srcPagesRef] = dstPagesRef
+
+	// names that will be rewritten must be known before anything
+	// that uses them is copied
+	c.renameDestinations()
+	err = c.prepareStructure()
+	if err != nil {
+		return err
+	}
+	err = c.prepareOutlines()
+	if err != nil {
+		return err
+	}
+
+	// pages are copied without their page tree nodes, with the
+	// attributes they inherited from them
+	refs := make([]ObjectReference, len(pages))
+	for i, page := range pages {
+		refs[i], err = d.File.Add(Null{})
+		if err != nil {
+			return err
+		}
+		c.refs[page.ObjectReference] = refs[i]
+	}
+	for i, page := range pages {
+		dict := page.pushDown(nil)
+		delete(dict, "Parent")
+		copied, err := c.copyDictionary(dict)
+		if err != nil {
+			return err
+		}
+		_, err = d.File.Add(IndirectObject{refs[i], copied})
+		if err != nil {
+			return err
+		}
+	}
+	err = d.InsertPages(len(current), refs...)
+	if err != nil {
+		return err
+	}
+
+	for _, merge := range []func() error{
+		c.mergeOutlines,
+		c.mergeNames,
+		c.mergeDests,
+		c.mergeAcroForm,
+		func() error { return c.mergePageLabels(len(current), len(pages)) },
+		c.mergeStructure,
+		c.mergeOptionalContent,
+	} {
+		err := merge()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.firstContent {
+		for _, key := range documentCatalogEntries {
+			if _, ok := c.catalog[key]; ok {
+				continue
+			}
+			if value, ok := c.srcCatalog[key]; ok {
+				c.catalog[key], err = c.copy(value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if version, ok := c.srcCatalog["Version"].(Name); ok && Version(version) > d.File.Version() {
+		c.catalog["Version"] = version
+	}
+
+	_, err = d.File.Add(IndirectObject{d.Catalog.ObjectReference, c.catalog})
+	return err
+}
+
+// copies objects from another file, along with the objects they refer to
+type copier struct {
+	dst, src *File
+
+	// the objects in dst that objects in src were copied to
+	refs map[ObjectReference]ObjectReference
+
+	// the objects in src that are not copied, which are copied as null
+	excluded map[ObjectReference]bool
+
+	// named destinations (§12.3.2.3) that were renamed, for names
+	// in name trees and names in the Dests dictionary
+	destStrings map[string]string
+	destNames   map[string]string
+
+	// the amount StructParent and StructParents keys are moved by
+	// so that they are after the merged parent tree's keys
+	structParents Integer
+
+	// the catalogs, of which the merged one is changed as the
+	// source's structures are merged into it
+	catalog, srcCatalog Dictionary
+
+	// whether the merged document has no pages before the source's
+	firstContent bool
+
+	// the merged outline dictionary and its last item before the
+	// source's were added
+	outlinesRef     ObjectReference
+	outlines        Dictionary
+	lastOutlineItem Object
+	srcOutlines     Dictionary
+
+	// the merged structure tree root
+	structTreeRootRef ObjectReference
+	structTreeRoot    Dictionary
+	srcStructTreeRoot Dictionary
+}
+
+// copies the object, and the objects it refers to that have not already
+// been copied, to dst
+func (c *copier) copy(obj Object) (Object, error) {
+	switch typed := obj.(type) {
+	case ObjectReference:
+		if ref, ok := c.refs[typed]; ok {
+			return ref, nil
+		}
+		if c.excluded[typed] {
+			return Null{}, nil
+		}
+
+		// the reference is added before copying the object to
+		// break reference cycles
+		ref, err := c.dst.Add(Null{})
+		if err != nil {
+			return nil, err
+		}
+		c.refs[typed] = ref
+		copied, err := c.copy(c.src.Get(typed))
+		if err != nil {
+			return nil, err
+		}
+		_, err = c.dst.Add(IndirectObject{ref, copied})
+		return ref, err
+	case Dictionary:
+		return c.copyDictionary(typed)
+	case Array:
+		copied := make(Array, len(typed))
+		for i, value := range typed {
+			var err error
+			copied[i], err = c.copy(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return copied, nil
+	case Stream:
+		dict, err := c.copyDictionary(typed.Dictionary)
+		if err != nil {
+			return nil, err
+		}
+		return Stream{Dictionary: dict, Stream: typed.Stream, file: c.dst}, nil
+	}
+	return obj, nil
+}
+
+func (c *copier) copyDictionary(dict Dictionary) (Dictionary, error) {
+	copied := Dictionary{}
+	for key, value := range dict {
+		var err error
+		copied[key], err = c.copy(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// links, outline items and go-to actions use renamed destinations
+	if dest, ok := copied["Dest"]; ok {
+		copied["Dest"] = c.destination(dest)
+	}
+	if d, ok := copied["D"]; ok && copied["S"] == Name("GoTo") {
+		copied["D"] = c.destination(d)
+	}
+
+	for _, key := range []Name{"StructParent", "StructParents"} {
+		if n, ok := copied[key].(Integer); ok {
+			copied[key] = n + c.structParents
+		}
+	}
+	return copied, nil
+}
+
+// the possibly renamed named destination
+func (c *copier) destination(dest Object) Object {
+	switch name := dest.(type) {
+	case String:
+		if renamed, ok := c.destStrings[string(name)]; ok {
+			return String(renamed)
+		}
+	case Name:
+		if renamed, ok := c.destNames[string(name)]; ok {
+			return Name(renamed)
+		}
+	}
+	return dest
+}
+
+// returns the name, or the name with the lowest number after it,
+// that is not used
+func uniqueName(name string, used func(string) bool) string {
+	unique := name
+	for n := 2; used(unique); n++ {
+		unique = name + "-" + strconv.Itoa(n)
+	}
+	return unique
+}
+
+// the name dictionary (§7.7.4)
+func (f *File) names(catalog Dictionary) Dictionary {
+	names, _ := f.resolve(catalog["Names"]).(Dictionary)
+	return names
+}
+
+// chooses new names for the source's named destinations that the
+// merged document already has
+func (c *copier) renameDestinations() {
+	taken := map[string]bool{}
+	for _, entry := range c.dst.treeEntries(c.dst.names(c.catalog)["Dests"], "Names") {
+		if name, ok := entry.key.(String); ok {
+			taken[string(name)] = true
+		}
+	}
+	names := []string{}
+	for _, entry := range c.src.treeEntries(c.src.names(c.srcCatalog)["Dests"], "Names") {
+		if name, ok := entry.key.(String); ok {
+			names = append(names, string(name))
+		}
+	}
+	c.destStrings = renames(taken, names)
+
+	taken = map[string]bool{}
+	dests, _ := c.dst.resolve(c.catalog["Dests"]).(Dictionary)
+	for name := range dests {
+		taken[string(name)] = true
+	}
+	names = []string{}
+	srcDests, _ := c.src.resolve(c.srcCatalog["Dests"]).(Dictionary)
+	for name := range srcDests {
+		names = append(names, string(name))
+	}
+	c.destNames = renames(taken, names)
+}
+
+// chooses new names for the names that are taken, which are neither
+// taken nor one of the names
+func renames(taken map[string]bool, names []string) map[string]string {
+	sort.Strings(names)
+	used := map[string]bool{}
+	for name := range taken {
+		used[name] = true
+	}
+	for _, name := range names {
+		used[name] = true
+	}
+
+	renamed := map[string]string{}
+	for _, name := range names {
+		if !taken[name] {
+			continue
+		}
+		unique := uniqueName(name, func(name string) bool { return used[name] })
+		used[unique] = true
+		renamed[name] = unique
+	}
+	return renamed
+}
+
+// merges each of the name trees in the name dictionary, keeping the
+// merged document's entry when both have one, except for destinations
+func (c *copier) mergeNames() error {
+	srcNames := c.src.names(c.srcCatalog)
+	if len(srcNames) == 0 {
+		return nil
+	}
+	names := c.dst.names(c.catalog)
+	if names == nil {
+		names = Dictionary{}
+	} else {
+		names = copyDictionary(names)
+	}
+
+	for key, srcTree := range srcNames {
+		entries := c.dst.treeEntries(names[key], "Names")
+		used := map[string]bool{}
+		for _, entry := range entries {
+			if name, ok := entry.key.(String); ok {
+				used[string(name)] = true
+			}
+		}
+		for _, entry := range c.src.treeEntries(srcTree, "Names") {
+			name, ok := entry.key.(String)
+			if !ok {
+				continue
+			}
+			if key == "Dests" {
+				if renamed, ok := c.destStrings[string(name)]; ok {
+					name = String(renamed)
+				}
+			}
+			if used[string(name)] {
+				continue
+			}
+			value, err := c.copy(entry.value)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, treeEntry{name, value})
+		}
+
+		tree, err := c.dst.Add(newTree("Names", entries))
+		if err != nil {
+			return err
+		}
+		names[key] = tree
+	}
+
+	var err error
+	c.catalog["Names"], err = c.dst.Add(names)
+	return err
+}
+
+// merges the Dests dictionary of named destinations (§12.3.2.3)
+func (c *copier) mergeDests() error {
+	srcDests, _ := c.src.resolve(c.srcCatalog["Dests"]).(Dictionary)
+	if len(srcDests) == 0 {
+		return nil
+	}
+	dests, _ := c.dst.resolve(c.catalog["Dests"]).(Dictionary)
+	if dests == nil {
+		dests = Dictionary{}
+	} else {
+		dests = copyDictionary(dests)
+	}
+	for name, dest := range srcDests {
+		if renamed, ok := c.destNames[string(name)]; ok {
+			name = Name(renamed)
+		}
+		var err error
+		dests[name], err = c.copy(dest)
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	c.catalog["Dests"], err = c.dst.Add(dests)
+	return err
+}
+
+// finds or makes the merged outline dictionary, which the source's
+// outline items are copied under
+func (c *copier) prepareOutlines() error {
+	srcRef, ok := c.srcCatalog["Outlines"].(ObjectReference)
+	if !ok {
+		return nil
+	}
+	c.srcOutlines, _ = c.src.Get(srcRef).(Dictionary)
+	if _, ok := c.srcOutlines["First"]; !ok {
+		return nil
+	}
+
+	var err error
+	c.outlinesRef, ok = c.catalog["Outlines"].(ObjectReference)
+	if ok {
+		c.outlines, _ = c.dst.Get(c.outlinesRef).(Dictionary)
+	}
+	if c.outlines == nil {
+		c.outlines = Dictionary{"Type": Name("Outlines")}
+		c.outlinesRef, err = c.dst.Add(c.outlines)
+		if err != nil {
+			return err
+		}
+		c.catalog["Outlines"] = c.outlinesRef
+	}
+	c.lastOutlineItem = c.outlines["Last"]
+	c.refs[srcRef] = c.outlinesRef
+	return nil
+}
+
+// appends the source's top-level outline items to the merged ones
+func (c *copier) mergeOutlines() error {
+	if c.srcOutlines == nil || c.outlines == nil {
+		return nil
+	}
+
+	first, err := c.copy(c.srcOutlines["First"])
+	if err != nil {
+		return err
+	}
+	last, err := c.copy(c.srcOutlines["Last"])
+	if err != nil {
+		return err
+	}
+
+	if previousRef, ok := c.lastOutlineItem.(ObjectReference); ok {
+		firstRef, _ := first.(ObjectReference)
+		previous, _ := c.dst.Get(previousRef).(Dictionary)
+		item, _ := c.dst.Get(firstRef).(Dictionary)
+		if previous != nil && item != nil {
+			previous["Next"] = firstRef
+			item["Prev"] = previousRef
+			_, err = c.dst.Add(IndirectObject{previousRef, previous})
+			if err == nil {
+				_, err = c.dst.Add(IndirectObject{firstRef, item})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		c.outlines["First"] = first
+	}
+	c.outlines["Last"] = last
+
+	// the number of open items
+	count, _ := c.dst.resolve(c.outlines["Count"]).(Integer)
+	srcCount, _ := c.src.resolve(c.srcOutlines["Count"]).(Integer)
+	if srcCount > 0 {
+		count += srcCount
+	}
+	if count > 0 {
+		c.outlines["Count"] = count
+	}
+
+	_, err = c.dst.Add(IndirectObject{c.outlinesRef, c.outlines})
+	return err
+}
+
+// merges the interactive form dictionary (§12.7.2)
+func (c *copier) mergeAcroForm() error {
+	srcForm, _ := c.src.resolve(c.srcCatalog["AcroForm"]).(Dictionary)
+	if srcForm == nil {
+		return nil
+	}
+	form, _ := c.dst.resolve(c.catalog["AcroForm"]).(Dictionary)
+	if form == nil {
+		form = Dictionary{}
+	} else {
+		form = copyDictionary(form)
+	}
+
+	// top-level fields with names that are already used are renamed,
+	// which renames the fields under them
+	fields, _ := c.dst.resolve(form["Fields"]).(Array)
+	fields = append(Array{}, fields...)
+	used := map[string]bool{}
+	for _, field := range fields {
+		dict, _ := c.dst.resolve(field).(Dictionary)
+		if name, ok := dict["T"].(String); ok {
+			used[textString(name)] = true
+		}
+	}
+	srcFields, _ := c.src.resolve(srcForm["Fields"]).(Array)
+	for _, field := range srcFields {
+		copied, err := c.copy(field)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, copied)
+
+		ref, ok := copied.(ObjectReference)
+		if !ok {
+			continue
+		}
+		dict, ok := c.dst.Get(ref).(Dictionary)
+		if !ok {
+			continue
+		}
+		changed := false
+		if name, ok := dict["T"].(String); ok {
+			unique := uniqueName(textString(name), func(name string) bool { return used[name] })
+			used[unique] = true
+			if unique != textString(name) {
+				dict["T"] = newTextString(unique)
+				changed = true
+			}
+		}
+		// fields keep the defaults they inherited from the source's
+		// form when the merged form's differ
+		for _, key := range []Name{"DA", "Q"} {
+			if _, ok := dict[key]; ok {
+				continue
+			}
+			value, ok := srcForm[key]
+			formValue, formHasValue := form[key]
+			if ok && formHasValue && !equal(c.src.resolve(value), c.dst.resolve(formValue)) {
+				dict[key] = c.src.resolve(value)
+				changed = true
+			}
+		}
+		if changed {
+			_, err = c.dst.Add(IndirectObject{ref, dict})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	form["Fields"] = fields
+
+	if srcForm["NeedAppearances"] == Boolean(true) {
+		form["NeedAppearances"] = Boolean(true)
+	}
+	sigFlags, _ := c.dst.resolve(form["SigFlags"]).(Integer)
+	srcSigFlags, _ := c.src.resolve(srcForm["SigFlags"]).(Integer)
+	if sigFlags|srcSigFlags != 0 {
+		form["SigFlags"] = sigFlags | srcSigFlags
+	}
+	if srcOrder, ok := c.src.resolve(srcForm["CO"]).(Array); ok {
+		order, _ := c.dst.resolve(form["CO"]).(Array)
+		copied, err := c.copy(srcOrder)
+		if err != nil {
+			return err
+		}
+		form["CO"] = append(append(Array{}, order...), copied.(Array)...)
+	}
+	for _, key := range []Name{"DA", "Q"} {
+		if _, ok := form[key]; !ok && srcForm[key] != nil {
+			form[key] = c.src.resolve(srcForm[key])
+		}
+	}
+
+	// resources of each kind are combined, with the merged
+	// form's used for names both have
+	if srcResources, ok := c.src.resolve(srcForm["DR"]).(Dictionary); ok {
+		resources, _ := c.dst.resolve(form["DR"]).(Dictionary)
+		resources = copyDictionary(resources)
+		for kind, srcNamed := range srcResources {
+			named, _ := c.dst.resolve(resources[kind]).(Dictionary)
+			named = copyDictionary(named)
+			srcNamed, _ := c.src.resolve(srcNamed).(Dictionary)
+			for name, value := range srcNamed {
+				if _, ok := named[name]; ok {
+					continue
+				}
+				var err error
+				named[name], err = c.copy(value)
+				if err != nil {
+					return err
+				}
+			}
+			resources[kind] = named
+		}
+		form["DR"] = resources
+	}
+	delete(form, "XFA")
+
+	var err error
+	c.catalog["AcroForm"], err = c.dst.Add(form)
+	return err
+}
+
+// merges the page labels (§12.4.2) of the source's pages, which start
+// at the index in the merged document
+func (c *copier) mergePageLabels(index, count int) error {
+	labels := c.dst.treeEntries(c.catalog["PageLabels"], "Nums")
+	srcLabels := c.src.treeEntries(c.srcCatalog["PageLabels"], "Nums")
+	if len(labels) == 0 && len(srcLabels) == 0 {
+		return nil
+	}
+
+	// pages without labels are given decimal numbers
+	if len(labels) == 0 && index > 0 {
+		labels = append(labels, treeEntry{Integer(0), Dictionary{"S": Name("D")}})
+	}
+	if len(srcLabels) == 0 || srcLabels[0].key != Integer(0) {
+		labels = append(labels, treeEntry{Integer(index), Dictionary{"S": Name("D")}})
+	}
+	for _, entry := range srcLabels {
+		start, ok := entry.key.(Integer)
+		if !ok || int(start) >= count {
+			continue
+		}
+		value, err := c.copy(entry.value)
+		if err != nil {
+			return err
+		}
+		labels = append(labels, treeEntry{start + Integer(index), value})
+	}
+
+	var err error
+	c.catalog["PageLabels"], err = c.dst.Add(newTree("Nums", labels))
+	return err
+}
+
+// finds or makes the merged structure tree root, which the source's
+// structure elements are copied under, and finds the parent tree keys
+// the source's are moved after
+func (c *copier) prepareStructure() error {
+	srcRef, ok := c.srcCatalog["StructTreeRoot"].(ObjectReference)
+	if !ok {
+		return nil
+	}
+	c.srcStructTreeRoot, _ = c.src.Get(srcRef).(Dictionary)
+	if c.srcStructTreeRoot == nil {
+		return nil
+	}
+
+	var err error
+	c.structTreeRootRef, ok = c.catalog["StructTreeRoot"].(ObjectReference)
+	if ok {
+		c.structTreeRoot, _ = c.dst.Get(c.structTreeRootRef).(Dictionary)
+	}
+	if c.structTreeRoot == nil {
+		c.structTreeRoot = Dictionary{"Type": Name("StructTreeRoot")}
+		c.structTreeRootRef, err = c.dst.Add(c.structTreeRoot)
+		if err != nil {
+			return err
+		}
+		c.catalog["StructTreeRoot"] = c.structTreeRootRef
+	}
+	c.refs[srcRef] = c.structTreeRootRef
+
+	next, ok := c.dst.resolve(c.structTreeRoot["ParentTreeNextKey"]).(Integer)
+	if !ok {
+		for _, entry := range c.dst.treeEntries(c.structTreeRoot["ParentTree"], "Nums") {
+			if key, ok := entry.key.(Integer); ok && key >= next {
+				next = key + 1
+			}
+		}
+	}
+	c.structParents = next
+	return nil
+}
+
+// appends the source's structure elements, parent tree, role map and
+// class map to the merged structure tree root (§14.7.2)
+func (c *copier) mergeStructure() error {
+	if c.srcStructTreeRoot == nil {
+		return nil
+	}
+	root := c.structTreeRoot
+
+	kids := Array{}
+	switch k := c.dst.resolve(root["K"]).(type) {
+	case Array:
+		kids = append(kids, k...)
+	case Dictionary:
+		kids = append(kids, root["K"])
+	}
+	srcKids, err := c.copy(c.srcStructTreeRoot["K"])
+	if err != nil {
+		return err
+	}
+	switch k := srcKids.(type) {
+	case Array:
+		kids = append(kids, k...)
+	case ObjectReference, Dictionary:
+		kids = append(kids, k)
+	}
+	root["K"] = kids
+
+	entries := c.dst.treeEntries(root["ParentTree"], "Nums")
+	next := c.structParents
+	for _, entry := range c.src.treeEntries(c.srcStructTreeRoot["ParentTree"], "Nums") {
+		key, ok := entry.key.(Integer)
+		if !ok {
+			continue
+		}
+		value, err := c.copy(entry.value)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, treeEntry{key + c.structParents, value})
+		if key+c.structParents >= next {
+			next = key + c.structParents + 1
+		}
+	}
+	if srcNext, ok := c.src.resolve(c.srcStructTreeRoot["ParentTreeNextKey"]).(Integer); ok && srcNext+c.structParents > next {
+		next = srcNext + c.structParents
+	}
+	root["ParentTree"], err = c.dst.Add(newTree("Nums", entries))
+	if err != nil {
+		return err
+	}
+	root["ParentTreeNextKey"] = next
+
+	for _, key := range []Name{"RoleMap", "ClassMap"} {
+		srcMap, ok := c.src.resolve(c.srcStructTreeRoot[key]).(Dictionary)
+		if !ok {
+			continue
+		}
+		merged, _ := c.dst.resolve(root[key]).(Dictionary)
+		merged = copyDictionary(merged)
+		for name, value := range srcMap {
+			if _, ok := merged[name]; ok {
+				continue
+			}
+			merged[name], err = c.copy(value)
+			if err != nil {
+				return err
+			}
+		}
+		root[key] = merged
+	}
+
+	if srcIDs, ok := c.srcStructTreeRoot["IDTree"]; ok {
+		entries := c.dst.treeEntries(root["IDTree"], "Names")
+		used := map[string]bool{}
+		for _, entry := range entries {
+			if id, ok := entry.key.(String); ok {
+				used[string(id)] = true
+			}
+		}
+		for _, entry := range c.src.treeEntries(srcIDs, "Names") {
+			if id, ok := entry.key.(String); !ok || used[string(id)] {
+				continue
+			}
+			value, err := c.copy(entry.value)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, treeEntry{entry.key, value})
+		}
+		root["IDTree"], err = c.dst.Add(newTree("Names", entries))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = c.dst.Add(IndirectObject{c.structTreeRootRef, root})
+	if err != nil {
+		return err
+	}
+
+	if markInfo, ok := c.src.resolve(c.srcCatalog["MarkInfo"]).(Dictionary); ok && markInfo["Marked"] == Boolean(true) {
+		merged, _ := c.dst.resolve(c.catalog["MarkInfo"]).(Dictionary)
+		merged = copyDictionary(merged)
+		merged["Marked"] = Boolean(true)
+		c.catalog["MarkInfo"] = merged
+	}
+	return nil
+}
+
+// merges the optional content groups and the default configuration
+// of the optional content properties (§8.11.4.2)
+func (c *copier) mergeOptionalContent() error {
+	srcProperties, ok := c.src.resolve(c.srcCatalog["OCProperties"]).(Dictionary)
+	if !ok {
+		return nil
+	}
+	properties, _ := c.dst.resolve(c.catalog["OCProperties"]).(Dictionary)
+	properties = copyDictionary(properties)
+
+	appendCopies := func(dst Dictionary, src Dictionary, key Name) error {
+		srcArray, ok := c.src.resolve(src[key]).(Array)
+		if !ok {
+			return nil
+		}
+		copied, err := c.copy(srcArray)
+		if err != nil {
+			return err
+		}
+		array, _ := c.dst.resolve(dst[key]).(Array)
+		dst[key] = append(append(Array{}, array...), copied.(Array)...)
+		return nil
+	}
+
+	err := appendCopies(properties, srcProperties, "OCGs")
+	if err != nil {
+		return err
+	}
+	err = appendCopies(properties, srcProperties, "Configs")
+	if err != nil {
+		return err
+	}
+
+	config, _ := c.dst.resolve(properties["D"]).(Dictionary)
+	config = copyDictionary(config)
+	srcConfig, _ := c.src.resolve(srcProperties["D"]).(Dictionary)
+	for _, key := range []Name{"ON", "OFF", "Order", "RBGroups", "Locked", "AS"} {
+		err = appendCopies(config, srcConfig, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	// groups are in their default state in the merged configuration
+	// when it has a different base state
+	srcBaseState, _ := srcConfig["BaseState"].(Name)
+	if srcBaseState == "" {
+		srcBaseState = "ON"
+	}
+	baseState, _ := config["BaseState"].(Name)
+	if baseState == "" {
+		baseState = "ON"
+	}
+	if srcBaseState != baseState && srcBaseState != "Unchanged" {
+		listed := map[ObjectReference]bool{}
+		for _, key := range []Name{"ON", "OFF"} {
+			states, _ := c.src.resolve(srcConfig[key]).(Array)
+			for _, group := range states {
+				if ref, ok := group.(ObjectReference); ok {
+					listed[ref] = true
+				}
+			}
+		}
+		states, _ := config[srcBaseState].(Array)
+		states = append(Array{}, states...)
+		groups, _ := c.src.resolve(srcProperties["OCGs"]).(Array)
+		for _, group := range groups {
+			if ref, ok := group.(ObjectReference); ok && !listed[ref] {
+				states = append(states, c.refs[ref])
+			}
+		}
+		config[srcBaseState] = states
+	}
+	properties["D"] = config
+
+	c.catalog["OCProperties"] = properties
+	return nil
+}
+
+// a shallow copy of the dictionary, which is empty when it is nil
+func copyDictionary(dict Dictionary) Dictionary {
+	copied := Dictionary{}
+	for key, value := range dict {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,255 @@
+package pdf
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// creates a document with the pages, each with an outline item and a
+// structure element, a named destination intro of the first page, a
+// link to it on the last page, a field, an optional content group,
+// and, when labeled, roman page labels
+func testMergeSource(t *testing.T, filename string, n int, labeled bool) *Document {
+	file, err := Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add := func(obj Object) ObjectReference {
+		ref, err := file.Add(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ref
+	}
+	set := func(ref ObjectReference, obj Object) {
+		_, err := file.Add(IndirectObject{ref, obj})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pagesRef := add(Null{})
+	outlinesRef := add(Null{})
+	structRootRef := add(Null{})
+	pages := make([]ObjectReference, n)
+	items := make([]ObjectReference, n)
+	elements := make([]ObjectReference, n)
+	for i := range pages {
+		pages[i] = add(Null{})
+		items[i] = add(Null{})
+		elements[i] = add(Null{})
+	}
+	parentTree := Array{}
+	for i, page := range pages {
+		dict := Dictionary{
+			"Type":          Name("Page"),
+			"Parent":        pagesRef,
+			"StructParents": Integer(i),
+		}
+		if i == n-1 {
+			dict["Annots"] = Array{Dictionary{
+				"Type":    Name("Annot"),
+				"Subtype": Name("Link"),
+				"Rect":    Array{Integer(0), Integer(0), Integer(10), Integer(10)},
+				"Dest":    String("intro"),
+			}}
+		}
+		set(page, dict)
+
+		item := Dictionary{
+			"Title":  String("item"),
+			"Parent": outlinesRef,
+			"Dest":   Array{page, Name("Fit")},
+		}
+		if i > 0 {
+			item["Prev"] = items[i-1]
+		}
+		if i < n-1 {
+			item["Next"] = items[i+1]
+		}
+		set(items[i], item)
+
+		set(elements[i], Dictionary{"S": Name("P"), "P": structRootRef, "Pg": page, "K": Integer(0)})
+		parentTree = append(parentTree, Integer(i), Array{elements[i]})
+	}
+	set(pagesRef, Dictionary{
+		"Type":     Name("Pages"),
+		"Kids":     refsToArray(pages),
+		"Count":    Integer(n),
+		"MediaBox": Array{Integer(0), Integer(0), Integer(612), Integer(792)},
+	})
+	set(outlinesRef, Dictionary{"First": items[0], "Last": items[n-1], "Count": Integer(n)})
+	set(structRootRef, Dictionary{
+		"Type":              Name("StructTreeRoot"),
+		"K":                 refsToArray(elements),
+		"ParentTree":        Dictionary{"Nums": parentTree},
+		"ParentTreeNextKey": Integer(n),
+		"RoleMap":           Dictionary{"Para": Name("P")},
+	})
+
+	ocg := add(Dictionary{"Type": Name("OCG"), "Name": String("layer")})
+	catalog := Dictionary{
+		"Type":     Name("Catalog"),
+		"Pages":    pagesRef,
+		"Outlines": outlinesRef,
+		"Names": Dictionary{"Dests": Dictionary{"Names": Array{
+			String("intro"), Array{pages[0], Name("Fit")},
+		}}},
+		"AcroForm": Dictionary{
+			"Fields": Array{add(Dictionary{"FT": Name("Tx"), "T": String("name")})},
+			"DA":     String("/Helv 0 Tf 0 g"),
+		},
+		"StructTreeRoot": structRootRef,
+		"MarkInfo":       Dictionary{"Marked": Boolean(true)},
+		"OCProperties": Dictionary{
+			"OCGs": Array{ocg},
+			"D":    Dictionary{"BaseState": Name("OFF"), "Order": Array{ocg}},
+		},
+	}
+	if labeled {
+		catalog["PageLabels"] = Dictionary{"Nums": Array{Integer(0), Dictionary{"S": Name("r")}}}
+	}
+	file.Root = add(catalog)
+
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err := NewDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	a := testMergeSource(t, filepath.Join(dir, "a.pdf"), 2, true)
+	defer a.File.Close()
+	b := testMergeSource(t, filepath.Join(dir, "b.pdf"), 1, false)
+	defer b.File.Close()
+
+	filename := filepath.Join(dir, "merged.pdf")
+	file, err := Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := NewDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Merge(merged, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	file, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	merged, err = NewDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := merged.Catalog.Dictionary
+	pages, err := merged.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(pages))
+	}
+	for i, page := range pages {
+		if mediaBox, err := page.MediaBox(); err != nil || mediaBox.URX != 612 {
+			t.Errorf("page %d has MediaBox %v %v", i, mediaBox, err)
+		}
+	}
+
+	// the outline items are chained and go to the merged pages
+	outlinesRef := catalog["Outlines"].(ObjectReference)
+	outlines := file.Get(outlinesRef).(Dictionary)
+	if outlines["Count"] != Integer(3) {
+		t.Errorf("outlines have Count %v", outlines["Count"])
+	}
+	itemRef, ok := outlines["First"].(ObjectReference)
+	var previous Object
+	for i := 0; ok; i++ {
+		item := file.Get(itemRef).(Dictionary)
+		if item["Parent"] != outlinesRef || item["Prev"] != previous {
+			t.Errorf("item %d has Parent %v and Prev %v", i, item["Parent"], item["Prev"])
+		}
+		if dest := item["Dest"].(Array); i >= len(pages) || dest[0] != pages[i].ObjectReference {
+			t.Errorf("item %d goes to %v", i, dest)
+		}
+		if item["Next"] == nil && outlines["Last"] != itemRef {
+			t.Errorf("last item %v is not Last %v", itemRef, outlines["Last"])
+		}
+		previous = itemRef
+		itemRef, ok = item["Next"].(ObjectReference)
+	}
+
+	// the second intro destination is renamed, along with the link to it
+	names := file.resolve(catalog["Names"]).(Dictionary)
+	dests := map[string]ObjectReference{}
+	for _, entry := range file.treeEntries(names["Dests"], "Names") {
+		dests[str(entry.key)] = file.resolve(entry.value).(Array)[0].(ObjectReference)
+	}
+	if !reflect.DeepEqual(dests, map[string]ObjectReference{
+		"intro":   pages[0].ObjectReference,
+		"intro-2": pages[2].ObjectReference,
+	}) {
+		t.Errorf("unexpected destinations %v", dests)
+	}
+	for i, page := range []Page{pages[1], pages[2]} {
+		link := page.Dictionary["Annots"].(Array)[0].(Dictionary)
+		if expected := []string{"intro", "intro-2"}[i]; str(link["Dest"]) != expected {
+			t.Errorf("link goes to %q instead of %q", link["Dest"], expected)
+		}
+	}
+
+	form := file.resolve(catalog["AcroForm"]).(Dictionary)
+	fieldNames := []string{}
+	for _, field := range form["Fields"].(Array) {
+		fieldNames = append(fieldNames, str(file.resolve(field).(Dictionary)["T"]))
+	}
+	if !reflect.DeepEqual(fieldNames, []string{"name", "name-2"}) {
+		t.Errorf("unexpected fields %v", fieldNames)
+	}
+
+	labels := file.treeEntries(catalog["PageLabels"], "Nums")
+	if len(labels) != 2 || labels[0].key != Integer(0) || labels[1].key != Integer(2) ||
+		file.resolve(labels[1].value).(Dictionary)["S"] != Name("D") {
+		t.Errorf("unexpected page labels %v", labels)
+	}
+
+	// structure parents of the second source are after the first's
+	rootRef := catalog["StructTreeRoot"].(ObjectReference)
+	root := file.Get(rootRef).(Dictionary)
+	if kids := root["K"].(Array); len(kids) != 3 {
+		t.Errorf("structure tree root has kids %v", kids)
+	}
+	if root["ParentTreeNextKey"] != Integer(3) || pages[2].Dictionary["StructParents"] != Integer(2) {
+		t.Errorf("unexpected parent tree next key %v and StructParents %v", root["ParentTreeNextKey"], pages[2].Dictionary["StructParents"])
+	}
+	for _, entry := range file.treeEntries(root["ParentTree"], "Nums") {
+		element := file.resolve(file.resolve(entry.value).(Array)[0]).(Dictionary)
+		page := pages[int(entry.key.(Integer))]
+		if element["P"] != rootRef || element["Pg"] != page.ObjectReference {
+			t.Errorf("parent tree %v has element %v", entry.key, element)
+		}
+	}
+
+	properties := file.resolve(catalog["OCProperties"]).(Dictionary)
+	ocgs := properties["OCGs"].(Array)
+	config := properties["D"].(Dictionary)
+	if len(ocgs) != 2 || !reflect.DeepEqual(config["OFF"], ocgs) || len(config["Order"].(Array)) != 2 {
+		t.Errorf("unexpected optional content properties %v", properties)
+	}
+}
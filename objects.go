@@ -24,12 +24,15 @@ type Object interface {
 type Boolean bool
 
 // Integer objects represent mathematical integers.
+// The range is wider than most PDF versions allow (see Limits)
+// so that byte offsets and lengths in large files can be represented.
 // - §7.3.3
-type Integer int32
+type Integer int64
 
 // Real objects represent mathematical real numbers.
+// Double precision is used so that values survive being read and written.
 // - §7.3.3
-type Real float32
+type Real float64
 
 // A String object consists of zero or more bytes.
 // - §7.3.4
@@ -54,6 +57,10 @@ type Dictionary map[Name]Object
 type Stream struct {
 	Dictionary
 	Stream []byte
+
+	// the file the stream was read from, used to resolve
+	// references in its filter parameters
+	file *File
 }
 
 // The Null object has a type and value that are unequal to any other object.
@@ -125,12 +125,8 @@ func TestLiteralStringExample2(t *testing.T) {
 
 // §7.3.4.2 Examples 3, 4, 5
 // These examples deal with how pdf strings should
-// be interpreted. There is nothing in the spec that
-// says that an string with escaped characters which
-// is then interpreted is equivalent to one without
-// escaping those characters (with the exeption in
-// Example 2). These examples are included for
-// completeness.
+// be interpreted. Escape sequences (Table 3) are
+// replaced by the bytes they represent.
 func TestLiteralStringExamples345(t *testing.T) {
 	runTests(t, []test{
 		// Example 3
@@ -145,20 +141,20 @@ func TestLiteralStringExamples345(t *testing.T) {
 		// Example 4
 		test{
 			literal: []byte("(This string contains \\245two octal characters\\307.)"),
-			object:  String("This string contains \\245two octal characters\\307."),
+			object:  String("This string contains \245two octal characters\307."),
 		},
 		// Example 5
 		test{
 			literal: []byte("(\\0053)"),
-			object:  String("\\0053"),
+			object:  String("\0053"),
 		},
 		test{
 			literal: []byte("(\\053)"),
-			object:  String("\\053"),
+			object:  String("+"),
 		},
 		test{
 			literal: []byte("(\\53)"),
-			object:  String("\\53"),
+			object:  String("+"),
 		},
 	})
 }
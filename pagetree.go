@@ -0,0 +1,340 @@
+package pdf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// the most kids a page tree node has after the page tree is rebuilt
+const maxPageTreeKids = 32
+
+// the page attributes that are inherited from page tree nodes (§7.7.3.4)
+var inheritablePageAttributes = []Name{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// InsertPages inserts the pages, page objects (§7.7.3.3) already
+// added to the file, before the page at the index. Pages from other
+// page trees keep the attributes they inherited there.
+func (d *Document) InsertPages(index int, pages ...ObjectReference) error {
+	current, err := d.Pages()
+	if err != nil {
+		return err
+	}
+	if index < 0 || index > len(current) {
+		return fmt.Errorf("page index %d is out of range", index)
+	}
+
+	inserted := make([]Page, len(pages))
+	for i, ref := range pages {
+		page, ok := d.File.Get(ref).(Dictionary)
+		if !ok {
+			return fmt.Errorf("page %v is not a dictionary", ref)
+		}
+		inserted[i] = Page{
+			ObjectReference: ref,
+			Dictionary:      page,
+			parents:         d.File.parents(page),
+			file:            d.File,
+		}
+	}
+
+	updated := append(append(append([]Page{}, current[:index]...), inserted...), current[index:]...)
+	return d.setPages(current, updated)
+}
+
+// DeletePages removes the pages at the indexes from the page tree.
+// The page objects are not freed, as other objects, such as outline
+// items and links, may still refer to them.
+func (d *Document) DeletePages(indexes ...int) error {
+	current, err := d.Pages()
+	if err != nil {
+		return err
+	}
+	deleted := map[int]bool{}
+	for _, index := range indexes {
+		if index < 0 || index >= len(current) {
+			return fmt.Errorf("page index %d is out of range", index)
+		}
+		deleted[index] = true
+	}
+
+	updated := []Page{}
+	for i, page := range current {
+		if !deleted[i] {
+			updated = append(updated, page)
+		}
+	}
+	return d.setPages(current, updated)
+}
+
+// MovePage moves the page at the index from to the index to, which
+// is the page's index after it is moved.
+func (d *Document) MovePage(from, to int) error {
+	current, err := d.Pages()
+	if err != nil {
+		return err
+	}
+	if from < 0 || from >= len(current) {
+		return fmt.Errorf("page index %d is out of range", from)
+	}
+	if to < 0 || to >= len(current) {
+		return fmt.Errorf("page index %d is out of range", to)
+	}
+
+	page := current[from]
+	updated := append(append([]Page{}, current[:from]...), current[from+1:]...)
+	updated = append(updated[:to], append([]Page{page}, updated[to:]...)...)
+	return d.setPages(current, updated)
+}
+
+// ReorderPages puts the pages in the order, in which the page at
+// order[i] becomes the page at index i. The order must have each of
+// the page indexes once.
+func (d *Document) ReorderPages(order []int) error {
+	current, err := d.Pages()
+	if err != nil {
+		return err
+	}
+	if len(order) != len(current) {
+		return fmt.Errorf("order has %d pages, the document has %d", len(order), len(current))
+	}
+
+	used := make([]bool, len(current))
+	updated := make([]Page, len(order))
+	for i, index := range order {
+		if index < 0 || index >= len(current) || used[index] {
+			return fmt.Errorf("order %v is not a permutation of the page indexes", order)
+		}
+		used[index] = true
+		updated[i] = current[index]
+	}
+	return d.setPages(current, updated)
+}
+
+// SetRotation sets the clockwise rotation, in degrees, of the pages at
+// the indexes. The rotation must be a multiple of 90.
+func (d *Document) SetRotation(degrees int, indexes ...int) error {
+	if degrees%90 != 0 {
+		return fmt.Errorf("rotation %d is not a multiple of 90", degrees)
+	}
+	pages, err := d.Pages()
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if index < 0 || index >= len(pages) {
+			return fmt.Errorf("page index %d is out of range", index)
+		}
+	}
+
+	sort.Ints(indexes)
+	for i, index := range indexes {
+		if i > 0 && indexes[i-1] == index {
+			continue
+		}
+		page := pages[index]
+		page.Dictionary["Rotate"] = Integer(((degrees % 360) + 360) % 360)
+		_, err := d.File.Add(IndirectObject{page.ObjectReference, page.Dictionary})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the page tree nodes above a page, found with Parent, from the
+// nearest to the root
+func (f *File) parents(page Dictionary) []Dictionary {
+	parents := []Dictionary{}
+	visited := map[ObjectReference]bool{}
+	parentRef, ok := page["Parent"].(ObjectReference)
+	for ok && !visited[parentRef] {
+		visited[parentRef] = true
+		parent, isDictionary := f.Get(parentRef).(Dictionary)
+		if !isDictionary {
+			break
+		}
+		parents = append(parents, parent)
+		parentRef, ok = parent["Parent"].(ObjectReference)
+	}
+	return parents
+}
+
+// the unresolved value of an inheritable attribute
+func (p Page) inherited(key Name) (Object, bool) {
+	if value, ok := p.Dictionary[key]; ok {
+		return value, true
+	}
+	for _, parent := range p.parents {
+		if value, ok := parent[key]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// returns a copy of the page that has the same attributes when its
+// parent is the node, with the attributes it inherited put in the page
+// when the node does not have them
+func (p Page) pushDown(node Dictionary) Dictionary {
+	dict := Dictionary{}
+	for key, value := range p.Dictionary {
+		dict[key] = value
+	}
+	for _, key := range inheritablePageAttributes {
+		if _, ok := dict[key]; ok {
+			continue
+		}
+		value, ok := p.inherited(key)
+		if nodeValue, inheritsValue := node[key]; ok == inheritsValue && equal(value, nodeValue) {
+			continue
+		}
+		if ok {
+			dict[key] = value
+			continue
+		}
+		// the page must not inherit a value it did not have
+		switch key {
+		case "Resources":
+			dict[key] = Dictionary{}
+		case "CropBox":
+			if mediaBox := p.Inherited("MediaBox"); mediaBox != nil {
+				dict[key] = mediaBox
+			}
+		case "Rotate":
+			dict[key] = Integer(0)
+		}
+	}
+	return dict
+}
+
+// rebuilds the page tree with the updated pages, which replace the
+// current pages. The root keeps its attributes, which updated pages
+// inherit, and balanced page tree nodes are put between the root
+// and the pages. When a page would inherit a different value than it
+// did, the value it had is put in the page itself.
+func (d *Document) setPages(current, updated []Page) error {
+	rootRef, _ := d.Catalog.Pages()
+	root, ok := d.File.Get(rootRef).(Dictionary)
+	if !ok {
+		return fmt.Errorf("page tree root %v is not a dictionary", rootRef)
+	}
+
+	// page tree nodes are reused for the new page tree
+	seen := map[ObjectReference]bool{rootRef: true}
+	nodes := []ObjectReference{}
+	for _, page := range current {
+		for parentRef, ok := page.Dictionary["Parent"].(ObjectReference); ok && !seen[parentRef]; {
+			seen[parentRef] = true
+			nodes = append(nodes, parentRef)
+			parent, _ := d.File.Get(parentRef).(Dictionary)
+			parentRef, ok = parent["Parent"].(ObjectReference)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ObjectNumber < nodes[j].ObjectNumber })
+
+	pageRefs := make([]ObjectReference, len(updated))
+	pages := make([]Dictionary, len(updated))
+	for i, page := range updated {
+		if seen[page.ObjectReference] {
+			return fmt.Errorf("page %v is in the page tree more than once", page.ObjectReference)
+		}
+		seen[page.ObjectReference] = true
+		pageRefs[i] = page.ObjectReference
+
+		pages[i] = page.pushDown(root)
+	}
+
+	// group the kids into nodes until the root can hold them
+	kids, kidDicts := pageRefs, pages
+	counts := make([]int, len(kids))
+	for i := range counts {
+		counts[i] = 1
+	}
+	nodeObjects := []IndirectObject{}
+	for len(kids) > maxPageTreeKids {
+		groups := (len(kids) + maxPageTreeKids - 1) / maxPageTreeKids
+		groupRefs := make([]ObjectReference, groups)
+		groupDicts := make([]Dictionary, groups)
+		groupCounts := make([]int, groups)
+		for g := range groupRefs {
+			if len(nodes) > 0 {
+				groupRefs[g], nodes = nodes[0], nodes[1:]
+			} else {
+				var err error
+				groupRefs[g], err = d.File.Add(Null{})
+				if err != nil {
+					return err
+				}
+			}
+
+			// the kids are spread evenly over the nodes
+			start, end := g*len(kids)/groups, (g+1)*len(kids)/groups
+			for i := start; i < end; i++ {
+				kidDicts[i]["Parent"] = groupRefs[g]
+				groupCounts[g] += counts[i]
+			}
+			groupDicts[g] = Dictionary{
+				"Type":  Name("Pages"),
+				"Kids":  refsToArray(kids[start:end]),
+				"Count": Integer(groupCounts[g]),
+			}
+			nodeObjects = append(nodeObjects, IndirectObject{groupRefs[g], groupDicts[g]})
+		}
+		kids, kidDicts, counts = groupRefs, groupDicts, groupCounts
+	}
+	for _, kid := range kidDicts {
+		kid["Parent"] = rootRef
+	}
+	for _, node := range nodeObjects {
+		_, err := d.File.Add(node)
+		if err != nil {
+			return err
+		}
+	}
+
+	// removed pages keep their attributes and can be inserted again
+	for _, page := range current {
+		if seen[page.ObjectReference] {
+			continue
+		}
+		dict := page.pushDown(nil)
+		delete(dict, "Parent")
+		_, err := d.File.Add(IndirectObject{page.ObjectReference, dict})
+		if err != nil {
+			return err
+		}
+	}
+
+	// pages are only written when they changed
+	for i, page := range updated {
+		if !equal(page.Dictionary, pages[i]) {
+			_, err := d.File.Add(IndirectObject{pageRefs[i], pages[i]})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	root["Type"] = Name("Pages")
+	root["Kids"] = refsToArray(kids)
+	root["Count"] = Integer(len(pageRefs))
+	delete(root, "Parent")
+	_, err := d.File.Add(IndirectObject{rootRef, root})
+	if err != nil {
+		return err
+	}
+
+	// the page tree nodes that are no longer used
+	for _, node := range nodes {
+		d.File.Free(node.ObjectNumber)
+	}
+	return nil
+}
+
+func refsToArray(refs []ObjectReference) Array {
+	array := make(Array, len(refs))
+	for i, ref := range refs {
+		array[i] = ref
+	}
+	return array
+}
@@ -0,0 +1,235 @@
+package pdf
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// creates a document whose pages are in a deep page tree: each
+// node has a page and the next node, and the nodes alternate
+// between two MediaBoxes
+func testDeepDocument(t *testing.T, filename string, n int) (*Document, []ObjectReference) {
+	file, err := Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := make([]ObjectReference, n)
+	pages := make([]ObjectReference, n)
+	for i := range nodes {
+		nodes[i], err = file.Add(Null{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range pages {
+		pages[i], err = file.Add(Dictionary{"Type": Name("Page"), "Parent": nodes[i]})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range nodes {
+		node := Dictionary{
+			"Type":     Name("Pages"),
+			"Kids":     Array{pages[i]},
+			"Count":    Integer(n - i),
+			"MediaBox": Array{Integer(0), Integer(0), Integer(100 + i%2), Integer(100)},
+		}
+		if i > 0 {
+			node["Parent"] = nodes[i-1]
+		}
+		if i < n-1 {
+			node["Kids"] = append(node["Kids"].(Array), nodes[i+1])
+		}
+		_, err = file.Add(IndirectObject{nodes[i], node})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Root, err = file.Add(Dictionary{"Type": Name("Catalog"), "Pages": nodes[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document, err := NewDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document, pages
+}
+
+// checks the Parent, Count and Kids of the page tree and that
+// the pages are the expected ones in order, returning its depth
+func checkPageTree(t *testing.T, d *Document, expected []ObjectReference) int {
+	t.Helper()
+
+	var check func(ref, parent ObjectReference, depth int) (int, int)
+	check = func(ref, parent ObjectReference, depth int) (int, int) {
+		node := d.File.Get(ref).(Dictionary)
+		if node["Parent"] != parent && parent.ObjectNumber != 0 {
+			t.Errorf("%v has Parent %v instead of %v", ref, node["Parent"], parent)
+		}
+		if node["Type"] == Name("Page") {
+			return 1, depth
+		}
+		kids := node["Kids"].(Array)
+		if len(kids) > maxPageTreeKids {
+			t.Errorf("%v has %d kids", ref, len(kids))
+		}
+		count, maxDepth := 0, depth
+		for _, kid := range kids {
+			kidCount, kidDepth := check(kid.(ObjectReference), ref, depth+1)
+			count += kidCount
+			if kidDepth > maxDepth {
+				maxDepth = kidDepth
+			}
+		}
+		if node["Count"] != Integer(count) {
+			t.Errorf("%v has Count %v instead of %d", ref, node["Count"], count)
+		}
+		return count, maxDepth
+	}
+	root, _ := d.Catalog.Pages()
+	_, depth := check(root, ObjectReference{}, 0)
+
+	pages, err := d.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := []ObjectReference{}
+	for _, page := range pages {
+		refs = append(refs, page.ObjectReference)
+	}
+	if !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected pages %v, got %v", expected, refs)
+	}
+	return depth
+}
+
+// checks each page has its original MediaBox, which alternates
+func checkMediaBoxes(t *testing.T, d *Document, original []ObjectReference) {
+	t.Helper()
+	index := map[ObjectReference]int{}
+	for i, ref := range original {
+		index[ref] = i
+	}
+	pages, err := d.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range pages {
+		mediaBox, err := page.MediaBox()
+		i := index[page.ObjectReference]
+		if err != nil || mediaBox.URX != float64(100+i%2) {
+			t.Errorf("page %d has MediaBox %v %v", i, mediaBox, err)
+		}
+	}
+}
+
+func TestPageTreeEditing(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "edited.pdf")
+	d, pages := testDeepDocument(t, filename, 100)
+	defer d.File.Close()
+
+	// deleting rebalances the deep page tree
+	err := d.DeletePages(0, 99, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := append(append([]ObjectReference{}, pages[1:50]...), pages[51:99]...)
+	if depth := checkPageTree(t, d, expected); depth != 2 {
+		t.Errorf("page tree depth is %d", depth)
+	}
+	checkMediaBoxes(t, d, pages)
+
+	err = d.MovePage(0, 96)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected[1:], expected[0])
+	checkPageTree(t, d, expected)
+
+	// deleted pages keep the attributes they inherited
+	deleted := d.File.Get(pages[99]).(Dictionary)
+	if _, ok := deleted["Parent"]; ok || deleted["MediaBox"] == nil {
+		t.Errorf("unexpected deleted page %v", deleted)
+	}
+	err = d.InsertPages(10, pages[0], pages[99])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected[:10], append([]ObjectReference{pages[0], pages[99]}, expected[10:]...)...)
+	checkPageTree(t, d, expected)
+	checkMediaBoxes(t, d, pages)
+
+	err = d.InsertPages(0, expected[5])
+	if err == nil {
+		t.Error("expected an error inserting a page already in the page tree")
+	}
+
+	order := make([]int, len(expected))
+	reversed := make([]ObjectReference, len(expected))
+	for i := range order {
+		order[i] = len(order) - 1 - i
+		reversed[i] = expected[order[i]]
+	}
+	err = d.ReorderPages(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPageTree(t, d, reversed)
+
+	// few pages are put directly in the root
+	indexes := []int{}
+	for i := 5; i < len(reversed); i++ {
+		indexes = append(indexes, i)
+	}
+	err = d.DeletePages(indexes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth := checkPageTree(t, d, reversed[:5]); depth != 1 {
+		t.Errorf("page tree depth is %d", depth)
+	}
+	checkMediaBoxes(t, d, pages)
+
+	err = d.SetRotation(-90, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.SetRotation(45, 0)
+	if err == nil {
+		t.Error("expected an error for a rotation that is not a multiple of 90")
+	}
+
+	err = d.File.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.File.Close()
+	file, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	d, err = NewDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPageTree(t, d, reversed[:5])
+	checkMediaBoxes(t, d, pages)
+	saved, err := d.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, page := range saved {
+		rotate := 0
+		if i == 1 || i == 3 {
+			rotate = 270
+		}
+		if page.Rotate() != rotate {
+			t.Errorf("page %d has Rotate %d", i, page.Rotate())
+		}
+	}
+}
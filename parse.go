@@ -10,12 +10,65 @@ import (
 // where the error was discovered. The object will
 // be returned as far as it was completed (to allow
 // for inspection)
+//
+// Parsers work directly on the (usually mmap'ed) slice
+// and only allocate what the returned object needs.
 type parseFn func(slice []byte) (Object, int, error)
 
+// Parsing is often speculative (e.g., every integer might be the
+// start of an object reference), so errors are allocated once.
+var (
+	errUnexpectedEnd         = errors.New("unexpected end of data")
+	errExpectedNonWhitespace = errors.New("expected a non-whitespace char")
+	errUnexpectedCharacter   = errors.New("unexpected character")
+	errNotLiteralString      = errors.New("not a literal string")
+	errEndOfString           = errors.New("couldn't find end of string")
+	errNotDictionary         = errors.New("not a dictionary")
+	errNotName               = errors.New("not a name")
+	errNameEscape            = errors.New("invalid escape in name")
+	errNotBoolean            = errors.New("not a boolean")
+	errNotNumeric            = errors.New("not a numeric")
+	errNumericRange          = errors.New("numeric out of range")
+	errNotHexString          = errors.New("not a hexadecimal string")
+	errNotArray              = errors.New("not an array")
+	errEndOfArray            = errors.New("end of array not found")
+	errNotNull               = errors.New("not a Null")
+	errNotObjectReference    = errors.New("not an object reference")
+)
+
+// character classes §7.2.2
+const (
+	regular = iota
+	whitespace
+	delimiter
+)
+
+var charClass = [256]uint8{
+	// whitespace:
+	// null, tab, line feed, form feed, carriage return, or space
+	// §7.2.2 Table 1
+	0: whitespace, 9: whitespace, 10: whitespace, 12: whitespace, 13: whitespace, 32: whitespace,
+
+	// delimiters:
+	// (, ), <, >, [, ], {, }, /, %
+	// §7.2.2 Table 2
+	'(': delimiter, ')': delimiter, '<': delimiter, '>': delimiter,
+	'[': delimiter, ']': delimiter, '{': delimiter, '}': delimiter,
+	'/': delimiter, '%': delimiter,
+}
+
+// ParseObject parses the Object (§7.3) at the start of data,
+// skipping any leading white-space and comments.
+// It returns the Object and the number of bytes consumed.
+// On error, the number of bytes is the offset where the error was found.
+func ParseObject(data []byte) (Object, int, error) {
+	return parseObject(data)
+}
+
 func parseObject(slice []byte) (Object, int, error) {
 	start, ok := nextNonWhitespace(slice)
 	if !ok {
-		return nil, 0, errors.New("expected a non-whitespace char")
+		return nil, len(slice), errExpectedNonWhitespace
 	}
 
 	var parser parseFn
@@ -29,26 +82,28 @@ func parseObject(slice []byte) (Object, int, error) {
 	case 't', 'f':
 		// Boolean §7.3.2
 		parser = parseBoolean
-	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '+', '-':
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		// Integer §7.3.3
 		// Real §7.3.3
 		// could also be the start of an object reference
 		parser = parseNumeric
 		maybeObjectReference = true
+	case '+', '-', '.':
+		// Integer §7.3.3
+		// Real §7.3.3
+		parser = parseNumeric
 	case '(':
 		// String §7.3.4
 		parser = parseLiteralString
 	case '/':
 		// Name §7.3.5
-		// println("Name")
 		parser = parseName
 	case '[':
 		// Array §7.3.6
 		parser = parseArray
 	case '<':
-		if slice[start+1] == '<' {
+		if start+1 < len(slice) && slice[start+1] == '<' {
 			// Dictionary §7.3.7
-			// println("Dictionary")
 			parser = parseDictionary
 			maybeStream = true
 		} else {
@@ -59,279 +114,365 @@ func parseObject(slice []byte) (Object, int, error) {
 		// Null §7.3.9
 		parser = parseNull
 	default:
-		panic(string(slice[start]))
+		return nil, start, errUnexpectedCharacter
 	}
 
-	object, n, err := parser(slice[start:])
-
 	if maybeObjectReference {
-		objectref, n2, err := parseObjectReference(slice[start:])
-		if err == nil {
-			object = objectref
-			n = n2
+		objectref, n, ok := scanObjectReference(slice[start:])
+		if ok {
+			return objectref, start + n, nil
 		}
 	}
 
+	object, n, err := parser(slice[start:])
+	if err != nil || !maybeStream {
+		return object, start + n, err
+	}
+
 	// handle streams
-	if maybeStream {
-		n2, isStream := match(slice[start+n:], "stream")
-		if isStream {
-			n += n2
-
-			// consume end of line (§7.3.8.1 paragraph after example)
-			switch slice[start+n] {
-			case 13: // carriage return
-				n++
-				if slice[start+n] != '\n' {
-					return object, start + n + 1, errors.New("end of line marker cannot have only a carriage return")
-				}
-			case '\n': // new line
-			default:
-				return object, start + n + 1, errors.New("expected end of line marker")
-			}
-			n++
+	n2, isStream := match(slice[start+n:], "stream")
+	if !isStream {
+		return object, start + n, nil
+	}
+	n += n2
 
-			dict, isDictionary := object.(Dictionary)
-			if !isDictionary {
-				return object, start + n, errors.New("expected a Dictionary")
-			}
+	// consume end of line (§7.3.8.1 paragraph after example)
+	if start+n >= len(slice) {
+		return object, start + n, errUnexpectedEnd
+	}
+	switch slice[start+n] {
+	case 13: // carriage return
+		n++
+		if start+n >= len(slice) || slice[start+n] != '\n' {
+			return object, start + n, errors.New("end of line marker cannot have only a carriage return")
+		}
+	case '\n': // new line
+	default:
+		return object, start + n + 1, errors.New("expected end of line marker")
+	}
+	n++
 
-			var streamLengthInteger Integer
-			streamLengthInteger, ok = dict["Length"].(Integer)
-			if !ok {
-				object = Stream{
-					Dictionary: dict,
-					Stream:     slice[start+n:],
-				}
-			} else {
-				streamLength := int(streamLengthInteger)
-				object = Stream{
-					Dictionary: dict,
-					Stream:     slice[start+n : start+n+streamLength],
-				}
-				n += streamLength
+	dict := object.(Dictionary)
+	streamLengthInteger, ok := dict["Length"].(Integer)
+	if !ok {
+		// the length is not known yet (e.g., it is an indirect object)
+		return Stream{
+			Dictionary: dict,
+			Stream:     slice[start+n:],
+		}, start + n, nil
+	}
 
-				n2, ok = match(slice[start+n:], "endstream")
-				n += n2
-				if !ok {
-					return object, start + n, errors.New("expected 'endstream'")
-				}
-			}
-		}
+	streamLength := int(streamLengthInteger)
+	if streamLength < 0 || start+n+streamLength > len(slice) {
+		return dict, start + n, errors.New("stream length is out of range")
 	}
+	object = Stream{
+		Dictionary: dict,
+		Stream:     slice[start+n : start+n+streamLength],
+	}
+	n += streamLength
 
-	return object, start + n, err
+	n2, ok = match(slice[start+n:], "endstream")
+	n += n2
+	if !ok {
+		return object, start + n, errors.New("expected 'endstream'")
+	}
+
+	return object, start + n, nil
 }
 
 // for tokenized things, returns the next token
+// the returned token is a subslice of slice
 func nextToken(slice []byte) ([]byte, int) {
-	// whitespace:
-	// null, tab, line feed, form feed, carriage return, or space
-	// §7.2.2 Table 1
-
-	// delimiters:
-	// (, ), <, >, [, ], {, }, /, %
-	// §7.2.2 Table 2
-
-	var begin, end int
-	var ok bool
-
-	begin, ok = nextNonWhitespace(slice)
+	begin, ok := nextNonWhitespace(slice)
 	if !ok {
-		begin = 0
+		return slice[len(slice):], len(slice)
 	}
 
-	for end = begin; end < len(slice); end++ {
-		if isWhitespace(slice[end]) || isDelimiter(slice[end]) {
-			return slice[begin:end], end
-		}
+	end := begin
+	for end < len(slice) && charClass[slice[end]] == regular {
+		end++
 	}
 
-	return slice[begin:], len(slice)
+	return slice[begin:end], end
 }
 
 func isDelimiter(char byte) bool {
-	switch char {
-	case 40, 41, 60, 62, 91, 93, 123, 125, 47, 37: // delimiters
-		return true
-	}
-	return false
+	return charClass[char] == delimiter
 }
 
 func isWhitespace(char byte) bool {
-	switch char {
-	case 0, 9, 10, 12, 13, 32: // whitespace
-		return true
-	}
-	return false
+	return charClass[char] == whitespace
 }
 
 func isHexDigit(char byte) bool {
-	switch char {
-	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9',
-		'A', 'B', 'C', 'D', 'E', 'F',
-		'a', 'b', 'c', 'd', 'e', 'f':
-		return true
+	_, ok := hexValue(char)
+	return ok
+}
+
+func hexValue(char byte) (byte, bool) {
+	switch {
+	case '0' <= char && char <= '9':
+		return char - '0', true
+	case 'a' <= char && char <= 'f':
+		return char - 'a' + 10, true
+	case 'A' <= char && char <= 'F':
+		return char - 'A' + 10, true
 	}
-	return false
+	return 0, false
 }
 
+// skips whitespace and comments (§7.2.3)
+// comments are treated as a single white-space
 func nextNonWhitespace(slice []byte) (int, bool) {
 	for i := 0; i < len(slice); i++ {
-		if !isWhitespace(slice[i]) {
+		switch {
+		case slice[i] == '%':
+			for i < len(slice) && slice[i] != '\r' && slice[i] != '\n' {
+				i++
+			}
+		case !isWhitespace(slice[i]):
 			return i, true
 		}
 	}
-	return 0, false
+	return len(slice), false
 }
 
 func match(slice []byte, toMatch string) (int, bool) {
 	token, n := nextToken(slice)
 
-	if len(token) != len(toMatch) {
+	if string(token) != toMatch {
 		return 0, false
 	}
 
-	for i, char := range token {
-		if char != toMatch[i] {
-			return n + i, false
-		}
-	}
-
 	return n, true
 }
 
-func index(slice []byte, toFind byte) (int, bool) {
-	for i := 0; i < len(slice); i++ {
-		if slice[i] == toFind {
-			return i, true
+// parses an unsigned decimal integer token without allocating
+func parseUint(token []byte) (uint64, bool) {
+	if len(token) == 0 {
+		return 0, false
+	}
+
+	var value uint64
+	for _, char := range token {
+		if char < '0' || char > '9' {
+			return 0, false
 		}
+		digit := uint64(char - '0')
+		if value > (1<<64-1-digit)/10 {
+			return 0, false
+		}
+		value = value*10 + digit
 	}
-	return 0, false
+
+	return value, true
 }
 
+// String §7.3.4.2
 func parseLiteralString(slice []byte) (Object, int, error) {
-	decoded := make([]byte, len(slice))
-	decodedIndex := 0
+	if len(slice) == 0 || slice[0] != '(' {
+		return String{}, 0, errNotLiteralString
+	}
 
-	if slice[0] != '(' {
-		return String(decoded[:decodedIndex]), 0, errors.New("not a literal string")
+	// the first pass finds the size so that only
+	// the decoded string is allocated
+	size, n, ok := decodeLiteralString(nil, slice)
+	if !ok {
+		return String{}, n, errEndOfString
+	}
+
+	decoded := make(String, size)
+	decodeLiteralString(decoded, slice)
+
+	return decoded, n, nil
+}
+
+// decodes the literal string at the start of src into dst
+// when dst is nil, only the size of the decoded string is determined
+// returns the size of the decoded string, the bytes consumed from src,
+// and if the end of the string was found
+func decodeLiteralString(dst, src []byte) (int, int, bool) {
+	size := 0
+	emit := func(char byte) {
+		if dst != nil {
+			dst[size] = char
+		}
+		size++
 	}
 
 	parens := 0
-	i := 0
-	for i < len(slice) {
-		include := true
-		switch slice[i] {
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
 		case '(':
-			if parens == 0 {
-				include = false
-			}
 			parens++
+			emit('(')
 		case ')':
-			parens--
 			if parens == 0 {
-				return String(decoded[:decodedIndex]), i + 1, nil
+				return size, i + 1, true
 			}
-			include = true
-		case '\n':
-			if slice[i-1] == '\\' {
-				decodedIndex--
-				include = false
+			parens--
+			emit(')')
+		case '\r':
+			// all end of line markers are read as \n
+			if i+1 < len(src) && src[i+1] == '\n' {
+				i++
+			}
+			emit('\n')
+		case '\\':
+			i++
+			if i == len(src) {
+				return size, i, false
 			}
-		}
 
-		if include {
-			decoded[decodedIndex] = slice[i]
-			decodedIndex++
+			// escape sequences §7.3.4.2 Table 3
+			switch src[i] {
+			case 'n':
+				emit('\n')
+			case 'r':
+				emit('\r')
+			case 't':
+				emit('\t')
+			case 'b':
+				emit('\b')
+			case 'f':
+				emit('\f')
+			case '\r':
+				// line continuation
+				if i+1 < len(src) && src[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+				// line continuation
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				// up to three octal digits, high-order overflow is ignored
+				char := src[i] - '0'
+				for j := 0; j < 2 && i+1 < len(src) && '0' <= src[i+1] && src[i+1] <= '7'; j++ {
+					i++
+					char = char<<3 | (src[i] - '0')
+				}
+				emit(char)
+			default:
+				// includes \(, \) and \\
+				// otherwise the reverse solidus is ignored
+				emit(src[i])
+			}
+		default:
+			emit(src[i])
 		}
-		i++
 	}
 
-	return String(decoded[:decodedIndex]), i, errors.New("couldn't find end of string")
+	return size, len(src), false
 }
 
 // returned int is the length of slice consumed
 func parseDictionary(slice []byte) (Object, int, error) {
-	dict := make(Dictionary)
+	dict := Dictionary{}
 
-	if slice[0] != '<' && slice[1] != '<' {
-		return dict, 0, errors.New("not a dictionary")
+	if len(slice) < 2 || slice[0] != '<' || slice[1] != '<' {
+		return dict, 0, errNotDictionary
 	}
 
 	i := 2
-	for i < len(slice) {
+	for {
 		// skip whitespace
 		n, ok := nextNonWhitespace(slice[i:])
-		if !ok {
-			return dict, i, errors.New("expected a non-whitespace char")
-		}
 		i += n
+		if !ok || i+1 >= len(slice) {
+			return dict, i, errUnexpectedEnd
+		}
 
 		// check to see if end
 		if slice[i] == '>' && slice[i+1] == '>' {
-			i += 2
-			break
+			return dict, i + 2, nil
 		}
 
 		// get the key
-		var name Object
-		var err error
-		name, n, err = parseName(slice[i:])
-		if err != nil {
-			return dict, i + n, err
-		}
+		name, n, err := parseName(slice[i:])
 		i += n
-
-		var key Name
-		key, ok = name.(Name)
-		if !ok {
-			return dict, i, errors.New("unable to cast Name")
+		if err != nil {
+			return dict, i, err
 		}
 
 		// get the value
 		var value Object
 		value, n, err = parseObject(slice[i:])
 		if err != nil {
-			return dict, i, err
+			return dict, i + n, err
 		}
 		i += n
 
 		// set the key/value pair
-		dict[key] = value
+		dict[name.(Name)] = value
 	}
+}
 
-	return dict, i, nil
+// names that appear in most files are shared instead of
+// being allocated each time they are parsed
+var commonNames = map[string]Object{}
+
+func init() {
+	for _, name := range []Name{
+		"Type", "Subtype", "Length", "Filter", "DecodeParms", "Parent",
+		"Kids", "Count", "Page", "Pages", "Contents", "Resources",
+		"MediaBox", "CropBox", "Rotate", "Font", "XObject", "ExtGState",
+		"ProcSet", "PDF", "Text", "ImageB", "ImageC", "ImageI",
+		"FlateDecode", "BaseFont", "Encoding", "FirstChar", "LastChar",
+		"Widths", "FontDescriptor", "Width", "Height", "ColorSpace",
+		"BitsPerComponent", "DeviceRGB", "DeviceGray", "DeviceCMYK",
+		"Annots", "Rect", "Border", "A", "S", "URI", "Dest", "P", "K",
+		"N", "First", "Next", "Prev", "Last", "Title", "Size", "Root",
+		"Info", "ID", "Index", "W", "XRef", "ObjStm", "Catalog",
+		"Form", "Image", "Annot", "Link", "BBox", "Matrix",
+	} {
+		commonNames[string(name)] = name
+	}
 }
 
+// Name §7.3.5
 func parseName(slice []byte) (Object, int, error) {
-	name := make([]byte, 0, len(slice))
+	if len(slice) == 0 || slice[0] != '/' {
+		return Name(""), 0, errNotName
+	}
 
-	if slice[0] != '/' {
-		return Name(name), 0, errors.New("not a name")
+	// find the end and determine if there are escapes
+	escapes := 0
+	end := 1
+	for end < len(slice) && charClass[slice[end]] == regular {
+		if slice[end] == '#' {
+			escapes++
+		}
+		end++
 	}
 
-	i := 1
-	for i < len(slice) {
-		if isDelimiter(slice[i]) || isWhitespace(slice[i]) {
-			break
+	if escapes == 0 {
+		// the lookup does not allocate
+		if name, ok := commonNames[string(slice[1:end])]; ok {
+			return name, end, nil
 		}
+		return Name(slice[1:end]), end, nil
+	}
 
-		switch slice[i] {
-		case '#':
-			char, err := strconv.ParseUint(string(slice[i+1:i+3]), 16, 8)
-			if err != nil {
-				return Name(name), i, err
-			}
-			name = append(name, byte(char))
-			i += 2
-		default:
+	name := make([]byte, 0, end-1)
+	for i := 1; i < end; i++ {
+		if slice[i] != '#' {
 			name = append(name, slice[i])
+			continue
 		}
-		i++
+
+		if i+2 >= end {
+			return Name(name), i, errNameEscape
+		}
+		high, ok1 := hexValue(slice[i+1])
+		low, ok2 := hexValue(slice[i+2])
+		if !ok1 || !ok2 {
+			return Name(name), i, errNameEscape
+		}
+		name = append(name, high<<4|low)
+		i += 2
 	}
 
-	return Name(name), i, nil
+	return Name(name), end, nil
 }
 
 func parseBoolean(slice []byte) (Object, int, error) {
@@ -345,104 +486,167 @@ func parseBoolean(slice []byte) (Object, int, error) {
 		return Boolean(false), n, nil
 	}
 
-	return Boolean(false), 0, errors.New("not a boolean")
+	return Boolean(false), 0, errNotBoolean
+}
+
+// powers of 10 that are exactly representable as float64
+var exactPowersOf10 = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11,
+	1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22,
 }
 
 // returns Integer when integer, Real when real
 func parseNumeric(slice []byte) (Object, int, error) {
 	token, n := nextToken(slice)
 
+	i := 0
+	negative := false
+	if i < len(token) && (token[i] == '+' || token[i] == '-') {
+		negative = token[i] == '-'
+		i++
+	}
+
+	// significant digits, ignoring the decimal point
+	var mantissa uint64
+	digits := 0
+	fractionDigits := 0
 	isInteger := true
-	for _, char := range token {
-		if char == '.' {
+	overflow := false
+	for ; i < len(token); i++ {
+		char := token[i]
+		switch {
+		case '0' <= char && char <= '9':
+			digits++
+			if !isInteger {
+				fractionDigits++
+			}
+			// allows for the magnitude of the smallest Integer
+			digit := uint64(char - '0')
+			if mantissa > (1<<63-digit)/10 {
+				overflow = true
+				continue
+			}
+			mantissa = mantissa*10 + digit
+		case char == '.' && isInteger:
 			isInteger = false
-			break
+		default:
+			return Integer(0), n, errNotNumeric
 		}
 	}
 
+	if digits == 0 {
+		return Integer(0), n, errNotNumeric
+	}
+
 	if isInteger {
-		integer, err := strconv.ParseInt(string(token), 10, 0)
-		if err != nil {
-			return Integer(integer), n, err
+		if overflow || (!negative && mantissa > 1<<63-1) {
+			return Integer(0), n, errNumericRange
 		}
 
+		integer := int64(mantissa)
+		if negative {
+			integer = -integer
+		}
 		return Integer(integer), n, nil
 	}
 
-	real, err := strconv.ParseFloat(string(token), 64)
-	if err != nil {
-		return Real(0), n, err
+	// exact when both the mantissa and the power of 10 are exactly
+	// representable, otherwise let strconv do the rounding
+	var real float64
+	if !overflow && mantissa < 1<<53 && fractionDigits < len(exactPowersOf10) {
+		real = float64(mantissa) / exactPowersOf10[fractionDigits]
+		if negative {
+			real = -real
+		}
+	} else {
+		var err error
+		real, err = strconv.ParseFloat(string(token), 64)
+		if err != nil {
+			return Real(0), n, errNumericRange
+		}
 	}
 
 	return Real(real), n, nil
 }
 
+// String §7.3.4.3
 func parseHexadecimalString(slice []byte) (Object, int, error) {
-	hex := make(String, 0, int(len(slice)/2))
-
-	if slice[0] != '<' {
-		return hex, 0, errors.New("not a hexadecimal string")
-	}
-
-	i := 1
-	for i < len(slice) {
-		if slice[i] == '>' {
-			i++
-			break
+	if len(slice) == 0 || slice[0] != '<' {
+		return String{}, 0, errNotHexString
+	}
+
+	// count the digits, white-space is ignored
+	digits := 0
+	end := 1
+	for ; end < len(slice) && slice[end] != '>'; end++ {
+		switch {
+		case isHexDigit(slice[end]):
+			digits++
+		case isWhitespace(slice[end]):
+		default:
+			return String{}, end, errNotHexString
 		}
+	}
+	if end == len(slice) {
+		return String{}, end, errEndOfString
+	}
 
-		if isHexDigit(slice[i]) && isHexDigit(slice[i+1]) {
-			b, err := strconv.ParseUint(string(slice[i:i+2]), 16, 8)
-			if err != nil {
-				return hex, i, err
-			}
-			hex = append(hex, byte(b))
-			i += 2
+	// a missing final digit is assumed to be 0
+	hex := make(String, (digits+1)/2)
+	digit := 0
+	for i := 1; i < end; i++ {
+		value, ok := hexValue(slice[i])
+		if !ok {
 			continue
 		}
 
-		if isHexDigit(slice[i]) && slice[i+1] == '>' {
-			b, err := strconv.ParseUint(string(slice[i])+"0", 16, 8)
-			if err != nil {
-				return hex, i, err
-			}
-			hex = append(hex, byte(b))
-			i += 2
-			break
+		if digit%2 == 0 {
+			hex[digit/2] = value << 4
+		} else {
+			hex[digit/2] |= value
 		}
+		digit++
 	}
 
-	return hex, i, nil
+	return hex, end + 1, nil
 }
 
+// Array §7.3.6
 func parseArray(slice []byte) (Object, int, error) {
-	array := make(Array, 0)
+	if len(slice) == 0 || slice[0] != '[' {
+		return Array{}, 0, errNotArray
+	}
 
-	if slice[0] != '[' {
-		return array, 0, errors.New("not an array")
+	// collect the elements on the stack so that
+	// the array is allocated once at its final size
+	var scratch [16]Object
+	elements := scratch[:0]
+	toArray := func() Array {
+		array := make(Array, len(elements))
+		copy(array, elements)
+		return array
 	}
 
 	i := 1
-	for i < len(slice) {
-		if isWhitespace(slice[i]) {
-			i++
-			continue
+	for {
+		n, ok := nextNonWhitespace(slice[i:])
+		i += n
+		if !ok {
+			return toArray(), i, errEndOfArray
 		}
 
 		if slice[i] == ']' {
-			return array, i + 1, nil
+			return toArray(), i + 1, nil
 		}
 
 		object, n, err := parseObject(slice[i:])
 		if err != nil {
-			return array, i, err
+			return toArray(), i + n, err
 		}
 		i += n
 
-		array = append(array, object)
+		elements = append(elements, object)
 	}
-
-	return array, i, errors.New("end of array not found")
 }
 
 func parseNull(slice []byte) (Object, int, error) {
@@ -451,43 +655,44 @@ func parseNull(slice []byte) (Object, int, error) {
 		return Null{}, n, nil
 	}
 
-	return Null{}, 0, errors.New("not a Null")
+	return Null{}, 0, errNotNull
 }
 
+// object references are two non-negative integers followed by R
 func parseObjectReference(slice []byte) (Object, int, error) {
+	objref, n, ok := scanObjectReference(slice)
+	if !ok {
+		return objref, n, errNotObjectReference
+	}
+	return objref, n, nil
+}
+
+// does not allocate, even when slice is not an object reference
+func scanObjectReference(slice []byte) (ObjectReference, int, bool) {
 	objref := ObjectReference{}
-	i := 0
 
-	objectNumber, n, err := parseNumeric(slice[i:])
-	i += n
-	if err != nil {
-		return objref, i, err
-	}
-	integer, ok := objectNumber.(Integer)
+	token, i := nextToken(slice)
+	objectNumber, ok := parseUint(token)
 	if !ok {
-		return objref, i, errors.New("expected object number not an integer")
+		return objref, i, false
 	}
-	objref.ObjectNumber = uint(integer)
 
-	var generationNumber Object
-	generationNumber, n, err = parseNumeric(slice[i:])
+	token, n := nextToken(slice[i:])
 	i += n
-	if err != nil {
-		return objref, i, err
-	}
-	integer, ok = generationNumber.(Integer)
+	generationNumber, ok := parseUint(token)
 	if !ok {
-		return objref, i, errors.New("expected generation number not an integer")
+		return objref, i, false
 	}
-	objref.GenerationNumber = uint(integer)
 
 	n, ok = match(slice[i:], "R")
 	i += n
 	if !ok {
-		return objref, i, errors.New("could not find end of object reference")
+		return objref, i, false
 	}
 
-	return objref, i, nil
+	objref.ObjectNumber = uint(objectNumber)
+	objref.GenerationNumber = uint(generationNumber)
+	return objref, i, true
 }
 
 func parseIndirectObject(slice []byte) (Object, int, error) {
@@ -495,10 +700,10 @@ func parseIndirectObject(slice []byte) (Object, int, error) {
 
 	// Object Number
 	token, n := nextToken(slice[i:])
-	objectNumber, err := strconv.ParseUint(string(token), 10, 64)
 	i += n
-	if err != nil {
-		return nil, i, err
+	objectNumber, ok := parseUint(token)
+	if !ok {
+		return nil, i, errors.New("expected object number")
 	}
 
 	var io IndirectObject
@@ -506,15 +711,14 @@ func parseIndirectObject(slice []byte) (Object, int, error) {
 
 	// Generation Number
 	token, n = nextToken(slice[i:])
-	generationNumber, err := strconv.ParseUint(string(token), 10, 64)
 	i += n
-	if err != nil {
-		return nil, i, err
+	generationNumber, ok := parseUint(token)
+	if !ok {
+		return nil, i, errors.New("expected generation number")
 	}
 	io.GenerationNumber = uint(generationNumber)
 
 	// "obj"
-	var ok bool
 	n, ok = match(slice[i:], "obj")
 	i += n
 	if !ok {
@@ -522,8 +726,7 @@ func parseIndirectObject(slice []byte) (Object, int, error) {
 	}
 
 	// the object
-	var object Object
-	object, n, err = parseObject(slice[i:])
+	object, n, err := parseObject(slice[i:])
 	i += n
 	io.Object = object
 	if err != nil {
@@ -0,0 +1,181 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// syntheticObjects builds a sequence of indirect objects that exercise
+// every parser until the result is at least size bytes long.
+func syntheticObjects(size int) ([]byte, int) {
+	buf := &bytes.Buffer{}
+	n := 0
+	for buf.Len() < size {
+		n++
+		fmt.Fprintf(buf, "%d 0 obj\n<< /Type /Example /Label /Name#20With#20Escapes /Count %d /Offset -%d.%d\n", n, n, n, n%1000)
+		fmt.Fprintf(buf, "/Kids [%d 0 R %d 0 R %d 0 R] /Title (A literal \\(string\\) number %d\\n) ", n+1, n+2, n+3, n)
+		fmt.Fprintf(buf, "/ID <901FA3 4E6F76 2073686D6F7A> /Flag true /Missing null /Box [0 0 612.5 792] >>\nendobj\n")
+	}
+	return buf.Bytes(), n
+}
+
+func benchmarkParse(b *testing.B, size int) {
+	data, count := syntheticObjects(size)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		offset := 0
+		for j := 0; j < count; j++ {
+			_, n, err := parseIndirectObject(data[offset:])
+			if err != nil {
+				b.Fatal(err)
+			}
+			offset += n
+		}
+	}
+}
+
+func BenchmarkParse1MB(b *testing.B)  { benchmarkParse(b, 1<<20) }
+func BenchmarkParse16MB(b *testing.B) { benchmarkParse(b, 16<<20) }
+
+// §7.3.4.2 Table 3
+func TestLiteralStringEscapes(t *testing.T) {
+	runTests(t, []test{
+		test{
+			literal: []byte("(unbalanced \\( and \\) parentheses)"),
+			object:  String("unbalanced ( and ) parentheses"),
+		},
+		test{
+			literal: []byte("(\\n\\r\\t\\b\\f\\\\\\q)"),
+			object:  String("\n\r\t\b\f\\q"),
+		},
+		test{
+			literal: []byte("(end of line\r\nmarkers\rare\nnewlines)"),
+			object:  String("end of line\nmarkers\nare\nnewlines"),
+		},
+	})
+}
+
+// §7.3.4.3
+func TestHexadecimalStringWhitespace(t *testing.T) {
+	runTests(t, []test{
+		test{
+			literal: []byte("<90 1F\nA3>"),
+			object:  String{0x90, 0x1F, 0xA3},
+		},
+		test{
+			literal: []byte("<>"),
+			object:  String{},
+		},
+	})
+}
+
+// §7.2.3
+func TestComments(t *testing.T) {
+	runTests(t, []test{
+		test{
+			literal: []byte("[1 % a comment\n2]"),
+			object:  Array{Integer(1), Integer(2)},
+		},
+		test{
+			literal: []byte("<</Key % a comment\r/Value>>"),
+			object:  Dictionary{Name("Key"): Name("Value")},
+		},
+	})
+}
+
+func TestParseErrors(t *testing.T) {
+	for i, literal := range []string{
+		"(unterminated",
+		"<</Key /Value",
+		"[1 2",
+		"<12 zz>",
+		"/A#4",
+		"/##",
+		"/###x",
+		"1.2.3",
+		"}",
+	} {
+		_, _, err := parseObject([]byte(literal))
+		if err == nil {
+			t.Errorf("%d: expected an error for %q", i, literal)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for i, expected := range []String{
+		String("plain"),
+		String("(unbalanced"),
+		String("back\\slash)"),
+		String("carriage\rreturn"),
+		String{0, 1, 2, 0xff},
+	} {
+		buf := &bytes.Buffer{}
+		_, err := expected.writeTo(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, _, err := parseObject(buf.Bytes())
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+		}
+
+		err = compare(got, expected)
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+		}
+	}
+}
+
+func TestNumericRoundTrip(t *testing.T) {
+	for i, expected := range []Object{
+		Integer(0),
+		Integer(-98),
+		Integer(1 << 31),        // offsets in files larger than 2 GiB
+		Integer(1<<63 - 1),      // largest Integer
+		Integer(-1 << 63),       // smallest Integer
+		Real(4),                 // must not become an Integer
+		Real(-0.002),            // must not use exponential notation
+		Real(612.375),           // coordinates
+		Real(0.1 + 0.2),         // not exactly representable
+		Real(1e-10),             // small
+		Real(1.7976931348e+300), // large
+	} {
+		buf := &bytes.Buffer{}
+		_, err := expected.writeTo(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, n, err := parseNumeric(buf.Bytes())
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+		}
+		if n != buf.Len() {
+			t.Errorf("%d: parsed %d of %q", i, n, buf.Bytes())
+		}
+
+		err = compare(got, expected)
+		if err != nil {
+			t.Errorf("%d: %q %v", i, buf.Bytes(), err)
+		}
+	}
+}
+
+func TestNumericOutOfRange(t *testing.T) {
+	_, _, err := parseNumeric([]byte("9223372036854775808"))
+	if err == nil {
+		t.Error("expected an error for an integer larger than 64 bits")
+	}
+
+	_, err = Real(math.Inf(1)).writeTo(&bytes.Buffer{})
+	if err == nil {
+		t.Error("expected an error for an infinite real")
+	}
+}
@@ -0,0 +1,426 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// predictor parameters for FlateDecode and LZWDecode (§7.4.4.4 Table 8)
+type predictorParameters struct {
+	predictor        int
+	colors           int
+	bitsPerComponent int
+	columns          int
+}
+
+func newPredictorParameters(dict Dictionary) (predictorParameters, error) {
+	p := predictorParameters{
+		predictor:        1,
+		colors:           1,
+		bitsPerComponent: 8,
+		columns:          1,
+	}
+
+	for key, value := range map[Name]*int{
+		"Predictor":        &p.predictor,
+		"Colors":           &p.colors,
+		"BitsPerComponent": &p.bitsPerComponent,
+		"Columns":          &p.columns,
+	} {
+		obj, ok := dict[key]
+		if !ok {
+			continue
+		}
+		integer, ok := obj.(Integer)
+		if !ok {
+			return p, fmt.Errorf("%s must be an integer, not %T", key, obj)
+		}
+		*value = int(integer)
+	}
+
+	switch {
+	case p.predictor != 1 && p.predictor != 2 && (p.predictor < 10 || p.predictor > 15):
+		return p, fmt.Errorf("unknown predictor %d", p.predictor)
+	case p.colors < 1 || p.colors > 32:
+		return p, fmt.Errorf("invalid number of colors %d", p.colors)
+	case p.columns < 1 || p.columns > 1<<24:
+		return p, fmt.Errorf("invalid number of columns %d", p.columns)
+	}
+	switch p.bitsPerComponent {
+	case 1, 2, 4, 8, 16:
+	default:
+		return p, fmt.Errorf("invalid bits per component %d", p.bitsPerComponent)
+	}
+
+	return p, nil
+}
+
+// bytes in a row of samples, not including the PNG filter type byte
+func (p predictorParameters) rowLength() int {
+	return (p.colors*p.bitsPerComponent*p.columns + 7) / 8
+}
+
+// distance to the corresponding byte of the previous pixel
+func (p predictorParameters) bytesPerPixel() int {
+	return (p.colors*p.bitsPerComponent + 7) / 8
+}
+
+// unpredict reverses the predictor described by the filter's
+// DecodeParms dictionary
+func unpredict(data []byte, dict Dictionary) ([]byte, error) {
+	return ioutil.ReadAll(newPredictorReader(bytes.NewReader(data), dict))
+}
+
+// predict applies the predictor described by the filter's
+// DecodeParms dictionary, it is the inverse of unpredict
+func predict(data []byte, dict Dictionary) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := newPredictorWriter(nopCloser{buf}, dict)
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// predictorReader reverses a predictor a row at a time
+type predictorReader struct {
+	p        predictorParameters
+	r        io.Reader
+	previous []byte // the previous decoded row
+	decoded  []byte // decoded bytes that have not been read
+	err      error
+}
+
+func newPredictorReader(r io.Reader, dict Dictionary) io.Reader {
+	p, err := newPredictorParameters(dict)
+	if err != nil {
+		return errorReader{err}
+	}
+	if p.predictor == 1 {
+		return r
+	}
+
+	return &predictorReader{
+		p:        p,
+		r:        r,
+		previous: make([]byte, p.rowLength()),
+	}
+}
+
+func (pr *predictorReader) Read(b []byte) (int, error) {
+	for len(pr.decoded) == 0 {
+		if pr.err != nil {
+			return 0, pr.err
+		}
+		pr.decoded, pr.err = pr.row()
+	}
+
+	n := copy(b, pr.decoded)
+	pr.decoded = pr.decoded[n:]
+	return n, nil
+}
+
+// a truncated final row is decoded as far as it goes
+func (pr *predictorReader) row() ([]byte, error) {
+	rowLength := pr.p.rowLength()
+	size := rowLength
+	if pr.p.predictor >= 10 {
+		size++ // PNG filter type
+	}
+
+	data := make([]byte, size)
+	n, err := io.ReadFull(pr.r, data)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if n == 0 {
+		return nil, err
+	}
+	data = data[:n]
+
+	if pr.p.predictor == 2 {
+		return pr.p.tiffDecode(data), err
+	}
+
+	row := make([]byte, rowLength)
+	copy(row, data[1:])
+	decodeErr := pr.p.pngDecode(data[0], row[:n-1], pr.previous)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	pr.previous = row
+	return row[:n-1], err
+}
+
+// predictorWriter applies a predictor a row at a time
+type predictorWriter struct {
+	p        predictorParameters
+	w        io.WriteCloser
+	row      []byte // the partial row that has not been written
+	previous []byte
+}
+
+func newPredictorWriter(w io.WriteCloser, dict Dictionary) io.WriteCloser {
+	p, err := newPredictorParameters(dict)
+	if err != nil {
+		return errorWriter{err}
+	}
+	if p.predictor == 1 {
+		return w
+	}
+
+	return &predictorWriter{
+		p:        p,
+		w:        w,
+		row:      make([]byte, 0, p.rowLength()),
+		previous: make([]byte, p.rowLength()),
+	}
+}
+
+func (pw *predictorWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		n := cap(pw.row) - len(pw.row)
+		if n > len(b) {
+			n = len(b)
+		}
+		pw.row = append(pw.row, b[:n]...)
+		b = b[n:]
+		written += n
+
+		if len(pw.row) == cap(pw.row) {
+			err := pw.writeRow()
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// a partial final row is written as far as it goes
+func (pw *predictorWriter) Close() error {
+	if len(pw.row) > 0 {
+		err := pw.writeRow()
+		if err != nil {
+			return err
+		}
+	}
+	return pw.w.Close()
+}
+
+func (pw *predictorWriter) writeRow() error {
+	var encoded []byte
+	if pw.p.predictor == 2 {
+		encoded = pw.p.tiffEncode(pw.row)
+	} else {
+		row := make([]byte, pw.p.rowLength())
+		n := copy(row, pw.row)
+		encoded = pw.p.pngEncode(row, pw.previous, n)
+		pw.previous = row
+	}
+	pw.row = pw.row[:0]
+
+	_, err := pw.w.Write(encoded)
+	return err
+}
+
+// PNG filter types (RFC 2083 §6)
+const (
+	pngNone = iota
+	pngSub
+	pngUp
+	pngAverage
+	pngPaeth
+)
+
+// each row starts with its filter type (§7.4.4.4),
+// row is decoded in place
+func (p predictorParameters) pngDecode(filter byte, row, previous []byte) error {
+	bpp := p.bytesPerPixel()
+
+	for i := range row {
+		var left, upperLeft byte
+		if i >= bpp {
+			left = row[i-bpp]
+			upperLeft = previous[i-bpp]
+		}
+		up := previous[i]
+
+		switch filter {
+		case pngNone:
+		case pngSub:
+			row[i] += left
+		case pngUp:
+			row[i] += up
+		case pngAverage:
+			row[i] += byte((int(left) + int(up)) / 2)
+		case pngPaeth:
+			row[i] += paeth(left, up, upperLeft)
+		default:
+			return fmt.Errorf("unknown PNG filter type %d", filter)
+		}
+	}
+
+	return nil
+}
+
+// Predictor 15 (optimum) chooses the filter type for each row using
+// the minimum sum of absolute differences heuristic (RFC 2083 §9.6),
+// the other predictors use a single filter type for every row.
+// The first n bytes of row are encoded after the filter type.
+func (p predictorParameters) pngEncode(row, previous []byte, n int) []byte {
+	bpp := p.bytesPerPixel()
+
+	filters := []byte{byte(p.predictor - 10)}
+	if p.predictor == 15 {
+		filters = []byte{pngNone, pngSub, pngUp, pngAverage, pngPaeth}
+	}
+
+	var best []byte
+	bestSum := -1
+	for _, filter := range filters {
+		candidate := make([]byte, n+1)
+		candidate[0] = filter
+
+		sum := 0
+		for i := 0; i < n; i++ {
+			var left, upperLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upperLeft = previous[i-bpp]
+			}
+			up := previous[i]
+
+			value := row[i]
+			switch filter {
+			case pngSub:
+				value -= left
+			case pngUp:
+				value -= up
+			case pngAverage:
+				value -= byte((int(left) + int(up)) / 2)
+			case pngPaeth:
+				value -= paeth(left, up, upperLeft)
+			}
+			candidate[i+1] = value
+			sum += abs(int(int8(value)))
+		}
+
+		if bestSum < 0 || sum < bestSum {
+			best, bestSum = candidate, sum
+		}
+	}
+
+	return best
+}
+
+// RFC 2083 §6.6
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// TIFF Predictor 2 (TIFF 6.0 §14) stores each component as the
+// difference from the same component of the previous pixel in the row
+func (p predictorParameters) tiffDecode(data []byte) []byte {
+	decoded := append([]byte{}, data...)
+	p.tiffRows(decoded, false, func(component, previous uint32) uint32 {
+		return component + previous
+	})
+	return decoded
+}
+
+func (p predictorParameters) tiffEncode(data []byte) []byte {
+	encoded := append([]byte{}, data...)
+
+	// differences are taken from the original values,
+	// so work backwards through each row
+	p.tiffRows(encoded, true, func(component, previous uint32) uint32 {
+		return component - previous
+	})
+	return encoded
+}
+
+// replaces each component, after the first pixel in each row,
+// with f of it and the same component in the previous pixel
+func (p predictorParameters) tiffRows(data []byte, reverse bool, f func(component, previous uint32) uint32) {
+	rowLength := p.rowLength()
+	components := p.columns * p.colors
+	mask := uint32(1)<<uint(p.bitsPerComponent) - 1
+
+	for start := 0; start < len(data); start += rowLength {
+		row := data[start:]
+		if len(row) > rowLength {
+			row = row[:rowLength]
+		}
+
+		// a truncated final row is processed as far as it goes
+		n := len(row) * 8 / p.bitsPerComponent
+		if n > components {
+			n = components
+		}
+
+		for j := p.colors; j < n; j++ {
+			i := j
+			if reverse {
+				i = n - 1 - (j - p.colors)
+			}
+			value := f(p.component(row, i), p.component(row, i-p.colors)) & mask
+			p.setComponent(row, i, value)
+		}
+	}
+}
+
+// components are packed most significant bit first
+func (p predictorParameters) component(row []byte, i int) uint32 {
+	switch p.bitsPerComponent {
+	case 16:
+		return uint32(row[2*i])<<8 | uint32(row[2*i+1])
+	case 8:
+		return uint32(row[i])
+	}
+
+	bit := i * p.bitsPerComponent
+	shift := uint(8 - p.bitsPerComponent - bit%8)
+	mask := byte(1)<<uint(p.bitsPerComponent) - 1
+	return uint32(row[bit/8] >> shift & mask)
+}
+
+func (p predictorParameters) setComponent(row []byte, i int, value uint32) {
+	switch p.bitsPerComponent {
+	case 16:
+		row[2*i] = byte(value >> 8)
+		row[2*i+1] = byte(value)
+		return
+	case 8:
+		row[i] = byte(value)
+		return
+	}
+
+	bit := i * p.bitsPerComponent
+	shift := uint(8 - p.bitsPerComponent - bit%8)
+	mask := byte(1)<<uint(p.bitsPerComponent) - 1
+	row[bit/8] = row[bit/8]&^(mask<<shift) | byte(value)<<shift
+}
@@ -0,0 +1,167 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"math/rand"
+	"testing"
+)
+
+// cross-reference streams written by Acrobat use /Predictor 12
+func TestPNGPredictorXrefStream(t *testing.T) {
+	// W [1 2 1], three entries
+	entries := []byte{
+		0, 0x00, 0x00, 0xff,
+		1, 0x00, 0x0f, 0x00,
+		1, 0x01, 0x20, 0x00,
+	}
+	// PNG Up filtered rows
+	predicted := []byte{
+		2, 0, 0x00, 0x00, 0xff,
+		2, 1, 0x00, 0x0f, 0x01,
+		2, 0, 0x01, 0x11, 0x00,
+	}
+
+	buf := &bytes.Buffer{}
+	w := zlib.NewWriter(buf)
+	w.Write(predicted)
+	w.Close()
+
+	stream := Stream{
+		Dictionary: Dictionary{
+			"Type":        Name("XRef"),
+			"W":           Array{Integer(1), Integer(2), Integer(1)},
+			"Filter":      Name("FlateDecode"),
+			"DecodeParms": Dictionary{"Predictor": Integer(12), "Columns": Integer(4)},
+		},
+		Stream: buf.Bytes(),
+	}
+
+	decoded, err := stream.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, entries) {
+		t.Errorf("expected %v, got %v", entries, decoded)
+	}
+}
+
+func TestPredictorExamples(t *testing.T) {
+	type test struct {
+		params             Dictionary
+		decoded, predicted []byte
+	}
+	tests := []test{
+		// 8 bit RGB
+		test{
+			Dictionary{"Predictor": Integer(2), "Colors": Integer(3), "Columns": Integer(2)},
+			[]byte{10, 20, 30, 11, 22, 33},
+			[]byte{10, 20, 30, 1, 2, 3},
+		},
+		// 4 bit gray, components wrap around
+		test{
+			Dictionary{"Predictor": Integer(2), "BitsPerComponent": Integer(4), "Columns": Integer(4)},
+			[]byte{0x1f, 0x0e},
+			[]byte{0x1e, 0x1e},
+		},
+		// 16 bit gray
+		test{
+			Dictionary{"Predictor": Integer(2), "BitsPerComponent": Integer(16), "Columns": Integer(2)},
+			[]byte{0x01, 0x00, 0x00, 0xff},
+			[]byte{0x01, 0x00, 0xff, 0xff},
+		},
+		// PNG Sub
+		test{
+			Dictionary{"Predictor": Integer(11), "Columns": Integer(3)},
+			[]byte{1, 2, 4, 8, 16, 32},
+			[]byte{1, 1, 1, 2, 1, 8, 8, 16},
+		},
+		// PNG Average and Paeth with 2 bytes per pixel
+		test{
+			Dictionary{"Predictor": Integer(13), "BitsPerComponent": Integer(16), "Columns": Integer(2)},
+			[]byte{10, 20, 30, 40, 50, 60, 70, 80},
+			[]byte{3, 10, 20, 25, 30, 3, 45, 50, 30, 30},
+		},
+		test{
+			Dictionary{"Predictor": Integer(14), "Columns": Integer(2)},
+			[]byte{10, 20, 30, 40},
+			[]byte{4, 10, 10, 4, 20, 10},
+		},
+	}
+
+	for i, test := range tests {
+		predicted, err := predict(test.decoded, test.params)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if !bytes.Equal(predicted, test.predicted) {
+			t.Errorf("%d: predict: expected %v, got %v", i, test.predicted, predicted)
+		}
+
+		decoded, err := unpredict(test.predicted, test.params)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if !bytes.Equal(decoded, test.decoded) {
+			t.Errorf("%d: unpredict: expected %v, got %v", i, test.decoded, decoded)
+		}
+	}
+}
+
+func TestPredictorRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for _, predictor := range []int{1, 2, 10, 11, 12, 13, 14, 15} {
+		for _, colors := range []int{1, 3, 4} {
+			for _, bpc := range []int{1, 2, 4, 8, 16} {
+				for _, columns := range []int{1, 5, 17} {
+					params := Dictionary{
+						"Predictor":        Integer(predictor),
+						"Colors":           Integer(colors),
+						"BitsPerComponent": Integer(bpc),
+						"Columns":          Integer(columns),
+					}
+
+					rowLength := (colors*bpc*columns + 7) / 8
+					data := make([]byte, rowLength*7)
+					random.Read(data)
+
+					encoded, err := encode("FlateDecode", data, params)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					decoded, err := decode("FlateDecode", encoded, params)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if !bytes.Equal(decoded, data) {
+						t.Errorf("%v: expected %v, got %v", params, data, decoded)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPredictorErrors(t *testing.T) {
+	for i, params := range []Dictionary{
+		Dictionary{"Predictor": Integer(3)},
+		Dictionary{"Predictor": Integer(12), "Colors": Integer(0)},
+		Dictionary{"Predictor": Integer(12), "BitsPerComponent": Integer(3)},
+		Dictionary{"Predictor": Integer(12), "Columns": Integer(0)},
+		Dictionary{"Predictor": Real(12)},
+	} {
+		_, err := unpredict([]byte{0, 0}, params)
+		if err == nil {
+			t.Errorf("%d: expected an error for %v", i, params)
+		}
+	}
+
+	// PNG filter type 5 does not exist
+	_, err := unpredict([]byte{5, 0}, Dictionary{"Predictor": Integer(10)})
+	if err == nil {
+		t.Error("expected an error for an unknown PNG filter type")
+	}
+}
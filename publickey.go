@@ -0,0 +1,443 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+// ErrRecipient is returned when a file encrypted with the public-key
+// security handler is opened with a certificate that is not one of
+// its recipients.
+var ErrRecipient = errors.New("certificate is not a recipient")
+
+// OpenWithCertificate opens a PDF file that may be encrypted with the
+// public-key security handler (§7.6.5) for the certificate, whose
+// private key decrypts the file encryption key.
+// Strings and streams read with Get are decrypted.
+// ErrRecipient is returned when the certificate is not a recipient.
+func OpenWithCertificate(filename string, cert *x509.Certificate, key crypto.Decrypter) (*File, error) {
+	file := &File{filename: filename}
+	err := file.open()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file.Encrypt) != 0 {
+		file.security, err = newPublicKeySecurityHandler(file.Encrypt, cert, key)
+		if err != nil {
+			err2 := file.Close()
+			if err2 != nil {
+				return nil, fmt.Errorf("%v %v", err, err2)
+			}
+			return nil, err
+		}
+		file.saveSecurity = file.security
+	}
+
+	return file, nil
+}
+
+// creates the public-key security handler for an encryption
+// dictionary, decrypting the seed with the recipient's key
+func newPublicKeySecurityHandler(encrypt Dictionary, cert *x509.Certificate, key crypto.Decrypter) (*securityHandler, error) {
+	if filter, _ := encrypt["Filter"].(Name); filter != "Adobe.PubSec" {
+		return nil, fmt.Errorf("the %s security handler is not supported with certificates", filter)
+	}
+
+	h, n, err := parseEncryption(encrypt)
+	if err != nil {
+		return nil, err
+	}
+	// recipients are not told apart by the handler
+	h.owner = true
+
+	// the recipients are in the encryption dictionary,
+	// or in the crypt filters for adbe.pkcs7.s5 (§7.6.5.2)
+	recipients, _ := encrypt["Recipients"].(Array)
+	subFilter, _ := encrypt["SubFilter"].(Name)
+	switch subFilter {
+	case "adbe.pkcs7.s3", "adbe.pkcs7.s4":
+	case "adbe.pkcs7.s5":
+		cf, _ := encrypt["CF"].(Dictionary)
+		for _, name := range []Name{h.stmF, h.strF, h.eff} {
+			filter, ok := cf[name].(Dictionary)
+			if !ok {
+				continue
+			}
+			recipients, _ = filter["Recipients"].(Array)
+			if encryptMetadata, ok := filter["EncryptMetadata"].(Boolean); ok {
+				h.encryptMetadata = bool(encryptMetadata)
+			}
+			break
+		}
+	default:
+		return nil, fmt.Errorf("the %s public-key security handler is not supported", subFilter)
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("the encryption dictionary does not have any recipients")
+	}
+
+	var seed []byte
+	for _, recipient := range recipients {
+		envelope, ok := recipient.(String)
+		if !ok {
+			return nil, fmt.Errorf("recipients must be strings, not %T", recipient)
+		}
+		content, err := openEnvelope(envelope, cert, key)
+		if err == ErrRecipient {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// a 20 byte seed followed by the permissions
+		if len(content) < 24 {
+			return nil, errors.New("recipient data is too short")
+		}
+		seed = content[:20]
+		h.p = binary.BigEndian.Uint32(content[20:24])
+		break
+	}
+	if seed == nil {
+		return nil, ErrRecipient
+	}
+
+	h.key = h.publicKey(seed, recipients, n)
+	return h, nil
+}
+
+// the file encryption key from the seed and recipients (§7.6.5.3),
+// using SHA-256 for AES-256
+func (h *securityHandler) publicKey(seed []byte, recipients Array, n int) []byte {
+	var digest hash.Hash
+	if h.v == 5 {
+		digest = sha256.New()
+		n = 32
+	} else {
+		digest = sha1.New()
+	}
+
+	digest.Write(seed)
+	for _, recipient := range recipients {
+		envelope, _ := recipient.(String)
+		digest.Write([]byte(envelope))
+	}
+	if !h.encryptMetadata {
+		digest.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	return digest.Sum(nil)[:n]
+}
+
+// creates the public-key security handler and encryption
+// dictionary that encrypt a file for the recipients
+func newPublicKeyEncryption(e *Encryption) (*securityHandler, Dictionary, error) {
+	h := &securityHandler{
+		owner:           true,
+		encryptMetadata: !e.UnencryptedMetadata,
+		stmF:            "DefaultCryptFilter",
+		strF:            "DefaultCryptFilter",
+		eff:             "DefaultCryptFilter",
+		filters:         map[Name]Name{"Identity": "None"},
+	}
+	// the reserved bits are set (§7.6.4.2 Table 22)
+	h.p = uint32(e.Permissions)&0xf3c | 0xfffff0c0
+
+	var n int
+	var method Name
+	switch e.Algorithm {
+	case AES128:
+		h.v, n, method = 4, 16, "AESV2"
+	case AES256:
+		h.v, n, method = 5, 32, "AESV3"
+	default:
+		return nil, nil, fmt.Errorf("unknown encryption algorithm %d", e.Algorithm)
+	}
+	h.filters["DefaultCryptFilter"] = method
+
+	content := make([]byte, 24)
+	_, err := rand.Read(content[:20])
+	if err != nil {
+		return nil, nil, err
+	}
+	binary.BigEndian.PutUint32(content[20:], h.p)
+
+	recipients := Array{}
+	for _, cert := range e.Recipients {
+		envelope, err := sealEnvelope(content, cert)
+		if err != nil {
+			return nil, nil, err
+		}
+		recipients = append(recipients, String(envelope))
+	}
+	h.key = h.publicKey(content[:20], recipients, n)
+
+	encrypt := Dictionary{
+		"Filter":    Name("Adobe.PubSec"),
+		"SubFilter": Name("adbe.pkcs7.s5"),
+		"V":         Integer(h.v),
+		"Length":    Integer(n * 8),
+		"CF": Dictionary{"DefaultCryptFilter": Dictionary{
+			"CFM":        method,
+			"AuthEvent":  Name("DocOpen"),
+			"Length":     Integer(n),
+			"Recipients": recipients,
+		}},
+		"StmF": Name("DefaultCryptFilter"),
+		"StrF": Name("DefaultCryptFilter"),
+	}
+	if !h.encryptMetadata {
+		encrypt["EncryptMetadata"] = Boolean(false)
+	}
+	return h, encrypt, nil
+}
+
+// CMS enveloped data (RFC 5652 section 6)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidDESEDE3CBC    = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidAES128CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue // [0] EXPLICIT
+}
+
+type envelopedData struct {
+	Version              int
+	OriginatorInfo       asn1.RawValue   `asn1:"optional,tag:0"`
+	RecipientInfos       []asn1.RawValue `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type keyTransRecipientInfo struct {
+	Version                int
+	RecipientIdentifier    asn1.RawValue
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// decrypts the content of an enveloped data content info,
+// returning ErrRecipient when it is not for the certificate
+func openEnvelope(der []byte, cert *x509.Certificate, key crypto.Decrypter) ([]byte, error) {
+	var info contentInfo
+	_, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, err
+	}
+	if !info.ContentType.Equal(oidEnvelopedData) {
+		return nil, fmt.Errorf("recipient content type %v is not enveloped data", info.ContentType)
+	}
+	var enveloped envelopedData
+	_, err = asn1.Unmarshal(info.Content.Bytes, &enveloped)
+	if err != nil {
+		return nil, err
+	}
+
+	var contentKey []byte
+	for _, raw := range enveloped.RecipientInfos {
+		// only key transport recipients are supported
+		if raw.Class != asn1.ClassUniversal || raw.Tag != asn1.TagSequence {
+			continue
+		}
+		var recipient keyTransRecipientInfo
+		_, err := asn1.Unmarshal(raw.FullBytes, &recipient)
+		if err != nil {
+			return nil, err
+		}
+		if !identifies(recipient.RecipientIdentifier, cert) {
+			continue
+		}
+
+		if !recipient.KeyEncryptionAlgorithm.Algorithm.Equal(oidRSAEncryption) {
+			return nil, fmt.Errorf("key encryption algorithm %v is not supported", recipient.KeyEncryptionAlgorithm.Algorithm)
+		}
+		contentKey, err = key.Decrypt(rand.Reader, recipient.EncryptedKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if contentKey == nil {
+		return nil, ErrRecipient
+	}
+
+	return decryptContent(enveloped.EncryptedContentInfo, contentKey)
+}
+
+// recipients and signers are identified by issuer and
+// serial number, or by subject key identifier
+func identifies(id asn1.RawValue, cert *x509.Certificate) bool {
+	if id.Class == asn1.ClassContextSpecific && id.Tag == 0 {
+		return len(cert.SubjectKeyId) > 0 && bytes.Equal(id.Bytes, cert.SubjectKeyId)
+	}
+
+	var serial issuerAndSerialNumber
+	_, err := asn1.Unmarshal(id.FullBytes, &serial)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(serial.Issuer.FullBytes, cert.RawIssuer) && serial.SerialNumber.Cmp(cert.SerialNumber) == 0
+}
+
+func decryptContent(info encryptedContentInfo, key []byte) ([]byte, error) {
+	// the encrypted content may be split into several octet strings
+	encrypted := info.EncryptedContent.Bytes
+	if info.EncryptedContent.IsCompound {
+		encrypted = []byte{}
+		rest := info.EncryptedContent.Bytes
+		for len(rest) > 0 {
+			var part []byte
+			var err error
+			rest, err = asn1.Unmarshal(rest, &part)
+			if err != nil {
+				return nil, err
+			}
+			encrypted = append(encrypted, part...)
+		}
+	}
+
+	var block cipher.Block
+	var err error
+	algorithm := info.ContentEncryptionAlgorithm.Algorithm
+	switch {
+	case algorithm.Equal(oidAES128CBC), algorithm.Equal(oidAES192CBC), algorithm.Equal(oidAES256CBC):
+		block, err = aes.NewCipher(key)
+	case algorithm.Equal(oidDESEDE3CBC):
+		block, err = des.NewTripleDESCipher(key)
+	default:
+		return nil, fmt.Errorf("content encryption algorithm %v is not supported", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var iv []byte
+	_, err = asn1.Unmarshal(info.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() || len(encrypted) == 0 || len(encrypted)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid encrypted content")
+	}
+
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, encrypted)
+
+	padding := int(decrypted[len(decrypted)-1])
+	if padding == 0 || padding > block.BlockSize() {
+		return nil, errors.New("invalid content padding")
+	}
+	return decrypted[:len(decrypted)-padding], nil
+}
+
+// encrypts content for the certificate with AES-256
+// and returns the enveloped data content info
+func sealEnvelope(content []byte, cert *x509.Certificate) ([]byte, error) {
+	public, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%T recipient keys are not supported", cert.PublicKey)
+	}
+
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	_, err := rand.Read(key)
+	if err == nil {
+		_, err = rand.Read(iv)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := rsa.EncryptPKCS1v15(rand.Reader, public, key)
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := asn1.Marshal(issuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+		SerialNumber: cert.SerialNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := asn1.Marshal(keyTransRecipientInfo{
+		RecipientIdentifier: asn1.RawValue{FullBytes: serialNumber},
+		KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidRSAEncryption,
+			Parameters: asn1.NullRawValue,
+		},
+		EncryptedKey: encryptedKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS#7 padding
+	padding := aes.BlockSize - len(content)%aes.BlockSize
+	encrypted := append(append([]byte{}, content...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	ivParameter, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	enveloped, err := asn1.Marshal(envelopedData{
+		RecipientInfos: []asn1.RawValue{{FullBytes: recipient}},
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType: oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidAES256CBC,
+				Parameters: asn1.RawValue{FullBytes: ivParameter},
+			},
+			EncryptedContent: asn1.RawValue{
+				Class: asn1.ClassContextSpecific,
+				Tag:   0,
+				Bytes: encrypted,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidEnvelopedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: enveloped},
+	})
+}
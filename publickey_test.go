@@ -0,0 +1,173 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// creates a self-signed certificate and its key
+func testCertificate(t *testing.T, name string, serialNumber int64) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestPublicKeyEncryption(t *testing.T) {
+	alice, aliceKey := testCertificate(t, "alice", 1)
+	bob, bobKey := testCertificate(t, "bob", 2)
+	eve, eveKey := testCertificate(t, "eve", 3)
+
+	for _, test := range []struct {
+		name       string
+		encryption Encryption
+	}{
+		{"AES-128", Encryption{Algorithm: AES128}},
+		{"AES-256", Encryption{Algorithm: AES256}},
+		{"AES-256 unencrypted metadata", Encryption{Algorithm: AES256, UnencryptedMetadata: true}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "encrypted.pdf")
+			file, err := Create(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Root, err = file.Add(Dictionary{"Type": Name("Catalog"), "Title": String("Secret title")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			content := []byte("BT (Secret content) Tj ET")
+			contentRef, err := file.Add(Stream{Dictionary: Dictionary{}, Stream: content})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			encryption := test.encryption
+			encryption.Recipients = []*x509.Certificate{alice, bob}
+			encryption.Permissions = PermissionPrint
+			err = file.SetEncryption(&encryption)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = file.Save()
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Close()
+
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("Secret")) {
+				t.Error("file was saved unencrypted")
+			}
+
+			_, err = OpenWithCertificate(filename, eve, eveKey)
+			if err != ErrRecipient {
+				t.Errorf("expected ErrRecipient, got %v", err)
+			}
+			_, err = Open(filename)
+			if err == nil {
+				t.Error("expected an error opening without a certificate")
+			}
+
+			for _, recipient := range []struct {
+				cert *x509.Certificate
+				key  *rsa.PrivateKey
+			}{{alice, aliceKey}, {bob, bobKey}} {
+				name := recipient.cert.Subject.CommonName
+				file, err := OpenWithCertificate(filename, recipient.cert, recipient.key)
+				if err != nil {
+					t.Fatal(name, err)
+				}
+				defer file.Close()
+
+				if file.security.p&0xf3c != uint32(PermissionPrint) {
+					t.Errorf("%s: permissions are %#x", name, file.security.p)
+				}
+				catalog := file.Get(file.Root).(Dictionary)
+				if str(catalog["Title"]) != "Secret title" {
+					t.Errorf("%s: title decrypted to %q", name, catalog["Title"])
+				}
+				decoded, err := file.Get(contentRef).(Stream).Decode()
+				if err != nil {
+					t.Fatal(name, err)
+				}
+				if !bytes.Equal(decoded, content) {
+					t.Errorf("%s: content decoded to %q", name, decoded)
+				}
+			}
+		})
+	}
+}
+
+// adbe.pkcs7.s4 has the recipients in the encryption dictionary
+func TestOpenWithCertificateRC4(t *testing.T) {
+	cert, key := testCertificate(t, "recipient", 1)
+
+	content := make([]byte, 24)
+	copy(content, "twenty byte seed....")
+	content[20], content[21], content[22], content[23] = 0xff, 0xff, 0xf0, 0xc4
+	envelope, err := sealEnvelope(content, cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypt := Dictionary{
+		"Filter":     Name("Adobe.PubSec"),
+		"SubFilter":  Name("adbe.pkcs7.s4"),
+		"V":          Integer(2),
+		"Length":     Integer(128),
+		"Recipients": Array{String(envelope)},
+	}
+
+	h := &securityHandler{v: 2, encryptMetadata: true, filters: map[Name]Name{"StdCF": "V2"}}
+	h.key = h.publicKey(content[:20], encrypt["Recipients"].(Array), 16)
+	ref := ObjectReference{ObjectNumber: 1}
+	title := testEncrypt(t, h, "StdCF", ref, []byte("Secret title"))
+
+	filename := filepath.Join(t.TempDir(), "encrypted.pdf")
+	writeTestPDF(t, filename, []Object{
+		Dictionary{"Type": Name("Catalog"), "Title": String(title)},
+		encrypt,
+	}, Dictionary{"Root": ref, "Encrypt": ObjectReference{ObjectNumber: 2}})
+
+	file, err := OpenWithCertificate(filename, cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	catalog := file.Get(ref).(Dictionary)
+	if str(catalog["Title"]) != "Secret title" {
+		t.Errorf("title decrypted to %q", catalog["Title"])
+	}
+	if file.security.p != 0xfffff0c4 {
+		t.Errorf("permissions are %#x", file.security.p)
+	}
+}
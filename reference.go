@@ -76,8 +76,21 @@ func (file *File) loadReferences() error {
 		file.Root = root.(ObjectReference)
 	}
 
+	// the encryption dictionary is often an indirect object,
+	// whose strings are not encrypted (§7.6.1)
 	if encrypt, ok := trailer[Name("Encrypt")]; ok {
-		file.Encrypt = encrypt.(Dictionary)
+		if ref, ok := encrypt.(ObjectReference); ok {
+			file.encryptRef = ref
+			encrypt = file.Get(ref)
+		}
+		switch encrypt := encrypt.(type) {
+		case Dictionary:
+			file.Encrypt = encrypt
+		case Null:
+			return fmt.Errorf("could not get the encryption dictionary: %v", encrypt.Error)
+		default:
+			return fmt.Errorf("Encrypt must be a dictionary, not %T", encrypt)
+		}
 	}
 
 	if info, ok := trailer[Name("Info")]; ok {
@@ -285,34 +298,34 @@ func parseXrefBlock(slice []byte) (crossReferences, int) {
 
 	// object number
 	token, n := nextToken(slice[i:])
-	objectNumber, err := strconv.ParseUint(string(token), 10, 64)
-	if err != nil {
-		log.Fatalln(err)
+	objectNumber, ok := parseUint(token)
+	if !ok {
+		log.Fatalln("invalid object number:", string(token))
 	}
 	i += n
 
 	// number of objects
 	token, n = nextToken(slice[i:])
-	nObjects, err := strconv.ParseUint(string(token), 10, 64)
-	if err != nil {
-		log.Fatalln(err)
+	nObjects, ok := parseUint(token)
+	if !ok {
+		log.Fatalln("invalid number of objects:", string(token))
 	}
 	i += n
 
 	for j := 0; j < int(nObjects); j++ {
 		// offset
 		token, n = nextToken(slice[i:])
-		offset, err := strconv.ParseUint(string(token), 10, 64)
-		if err != nil {
-			log.Fatalln(err)
+		offset, ok := parseUint(token)
+		if !ok {
+			log.Fatalln("invalid offset:", string(token))
 		}
 		i += n
 
 		// generation number
 		token, n = nextToken(slice[i:])
-		generation, err := strconv.ParseUint(string(token), 10, 64)
-		if err != nil {
-			log.Fatalln(err)
+		generation, ok := parseUint(token)
+		if !ok {
+			log.Fatalln("invalid generation number:", string(token))
 		}
 		i += n
 
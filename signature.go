@@ -0,0 +1,776 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha512" // SHA-384 and SHA-512 digests
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// digital signatures (§12.8)
+
+// SignatureFormat is the encoding of a signature's Contents,
+// which is named by the SubFilter of the signature dictionary (§12.8.3).
+type SignatureFormat Name
+
+const (
+	// A detached CMS signature of the signed bytes (§12.8.3.3)
+	PKCS7Detached SignatureFormat = "adbe.pkcs7.detached"
+
+	// A detached CMS signature following CAdES,
+	// as required for PAdES signatures (§12.8.3.4)
+	CAdESDetached SignatureFormat = "ETSI.CAdES.detached"
+)
+
+// Signature describes a digital signature added by Sign.
+type Signature struct {
+	// Signs the digest of the signed attributes.
+	// RSA and ECDSA keys are supported.
+	Signer crypto.Signer
+
+	// The signer's certificate, whose public key is the Signer's,
+	// and the intermediate certificates needed to validate it.
+	Certificate *x509.Certificate
+	Chain       []*x509.Certificate
+
+	// PKCS7Detached when empty
+	Format SignatureFormat
+
+	// SHA-256 when zero
+	Hash crypto.Hash
+
+	// The name of the new signature field,
+	// "Signature" followed by a number when empty.
+	FieldName string
+
+	// The page the signature's invisible widget annotation is on,
+	// the first page when zero.
+	Page ObjectReference
+
+	// Optional information about the signing
+	Name        string
+	Reason      string
+	Location    string
+	ContactInfo string
+
+	// The signing time, the current time when zero.
+	Time time.Time
+
+	// When non-zero, the signature certifies the document and
+	// permits the changes of the DocMDP permission level (§12.8.2.2):
+	// 1 for none, 2 for filling in forms and signing, and 3 for
+	// annotating as well.
+	DocMDP int
+
+	// The names of the fields that are locked by the signature with
+	// a FieldMDP transform (§12.8.2.4).
+	LockedFields []string
+
+	// When set, the signature is timestamped by a time-stamping
+	// authority, as required for PAdES B-T signatures.
+	Timestamper Timestamper
+
+	// The number of bytes reserved for the CMS signature,
+	// 8192 when zero, or 16384 with a Timestamper.
+	Size int
+}
+
+// Sign adds a signature field with a signature of the whole file to
+// the catalog's interactive form. The added and changed objects are
+// saved as with Save, after which the file is signed in place.
+// Objects added or changed after Sign must be saved by later calls to
+// Save, which appends them without invalidating the signature.
+//
+// The signature dictionary is saved with space reserved for the
+// signature, so an error is returned after saving when the
+// signature does not fit in the Size reserved for it.
+func (f *File) Sign(s *Signature) error {
+	if s.Signer == nil || s.Certificate == nil {
+		return errors.New("a signature needs a signer and its certificate")
+	}
+	format := s.Format
+	if format == "" {
+		format = PKCS7Detached
+	}
+	if format != PKCS7Detached && format != CAdESDetached {
+		return fmt.Errorf("signature format %s is not supported", format)
+	}
+	hash := s.Hash
+	if hash == 0 {
+		hash = crypto.SHA256
+	}
+	if _, ok := digestAlgorithms[hash]; !ok || !hash.Available() {
+		return fmt.Errorf("hash %v is not supported", hash)
+	}
+	size := s.Size
+	if size == 0 {
+		size = 8192
+		if s.Timestamper != nil {
+			size = 16384
+		}
+	}
+	signingTime := s.Time
+	if signingTime.IsZero() {
+		signingTime = time.Now()
+	}
+
+	if s.DocMDP < 0 || s.DocMDP > 3 {
+		return fmt.Errorf("DocMDP permission level %d is not 1, 2 or 3", s.DocMDP)
+	}
+
+	// the signature dictionary (§12.8.1)
+	sig := Dictionary{
+		"Type":      Name("Sig"),
+		"Filter":    Name("Adobe.PPKLite"),
+		"SubFilter": Name(format),
+		"M":         dateString(signingTime),
+	}
+	for key, value := range map[Name]string{
+		"Name":        s.Name,
+		"Reason":      s.Reason,
+		"Location":    s.Location,
+		"ContactInfo": s.ContactInfo,
+	} {
+		if value != "" {
+			sig[key] = newTextString(value)
+		}
+	}
+
+	// signature references (§12.8.1)
+	references := Array{}
+	if s.DocMDP != 0 {
+		references = append(references, Dictionary{
+			"Type":            Name("SigRef"),
+			"TransformMethod": Name("DocMDP"),
+			"TransformParams": Dictionary{
+				"Type": Name("TransformParams"),
+				"P":    Integer(s.DocMDP),
+				"V":    Name("1.2"),
+			},
+		})
+	}
+	if len(s.LockedFields) != 0 {
+		lockedFields := Array{}
+		for _, name := range s.LockedFields {
+			lockedFields = append(lockedFields, newTextString(name))
+		}
+		references = append(references, Dictionary{
+			"Type":            Name("SigRef"),
+			"TransformMethod": Name("FieldMDP"),
+			// the catalog of the signed document (Table 253)
+			"Data": f.Root,
+			"TransformParams": Dictionary{
+				"Type":   Name("TransformParams"),
+				"Action": Name("Include"),
+				"Fields": lockedFields,
+				"V":      Name("1.2"),
+			},
+		})
+	}
+	if len(references) != 0 {
+		sig["Reference"] = references
+	}
+
+	return f.addSignature(sig, signing{
+		fieldName: s.FieldName,
+		page:      s.Page,
+		certify:   s.DocMDP != 0,
+		size:      size,
+		hash:      hash,
+		sign: func(digest []byte) ([]byte, error) {
+			return signCMS(digest, hash, format, s, signingTime)
+		},
+	})
+}
+
+// how addSignature signs the file
+type signing struct {
+	fieldName string
+	page      ObjectReference
+	certify   bool
+	size      int
+	hash      crypto.Hash
+
+	// returns the signature of the digest of the signed bytes
+	sign func(digest []byte) ([]byte, error)
+}
+
+// adds a signature field with the signature dictionary, saves the
+// file and then fills in the signature of the file's bytes
+func (f *File) addSignature(sig Dictionary, s signing) error {
+	catalog, ok := f.Get(f.Root).(Dictionary)
+	if !ok {
+		return errors.New("the file does not have a catalog")
+	}
+	catalogChanged := false
+
+	// the interactive form, which may be indirect
+	acroForm := Dictionary{}
+	acroFormRef, indirect := catalog["AcroForm"].(ObjectReference)
+	if indirect {
+		acroForm, ok = f.Get(acroFormRef).(Dictionary)
+		if !ok {
+			return errors.New("AcroForm is not a dictionary")
+		}
+	} else if dict, ok := catalog["AcroForm"].(Dictionary); ok {
+		acroForm = dict
+	}
+
+	fields, fieldsRef, err := f.array(acroForm["Fields"])
+	if err != nil {
+		return err
+	}
+	fieldName := s.fieldName
+	if fieldName == "" {
+		fieldName = fmt.Sprintf("Signature%d", len(fields)+1)
+	}
+	for _, field := range fields {
+		if ref, ok := field.(ObjectReference); ok {
+			field = f.Get(ref)
+		}
+		if dict, ok := field.(Dictionary); ok {
+			if name, ok := dict["T"].(String); ok && textString(name) == fieldName {
+				return fmt.Errorf("the form already has a field named %s", fieldName)
+			}
+		}
+	}
+
+	var perms Dictionary
+	if s.certify {
+		// the permissions are made direct
+		perms = Dictionary{}
+		switch typed := catalog["Perms"].(type) {
+		case Dictionary:
+			perms = typed
+		case ObjectReference:
+			if dict, ok := f.Get(typed).(Dictionary); ok {
+				for key, value := range dict {
+					perms[key] = value
+				}
+			}
+		}
+		if _, ok := perms["DocMDP"]; ok {
+			return errors.New("the document is already certified")
+		}
+	}
+
+	sig["ByteRange"] = byteRangePlaceholder{}
+	sig["Contents"] = contentsPlaceholder(s.size)
+	sigRef, err := f.Add(sig)
+	if err != nil {
+		return err
+	}
+	if s.certify {
+		perms["DocMDP"] = sigRef
+		catalog["Perms"] = perms
+		catalogChanged = true
+	}
+
+	// a signature field merged with its invisible widget annotation (§12.7.5.5)
+	widget := Dictionary{
+		"FT":      Name("Sig"),
+		"T":       newTextString(fieldName),
+		"V":       sigRef,
+		"Type":    Name("Annot"),
+		"Subtype": Name("Widget"),
+		"Rect":    Array{Integer(0), Integer(0), Integer(0), Integer(0)},
+		"F":       Integer(132), // Print and Locked
+	}
+	pageRef := s.page
+	if pageRef.ObjectNumber == 0 {
+		pageRef = f.firstPage(catalog)
+	}
+	var page Dictionary
+	if pageRef.ObjectNumber != 0 {
+		page, ok = f.Get(pageRef).(Dictionary)
+		if !ok {
+			return errors.New("the signature's page is not a dictionary")
+		}
+		widget["P"] = pageRef
+	}
+	widgetRef, err := f.Add(widget)
+	if err != nil {
+		return err
+	}
+
+	if page != nil {
+		annots, annotsRef, err := f.array(page["Annots"])
+		if err != nil {
+			return err
+		}
+		annots = append(annots, widgetRef)
+		if annotsRef.ObjectNumber != 0 {
+			_, err = f.Add(IndirectObject{annotsRef, annots})
+		} else {
+			page["Annots"] = annots
+			_, err = f.Add(IndirectObject{pageRef, page})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	fields = append(fields, widgetRef)
+	if fieldsRef.ObjectNumber != 0 {
+		_, err = f.Add(IndirectObject{fieldsRef, fields})
+		if err != nil {
+			return err
+		}
+	} else {
+		acroForm["Fields"] = fields
+	}
+	// SignaturesExist and AppendOnly (§12.7.3)
+	flags, _ := acroForm["SigFlags"].(Integer)
+	acroForm["SigFlags"] = flags | 3
+	if indirect {
+		_, err = f.Add(IndirectObject{acroFormRef, acroForm})
+		if err != nil {
+			return err
+		}
+	} else {
+		catalog["AcroForm"] = acroForm
+		catalogChanged = true
+	}
+	if catalogChanged {
+		_, err = f.Add(IndirectObject{f.Root, catalog})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = f.Save()
+	if err != nil {
+		return err
+	}
+
+	err = f.signSaved(s)
+	if err != nil {
+		return err
+	}
+
+	// the signature dictionary now has the signature
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	f.created = false
+	return f.open()
+}
+
+// returns the elements of an array that may be indirect,
+// with the reference when it is
+func (f *File) array(obj Object) (Array, ObjectReference, error) {
+	switch typed := obj.(type) {
+	case nil:
+		return Array{}, ObjectReference{}, nil
+	case Array:
+		return typed, ObjectReference{}, nil
+	case ObjectReference:
+		array, ok := f.Get(typed).(Array)
+		if !ok {
+			return nil, typed, fmt.Errorf("%v is not an array", typed)
+		}
+		return array, typed, nil
+	}
+	return nil, ObjectReference{}, fmt.Errorf("expected an array, got %T", obj)
+}
+
+// the first leaf of the page tree (§7.7.3), or the zero reference
+func (f *File) firstPage(catalog Dictionary) ObjectReference {
+	ref, _ := catalog["Pages"].(ObjectReference)
+	for depth := 0; ref.ObjectNumber != 0 && depth < 64; depth++ {
+		node, ok := f.Get(ref).(Dictionary)
+		if !ok {
+			break
+		}
+		if node["Type"] == Name("Page") {
+			return ref
+		}
+		kids, _, err := f.array(node["Kids"])
+		if err != nil || len(kids) == 0 {
+			break
+		}
+		ref, _ = kids[0].(ObjectReference)
+	}
+	return ObjectReference{}
+}
+
+// dates are (D:YYYYMMDDHHmmSSOHH'mm) (§7.9.4)
+func dateString(t time.Time) String {
+	return String(t.Format("D:20060102150405-07'00'"))
+}
+
+// byte ranges are written with 10 digit offsets,
+// like those in cross-reference tables
+var byteRangePlaceholderBytes = []byte("[0 0000000000 0000000000 0000000000]")
+
+// reserves space in the saved signature dictionary for the ByteRange
+type byteRangePlaceholder struct{}
+
+func (byteRangePlaceholder) writeTo(w io.Writer) (int64, error) {
+	n, err := w.Write(byteRangePlaceholderBytes)
+	return int64(n), err
+}
+
+// reserves space in the saved signature dictionary for
+// a hexadecimal string with the number of bytes
+type contentsPlaceholder int
+
+func (c contentsPlaceholder) writeTo(w io.Writer) (int64, error) {
+	n, err := w.Write(c.bytes())
+	return int64(n), err
+}
+
+func (c contentsPlaceholder) bytes() []byte {
+	return []byte("<" + strings.Repeat("0", 2*int(c)) + ">")
+}
+
+// fills in the placeholders of the saved signature dictionary,
+// signing all of the file's bytes except those of the Contents
+func (f *File) signSaved(s signing) error {
+	data, err := ioutil.ReadFile(f.filename)
+	if err != nil {
+		return err
+	}
+
+	byteRange := bytes.LastIndex(data, byteRangePlaceholderBytes)
+	contents := bytes.LastIndex(data, append([]byte("/Contents "), contentsPlaceholder(s.size).bytes()...))
+	if byteRange == -1 || contents == -1 {
+		return errors.New("saved signature dictionary was not found")
+	}
+	start := contents + len("/Contents ")
+	end := start + len(contentsPlaceholder(s.size).bytes())
+
+	ranges := []byte(fmt.Sprintf("[0 %d %d %d]", start, end, len(data)-end))
+	if len(ranges) > len(byteRangePlaceholderBytes) {
+		return errors.New("file is too large to sign")
+	}
+	ranges = append(ranges, bytes.Repeat([]byte{' '}, len(byteRangePlaceholderBytes)-len(ranges))...)
+	copy(data[byteRange:], ranges)
+
+	digest := s.hash.New()
+	digest.Write(data[:start])
+	digest.Write(data[end:])
+
+	signed, err := s.sign(digest.Sum(nil))
+	if err != nil {
+		return err
+	}
+	if len(signed) > s.size {
+		return fmt.Errorf("signature needs %d bytes, but only %d were reserved", len(signed), s.size)
+	}
+
+	file, err := os.OpenFile(f.filename, os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteAt(ranges, int64(byteRange))
+	if err == nil {
+		_, err = file.WriteAt([]byte(hex.EncodeToString(signed)), int64(start+1))
+	}
+	err2 := file.Close()
+	if err == nil {
+		err = err2
+	}
+	return err
+}
+
+// CMS signed data (RFC 5652 section 5)
+
+var (
+	oidSignedData            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSigningCertificateV2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidTimeStampToken        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidTSTInfo               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidECDSAWithSHA256       = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384       = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512       = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidSHA256                = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384                = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512                = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	ecdsaSignatureAlgorithms = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA256: oidECDSAWithSHA256,
+		crypto.SHA384: oidECDSAWithSHA384,
+		crypto.SHA512: oidECDSAWithSHA512,
+	}
+	digestAlgorithms = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA256: oidSHA256,
+		crypto.SHA384: oidSHA384,
+		crypto.SHA512: oidSHA512,
+	}
+)
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"` // [0] EXPLICIT
+}
+
+type signerInfo struct {
+	Version            int
+	SignerIdentifier   asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// ESS signing certificate v2 (RFC 5035)
+type signingCertificateV2 struct {
+	Certificates []essCertIDv2
+}
+
+type essCertIDv2 struct {
+	HashAlgorithm pkix.AlgorithmIdentifier `asn1:"optional"` // SHA-256 when absent
+	CertHash      []byte
+	IssuerSerial  issuerSerial `asn1:"optional"`
+}
+
+type issuerSerial struct {
+	Issuer       asn1.RawValue // GeneralNames
+	SerialNumber *big.Int
+}
+
+// creates the detached signed data content info for the digest
+func signCMS(digest []byte, hash crypto.Hash, format SignatureFormat, s *Signature, signingTime time.Time) ([]byte, error) {
+	attributes := []attribute{}
+	var err error
+	switch format {
+	case PKCS7Detached:
+		err = addAttribute(&attributes, oidSigningTime, signingTime.UTC())
+	case CAdESDetached:
+		// the signing time is M in the signature dictionary, and
+		// the certificate is protected by the signature
+		err = addSigningCertificate(&attributes, s.Certificate)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	certificates := append([]*x509.Certificate{s.Certificate}, s.Chain...)
+	return signData(oidData, nil, digest, hash, attributes, s.Signer, certificates, s.Timestamper)
+}
+
+// creates a signed data content info with one signer, the first of
+// the certificates, whose signed attributes are the content type,
+// the digest of the content and the others. The content is
+// encapsulated unless it is nil. With a timestamper, the signature
+// is timestamped (RFC 3161 appendix A).
+func signData(contentType asn1.ObjectIdentifier, content, digest []byte, hash crypto.Hash, others []attribute, signer crypto.Signer, certificates []*x509.Certificate, timestamper Timestamper) ([]byte, error) {
+	digestAlgorithm := pkix.AlgorithmIdentifier{Algorithm: digestAlgorithms[hash]}
+
+	attributes := append([]attribute{}, others...)
+	err := addAttribute(&attributes, oidContentType, contentType)
+	if err == nil {
+		err = addAttribute(&attributes, oidMessageDigest, digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signedAttributes, err := marshalSet(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	// the signed attributes are signed with their SET tag
+	signedSet, err := asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      signedAttributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	attributesHash := hash.New()
+	attributesHash.Write(signedSet)
+
+	var signatureAlgorithm pkix.AlgorithmIdentifier
+	switch signer.Public().(type) {
+	case *rsa.PublicKey:
+		signatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		signatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: ecdsaSignatureAlgorithms[hash]}
+	default:
+		return nil, fmt.Errorf("%T signing keys are not supported", signer.Public())
+	}
+	signature, err := signer.Sign(rand.Reader, attributesHash.Sum(nil), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	var unsignedAttributes asn1.RawValue
+	if timestamper != nil {
+		signatureHash := hash.New()
+		signatureHash.Write(signature)
+		token, err := timestamper.Timestamp(signatureHash.Sum(nil), hash)
+		if err != nil {
+			return nil, err
+		}
+		unsigned := []attribute{{
+			Type:   oidTimeStampToken,
+			Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: token},
+		}}
+		encoded, err := marshalSet(unsigned)
+		if err != nil {
+			return nil, err
+		}
+		unsignedAttributes = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: encoded}
+	}
+
+	sid, err := asn1.Marshal(issuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: certificates[0].RawIssuer},
+		SerialNumber: certificates[0].SerialNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encodedCertificates := []byte{}
+	for _, cert := range certificates {
+		encodedCertificates = append(encodedCertificates, cert.Raw...)
+	}
+
+	encapsulated := encapsulatedContentInfo{ContentType: contentType}
+	if content != nil {
+		octets, err := asn1.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		encapsulated.Content = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: octets}
+	}
+
+	signed, err := asn1.Marshal(signedData{
+		Version:          signedDataVersion(contentType),
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithm},
+		EncapContentInfo: encapsulated,
+		Certificates: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      encodedCertificates,
+		},
+		SignerInfos: []signerInfo{{
+			Version:          1,
+			SignerIdentifier: asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm:  digestAlgorithm,
+			SignedAttributes: asn1.RawValue{
+				Class:      asn1.ClassContextSpecific,
+				Tag:        0,
+				IsCompound: true,
+				Bytes:      signedAttributes,
+			},
+			SignatureAlgorithm: signatureAlgorithm,
+			Signature:          signature,
+			UnsignedAttributes: unsignedAttributes,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed},
+	})
+}
+
+// signed data with content other than data is version 3
+// (RFC 5652 section 5.1)
+func signedDataVersion(contentType asn1.ObjectIdentifier) int {
+	if contentType.Equal(oidData) {
+		return 1
+	}
+	return 3
+}
+
+// adds the ESS signing certificate v2 attribute (RFC 5035)
+func addSigningCertificate(attributes *[]attribute, cert *x509.Certificate) error {
+	certHash := crypto.SHA256.New()
+	certHash.Write(cert.Raw)
+	id := essCertIDv2{
+		CertHash: certHash.Sum(nil),
+		IssuerSerial: issuerSerial{
+			Issuer: asn1.RawValue{
+				Class:      asn1.ClassUniversal,
+				Tag:        asn1.TagSequence,
+				IsCompound: true,
+				Bytes:      directoryName(cert.RawIssuer),
+			},
+			SerialNumber: cert.SerialNumber,
+		},
+	}
+	return addAttribute(attributes, oidSigningCertificateV2, signingCertificateV2{[]essCertIDv2{id}})
+}
+
+// appends an attribute with a single value
+func addAttribute(attributes *[]attribute, oid asn1.ObjectIdentifier, value interface{}) error {
+	encoded, err := asn1.Marshal(value)
+	if err != nil {
+		return err
+	}
+	*attributes = append(*attributes, attribute{
+		Type:   oid,
+		Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: encoded},
+	})
+	return nil
+}
+
+// the contents of a DER SET OF, whose elements are sorted
+func marshalSet(attributes []attribute) ([]byte, error) {
+	encoded := [][]byte{}
+	for _, attribute := range attributes {
+		der, err := asn1.Marshal(attribute)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, der)
+	}
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+	return bytes.Join(encoded, nil), nil
+}
+
+// a GeneralName with the directory name (RFC 5280 section 4.2.1.6)
+func directoryName(name []byte) []byte {
+	encoded, _ := asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        4,
+		IsCompound: true,
+		Bytes:      name,
+	})
+	return encoded
+}
@@ -0,0 +1,247 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// creates a file with a one page document
+func testDocument(t *testing.T, filename string) *File {
+	file, err := Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pagesRef, err := file.Add(Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageRef, err := file.Add(Dictionary{
+		"Type":     Name("Page"),
+		"Parent":   pagesRef,
+		"MediaBox": Array{Integer(0), Integer(0), Integer(612), Integer(792)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.Add(IndirectObject{pagesRef, Dictionary{
+		"Type":  Name("Pages"),
+		"Kids":  Array{pageRef},
+		"Count": Integer(1),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Root, err = file.Add(Dictionary{"Type": Name("Catalog"), "Pages": pagesRef})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestSign(t *testing.T) {
+	rsaCert, rsaKey := testCertificate(t, "rsa signer", 1)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "ecdsa signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &ecdsaKey.PublicKey, ecdsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name      string
+		signature Signature
+		algorithm x509.SignatureAlgorithm
+	}{
+		{"pkcs7 rsa", Signature{Signer: rsaKey, Certificate: rsaCert}, x509.SHA256WithRSA},
+		{"cades ecdsa", Signature{Signer: ecdsaKey, Certificate: ecdsaCert, Format: CAdESDetached, Hash: crypto.SHA384}, x509.ECDSAWithSHA384},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "signed.pdf")
+			file := testDocument(t, filename)
+
+			signature := test.signature
+			signature.Reason = "Approved"
+			signature.Location = "Zürich"
+			err := file.Sign(&signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// later changes are appended
+			infoRef, err := file.Add(Dictionary{"Title": String("Signed")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Info = infoRef
+			err = file.Save()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err = Open(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			catalog := file.Get(file.Root).(Dictionary)
+			acroForm := catalog["AcroForm"].(Dictionary)
+			if acroForm["SigFlags"] != Integer(3) {
+				t.Errorf("SigFlags is %v", acroForm["SigFlags"])
+			}
+			fields := acroForm["Fields"].(Array)
+			if len(fields) != 1 {
+				t.Fatalf("expected 1 field, got %d", len(fields))
+			}
+			widgetRef := fields[0].(ObjectReference)
+			widget := file.Get(widgetRef).(Dictionary)
+			if str(widget["T"]) != "Signature1" {
+				t.Errorf("field is named %q", widget["T"])
+			}
+			page := file.Get(widget["P"].(ObjectReference)).(Dictionary)
+			if annots := page["Annots"].(Array); len(annots) != 1 || annots[0] != widgetRef {
+				t.Errorf("page annotations are %v", annots)
+			}
+
+			sig := file.Get(widget["V"].(ObjectReference)).(Dictionary)
+			if sig["SubFilter"] != Name(test.signature.Format) && test.signature.Format != "" {
+				t.Errorf("SubFilter is %v", sig["SubFilter"])
+			}
+			if textString(sig["Location"].(String)) != "Zürich" {
+				t.Errorf("Location is %q", sig["Location"])
+			}
+
+			// the byte range covers everything up to the info
+			// dictionary, except for the signature
+			byteRange := sig["ByteRange"].(Array)
+			start := int(byteRange[1].(Integer))
+			end := int(byteRange[2].(Integer))
+			length := int(byteRange[3].(Integer))
+			if byteRange[0] != Integer(0) || data[start] != '<' || data[end-1] != '>' {
+				t.Fatalf("byte range %v does not exclude the contents", byteRange)
+			}
+			if end+length >= len(data) || !bytes.HasSuffix(data[:end+length], []byte("%%EOF")) {
+				t.Errorf("byte range %v does not end at a revision", byteRange)
+			}
+			signed := append(append([]byte{}, data[:start]...), data[end:end+length]...)
+
+			var info contentInfo
+			_, err = asn1.Unmarshal(sig["Contents"].(String), &info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ContentType.Equal(oidSignedData) {
+				t.Fatalf("content type is %v", info.ContentType)
+			}
+			var sd signedData
+			_, err = asn1.Unmarshal(info.Content.Bytes, &sd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sd.EncapContentInfo.Content.Bytes) != 0 {
+				t.Error("signature is not detached")
+			}
+			certificates, err := x509.ParseCertificates(sd.Certificates.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(certificates) != 1 || !certificates[0].Equal(test.signature.Certificate) {
+				t.Error("signer certificate is not included")
+			}
+
+			signer := sd.SignerInfos[0]
+			attributes := map[string]asn1.RawValue{}
+			rest := signer.SignedAttributes.Bytes
+			for len(rest) > 0 {
+				var a attribute
+				rest, err = asn1.Unmarshal(rest, &a)
+				if err != nil {
+					t.Fatal(err)
+				}
+				attributes[a.Type.String()] = a.Values
+			}
+
+			hash := test.signature.Hash
+			if hash == 0 {
+				hash = crypto.SHA256
+			}
+			digest := hash.New()
+			digest.Write(signed)
+			var messageDigest []byte
+			_, err = asn1.Unmarshal(attributes[oidMessageDigest.String()].Bytes, &messageDigest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(messageDigest, digest.Sum(nil)) {
+				t.Error("message digest is not of the byte range")
+			}
+
+			_, hasSigningTime := attributes[oidSigningTime.String()]
+			_, hasSigningCertificate := attributes[oidSigningCertificateV2.String()]
+			if test.signature.Format == CAdESDetached {
+				if hasSigningTime || !hasSigningCertificate {
+					t.Error("CAdES signatures have the signing certificate and not the signing time")
+				}
+			} else if !hasSigningTime {
+				t.Error("missing signing time")
+			}
+
+			signedAttributes := append([]byte{}, signer.SignedAttributes.FullBytes...)
+			signedAttributes[0] = 0x31 // SET
+			err = test.signature.Certificate.CheckSignature(test.algorithm, signedAttributes, signer.Signature)
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSignTooSmall(t *testing.T) {
+	cert, key := testCertificate(t, "signer", 1)
+	file := testDocument(t, filepath.Join(t.TempDir(), "signed.pdf"))
+	defer file.Close()
+
+	err := file.Sign(&Signature{Signer: key, Certificate: cert, Size: 16})
+	if err == nil {
+		t.Error("expected an error when the signature does not fit")
+	}
+}
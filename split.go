@@ -0,0 +1,683 @@
+package pdf
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// Extract appends the pages at the indexes in the source to the
+// document. Only the objects the pages reach are copied, with links to
+// other pages removed. The source's outline items (§12.3.3) and named
+// destinations (§12.3.2.3) are copied when they go to the pages, along
+// with the page labels (§12.4.2) of the pages and the fields (§12.7)
+// of their widgets. The source must remain open until the document is
+// saved.
+func Extract(dst, src *Document, indexes []int) error {
+	current, err := dst.Pages()
+	if err != nil {
+		return err
+	}
+	pages, err := src.Pages()
+	if err != nil {
+		return err
+	}
+
+	c := &copier{
+		dst:          dst.File,
+		src:          src.File,
+		refs:         map[ObjectReference]ObjectReference{},
+		catalog:      dst.Catalog.Dictionary,
+		srcCatalog:   src.Catalog.Dictionary,
+		firstContent: len(current) == 0,
+		excluded:     src.excluded(pages),
+	}
+	selected := make([]Page, len(indexes))
+	refs := make([]ObjectReference, len(indexes))
+	for i, index := range indexes {
+		if index < 0 || index >= len(pages) {
+			return fmt.Errorf("page index %d is out of range", index)
+		}
+		page := pages[index]
+		if _, ok := c.refs[page.ObjectReference]; ok {
+			return fmt.Errorf("page index %d is extracted more than once", index)
+		}
+		selected[i] = page
+		delete(c.excluded, page.ObjectReference)
+
+		refs[i], err = dst.File.Add(Null{})
+		if err != nil {
+			return err
+		}
+		c.refs[page.ObjectReference] = refs[i]
+	}
+
+	destinations := src.destinations()
+	for i, page := range selected {
+		dict := page.pushDown(nil)
+		delete(dict, "Parent")
+
+		// links to pages that are not extracted are removed
+		if annots, ok := src.File.resolve(dict["Annots"]).(Array); ok {
+			kept := Array{}
+			for _, annot := range annots {
+				target, ok := destinations.annotationPage(src.File.resolve(annot))
+				if !ok || !c.excluded[target] {
+					kept = append(kept, annot)
+				}
+			}
+			dict["Annots"] = kept
+		}
+
+		copied, err := c.copyDictionary(dict)
+		if err != nil {
+			return err
+		}
+		_, err = dst.File.Add(IndirectObject{refs[i], copied})
+		if err != nil {
+			return err
+		}
+	}
+	err = dst.InsertPages(len(current), refs...)
+	if err != nil {
+		return err
+	}
+
+	for _, extract := range []func() error{
+		func() error { return c.extractOutlines(destinations) },
+		func() error { return c.extractDestinations(destinations) },
+		func() error { return c.extractPageLabels(len(current), indexes) },
+		c.extractFields,
+	} {
+		err := extract()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.firstContent {
+		for _, key := range documentCatalogEntries {
+			if _, ok := c.catalog[key]; ok {
+				continue
+			}
+			if value, ok := c.srcCatalog[key]; ok {
+				c.catalog[key], err = c.copy(value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	_, err = dst.File.Add(IndirectObject{dst.Catalog.ObjectReference, c.catalog})
+	return err
+}
+
+// Split creates a file for each of the ranges of page indexes, named
+// by the filename function, with the pages extracted from the document
+// as with Extract. The files are saved and left open.
+func Split(src *Document, ranges [][]int, filename func(part int) string) ([]*File, error) {
+	files := []*File{}
+	closeAll := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+
+	for i, indexes := range ranges {
+		file, err := Create(filename(i))
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		files = append(files, file)
+
+		document, err := NewDocument(file)
+		if err == nil {
+			err = Extract(document, src, indexes)
+		}
+		if err == nil {
+			err = file.Save()
+		}
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// CountRanges returns the page indexes in ranges of n pages, except
+// for the last, which has the pages that are left.
+func (d *Document) CountRanges(n int) ([][]int, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("ranges must have at least 1 page, not %d", n)
+	}
+	pages, err := d.Pages()
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := [][]int{}
+	for start := 0; start < len(pages); start += n {
+		end := start + n
+		if end > len(pages) {
+			end = len(pages)
+		}
+		ranges = append(ranges, indexRange(start, end))
+	}
+	return ranges, nil
+}
+
+// BookmarkRanges returns the page indexes in ranges that start at the
+// pages the top-level outline items go to, with the titles of the
+// items. Pages before the first item's are in the first range.
+func (d *Document) BookmarkRanges() ([][]int, []string, error) {
+	pages, err := d.Pages()
+	if err != nil {
+		return nil, nil, err
+	}
+	index := map[ObjectReference]int{}
+	for i, page := range pages {
+		index[page.ObjectReference] = i
+	}
+
+	starts := []int{}
+	titles := []string{}
+	destinations := d.destinations()
+	outlines, _ := d.File.resolve(d.Catalog.Dictionary["Outlines"]).(Dictionary)
+	visited := map[ObjectReference]bool{}
+	for ref, ok := outlines["First"].(ObjectReference); ok && !visited[ref]; {
+		visited[ref] = true
+		item, _ := d.File.Get(ref).(Dictionary)
+		target, hasTarget := destinations.itemPage(item)
+		start, isPage := index[target]
+
+		// items going to the same page or back are in the range
+		// of the item before them
+		if hasTarget && isPage && (len(starts) == 0 || start > starts[len(starts)-1]) {
+			starts = append(starts, start)
+			title, _ := d.File.resolve(item["Title"]).(String)
+			titles = append(titles, textString(title))
+		}
+		ref, ok = item["Next"].(ObjectReference)
+	}
+
+	if len(starts) == 0 {
+		return [][]int{indexRange(0, len(pages))}, []string{""}, nil
+	}
+	starts[0] = 0
+	ranges := [][]int{}
+	for i, start := range starts {
+		end := len(pages)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		ranges = append(ranges, indexRange(start, end))
+	}
+	return ranges, titles, nil
+}
+
+// SizeRanges returns the page indexes in ranges of consecutive pages
+// whose objects, including those shared by the pages, take up at most
+// size bytes. A page that is larger than size is in a range by itself.
+func (d *Document) SizeRanges(size int64) ([][]int, error) {
+	pages, err := d.Pages()
+	if err != nil {
+		return nil, err
+	}
+	excluded := d.excluded(pages)
+	sizes := map[ObjectReference]int64{}
+
+	ranges := [][]int{}
+	objects := map[ObjectReference]bool{} // in the last range
+	var total int64                       // of the last range
+	for i, page := range pages {
+		reached := d.pageObjects(page, excluded)
+
+		// the size the page adds to the last range
+		added := int64(0)
+		for _, ref := range reached {
+			if _, ok := sizes[ref]; !ok {
+				sizes[ref], err = d.File.Get(ref).writeTo(ioutil.Discard)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if !objects[ref] {
+				added += sizes[ref]
+			}
+		}
+
+		if len(ranges) == 0 || total+added > size && len(ranges[len(ranges)-1]) > 0 {
+			ranges = append(ranges, []int{})
+			objects = map[ObjectReference]bool{}
+			total = 0
+			added = 0
+			for _, ref := range reached {
+				added += sizes[ref]
+			}
+		}
+		for _, ref := range reached {
+			objects[ref] = true
+		}
+		total += added
+		ranges[len(ranges)-1] = append(ranges[len(ranges)-1], i)
+	}
+	return ranges, nil
+}
+
+// the page and the objects it reaches without going through the
+// excluded objects
+func (d *Document) pageObjects(page Page, excluded map[ObjectReference]bool) []ObjectReference {
+	reached := map[ObjectReference]bool{page.ObjectReference: true}
+	objects := []ObjectReference{page.ObjectReference}
+	var walk func(obj Object)
+	walk = func(obj Object) {
+		switch typed := obj.(type) {
+		case ObjectReference:
+			if reached[typed] || excluded[typed] {
+				return
+			}
+			reached[typed] = true
+			objects = append(objects, typed)
+			walk(d.File.Get(typed))
+		case Array:
+			for _, element := range typed {
+				walk(element)
+			}
+		case Dictionary:
+			for _, value := range typed {
+				walk(value)
+			}
+		case Stream:
+			walk(typed.Dictionary)
+		}
+	}
+	walk(page.Dictionary)
+	return objects
+}
+
+func indexRange(start, end int) []int {
+	indexes := []int{}
+	for i := start; i < end; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// the objects reached by pages that are not part of any page: the
+// pages themselves, the page tree nodes and the catalog
+func (d *Document) excluded(pages []Page) map[ObjectReference]bool {
+	excluded := map[ObjectReference]bool{d.Catalog.ObjectReference: true}
+	root, _ := d.Catalog.Pages()
+	excluded[root] = true
+	for _, page := range pages {
+		excluded[page.ObjectReference] = true
+		visited := map[ObjectReference]bool{}
+		for parentRef, ok := page.Dictionary["Parent"].(ObjectReference); ok && !visited[parentRef]; {
+			visited[parentRef] = true
+			excluded[parentRef] = true
+			parent, _ := d.File.Get(parentRef).(Dictionary)
+			parentRef, ok = parent["Parent"].(ObjectReference)
+		}
+	}
+	return excluded
+}
+
+// the named destinations (§12.3.2.3) of a document
+type destinations struct {
+	file    *File
+	strings map[string]Object // in the Dests name tree
+	names   map[Name]Object   // in the Dests dictionary
+}
+
+func (d *Document) destinations() destinations {
+	dests := destinations{
+		file:    d.File,
+		strings: map[string]Object{},
+		names:   map[Name]Object{},
+	}
+	for _, entry := range d.File.treeEntries(d.File.names(d.Catalog.Dictionary)["Dests"], "Names") {
+		if name, ok := entry.key.(String); ok {
+			dests.strings[string(name)] = entry.value
+		}
+	}
+	dict, _ := d.File.resolve(d.Catalog.Dictionary["Dests"]).(Dictionary)
+	for name, dest := range dict {
+		dests.names[name] = dest
+	}
+	return dests
+}
+
+// the page of an explicit or named destination
+func (d destinations) page(dest Object) (ObjectReference, bool) {
+	switch typed := d.file.resolve(dest).(type) {
+	case String:
+		dest = d.strings[string(typed)]
+	case Name:
+		dest = d.names[typed]
+	}
+	// named destinations may be dictionaries with the destination in D
+	dest = d.file.resolve(dest)
+	if dict, ok := dest.(Dictionary); ok {
+		dest = d.file.resolve(dict["D"])
+	}
+	array, ok := dest.(Array)
+	if !ok || len(array) == 0 {
+		return ObjectReference{}, false
+	}
+	ref, ok := array[0].(ObjectReference)
+	return ref, ok
+}
+
+// the page an outline item or link annotation goes to, with its
+// destination or its go-to action
+func (d destinations) itemPage(item Dictionary) (ObjectReference, bool) {
+	if dest, ok := item["Dest"]; ok {
+		return d.page(dest)
+	}
+	action, _ := d.file.resolve(item["A"]).(Dictionary)
+	if action["S"] == Name("GoTo") {
+		return d.page(action["D"])
+	}
+	return ObjectReference{}, false
+}
+
+// the page a link annotation goes to
+func (d destinations) annotationPage(annot Object) (ObjectReference, bool) {
+	dict, ok := annot.(Dictionary)
+	if !ok || dict["Subtype"] != Name("Link") {
+		return ObjectReference{}, false
+	}
+	return d.itemPage(dict)
+}
+
+// whether the page was extracted
+func (c *copier) extracted(page ObjectReference) bool {
+	_, ok := c.refs[page]
+	return ok && !c.excluded[page]
+}
+
+// copies the outline items that go to extracted pages, or have
+// items under them that do, keeping the outline's structure
+func (c *copier) extractOutlines(dests destinations) error {
+	srcOutlines, _ := c.src.resolve(c.srcCatalog["Outlines"]).(Dictionary)
+	if srcOutlines == nil {
+		return nil
+	}
+
+	// whether each item, or an item under it, is kept
+	kept := map[ObjectReference]bool{}
+	var keep func(first Object, visited map[ObjectReference]bool) bool
+	keep = func(first Object, visited map[ObjectReference]bool) bool {
+		found := false
+		for ref, ok := first.(ObjectReference); ok && !visited[ref]; {
+			visited[ref] = true
+			item, _ := c.src.Get(ref).(Dictionary)
+			target, hasTarget := dests.itemPage(item)
+			if keep(item["First"], visited) || hasTarget && c.extracted(target) {
+				kept[ref] = true
+				found = true
+			}
+			ref, ok = item["Next"].(ObjectReference)
+		}
+		return found
+	}
+	if !keep(srcOutlines["First"], map[ObjectReference]bool{}) {
+		return nil
+	}
+
+	outlines := Dictionary{"Type": Name("Outlines")}
+	var err error
+	outlinesRef, ok := c.catalog["Outlines"].(ObjectReference)
+	if ok {
+		existing, isDictionary := c.dst.Get(outlinesRef).(Dictionary)
+		if isDictionary {
+			outlines = existing
+		}
+	} else {
+		outlinesRef, err = c.dst.Add(outlines)
+		if err != nil {
+			return err
+		}
+		c.catalog["Outlines"] = outlinesRef
+	}
+
+	// copies the kept items from first and its siblings under the
+	// parent, returning the number of open items
+	var copyItems func(first Object, parentRef ObjectReference, parent Dictionary) (int, error)
+	copyItems = func(first Object, parentRef ObjectReference, parent Dictionary) (int, error) {
+		open := 0
+		previous, _ := parent["Last"].(ObjectReference)
+		items := []ObjectReference{}
+		visited := map[ObjectReference]bool{}
+		for ref, ok := first.(ObjectReference); ok && !visited[ref]; {
+			visited[ref] = true
+			if kept[ref] {
+				items = append(items, ref)
+			}
+			item, _ := c.src.Get(ref).(Dictionary)
+			ref, ok = item["Next"].(ObjectReference)
+		}
+
+		for _, ref := range items {
+			item := c.src.Get(ref).(Dictionary)
+			copied := Dictionary{}
+			for key, value := range item {
+				switch key {
+				case "Parent", "Prev", "Next", "First", "Last", "Count":
+				default:
+					copied[key] = value
+				}
+			}
+			target, hasTarget := dests.itemPage(item)
+			if hasTarget && !c.extracted(target) {
+				delete(copied, "Dest")
+				delete(copied, "A")
+			}
+			copied, err := c.copyDictionary(copied)
+			if err != nil {
+				return 0, err
+			}
+			copiedRef, err := c.dst.Add(copied)
+			if err != nil {
+				return 0, err
+			}
+			copied["Parent"] = parentRef
+
+			children, err := copyItems(item["First"], copiedRef, copied)
+			if err != nil {
+				return 0, err
+			}
+			// closed items have a negative count
+			if count, _ := c.src.resolve(item["Count"]).(Integer); count < 0 && children > 0 {
+				copied["Count"] = Integer(-children)
+			} else if children > 0 {
+				copied["Count"] = Integer(children)
+				open += children
+			}
+			open++
+
+			if previous.ObjectNumber != 0 {
+				copied["Prev"] = previous
+				previousItem := c.dst.Get(previous).(Dictionary)
+				previousItem["Next"] = copiedRef
+				_, err = c.dst.Add(IndirectObject{previous, previousItem})
+				if err != nil {
+					return 0, err
+				}
+			} else {
+				parent["First"] = copiedRef
+			}
+			parent["Last"] = copiedRef
+			previous = copiedRef
+
+			_, err = c.dst.Add(IndirectObject{copiedRef, copied})
+			if err != nil {
+				return 0, err
+			}
+		}
+		return open, nil
+	}
+
+	open, err := copyItems(srcOutlines["First"], outlinesRef, outlines)
+	if err != nil {
+		return err
+	}
+	count, _ := outlines["Count"].(Integer)
+	outlines["Count"] = count + Integer(open)
+	_, err = c.dst.Add(IndirectObject{outlinesRef, outlines})
+	return err
+}
+
+// copies the named destinations of extracted pages
+func (c *copier) extractDestinations(dests destinations) error {
+	entries := c.dst.treeEntries(c.dst.names(c.catalog)["Dests"], "Names")
+	used := map[string]bool{}
+	for _, entry := range entries {
+		if name, ok := entry.key.(String); ok {
+			used[string(name)] = true
+		}
+	}
+	added := false
+	for name, dest := range dests.strings {
+		target, ok := dests.page(dest)
+		if !ok || !c.extracted(target) || used[name] {
+			continue
+		}
+		copied, err := c.copy(dest)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, treeEntry{String(name), copied})
+		added = true
+	}
+	if added {
+		names := copyDictionary(c.dst.names(c.catalog))
+		var err error
+		names["Dests"], err = c.dst.Add(newTree("Names", entries))
+		if err != nil {
+			return err
+		}
+		c.catalog["Names"], err = c.dst.Add(names)
+		if err != nil {
+			return err
+		}
+	}
+
+	dict, _ := c.dst.resolve(c.catalog["Dests"]).(Dictionary)
+	dict = copyDictionary(dict)
+	added = false
+	for name, dest := range dests.names {
+		target, ok := dests.page(dest)
+		if !ok || !c.extracted(target) {
+			continue
+		}
+		if _, ok := dict[name]; ok {
+			continue
+		}
+		var err error
+		dict[name], err = c.copy(dest)
+		if err != nil {
+			return err
+		}
+		added = true
+	}
+	if added {
+		var err error
+		c.catalog["Dests"], err = c.dst.Add(dict)
+		return err
+	}
+	return nil
+}
+
+// labels the extracted pages, which start at the index in the
+// document, as they were labeled in the source
+func (c *copier) extractPageLabels(index int, indexes []int) error {
+	srcLabels := c.src.treeEntries(c.srcCatalog["PageLabels"], "Nums")
+	if len(srcLabels) == 0 {
+		return nil
+	}
+	labels := c.dst.treeEntries(c.catalog["PageLabels"], "Nums")
+	if len(labels) == 0 && index > 0 {
+		labels = append(labels, treeEntry{Integer(0), Dictionary{"S": Name("D")}})
+	}
+
+	// the range each page is in, which is the last one starting at
+	// or before it
+	rangeOf := func(page int) int {
+		r := -1
+		for i, entry := range srcLabels {
+			if start, ok := entry.key.(Integer); ok && int(start) <= page {
+				r = i
+			}
+		}
+		return r
+	}
+
+	for i, page := range indexes {
+		r := rangeOf(page)
+		continues := i > 0 && indexes[i-1] == page-1 && rangeOf(page-1) == r
+		if continues {
+			continue
+		}
+		if r < 0 {
+			labels = append(labels, treeEntry{Integer(index + i), Dictionary{"S": Name("D"), "St": Integer(page + 1)}})
+			continue
+		}
+		label, _ := c.src.resolve(srcLabels[r].value).(Dictionary)
+		copied, err := c.copyDictionary(label)
+		if err != nil {
+			return err
+		}
+		start, _ := c.src.resolve(label["St"]).(Integer)
+		if start == 0 {
+			start = 1
+		}
+		copied["St"] = start + Integer(page) - srcLabels[r].key.(Integer)
+		labels = append(labels, treeEntry{Integer(index + i), copied})
+	}
+
+	var err error
+	c.catalog["PageLabels"], err = c.dst.Add(newTree("Nums", labels))
+	return err
+}
+
+// adds the top-level fields of copied widget annotations to the
+// interactive form (§12.7.2)
+func (c *copier) extractFields() error {
+	srcForm, _ := c.src.resolve(c.srcCatalog["AcroForm"]).(Dictionary)
+	srcFields, _ := c.src.resolve(srcForm["Fields"]).(Array)
+	copied := Array{}
+	for _, field := range srcFields {
+		if ref, ok := field.(ObjectReference); ok {
+			if copiedRef, ok := c.refs[ref]; ok {
+				copied = append(copied, copiedRef)
+			}
+		}
+	}
+	if len(copied) == 0 {
+		return nil
+	}
+
+	form, _ := c.dst.resolve(c.catalog["AcroForm"]).(Dictionary)
+	form = copyDictionary(form)
+	fields, _ := c.dst.resolve(form["Fields"]).(Array)
+	form["Fields"] = append(append(Array{}, fields...), copied...)
+	for _, key := range []Name{"DA", "Q", "NeedAppearances", "SigFlags", "DR"} {
+		if _, ok := form[key]; ok {
+			continue
+		}
+		if value, ok := srcForm[key]; ok {
+			var err error
+			form[key], err = c.copy(value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var err error
+	c.catalog["AcroForm"], err = c.dst.Add(form)
+	return err
+}
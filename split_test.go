@@ -0,0 +1,175 @@
+package pdf
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// returns the number of page objects the catalog reaches
+func reachedPages(file *File) int {
+	refs := map[ObjectReference]bool{}
+	file.references(file.Root, refs)
+	pages := 0
+	for ref := range refs {
+		if dict, ok := file.Get(ref).(Dictionary); ok && dict["Type"] == Name("Page") {
+			pages++
+		}
+	}
+	return pages
+}
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	src := testMergeSource(t, filepath.Join(dir, "src.pdf"), 6, true)
+	defer src.File.Close()
+	srcPages, err := src.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		indexes []int
+		links   int
+		dests   []string
+		labels  []Integer // the starts of the roman ranges
+	}{
+		// the link to the first page is removed
+		{[]int{4, 5}, 0, nil, []Integer{5}},
+		{[]int{0, 5}, 1, []string{"intro"}, []Integer{1, 6}},
+	} {
+		t.Run(fmt.Sprint(test.indexes), func(t *testing.T) {
+			file, err := Create(filepath.Join(t.TempDir(), "extracted.pdf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			dst, err := NewDocument(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = Extract(dst, src, test.indexes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pages, err := dst.Pages()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != len(test.indexes) || reachedPages(file) != len(test.indexes) {
+				t.Fatalf("expected %d pages, got %d reaching %d", len(test.indexes), len(pages), reachedPages(file))
+			}
+			annots, _ := pages[len(pages)-1].Dictionary["Annots"].(Array)
+			if len(annots) != test.links {
+				t.Errorf("expected %d links, got %v", test.links, annots)
+			}
+
+			// an outline item for each extracted page
+			outlines := file.resolve(dst.Catalog.Dictionary["Outlines"]).(Dictionary)
+			if outlines["Count"] != Integer(len(pages)) {
+				t.Errorf("outlines have Count %v", outlines["Count"])
+			}
+			itemRef, _ := outlines["First"].(ObjectReference)
+			for i, page := range pages {
+				item := file.Get(itemRef).(Dictionary)
+				if dest := item["Dest"].(Array); dest[0] != page.ObjectReference {
+					t.Errorf("item %d goes to %v instead of %v", i, dest, page.ObjectReference)
+				}
+				next, ok := item["Next"].(ObjectReference)
+				if ok == (i == len(pages)-1) {
+					t.Errorf("item %d has Next %v", i, item["Next"])
+				}
+				itemRef = next
+			}
+
+			var dests []string
+			for _, entry := range file.treeEntries(file.names(dst.Catalog.Dictionary)["Dests"], "Names") {
+				dests = append(dests, str(entry.key))
+			}
+			if !reflect.DeepEqual(dests, test.dests) {
+				t.Errorf("expected destinations %v, got %v", test.dests, dests)
+			}
+
+			var labels []Integer
+			for i, entry := range file.treeEntries(dst.Catalog.Dictionary["PageLabels"], "Nums") {
+				label := file.resolve(entry.value).(Dictionary)
+				if label["S"] != Name("r") || entry.key != Integer(i) {
+					t.Errorf("unexpected label %v for %v", label, entry.key)
+				}
+				labels = append(labels, label["St"].(Integer))
+			}
+			if !reflect.DeepEqual(labels, test.labels) {
+				t.Errorf("expected labels starting at %v, got %v", test.labels, labels)
+			}
+		})
+	}
+
+	// the source is unchanged
+	pages, err := src.Pages()
+	if err != nil || !reflect.DeepEqual(pages, srcPages) {
+		t.Error("the source's pages changed")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	dir := t.TempDir()
+	src := testMergeSource(t, filepath.Join(dir, "src.pdf"), 6, false)
+	defer src.File.Close()
+
+	ranges, err := src.CountRanges(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ranges, [][]int{{0, 1, 2, 3}, {4, 5}}) {
+		t.Errorf("unexpected count ranges %v", ranges)
+	}
+
+	ranges, titles, err := src.BookmarkRanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 6 || len(titles) != 6 || titles[0] != "item" {
+		t.Errorf("unexpected bookmark ranges %v %v", ranges, titles)
+	}
+
+	ranges, err = src.SizeRanges(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ranges, [][]int{{0, 1, 2, 3, 4, 5}}) {
+		t.Errorf("unexpected size ranges %v", ranges)
+	}
+	ranges, err = src.SizeRanges(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 6 {
+		t.Errorf("unexpected size ranges %v", ranges)
+	}
+
+	ranges, _ = src.CountRanges(4)
+	files, err := Split(src, ranges, func(part int) string {
+		return filepath.Join(dir, fmt.Sprintf("part%d.pdf", part))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, file := range files {
+		file.Close()
+		file, err = Open(filepath.Join(dir, fmt.Sprintf("part%d.pdf", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		document, err := NewDocument(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages, err := document.Pages()
+		if err != nil || len(pages) != len(ranges[i]) {
+			t.Errorf("part %d has %d pages instead of %d %v", i, len(pages), len(ranges[i]), err)
+		}
+		file.Close()
+	}
+}
@@ -1,86 +1,526 @@
 package pdf
 
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
 	"encoding/ascii85"
 	"errors"
 	"fmt"
-	// "compress/lzw"
-	"bytes"
-	"compress/flate"
+	"io"
 	"io/ioutil"
 )
 
-// TODO:
-// - file external file processing
-
 // Decode decodes the stream data using the filters in the stream's dictionary.
 func (s Stream) Decode() ([]byte, error) {
 	// when there are no filters, it is already decoded
-	if _, ok := s.Dictionary["Filter"]; !ok {
+	_, filtered := s.Dictionary["Filter"]
+	_, external := s.Dictionary["F"]
+	if !filtered && !external {
 		return s.Stream, nil
 	}
 
+	r, err := s.Reader()
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+// Reader returns a reader of the decoded stream data.
+// The filters in the stream's dictionary are applied as it is read,
+// except that CCITTFaxDecode and JBIG2Decode read all of their
+// encoded data before returning any of the decoded data.
+//
+// When the stream's F entry specifies an external file (§7.3.8.2),
+// the data is read from that file, see File.ExternalStreams, and
+// decoded with FFilter and FDecodeParms. Streams that have not been
+// saved yet hold the external data themselves.
+func (s Stream) Reader() (io.Reader, error) {
+	data := s.Stream
+	filterKey, parametersKey := Name("Filter"), Name("DecodeParms")
+	if spec, ok := s.Dictionary["F"]; ok {
+		filterKey, parametersKey = Name("FFilter"), Name("FDecodeParms")
+		if len(data) == 0 {
+			var err error
+			data, err = s.readExternal(spec)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	filters, parameters, err := s.filters(filterKey, parametersKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	for i, name := range filters {
+		filter, ok := lookupFilter(name)
+		if !ok {
+			return nil, errors.New("No decoder for " + string(name))
+		}
+		r = filterReader{name, filter.NewDecoder(r, parameters[i])}
+	}
+	return r, nil
+}
+
+// Encode returns a copy of the stream with its data encoded by filters,
+// which are listed in the order they are to be decoded. Data that is
+// already encoded is decoded first. With no filters the data is
+// left decoded.
+func (s Stream) Encode(filters ...Name) (Stream, error) {
+	decoded, err := s.Decode()
+	if err != nil {
+		return Stream{}, err
+	}
+	return EncodeStream(s.Dictionary, decoded, filters, nil)
+}
+
+// EncodeStream returns a stream of data encoded by filters, which are
+// listed in the order they are to be decoded. The stream's dictionary
+// is a copy of dict with Filter and DecodeParms set to match filters
+// and parameters, where parameters[i] is for filters[i] and may be nil.
+func EncodeStream(dict Dictionary, data []byte, filters []Name, parameters []Dictionary) (Stream, error) {
+	stream := Stream{Dictionary: Dictionary{}}
+	for key, value := range dict {
+		switch key {
+		case "Filter", "DecodeParms", "DL", "F", "FFilter", "FDecodeParms":
+			continue
+		}
+		stream.Dictionary[key] = value
+	}
+
+	// the last filter to decode is the first to encode
+	for i := len(filters) - 1; i >= 0; i-- {
+		filter, ok := lookupFilter(filters[i])
+		if !ok {
+			return Stream{}, errors.New("No encoder for " + string(filters[i]))
+		}
+
+		params := Dictionary{}
+		if i < len(parameters) && parameters[i] != nil {
+			params = parameters[i]
+		}
+
+		buf := &bytes.Buffer{}
+		w := filter.NewEncoder(buf, params)
+		_, err := w.Write(data)
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			return Stream{}, errors.New(string(filters[i]) + ": " + err.Error())
+		}
+		data = buf.Bytes()
+	}
+	stream.Stream = data
+
+	// a single filter is not put in an array,
+	// null is used for filters without parameters
+	hasParameters := false
+	decodeParms := Array{}
+	for i := range filters {
+		if i < len(parameters) && len(parameters[i]) > 0 {
+			decodeParms = append(decodeParms, parameters[i])
+			hasParameters = true
+		} else {
+			decodeParms = append(decodeParms, Null{})
+		}
+	}
+	switch len(filters) {
+	case 0:
+	case 1:
+		stream.Dictionary["Filter"] = filters[0]
+		if hasParameters {
+			stream.Dictionary["DecodeParms"] = decodeParms[0]
+		}
+	default:
+		filterArray := Array{}
+		for _, filter := range filters {
+			filterArray = append(filterArray, filter)
+		}
+		stream.Dictionary["Filter"] = filterArray
+		if hasParameters {
+			stream.Dictionary["DecodeParms"] = decodeParms
+		}
+	}
+
+	return stream, nil
+}
+
+// the names of the stream's filters and their parameters,
+// from the filterKey and parametersKey entries
+func (s Stream) filters(filterKey, parametersKey Name) ([]Name, []Dictionary, error) {
 	// extract the list of filters to use
 	filters := []Name{}
-	switch streamFilter := s.Dictionary[Name("Filter")].(type) {
+	switch streamFilter := s.resolveObject(s.Dictionary[filterKey]).(type) {
+	case nil:
 	case Name:
 		filters = append(filters, streamFilter)
 	case Array:
 		for _, filter := range streamFilter {
-			filters = append(filters, filter.(Name))
+			name, ok := s.resolveObject(filter).(Name)
+			if !ok {
+				return nil, nil, fmt.Errorf("filter must be a name, not %T", filter)
+			}
+			filters = append(filters, name)
 		}
 	default:
-		panic(fmt.Sprintf("unhandled type: %T", streamFilter))
-	}
-
-	// extract the filter parameters
-	parameters := []Dictionary{}
-	if dict, ok := s.Dictionary[Name("DecodeParms")]; ok {
-		switch streamParameter := dict.(type) {
-		case Dictionary:
-			parameters = append(parameters, streamParameter)
-		case Array:
-			for _, parameter := range streamParameter {
-				parameters = append(parameters, parameter.(Dictionary))
+		return nil, nil, fmt.Errorf("%s must be a name or an array, not %T", filterKey, streamFilter)
+	}
+
+	// extract the filter parameters,
+	// null is used for filters without parameters
+	parameters := make([]Dictionary, len(filters))
+	switch streamParameter := s.resolveObject(s.Dictionary[parametersKey]).(type) {
+	case nil, Null:
+	case Dictionary:
+		if len(parameters) > 0 {
+			parameters[0] = streamParameter
+		}
+	case Array:
+		for i, parameter := range streamParameter {
+			if i >= len(parameters) {
+				break
+			}
+			switch parameter := s.resolveObject(parameter).(type) {
+			case Dictionary:
+				parameters[i] = parameter
+			case Null:
+			default:
+				return nil, nil, fmt.Errorf("filter parameters must be a dictionary, not %T", parameter)
 			}
-		default:
-			panic(fmt.Sprintf("unhandled type: %T", streamParameter))
 		}
+	default:
+		return nil, nil, fmt.Errorf("%s must be a dictionary or an array, not %T", parametersKey, streamParameter)
 	}
 
-	// apply the filters
-	stream := s.Stream
-	for i, filter := range filters {
-		decoder, ok := decoders[filter]
-		if !ok {
-			return nil, errors.New("No decoder for " + string(filter))
+	for i := range parameters {
+		if parameters[i] == nil {
+			parameters[i] = Dictionary{}
+		}
+		parameters[i] = s.resolve(parameters[i])
+	}
+
+	return filters, parameters, nil
+}
+
+// resolves a reference when the stream was read from a file
+func (s Stream) resolveObject(obj Object) Object {
+	if ref, ok := obj.(ObjectReference); ok && s.file != nil {
+		return s.file.Get(ref)
+	}
+	return obj
+}
+
+// resolves the references in filter parameters, such as JBIG2Globals,
+// when the stream was read from a file
+func (s Stream) resolve(dict Dictionary) Dictionary {
+	if s.file == nil {
+		return dict
+	}
+
+	resolved := Dictionary{}
+	for key, value := range dict {
+		resolved[key] = s.resolveObject(value)
+	}
+	return resolved
+}
+
+// FlateDecode (§7.4.4)
+type flateFilter struct{}
+
+func (flateFilter) NewDecoder(r io.Reader, params Dictionary) io.Reader {
+	return newPredictorReader(&zlibReader{r: r}, params)
+}
+
+func (flateFilter) NewEncoder(w io.Writer, params Dictionary) io.WriteCloser {
+	return newPredictorWriter(zlib.NewWriter(w), params)
+}
+
+// zlibReader reads zlib (RFC 1950) data without checking the
+// checksum at the end, as it is often wrong. Data without a
+// zlib header is read as raw deflate data.
+type zlibReader struct {
+	r     io.Reader
+	flate io.Reader
+}
+
+func (z *zlibReader) Read(p []byte) (int, error) {
+	if z.flate == nil {
+		r := bufio.NewReader(z.r)
+		header, err := r.Peek(2)
+		if err != nil && len(header) == 0 {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			z.flate = errorReader{err}
+			return 0, err
+		}
+
+		// deflate compression with a header check that is a multiple of 31
+		if len(header) == 2 && header[0]&0x0f == 8 && (int(header[0])<<8|int(header[1]))%31 == 0 {
+			if header[1]&0x20 != 0 {
+				err = errors.New("zlib preset dictionaries are not supported")
+				z.flate = errorReader{err}
+				return 0, err
+			}
+			r.Discard(2)
+		}
+		z.flate = flate.NewReader(r)
+	}
+	return z.flate.Read(p)
+}
+
+// ASCII85Decode (§7.4.3)
+type ascii85Filter struct{}
+
+func (ascii85Filter) NewDecoder(r io.Reader, params Dictionary) io.Reader {
+	return ascii85.NewDecoder(&eodReader{r: r, eod: '~'})
+}
+
+func (ascii85Filter) NewEncoder(w io.Writer, params Dictionary) io.WriteCloser {
+	return &ascii85Writer{w: w, encoder: ascii85.NewEncoder(w)}
+}
+
+// ends the data at the first byte of the end of data marker
+type eodReader struct {
+	r   io.Reader
+	eod byte
+	err error
+}
+
+func (e *eodReader) Read(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	n, err := e.r.Read(p)
+	if i := bytes.IndexByte(p[:n], e.eod); i >= 0 {
+		n, err = i, io.EOF
+	}
+	e.err = err
+	return n, err
+}
+
+type ascii85Writer struct {
+	w       io.Writer
+	encoder io.WriteCloser
+}
+
+func (a *ascii85Writer) Write(p []byte) (int, error) {
+	return a.encoder.Write(p)
+}
+
+func (a *ascii85Writer) Close() error {
+	err := a.encoder.Close()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(a.w, "~>")
+	return err
+}
+
+// ASCIIHexDecode (§7.4.2)
+type asciiHexFilter struct{}
+
+func (asciiHexFilter) NewDecoder(r io.Reader, params Dictionary) io.Reader {
+	return &asciiHexReader{r: bufio.NewReader(r)}
+}
+
+func (asciiHexFilter) NewEncoder(w io.Writer, params Dictionary) io.WriteCloser {
+	return &asciiHexWriter{w: w}
+}
+
+type asciiHexReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (h *asciiHexReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && h.err == nil {
+		high := h.digit()
+		if high < 0 {
+			break
 		}
 
-		parameter := Dictionary{}
-		if i < len(parameters) {
-			parameter = parameters[i]
+		// a final odd digit is followed by 0
+		low := h.digit()
+		if low < 0 && h.err != io.EOF {
+			break
 		}
+		if low < 0 {
+			low = 0
+		}
+
+		p[n] = byte(high<<4 | low)
+		n++
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, h.err
+}
 
-		var err error
-		stream, err = decoder(stream, parameter)
+// the value of the next digit, or -1 at the end of the data or an error
+func (h *asciiHexReader) digit() int {
+	for {
+		char, err := h.r.ReadByte()
 		if err != nil {
-			return nil, errors.New(string(filter) + ": " + err.Error())
+			h.err = err
+			return -1
+		}
+
+		switch {
+		case char == '>':
+			h.err = io.EOF
+			return -1
+		case isWhitespace(char):
+			continue
 		}
+
+		value, ok := hexValue(char)
+		if !ok {
+			h.err = fmt.Errorf("%q is not a hexadecimal digit", char)
+			return -1
+		}
+		return int(value)
 	}
+}
 
-	return stream, nil
+type asciiHexWriter struct {
+	w       io.Writer
+	written int // bytes encoded so far
+}
+
+func (h *asciiHexWriter) Write(p []byte) (int, error) {
+	const digits = "0123456789ABCDEF"
+	const lineLength = 64 // characters
+
+	encoded := make([]byte, 0, len(p)*2+len(p)/(lineLength/2)+1)
+	for _, b := range p {
+		if h.written > 0 && h.written%(lineLength/2) == 0 {
+			encoded = append(encoded, '\n')
+		}
+		encoded = append(encoded, digits[b>>4], digits[b&0xf])
+		h.written++
+	}
+
+	_, err := h.w.Write(encoded)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
 
-var decoders = map[Name]func([]byte, Dictionary) ([]byte, error){
-	Name("ASCII85Decode"): func(encoded []byte, dict Dictionary) ([]byte, error) {
-		// the -3 strips the end of data marker
-		return ioutil.ReadAll(ascii85.NewDecoder(bytes.NewBuffer(encoded[:len(encoded)-3])))
-	},
-	Name("FlateDecode"): func(encoded []byte, dict Dictionary) ([]byte, error) {
-		return ioutil.ReadAll(flate.NewReader(bytes.NewBuffer(encoded[2:])))
-	},
-	// There is some problem with LZWDecode and TestFilterExample3
-	// Name("LZWDecode"): func(encoded []byte, dict Dictionary) ([]byte, error) {
-	// 	return ioutil.ReadAll(lzw.NewReader(bytes.NewBuffer(encoded[:len(encoded)-3]), lzw.MSB, 8))
-	// },
+func (h *asciiHexWriter) Close() error {
+	_, err := io.WriteString(h.w, ">")
+	return err
+}
+
+// RunLengthDecode (§7.4.5)
+type runLengthFilter struct{}
+
+func (runLengthFilter) NewDecoder(r io.Reader, params Dictionary) io.Reader {
+	return &runLengthReader{r: bufio.NewReader(r)}
+}
+
+func (runLengthFilter) NewEncoder(w io.Writer, params Dictionary) io.WriteCloser {
+	return bufferFilter{encode: runLengthEncode}.NewEncoder(w, params)
+}
+
+type runLengthReader struct {
+	r       *bufio.Reader
+	literal int // bytes left to copy
+	repeat  int // times left to repeat value
+	value   byte
+	err     error
+}
+
+func (rl *runLengthReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && rl.err == nil {
+		switch {
+		case rl.literal > 0:
+			b, err := rl.r.ReadByte()
+			if err != nil {
+				rl.err = errors.New("run length data ends within a literal run")
+				break
+			}
+			p[n] = b
+			n++
+			rl.literal--
+		case rl.repeat > 0:
+			p[n] = rl.value
+			n++
+			rl.repeat--
+		default:
+			length, err := rl.r.ReadByte()
+			switch {
+			case err != nil:
+				rl.err = err
+			case length == 128: // EOD
+				rl.err = io.EOF
+			case length < 128:
+				// copy the next length+1 bytes
+				rl.literal = int(length) + 1
+			default:
+				// repeat the next byte 257-length times
+				rl.value, err = rl.r.ReadByte()
+				if err != nil {
+					rl.err = errors.New("run length data ends within a repeated run")
+				}
+				rl.repeat = 257 - int(length)
+			}
+		}
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, rl.err
+}
+
+func runLengthEncode(decoded []byte, dict Dictionary) ([]byte, error) {
+	encoded := []byte{}
+
+	literalStart := 0
+	flushLiteral := func(end int) {
+		for literalStart < end {
+			n := end - literalStart
+			if n > 128 {
+				n = 128
+			}
+			encoded = append(encoded, byte(n-1))
+			encoded = append(encoded, decoded[literalStart:literalStart+n]...)
+			literalStart += n
+		}
+	}
+
+	for i := 0; i < len(decoded); {
+		// length of the run starting at i
+		run := 1
+		for i+run < len(decoded) && run < 128 && decoded[i+run] == decoded[i] {
+			run++
+		}
+
+		// runs of 2 are only worth it outside of a literal run
+		if run > 2 || (run == 2 && literalStart == i) {
+			flushLiteral(i)
+			encoded = append(encoded, byte(257-run), decoded[i])
+			i += run
+			literalStart = i
+			continue
+		}
+
+		i += run
+	}
+	flushLiteral(len(decoded))
+
+	return append(encoded, 128), nil
 }
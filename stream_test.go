@@ -2,33 +2,31 @@ package pdf
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/lzw"
+	"io/ioutil"
+	"math/rand"
 	"testing"
 )
 
-//§ 7.4.1
+// § 7.4.1
+//
+// The encoded data is from Example 3, which decodes to the content
+// stream from the PDF Reference rather than the one in Example 1.
+// The "8" duplicated across a line break in the published example
+// has been removed.
 func TestFilterExample3(t *testing.T) {
-	t.Skip()
-
-	indirectObjectString := "1 0 obj\n<< /Length 534\n/Filter [/ASCII85Decode /LZWDecode] >>\nstream\nJ..)6T`?p&<!J9%_[umg\"B7/Z7KNXbN'S+,*Q/&\"OLT'F\nLIDK#!n`$\"<Atdi`\\Vn%b%)&'cA*VnK\\CJY(sF>c!Jnl@\nRM]WM;jjH6Gnc75idkL5]+cPZKEBPWdR>FF(kj1_R%W_d\n&/jS!;iuad7h?[L-F$+]]0A3Ck*$I0KZ?;<)CJtqi65Xb\nVc3\\n5ua:Q/=0$W<#N3U;H,MQKqfg1?:lUpR;6oN[C2E4\nZNr8Udn.'p+?#X+1>0Kuk$bCDF/(3fL5]Oq)^kJZ!C2H1\n'TO]Rl?Q:&'<5&iP!$Rq;BXRecDN[IJB`,)o8XJOSJ9sD\nS]hQ;Rj@!ND)bD_q&C\\g:inYC%)&u#:u,M6Bm%IY!Kb1+\n\":aAa'S`ViJglLb8<W9k6Yl\\\\0McJQkDeLWdPN?9A'jX*\nal>iG1p&i;eVoK&juJHs9%;Xomop\"5KatWRT\"JQ#qYuL,\nJD?M$0QP)lKn06l1apKDC@\\qJ4B!!(5m+j.7F790m(Vj8\n8l8Q:_CZ(Gm1%X\\N1&u!FKHMB~>\nendstream\nendobj"
-	expectedStream := []byte(`2J
-BT
-/F1 12 Tf 0 Tc
-0 Tw
-72.5 712 TD
-[(Unfiltered streams can be read easily) 65 (, )] TJ
-0 -14 TD
-[(b) 20 (ut generally tak) 10 (e more space than \311)] TJ
-T* (compressed streams.) Tj
-0 -28 TD
-[(Se) 25 (v) 15 (eral encoding methods are a) 20 (v) 25 (ailable in PDF) 80 (.)] TJ 0 -14 TD
-(Some are used for compression and others simply) Tj
-T* [(to represent binary data in an ) 55 (ASCII format.)] TJ
-T* (Some of the compression filters are \
-suitable ) Tj
-T* (for both data and images, while others are \
-suitable only ) Tj
-T* (for continuous-tone images.) Tj
-ET`)
+	indirectObjectString := "1 0 obj\n<< /Length 533\n/Filter [/ASCII85Decode /LZWDecode] >>\nstream\nJ..)6T`?p&<!J9%_[umg\"B7/Z7KNXbN'S+,*Q/&\"OLT'F\nLIDK#!n`$\"<Atdi`\\Vn%b%)&'cA*VnK\\CJY(sF>c!Jnl@\nRM]WM;jjH6Gnc75idkL5]+cPZKEBPWdR>FF(kj1_R%W_d\n&/jS!;iuad7h?[L-F$+]]0A3Ck*$I0KZ?;<)CJtqi65Xb\nVc3\\n5ua:Q/=0$W<#N3U;H,MQKqfg1?:lUpR;6oN[C2E4\nZNr8Udn.'p+?#X+1>0Kuk$bCDF/(3fL5]Oq)^kJZ!C2H1\n'TO]Rl?Q:&'<5&iP!$Rq;BXRecDN[IJB`,)o8XJOSJ9sD\nS]hQ;Rj@!ND)bD_q&C\\g:inYC%)&u#:u,M6Bm%IY!Kb1+\n\":aAa'S`ViJglLb8<W9k6Yl\\\\0McJQkDeLWdPN?9A'jX*\nal>iG1p&i;eVoK&juJHs9%;Xomop\"5KatWRT\"JQ#qYuL,\nJD?M$0QP)lKn06l1apKDC@\\qJ4B!!(5m+j.7F790m(Vj8\nl8Q:_CZ(Gm1%X\\N1&u!FKHMB~>\nendstream\nendobj"
+	expectedStream := []byte("2 J \rBT\r/F1 12 Tf\r0 Tc 0 Tw 72.5 712 TD [ (Unencoded streams can be read easily)65 (,)] TJ\r" +
+		"0 -14 TD [ (b)20 (ut generally tak)10 (e more space than \\311)] TJ\r" +
+		"T* (encoded streams.)Tj\r" +
+		"0 -28 TD [ (Se)25 (v)15 (eral encoding methods are a)20 (v)25 (ailable in PDF)80 (.)] TJ\r" +
+		"0 -14 TD (Some are used for compression and others simply)Tj\r" +
+		"T* [ (to represent binary data in an )55 (ASCII format.)] TJ\r" +
+		"T* (Some of the compression encoding methods are suitable )Tj\r" +
+		"T* (for both data and images, while others are suitable only )Tj\r" +
+		"T* (for continuous-tone images.)Tj\r" +
+		"ET\r")
 
 	object, _, err := parseIndirectObject([]byte(indirectObjectString))
 	if err != nil {
@@ -47,3 +45,253 @@ ET`)
 		t.Errorf("Stream did not decode, got:\n\t%vexpected:\n\t%v", stream, expectedStream)
 	}
 }
+
+// §7.4.4.2 Example
+func TestLZWExample(t *testing.T) {
+	decoded := []byte{45, 45, 45, 45, 45, 65, 45, 45, 45, 66}
+	encoded := []byte{0x80, 0x0B, 0x60, 0x50, 0x22, 0x0C, 0x0C, 0x85, 0x01}
+
+	got, err := lzwDecode(encoded, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, decoded) {
+		t.Errorf("decode: expected % x, got % x", decoded, got)
+	}
+
+	got, err = lzwEncode(decoded, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, encoded) {
+		t.Errorf("encode: expected % x, got % x", encoded, got)
+	}
+}
+
+// EarlyChange 0 is the code width behaviour of compress/lzw
+func TestLZWEarlyChange(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	data := make([]byte, 100000)
+	for i := range data {
+		// enough repetition to fill the table several times
+		data[i] = byte(random.Intn(16))
+	}
+
+	buf := &bytes.Buffer{}
+	w := lzw.NewWriter(buf, lzw.MSB, 8)
+	w.Write(data)
+	w.Close()
+
+	decoded, err := lzwDecode(buf.Bytes(), Dictionary{"EarlyChange": Integer(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("could not decode compress/lzw data")
+	}
+
+	encoded, err := lzwEncode(data, Dictionary{"EarlyChange": Integer(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err = ioutil.ReadAll(lzw.NewReader(bytes.NewReader(encoded), lzw.MSB, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("compress/lzw could not decode the data")
+	}
+
+	for _, earlyChange := range []Integer{0, 1} {
+		params := Dictionary{"EarlyChange": earlyChange}
+		encoded, err := lzwEncode(data, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := lzwDecode(encoded, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("EarlyChange %d: round trip failed", earlyChange)
+		}
+	}
+}
+
+func TestASCIIHexDecode(t *testing.T) {
+	for encoded, expected := range map[string]string{
+		"48 65\n6c6C6f>": "Hello",
+		"901fA>":         "\x90\x1f\xa0",
+		">ignored":       "",
+		"7":              "\x70",
+		"":               "",
+		"2 0 2 0 >4142":  "  ",
+	} {
+		decoded, err := decode("ASCIIHexDecode", []byte(encoded), Dictionary{})
+		if err != nil {
+			t.Errorf("%q: %v", encoded, err)
+		}
+		if string(decoded) != expected {
+			t.Errorf("%q: expected %q, got %q", encoded, expected, decoded)
+		}
+	}
+
+	_, err := decode("ASCIIHexDecode", []byte("4G>"), Dictionary{})
+	if err == nil {
+		t.Error("expected an error for a non-hexadecimal digit")
+	}
+}
+
+func TestRunLengthDecode(t *testing.T) {
+	encoded := []byte{2, 'a', 'b', 'c', 254, 'x', 0, 'y', 128, 'z'}
+	decoded, err := decode("RunLengthDecode", encoded, Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != "abcxxxy" {
+		t.Errorf("expected %q, got %q", "abcxxxy", decoded)
+	}
+
+	for _, truncated := range [][]byte{{2, 'a'}, {254}} {
+		_, err := decode("RunLengthDecode", truncated, Dictionary{})
+		if err == nil {
+			t.Errorf("expected an error for %v", truncated)
+		}
+	}
+}
+
+func TestFilterRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	inputs := [][]byte{
+		{},
+		{1},
+		bytes.Repeat([]byte{7}, 1000),
+		[]byte("aabbbccccdeffffffffg"),
+	}
+	for _, n := range []int{1, 127, 128, 129, 5000} {
+		data := make([]byte, n)
+		random.Read(data)
+		inputs = append(inputs, data)
+	}
+
+	for _, filter := range []Name{"ASCIIHexDecode", "ASCII85Decode", "FlateDecode", "LZWDecode", "RunLengthDecode"} {
+		for _, input := range inputs {
+			encoded, err := encode(filter, input, Dictionary{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := decode(filter, encoded, Dictionary{})
+			if err != nil {
+				t.Fatalf("%s: %v", filter, err)
+			}
+			if !bytes.Equal(decoded, input) {
+				t.Errorf("%s: expected % x, got % x", filter, input, decoded)
+			}
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	content := []byte("BT /F1 12 Tf 72 712 Td (Hello) Tj ET")
+	stream := Stream{
+		Dictionary: Dictionary{"Type": Name("Example")},
+		Stream:     content,
+	}
+
+	encoded, err := stream.Encode("ASCII85Decode", "FlateDecode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, ok := encoded.Dictionary["Filter"].(Array)
+	if !ok || len(filter) != 2 || filter[0] != Name("ASCII85Decode") || filter[1] != Name("FlateDecode") {
+		t.Errorf("unexpected Filter %v", encoded.Dictionary["Filter"])
+	}
+	if _, ok := encoded.Dictionary["DecodeParms"]; ok {
+		t.Error("DecodeParms set without parameters")
+	}
+	if encoded.Dictionary["Type"] != Name("Example") {
+		t.Error("other entries were not kept")
+	}
+
+	// already encoded data is decoded first
+	reencoded, err := encoded.Encode("ASCIIHexDecode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reencoded.Dictionary["Filter"] != Name("ASCIIHexDecode") {
+		t.Errorf("unexpected Filter %v", reencoded.Dictionary["Filter"])
+	}
+
+	for _, s := range []Stream{encoded, reencoded} {
+		decoded, err := s.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded, content) {
+			t.Errorf("expected %q, got %q", content, decoded)
+		}
+	}
+
+	decoded, err := encoded.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.Dictionary["Filter"]; ok || !bytes.Equal(decoded.Stream, content) {
+		t.Error("encoding without filters did not decode the stream")
+	}
+
+	_, err = stream.Encode("NoSuchDecode")
+	if err == nil {
+		t.Error("expected an error for an unknown filter")
+	}
+}
+
+func TestEncodeStreamParameters(t *testing.T) {
+	data := bytes.Repeat([]byte{1, 2, 3, 4}, 100)
+	params := Dictionary{"Predictor": Integer(12), "Columns": Integer(4)}
+
+	stream, err := EncodeStream(nil, data, []Name{"ASCIIHexDecode", "FlateDecode"}, []Dictionary{nil, params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodeParms, ok := stream.Dictionary["DecodeParms"].(Array)
+	if !ok || len(decodeParms) != 2 {
+		t.Fatalf("unexpected DecodeParms %v", stream.Dictionary["DecodeParms"])
+	}
+	if _, ok := decodeParms[0].(Null); !ok {
+		t.Errorf("expected null parameters, got %v", decodeParms[0])
+	}
+
+	decoded, err := stream.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("round trip failed")
+	}
+
+	// a single filter's parameters are not in an array
+	stream, err = EncodeStream(nil, data, []Name{"FlateDecode"}, []Dictionary{params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stream.Dictionary["DecodeParms"].(Dictionary); !ok {
+		t.Errorf("unexpected DecodeParms %v", stream.Dictionary["DecodeParms"])
+	}
+}
+
+// deflate data without a zlib header
+func TestFlateRawDeflate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, _ := flate.NewWriter(buf, flate.BestCompression)
+	w.Write([]byte("raw deflate"))
+	w.Close()
+
+	decoded, err := decode("FlateDecode", buf.Bytes(), Dictionary{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != "raw deflate" {
+		t.Errorf("expected %q, got %q", "raw deflate", decoded)
+	}
+}
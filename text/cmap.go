@@ -0,0 +1,220 @@
+package text
+
+import (
+	"unicode/utf16"
+
+	"github.com/nathankerr/pdf"
+	"github.com/nathankerr/pdf/internal/lexer"
+)
+
+// the parts of a CMap (§9.7.5) or ToUnicode CMap (§9.10.3)
+// needed for text extraction
+type cmap struct {
+	codespace []codespaceRange
+	unicode   map[code]string
+	cids      map[code]uint32
+	cidRanges []cidRange
+}
+
+type codespaceRange struct {
+	low, high []byte
+}
+
+type cidRange struct {
+	low, high code
+	cid       uint32
+}
+
+// parses the CMap's mappings, ignoring everything else
+func parseCMap(data []byte) *cmap {
+	cm := &cmap{
+		unicode: map[code]string{},
+		cids:    map[code]uint32{},
+	}
+
+	operands := []pdf.Object{}
+	for i := 0; i < len(data); {
+		switch char := data[i]; {
+		case lexer.IsWhitespace(char):
+			i++
+		case char == '%':
+			for i < len(data) && data[i] != '\r' && data[i] != '\n' {
+				i++
+			}
+		case char == '{' || char == '}' || char == ']' || char == '>' || char == ')':
+			// PostScript procedures and stray delimiters are skipped
+			i++
+		case isOperandStart(char):
+			obj, n, err := pdf.ParseObject(data[i:])
+			if err != nil || n == 0 {
+				operands = operands[:0]
+				i++
+				continue
+			}
+			operands = append(operands, obj)
+			i += n
+		default:
+			end := i
+			for end < len(data) && !lexer.IsWhitespace(data[end]) && !lexer.IsDelimiter(data[end]) {
+				end++
+			}
+			cm.keyword(string(data[i:end]), operands)
+			operands = operands[:0]
+			i = end
+		}
+	}
+
+	return cm
+}
+
+// applies the operands collected for a section of the CMap
+func (cm *cmap) keyword(keyword string, operands []pdf.Object) {
+	switch keyword {
+	case "endcodespacerange":
+		for i := 0; i+1 < len(operands); i += 2 {
+			low, ok1 := operands[i].(pdf.String)
+			high, ok2 := operands[i+1].(pdf.String)
+			if ok1 && ok2 && len(low) == len(high) && len(low) > 0 && len(low) <= 4 {
+				cm.codespace = append(cm.codespace, codespaceRange{low, high})
+			}
+		}
+	case "endbfchar":
+		for i := 0; i+1 < len(operands); i += 2 {
+			src, ok := operands[i].(pdf.String)
+			if !ok || len(src) > 4 {
+				continue
+			}
+			switch dst := operands[i+1].(type) {
+			case pdf.String:
+				cm.unicode[toCode(src)] = decodeUTF16(dst)
+			case pdf.Name:
+				cm.unicode[toCode(src)] = glyphUnicode(string(dst))
+			}
+		}
+	case "endbfrange":
+		for i := 0; i+2 < len(operands); i += 3 {
+			low, ok1 := operands[i].(pdf.String)
+			high, ok2 := operands[i+1].(pdf.String)
+			if !ok1 || !ok2 || len(low) != len(high) || len(low) > 4 {
+				continue
+			}
+			first, last := toCode(low), toCode(high)
+			if last.value < first.value || last.value-first.value > 0xFFFF {
+				continue
+			}
+
+			switch dst := operands[i+2].(type) {
+			case pdf.String:
+				// the last character is incremented for each code
+				runes := []rune(decodeUTF16(dst))
+				if len(runes) == 0 {
+					continue
+				}
+				for offset := uint32(0); offset <= last.value-first.value; offset++ {
+					c := code{first.value + offset, first.length}
+					cm.unicode[c] = string(runes[:len(runes)-1]) + string(runes[len(runes)-1]+rune(offset))
+				}
+			case pdf.Array:
+				for offset, element := range dst {
+					if uint32(offset) > last.value-first.value {
+						break
+					}
+					if s, ok := element.(pdf.String); ok {
+						c := code{first.value + uint32(offset), first.length}
+						cm.unicode[c] = decodeUTF16(s)
+					}
+				}
+			}
+		}
+	case "endcidchar":
+		for i := 0; i+1 < len(operands); i += 2 {
+			src, ok1 := operands[i].(pdf.String)
+			cid, ok2 := operands[i+1].(pdf.Integer)
+			if ok1 && ok2 && len(src) <= 4 {
+				cm.cids[toCode(src)] = uint32(cid)
+			}
+		}
+	case "endcidrange":
+		for i := 0; i+2 < len(operands); i += 3 {
+			low, ok1 := operands[i].(pdf.String)
+			high, ok2 := operands[i+1].(pdf.String)
+			cid, ok3 := operands[i+2].(pdf.Integer)
+			if ok1 && ok2 && ok3 && len(low) == len(high) && len(low) <= 4 {
+				cm.cidRanges = append(cm.cidRanges, cidRange{toCode(low), toCode(high), uint32(cid)})
+			}
+		}
+	}
+}
+
+// reads the next code from s using the codespace ranges (§9.7.6.2)
+func (cm *cmap) next(s pdf.String) code {
+	shortest := 0
+	for length := 1; length <= 4 && length <= len(s); length++ {
+		for _, r := range cm.codespace {
+			if len(r.low) != length {
+				continue
+			}
+			if shortest == 0 {
+				shortest = length
+			}
+			if r.contains(s[:length]) {
+				return toCode(s[:length])
+			}
+		}
+	}
+
+	// codes that do not match are as long as the shortest range
+	if shortest == 0 {
+		shortest = 1
+	}
+	return toCode(s[:shortest])
+}
+
+func (r codespaceRange) contains(b []byte) bool {
+	for i := range b {
+		if b[i] < r.low[i] || b[i] > r.high[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// maps a code to a CID; unmapped codes use CID 0 (§9.7.6.3)
+func (cm *cmap) cid(c code) uint32 {
+	if cid, ok := cm.cids[c]; ok {
+		return cid
+	}
+	for _, r := range cm.cidRanges {
+		if c.length == r.low.length && c.value >= r.low.value && c.value <= r.high.value {
+			return r.cid + c.value - r.low.value
+		}
+	}
+	return 0
+}
+
+func toCode(b []byte) code {
+	c := code{length: len(b)}
+	for _, char := range b {
+		c.value = c.value<<8 | uint32(char)
+	}
+	return c
+}
+
+// ToUnicode destinations are UTF-16BE
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// the characters that can start an object
+func isOperandStart(char byte) bool {
+	switch char {
+	case '/', '(', '<', '[', '+', '-', '.',
+		'0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,1800 @@
+package text
+
+// StandardEncoding (Annex D.2)
+var standardEncoding = [256]string{
+	0040: "space",
+	0041: "exclam",
+	0042: "quotedbl",
+	0043: "numbersign",
+	0044: "dollar",
+	0045: "percent",
+	0046: "ampersand",
+	0047: "quoteright",
+	0050: "parenleft",
+	0051: "parenright",
+	0052: "asterisk",
+	0053: "plus",
+	0054: "comma",
+	0055: "hyphen",
+	0056: "period",
+	0057: "slash",
+	0060: "zero",
+	0061: "one",
+	0062: "two",
+	0063: "three",
+	0064: "four",
+	0065: "five",
+	0066: "six",
+	0067: "seven",
+	0070: "eight",
+	0071: "nine",
+	0072: "colon",
+	0073: "semicolon",
+	0074: "less",
+	0075: "equal",
+	0076: "greater",
+	0077: "question",
+	0100: "at",
+	0101: "A",
+	0102: "B",
+	0103: "C",
+	0104: "D",
+	0105: "E",
+	0106: "F",
+	0107: "G",
+	0110: "H",
+	0111: "I",
+	0112: "J",
+	0113: "K",
+	0114: "L",
+	0115: "M",
+	0116: "N",
+	0117: "O",
+	0120: "P",
+	0121: "Q",
+	0122: "R",
+	0123: "S",
+	0124: "T",
+	0125: "U",
+	0126: "V",
+	0127: "W",
+	0130: "X",
+	0131: "Y",
+	0132: "Z",
+	0133: "bracketleft",
+	0134: "backslash",
+	0135: "bracketright",
+	0136: "asciicircum",
+	0137: "underscore",
+	0140: "quoteleft",
+	0141: "a",
+	0142: "b",
+	0143: "c",
+	0144: "d",
+	0145: "e",
+	0146: "f",
+	0147: "g",
+	0150: "h",
+	0151: "i",
+	0152: "j",
+	0153: "k",
+	0154: "l",
+	0155: "m",
+	0156: "n",
+	0157: "o",
+	0160: "p",
+	0161: "q",
+	0162: "r",
+	0163: "s",
+	0164: "t",
+	0165: "u",
+	0166: "v",
+	0167: "w",
+	0170: "x",
+	0171: "y",
+	0172: "z",
+	0173: "braceleft",
+	0174: "bar",
+	0175: "braceright",
+	0176: "asciitilde",
+	0241: "exclamdown",
+	0242: "cent",
+	0243: "sterling",
+	0244: "fraction",
+	0245: "yen",
+	0246: "florin",
+	0247: "section",
+	0250: "currency",
+	0251: "quotesingle",
+	0252: "quotedblleft",
+	0253: "guillemotleft",
+	0254: "guilsinglleft",
+	0255: "guilsinglright",
+	0256: "fi",
+	0257: "fl",
+	0261: "endash",
+	0262: "dagger",
+	0263: "daggerdbl",
+	0264: "periodcentered",
+	0266: "paragraph",
+	0267: "bullet",
+	0270: "quotesinglbase",
+	0271: "quotedblbase",
+	0272: "quotedblright",
+	0273: "guillemotright",
+	0274: "ellipsis",
+	0275: "perthousand",
+	0277: "questiondown",
+	0301: "grave",
+	0302: "acute",
+	0303: "circumflex",
+	0304: "tilde",
+	0305: "macron",
+	0306: "breve",
+	0307: "dotaccent",
+	0310: "dieresis",
+	0312: "ring",
+	0313: "cedilla",
+	0315: "hungarumlaut",
+	0316: "ogonek",
+	0317: "caron",
+	0320: "emdash",
+	0341: "AE",
+	0343: "ordfeminine",
+	0350: "Lslash",
+	0351: "Oslash",
+	0352: "OE",
+	0353: "ordmasculine",
+	0361: "ae",
+	0365: "dotlessi",
+	0370: "lslash",
+	0371: "oslash",
+	0372: "oe",
+	0373: "germandbls",
+}
+
+// WinAnsiEncoding (Annex D.2)
+var winAnsiEncoding = [256]string{
+	0040: "space",
+	0041: "exclam",
+	0042: "quotedbl",
+	0043: "numbersign",
+	0044: "dollar",
+	0045: "percent",
+	0046: "ampersand",
+	0047: "quotesingle",
+	0050: "parenleft",
+	0051: "parenright",
+	0052: "asterisk",
+	0053: "plus",
+	0054: "comma",
+	0055: "hyphen",
+	0056: "period",
+	0057: "slash",
+	0060: "zero",
+	0061: "one",
+	0062: "two",
+	0063: "three",
+	0064: "four",
+	0065: "five",
+	0066: "six",
+	0067: "seven",
+	0070: "eight",
+	0071: "nine",
+	0072: "colon",
+	0073: "semicolon",
+	0074: "less",
+	0075: "equal",
+	0076: "greater",
+	0077: "question",
+	0100: "at",
+	0101: "A",
+	0102: "B",
+	0103: "C",
+	0104: "D",
+	0105: "E",
+	0106: "F",
+	0107: "G",
+	0110: "H",
+	0111: "I",
+	0112: "J",
+	0113: "K",
+	0114: "L",
+	0115: "M",
+	0116: "N",
+	0117: "O",
+	0120: "P",
+	0121: "Q",
+	0122: "R",
+	0123: "S",
+	0124: "T",
+	0125: "U",
+	0126: "V",
+	0127: "W",
+	0130: "X",
+	0131: "Y",
+	0132: "Z",
+	0133: "bracketleft",
+	0134: "backslash",
+	0135: "bracketright",
+	0136: "asciicircum",
+	0137: "underscore",
+	0140: "grave",
+	0141: "a",
+	0142: "b",
+	0143: "c",
+	0144: "d",
+	0145: "e",
+	0146: "f",
+	0147: "g",
+	0150: "h",
+	0151: "i",
+	0152: "j",
+	0153: "k",
+	0154: "l",
+	0155: "m",
+	0156: "n",
+	0157: "o",
+	0160: "p",
+	0161: "q",
+	0162: "r",
+	0163: "s",
+	0164: "t",
+	0165: "u",
+	0166: "v",
+	0167: "w",
+	0170: "x",
+	0171: "y",
+	0172: "z",
+	0173: "braceleft",
+	0174: "bar",
+	0175: "braceright",
+	0176: "asciitilde",
+	0200: "Euro",
+	0202: "quotesinglbase",
+	0203: "florin",
+	0204: "quotedblbase",
+	0205: "ellipsis",
+	0206: "dagger",
+	0207: "daggerdbl",
+	0210: "circumflex",
+	0211: "perthousand",
+	0212: "Scaron",
+	0213: "guilsinglleft",
+	0214: "OE",
+	0216: "Zcaron",
+	0221: "quoteleft",
+	0222: "quoteright",
+	0223: "quotedblleft",
+	0224: "quotedblright",
+	0225: "bullet",
+	0226: "endash",
+	0227: "emdash",
+	0230: "tilde",
+	0231: "trademark",
+	0232: "scaron",
+	0233: "guilsinglright",
+	0234: "oe",
+	0236: "zcaron",
+	0237: "Ydieresis",
+	0240: "space",
+	0241: "exclamdown",
+	0242: "cent",
+	0243: "sterling",
+	0244: "currency",
+	0245: "yen",
+	0246: "brokenbar",
+	0247: "section",
+	0250: "dieresis",
+	0251: "copyright",
+	0252: "ordfeminine",
+	0253: "guillemotleft",
+	0254: "logicalnot",
+	0255: "hyphen",
+	0256: "registered",
+	0257: "macron",
+	0260: "degree",
+	0261: "plusminus",
+	0262: "twosuperior",
+	0263: "threesuperior",
+	0264: "acute",
+	0265: "mu",
+	0266: "paragraph",
+	0267: "periodcentered",
+	0270: "cedilla",
+	0271: "onesuperior",
+	0272: "ordmasculine",
+	0273: "guillemotright",
+	0274: "onequarter",
+	0275: "onehalf",
+	0276: "threequarters",
+	0277: "questiondown",
+	0300: "Agrave",
+	0301: "Aacute",
+	0302: "Acircumflex",
+	0303: "Atilde",
+	0304: "Adieresis",
+	0305: "Aring",
+	0306: "AE",
+	0307: "Ccedilla",
+	0310: "Egrave",
+	0311: "Eacute",
+	0312: "Ecircumflex",
+	0313: "Edieresis",
+	0314: "Igrave",
+	0315: "Iacute",
+	0316: "Icircumflex",
+	0317: "Idieresis",
+	0320: "Eth",
+	0321: "Ntilde",
+	0322: "Ograve",
+	0323: "Oacute",
+	0324: "Ocircumflex",
+	0325: "Otilde",
+	0326: "Odieresis",
+	0327: "multiply",
+	0330: "Oslash",
+	0331: "Ugrave",
+	0332: "Uacute",
+	0333: "Ucircumflex",
+	0334: "Udieresis",
+	0335: "Yacute",
+	0336: "Thorn",
+	0337: "germandbls",
+	0340: "agrave",
+	0341: "aacute",
+	0342: "acircumflex",
+	0343: "atilde",
+	0344: "adieresis",
+	0345: "aring",
+	0346: "ae",
+	0347: "ccedilla",
+	0350: "egrave",
+	0351: "eacute",
+	0352: "ecircumflex",
+	0353: "edieresis",
+	0354: "igrave",
+	0355: "iacute",
+	0356: "icircumflex",
+	0357: "idieresis",
+	0360: "eth",
+	0361: "ntilde",
+	0362: "ograve",
+	0363: "oacute",
+	0364: "ocircumflex",
+	0365: "otilde",
+	0366: "odieresis",
+	0367: "divide",
+	0370: "oslash",
+	0371: "ugrave",
+	0372: "uacute",
+	0373: "ucircumflex",
+	0374: "udieresis",
+	0375: "yacute",
+	0376: "thorn",
+	0377: "ydieresis",
+}
+
+// MacRomanEncoding (Annex D.2)
+// includes the Mac OS characters that are not in the PDF encoding (Annex D.2 note 6)
+var macRomanEncoding = [256]string{
+	0040: "space",
+	0041: "exclam",
+	0042: "quotedbl",
+	0043: "numbersign",
+	0044: "dollar",
+	0045: "percent",
+	0046: "ampersand",
+	0047: "quotesingle",
+	0050: "parenleft",
+	0051: "parenright",
+	0052: "asterisk",
+	0053: "plus",
+	0054: "comma",
+	0055: "hyphen",
+	0056: "period",
+	0057: "slash",
+	0060: "zero",
+	0061: "one",
+	0062: "two",
+	0063: "three",
+	0064: "four",
+	0065: "five",
+	0066: "six",
+	0067: "seven",
+	0070: "eight",
+	0071: "nine",
+	0072: "colon",
+	0073: "semicolon",
+	0074: "less",
+	0075: "equal",
+	0076: "greater",
+	0077: "question",
+	0100: "at",
+	0101: "A",
+	0102: "B",
+	0103: "C",
+	0104: "D",
+	0105: "E",
+	0106: "F",
+	0107: "G",
+	0110: "H",
+	0111: "I",
+	0112: "J",
+	0113: "K",
+	0114: "L",
+	0115: "M",
+	0116: "N",
+	0117: "O",
+	0120: "P",
+	0121: "Q",
+	0122: "R",
+	0123: "S",
+	0124: "T",
+	0125: "U",
+	0126: "V",
+	0127: "W",
+	0130: "X",
+	0131: "Y",
+	0132: "Z",
+	0133: "bracketleft",
+	0134: "backslash",
+	0135: "bracketright",
+	0136: "asciicircum",
+	0137: "underscore",
+	0140: "grave",
+	0141: "a",
+	0142: "b",
+	0143: "c",
+	0144: "d",
+	0145: "e",
+	0146: "f",
+	0147: "g",
+	0150: "h",
+	0151: "i",
+	0152: "j",
+	0153: "k",
+	0154: "l",
+	0155: "m",
+	0156: "n",
+	0157: "o",
+	0160: "p",
+	0161: "q",
+	0162: "r",
+	0163: "s",
+	0164: "t",
+	0165: "u",
+	0166: "v",
+	0167: "w",
+	0170: "x",
+	0171: "y",
+	0172: "z",
+	0173: "braceleft",
+	0174: "bar",
+	0175: "braceright",
+	0176: "asciitilde",
+	0200: "Adieresis",
+	0201: "Aring",
+	0202: "Ccedilla",
+	0203: "Eacute",
+	0204: "Ntilde",
+	0205: "Odieresis",
+	0206: "Udieresis",
+	0207: "aacute",
+	0210: "agrave",
+	0211: "acircumflex",
+	0212: "adieresis",
+	0213: "atilde",
+	0214: "aring",
+	0215: "ccedilla",
+	0216: "eacute",
+	0217: "egrave",
+	0220: "ecircumflex",
+	0221: "edieresis",
+	0222: "iacute",
+	0223: "igrave",
+	0224: "icircumflex",
+	0225: "idieresis",
+	0226: "ntilde",
+	0227: "oacute",
+	0230: "ograve",
+	0231: "ocircumflex",
+	0232: "odieresis",
+	0233: "otilde",
+	0234: "uacute",
+	0235: "ugrave",
+	0236: "ucircumflex",
+	0237: "udieresis",
+	0240: "dagger",
+	0241: "degree",
+	0242: "cent",
+	0243: "sterling",
+	0244: "section",
+	0245: "bullet",
+	0246: "paragraph",
+	0247: "germandbls",
+	0250: "registered",
+	0251: "copyright",
+	0252: "trademark",
+	0253: "acute",
+	0254: "dieresis",
+	0255: "notequal",
+	0256: "AE",
+	0257: "Oslash",
+	0260: "infinity",
+	0261: "plusminus",
+	0262: "lessequal",
+	0263: "greaterequal",
+	0264: "yen",
+	0265: "mu",
+	0266: "partialdiff",
+	0267: "summation",
+	0270: "product",
+	0271: "pi",
+	0272: "integral",
+	0273: "ordfeminine",
+	0274: "ordmasculine",
+	0275: "Omega",
+	0276: "ae",
+	0277: "oslash",
+	0300: "questiondown",
+	0301: "exclamdown",
+	0302: "logicalnot",
+	0303: "radical",
+	0304: "florin",
+	0305: "approxequal",
+	0306: "Delta",
+	0307: "guillemotleft",
+	0310: "guillemotright",
+	0311: "ellipsis",
+	0312: "space",
+	0313: "Agrave",
+	0314: "Atilde",
+	0315: "Otilde",
+	0316: "OE",
+	0317: "oe",
+	0320: "endash",
+	0321: "emdash",
+	0322: "quotedblleft",
+	0323: "quotedblright",
+	0324: "quoteleft",
+	0325: "quoteright",
+	0326: "divide",
+	0327: "lozenge",
+	0330: "ydieresis",
+	0331: "Ydieresis",
+	0332: "fraction",
+	0333: "currency",
+	0334: "guilsinglleft",
+	0335: "guilsinglright",
+	0336: "fi",
+	0337: "fl",
+	0340: "daggerdbl",
+	0341: "periodcentered",
+	0342: "quotesinglbase",
+	0343: "quotedblbase",
+	0344: "perthousand",
+	0345: "Acircumflex",
+	0346: "Ecircumflex",
+	0347: "Aacute",
+	0350: "Edieresis",
+	0351: "Egrave",
+	0352: "Iacute",
+	0353: "Icircumflex",
+	0354: "Idieresis",
+	0355: "Igrave",
+	0356: "Oacute",
+	0357: "Ocircumflex",
+	0360: "apple",
+	0361: "Ograve",
+	0362: "Uacute",
+	0363: "Ucircumflex",
+	0364: "Ugrave",
+	0365: "dotlessi",
+	0366: "circumflex",
+	0367: "tilde",
+	0370: "macron",
+	0371: "breve",
+	0372: "dotaccent",
+	0373: "ring",
+	0374: "cedilla",
+	0375: "hungarumlaut",
+	0376: "ogonek",
+	0377: "caron",
+}
+
+// MacExpertEncoding (Annex D.3)
+var macExpertEncoding = [256]string{
+	0040: "space",
+	0041: "exclamsmall",
+	0042: "Hungarumlautsmall",
+	0043: "centoldstyle",
+	0044: "dollaroldstyle",
+	0045: "dollarsuperior",
+	0046: "ampersandsmall",
+	0047: "Acutesmall",
+	0050: "parenleftsuperior",
+	0051: "parenrightsuperior",
+	0052: "twodotenleader",
+	0053: "onedotenleader",
+	0054: "comma",
+	0055: "hyphen",
+	0056: "period",
+	0057: "fraction",
+	0060: "zerooldstyle",
+	0061: "oneoldstyle",
+	0062: "twooldstyle",
+	0063: "threeoldstyle",
+	0064: "fouroldstyle",
+	0065: "fiveoldstyle",
+	0066: "sixoldstyle",
+	0067: "sevenoldstyle",
+	0070: "eightoldstyle",
+	0071: "nineoldstyle",
+	0072: "colon",
+	0073: "semicolon",
+	0075: "threequartersemdash",
+	0077: "questionsmall",
+	0104: "Ethsmall",
+	0107: "onequarter",
+	0110: "onehalf",
+	0111: "threequarters",
+	0112: "oneeighth",
+	0113: "threeeighths",
+	0114: "fiveeighths",
+	0115: "seveneighths",
+	0116: "onethird",
+	0117: "twothirds",
+	0126: "ff",
+	0127: "fi",
+	0130: "fl",
+	0131: "ffi",
+	0132: "ffl",
+	0133: "parenleftinferior",
+	0135: "parenrightinferior",
+	0136: "Circumflexsmall",
+	0137: "hypheninferior",
+	0140: "Gravesmall",
+	0141: "Asmall",
+	0142: "Bsmall",
+	0143: "Csmall",
+	0144: "Dsmall",
+	0145: "Esmall",
+	0146: "Fsmall",
+	0147: "Gsmall",
+	0150: "Hsmall",
+	0151: "Ismall",
+	0152: "Jsmall",
+	0153: "Ksmall",
+	0154: "Lsmall",
+	0155: "Msmall",
+	0156: "Nsmall",
+	0157: "Osmall",
+	0160: "Psmall",
+	0161: "Qsmall",
+	0162: "Rsmall",
+	0163: "Ssmall",
+	0164: "Tsmall",
+	0165: "Usmall",
+	0166: "Vsmall",
+	0167: "Wsmall",
+	0170: "Xsmall",
+	0171: "Ysmall",
+	0172: "Zsmall",
+	0173: "colonmonetary",
+	0174: "onefitted",
+	0175: "rupiah",
+	0176: "Tildesmall",
+	0201: "asuperior",
+	0202: "centsuperior",
+	0207: "Aacutesmall",
+	0210: "Agravesmall",
+	0211: "Acircumflexsmall",
+	0212: "Adieresissmall",
+	0213: "Atildesmall",
+	0214: "Aringsmall",
+	0215: "Ccedillasmall",
+	0216: "Eacutesmall",
+	0217: "Egravesmall",
+	0220: "Ecircumflexsmall",
+	0221: "Edieresissmall",
+	0222: "Iacutesmall",
+	0223: "Igravesmall",
+	0224: "Icircumflexsmall",
+	0225: "Idieresissmall",
+	0226: "Ntildesmall",
+	0227: "Oacutesmall",
+	0230: "Ogravesmall",
+	0231: "Ocircumflexsmall",
+	0232: "Odieresissmall",
+	0233: "Otildesmall",
+	0234: "Uacutesmall",
+	0235: "Ugravesmall",
+	0236: "Ucircumflexsmall",
+	0237: "Udieresissmall",
+	0241: "eightsuperior",
+	0242: "fourinferior",
+	0243: "threeinferior",
+	0244: "sixinferior",
+	0245: "eightinferior",
+	0246: "seveninferior",
+	0247: "Scaronsmall",
+	0251: "centinferior",
+	0252: "twoinferior",
+	0254: "Dieresissmall",
+	0256: "Caronsmall",
+	0257: "osuperior",
+	0260: "fiveinferior",
+	0262: "commainferior",
+	0263: "periodinferior",
+	0264: "Yacutesmall",
+	0266: "dollarinferior",
+	0271: "Thornsmall",
+	0273: "nineinferior",
+	0274: "zeroinferior",
+	0275: "Zcaronsmall",
+	0276: "AEsmall",
+	0277: "Oslashsmall",
+	0300: "questiondownsmall",
+	0301: "oneinferior",
+	0302: "Lslashsmall",
+	0311: "Cedillasmall",
+	0317: "OEsmall",
+	0320: "figuredash",
+	0321: "hyphensuperior",
+	0326: "exclamdownsmall",
+	0330: "Ydieresissmall",
+	0332: "onesuperior",
+	0333: "twosuperior",
+	0334: "threesuperior",
+	0335: "foursuperior",
+	0336: "fivesuperior",
+	0337: "sixsuperior",
+	0340: "sevensuperior",
+	0341: "ninesuperior",
+	0342: "zerosuperior",
+	0344: "esuperior",
+	0345: "rsuperior",
+	0346: "tsuperior",
+	0351: "isuperior",
+	0352: "ssuperior",
+	0353: "dsuperior",
+	0361: "lsuperior",
+	0362: "Ogoneksmall",
+	0363: "Brevesmall",
+	0364: "Macronsmall",
+	0365: "bsuperior",
+	0366: "nsuperior",
+	0367: "msuperior",
+	0370: "commasuperior",
+	0371: "periodsuperior",
+	0372: "Dotaccentsmall",
+	0373: "Ringsmall",
+}
+
+// the built-in encoding of the Symbol font (Annex D.4)
+var symbolEncoding = [256]string{
+	0040: "space",
+	0041: "exclam",
+	0042: "universal",
+	0043: "numbersign",
+	0044: "existential",
+	0045: "percent",
+	0046: "ampersand",
+	0047: "suchthat",
+	0050: "parenleft",
+	0051: "parenright",
+	0052: "asteriskmath",
+	0053: "plus",
+	0054: "comma",
+	0055: "minus",
+	0056: "period",
+	0057: "slash",
+	0060: "zero",
+	0061: "one",
+	0062: "two",
+	0063: "three",
+	0064: "four",
+	0065: "five",
+	0066: "six",
+	0067: "seven",
+	0070: "eight",
+	0071: "nine",
+	0072: "colon",
+	0073: "semicolon",
+	0074: "less",
+	0075: "equal",
+	0076: "greater",
+	0077: "question",
+	0100: "congruent",
+	0101: "Alpha",
+	0102: "Beta",
+	0103: "Chi",
+	0104: "Delta",
+	0105: "Epsilon",
+	0106: "Phi",
+	0107: "Gamma",
+	0110: "Eta",
+	0111: "Iota",
+	0112: "theta1",
+	0113: "Kappa",
+	0114: "Lambda",
+	0115: "Mu",
+	0116: "Nu",
+	0117: "Omicron",
+	0120: "Pi",
+	0121: "Theta",
+	0122: "Rho",
+	0123: "Sigma",
+	0124: "Tau",
+	0125: "Upsilon",
+	0126: "sigma1",
+	0127: "Omega",
+	0130: "Xi",
+	0131: "Psi",
+	0132: "Zeta",
+	0133: "bracketleft",
+	0134: "therefore",
+	0135: "bracketright",
+	0136: "perpendicular",
+	0137: "underscore",
+	0140: "radicalex",
+	0141: "alpha",
+	0142: "beta",
+	0143: "chi",
+	0144: "delta",
+	0145: "epsilon",
+	0146: "phi",
+	0147: "gamma",
+	0150: "eta",
+	0151: "iota",
+	0152: "phi1",
+	0153: "kappa",
+	0154: "lambda",
+	0155: "mu",
+	0156: "nu",
+	0157: "omicron",
+	0160: "pi",
+	0161: "theta",
+	0162: "rho",
+	0163: "sigma",
+	0164: "tau",
+	0165: "upsilon",
+	0166: "omega1",
+	0167: "omega",
+	0170: "xi",
+	0171: "psi",
+	0172: "zeta",
+	0173: "braceleft",
+	0174: "bar",
+	0175: "braceright",
+	0176: "similar",
+	0240: "Euro",
+	0241: "Upsilon1",
+	0242: "minute",
+	0243: "lessequal",
+	0244: "fraction",
+	0245: "infinity",
+	0246: "florin",
+	0247: "club",
+	0250: "diamond",
+	0251: "heart",
+	0252: "spade",
+	0253: "arrowboth",
+	0254: "arrowleft",
+	0255: "arrowup",
+	0256: "arrowright",
+	0257: "arrowdown",
+	0260: "degree",
+	0261: "plusminus",
+	0262: "second",
+	0263: "greaterequal",
+	0264: "multiply",
+	0265: "proportional",
+	0266: "partialdiff",
+	0267: "bullet",
+	0270: "divide",
+	0271: "notequal",
+	0272: "equivalence",
+	0273: "approxequal",
+	0274: "ellipsis",
+	0275: "arrowvertex",
+	0276: "arrowhorizex",
+	0277: "carriagereturn",
+	0300: "aleph",
+	0301: "Ifraktur",
+	0302: "Rfraktur",
+	0303: "weierstrass",
+	0304: "circlemultiply",
+	0305: "circleplus",
+	0306: "emptyset",
+	0307: "intersection",
+	0310: "union",
+	0311: "propersuperset",
+	0312: "reflexsuperset",
+	0313: "notsubset",
+	0314: "propersubset",
+	0315: "reflexsubset",
+	0316: "element",
+	0317: "notelement",
+	0320: "angle",
+	0321: "gradient",
+	0322: "registerserif",
+	0323: "copyrightserif",
+	0324: "trademarkserif",
+	0325: "product",
+	0326: "radical",
+	0327: "dotmath",
+	0330: "logicalnot",
+	0331: "logicaland",
+	0332: "logicalor",
+	0333: "arrowdblboth",
+	0334: "arrowdblleft",
+	0335: "arrowdblup",
+	0336: "arrowdblright",
+	0337: "arrowdbldown",
+	0340: "lozenge",
+	0341: "angleleft",
+	0342: "registersans",
+	0343: "copyrightsans",
+	0344: "trademarksans",
+	0345: "summation",
+	0346: "parenlefttp",
+	0347: "parenleftex",
+	0350: "parenleftbt",
+	0351: "bracketlefttp",
+	0352: "bracketleftex",
+	0353: "bracketleftbt",
+	0354: "bracelefttp",
+	0355: "braceleftmid",
+	0356: "braceleftbt",
+	0357: "braceex",
+	0361: "angleright",
+	0362: "integral",
+	0363: "integraltp",
+	0364: "integralex",
+	0365: "integralbt",
+	0366: "parenrighttp",
+	0367: "parenrightex",
+	0370: "parenrightbt",
+	0371: "bracketrighttp",
+	0372: "bracketrightex",
+	0373: "bracketrightbt",
+	0374: "bracerighttp",
+	0375: "bracerightmid",
+	0376: "bracerightbt",
+}
+
+// the built-in encoding of the ZapfDingbats font (Annex D.5)
+var zapfDingbatsEncoding = [256]string{
+	0040: "space",
+	0041: "a1",
+	0042: "a2",
+	0043: "a202",
+	0044: "a3",
+	0045: "a4",
+	0046: "a5",
+	0047: "a119",
+	0050: "a118",
+	0051: "a117",
+	0052: "a11",
+	0053: "a12",
+	0054: "a13",
+	0055: "a14",
+	0056: "a15",
+	0057: "a16",
+	0060: "a105",
+	0061: "a17",
+	0062: "a18",
+	0063: "a19",
+	0064: "a20",
+	0065: "a21",
+	0066: "a22",
+	0067: "a23",
+	0070: "a24",
+	0071: "a25",
+	0072: "a26",
+	0073: "a27",
+	0074: "a28",
+	0075: "a6",
+	0076: "a7",
+	0077: "a8",
+	0100: "a9",
+	0101: "a10",
+	0102: "a29",
+	0103: "a30",
+	0104: "a31",
+	0105: "a32",
+	0106: "a33",
+	0107: "a34",
+	0110: "a35",
+	0111: "a36",
+	0112: "a37",
+	0113: "a38",
+	0114: "a39",
+	0115: "a40",
+	0116: "a41",
+	0117: "a42",
+	0120: "a43",
+	0121: "a44",
+	0122: "a45",
+	0123: "a46",
+	0124: "a47",
+	0125: "a48",
+	0126: "a49",
+	0127: "a50",
+	0130: "a51",
+	0131: "a52",
+	0132: "a53",
+	0133: "a54",
+	0134: "a55",
+	0135: "a56",
+	0136: "a57",
+	0137: "a58",
+	0140: "a59",
+	0141: "a60",
+	0142: "a61",
+	0143: "a62",
+	0144: "a63",
+	0145: "a64",
+	0146: "a65",
+	0147: "a66",
+	0150: "a67",
+	0151: "a68",
+	0152: "a69",
+	0153: "a70",
+	0154: "a71",
+	0155: "a72",
+	0156: "a73",
+	0157: "a74",
+	0160: "a203",
+	0161: "a75",
+	0162: "a204",
+	0163: "a76",
+	0164: "a77",
+	0165: "a78",
+	0166: "a79",
+	0167: "a81",
+	0170: "a82",
+	0171: "a83",
+	0172: "a84",
+	0173: "a97",
+	0174: "a98",
+	0175: "a99",
+	0176: "a100",
+	0200: "a89",
+	0201: "a90",
+	0202: "a93",
+	0203: "a94",
+	0204: "a91",
+	0205: "a92",
+	0206: "a205",
+	0207: "a85",
+	0210: "a206",
+	0211: "a86",
+	0212: "a87",
+	0213: "a88",
+	0214: "a95",
+	0215: "a96",
+	0241: "a101",
+	0242: "a102",
+	0243: "a103",
+	0244: "a104",
+	0245: "a106",
+	0246: "a107",
+	0247: "a108",
+	0250: "a112",
+	0251: "a111",
+	0252: "a110",
+	0253: "a109",
+	0254: "a120",
+	0255: "a121",
+	0256: "a122",
+	0257: "a123",
+	0260: "a124",
+	0261: "a125",
+	0262: "a126",
+	0263: "a127",
+	0264: "a128",
+	0265: "a129",
+	0266: "a130",
+	0267: "a131",
+	0270: "a132",
+	0271: "a133",
+	0272: "a134",
+	0273: "a135",
+	0274: "a136",
+	0275: "a137",
+	0276: "a138",
+	0277: "a139",
+	0300: "a140",
+	0301: "a141",
+	0302: "a142",
+	0303: "a143",
+	0304: "a144",
+	0305: "a145",
+	0306: "a146",
+	0307: "a147",
+	0310: "a148",
+	0311: "a149",
+	0312: "a150",
+	0313: "a151",
+	0314: "a152",
+	0315: "a153",
+	0316: "a154",
+	0317: "a155",
+	0320: "a156",
+	0321: "a157",
+	0322: "a158",
+	0323: "a159",
+	0324: "a160",
+	0325: "a161",
+	0326: "a163",
+	0327: "a164",
+	0330: "a196",
+	0331: "a165",
+	0332: "a192",
+	0333: "a166",
+	0334: "a167",
+	0335: "a168",
+	0336: "a169",
+	0337: "a170",
+	0340: "a171",
+	0341: "a172",
+	0342: "a173",
+	0343: "a162",
+	0344: "a174",
+	0345: "a175",
+	0346: "a176",
+	0347: "a177",
+	0350: "a178",
+	0351: "a179",
+	0352: "a193",
+	0353: "a180",
+	0354: "a199",
+	0355: "a181",
+	0356: "a200",
+	0357: "a182",
+	0361: "a201",
+	0362: "a183",
+	0363: "a184",
+	0364: "a197",
+	0365: "a185",
+	0366: "a194",
+	0367: "a198",
+	0370: "a186",
+	0371: "a195",
+	0372: "a187",
+	0373: "a188",
+	0374: "a189",
+	0375: "a190",
+	0376: "a191",
+}
+
+// glyph names from the Adobe Glyph List for the glyphs in the Latin,
+// Symbol and expert character sets (Annex D)
+var glyphNames = map[string]rune{
+	"A":              0x0041,
+	"AE":             0x00C6,
+	"Aacute":         0x00C1,
+	"Acircumflex":    0x00C2,
+	"Adieresis":      0x00C4,
+	"Agrave":         0x00C0,
+	"Aring":          0x00C5,
+	"Atilde":         0x00C3,
+	"B":              0x0042,
+	"C":              0x0043,
+	"Ccedilla":       0x00C7,
+	"D":              0x0044,
+	"Delta":          0x2206,
+	"E":              0x0045,
+	"Eacute":         0x00C9,
+	"Ecircumflex":    0x00CA,
+	"Edieresis":      0x00CB,
+	"Egrave":         0x00C8,
+	"Eth":            0x00D0,
+	"Euro":           0x20AC,
+	"F":              0x0046,
+	"G":              0x0047,
+	"H":              0x0048,
+	"I":              0x0049,
+	"Iacute":         0x00CD,
+	"Icircumflex":    0x00CE,
+	"Idieresis":      0x00CF,
+	"Igrave":         0x00CC,
+	"J":              0x004A,
+	"K":              0x004B,
+	"L":              0x004C,
+	"Lslash":         0x0141,
+	"M":              0x004D,
+	"N":              0x004E,
+	"Ntilde":         0x00D1,
+	"O":              0x004F,
+	"OE":             0x0152,
+	"Oacute":         0x00D3,
+	"Ocircumflex":    0x00D4,
+	"Odieresis":      0x00D6,
+	"Ograve":         0x00D2,
+	"Omega":          0x2126,
+	"Oslash":         0x00D8,
+	"Otilde":         0x00D5,
+	"P":              0x0050,
+	"Q":              0x0051,
+	"R":              0x0052,
+	"S":              0x0053,
+	"Scaron":         0x0160,
+	"T":              0x0054,
+	"Thorn":          0x00DE,
+	"U":              0x0055,
+	"Uacute":         0x00DA,
+	"Ucircumflex":    0x00DB,
+	"Udieresis":      0x00DC,
+	"Ugrave":         0x00D9,
+	"V":              0x0056,
+	"W":              0x0057,
+	"X":              0x0058,
+	"Y":              0x0059,
+	"Yacute":         0x00DD,
+	"Ydieresis":      0x0178,
+	"Z":              0x005A,
+	"Zcaron":         0x017D,
+	"a":              0x0061,
+	"aacute":         0x00E1,
+	"acircumflex":    0x00E2,
+	"acute":          0x00B4,
+	"adieresis":      0x00E4,
+	"ae":             0x00E6,
+	"agrave":         0x00E0,
+	"ampersand":      0x0026,
+	"apple":          0xF8FF,
+	"approxequal":    0x2248,
+	"aring":          0x00E5,
+	"asciicircum":    0x005E,
+	"asciitilde":     0x007E,
+	"asterisk":       0x002A,
+	"at":             0x0040,
+	"atilde":         0x00E3,
+	"b":              0x0062,
+	"backslash":      0x005C,
+	"bar":            0x007C,
+	"braceleft":      0x007B,
+	"braceright":     0x007D,
+	"bracketleft":    0x005B,
+	"bracketright":   0x005D,
+	"breve":          0x02D8,
+	"brokenbar":      0x00A6,
+	"bullet":         0x2022,
+	"c":              0x0063,
+	"caron":          0x02C7,
+	"ccedilla":       0x00E7,
+	"cedilla":        0x00B8,
+	"cent":           0x00A2,
+	"circumflex":     0x02C6,
+	"colon":          0x003A,
+	"comma":          0x002C,
+	"copyright":      0x00A9,
+	"currency":       0x00A4,
+	"d":              0x0064,
+	"dagger":         0x2020,
+	"daggerdbl":      0x2021,
+	"degree":         0x00B0,
+	"dieresis":       0x00A8,
+	"divide":         0x00F7,
+	"dollar":         0x0024,
+	"dotaccent":      0x02D9,
+	"dotlessi":       0x0131,
+	"dotlessj":       0x0237,
+	"e":              0x0065,
+	"eacute":         0x00E9,
+	"ecircumflex":    0x00EA,
+	"edieresis":      0x00EB,
+	"egrave":         0x00E8,
+	"eight":          0x0038,
+	"ellipsis":       0x2026,
+	"emdash":         0x2014,
+	"endash":         0x2013,
+	"equal":          0x003D,
+	"eth":            0x00F0,
+	"exclam":         0x0021,
+	"exclamdown":     0x00A1,
+	"f":              0x0066,
+	"ff":             0xFB00,
+	"ffi":            0xFB03,
+	"ffl":            0xFB04,
+	"fi":             0xFB01,
+	"five":           0x0035,
+	"fl":             0xFB02,
+	"florin":         0x0192,
+	"four":           0x0034,
+	"fraction":       0x2044,
+	"g":              0x0067,
+	"germandbls":     0x00DF,
+	"grave":          0x0060,
+	"greater":        0x003E,
+	"greaterequal":   0x2265,
+	"guillemotleft":  0x00AB,
+	"guillemotright": 0x00BB,
+	"guilsinglleft":  0x2039,
+	"guilsinglright": 0x203A,
+	"h":              0x0068,
+	"hungarumlaut":   0x02DD,
+	"hyphen":         0x002D,
+	"i":              0x0069,
+	"iacute":         0x00ED,
+	"icircumflex":    0x00EE,
+	"idieresis":      0x00EF,
+	"igrave":         0x00EC,
+	"infinity":       0x221E,
+	"integral":       0x222B,
+	"j":              0x006A,
+	"k":              0x006B,
+	"l":              0x006C,
+	"less":           0x003C,
+	"lessequal":      0x2264,
+	"logicalnot":     0x00AC,
+	"lozenge":        0x25CA,
+	"lslash":         0x0142,
+	"m":              0x006D,
+	"macron":         0x00AF,
+	"middot":         0x00B7,
+	"minus":          0x2212,
+	"mu":             0x00B5,
+	"multiply":       0x00D7,
+	"n":              0x006E,
+	"nbspace":        0x00A0,
+	"nine":           0x0039,
+	"notequal":       0x2260,
+	"ntilde":         0x00F1,
+	"numbersign":     0x0023,
+	"o":              0x006F,
+	"oacute":         0x00F3,
+	"ocircumflex":    0x00F4,
+	"odieresis":      0x00F6,
+	"oe":             0x0153,
+	"ogonek":         0x02DB,
+	"ograve":         0x00F2,
+	"one":            0x0031,
+	"onehalf":        0x00BD,
+	"onequarter":     0x00BC,
+	"onesuperior":    0x00B9,
+	"ordfeminine":    0x00AA,
+	"ordmasculine":   0x00BA,
+	"oslash":         0x00F8,
+	"otilde":         0x00F5,
+	"p":              0x0070,
+	"paragraph":      0x00B6,
+	"parenleft":      0x0028,
+	"parenright":     0x0029,
+	"partialdiff":    0x2202,
+	"percent":        0x0025,
+	"period":         0x002E,
+	"periodcentered": 0x00B7,
+	"perthousand":    0x2030,
+	"pi":             0x03C0,
+	"plus":           0x002B,
+	"plusminus":      0x00B1,
+	"product":        0x220F,
+	"q":              0x0071,
+	"question":       0x003F,
+	"questiondown":   0x00BF,
+	"quotedbl":       0x0022,
+	"quotedblbase":   0x201E,
+	"quotedblleft":   0x201C,
+	"quotedblright":  0x201D,
+	"quoteleft":      0x2018,
+	"quoteright":     0x2019,
+	"quotesinglbase": 0x201A,
+	"quotesingle":    0x0027,
+	"r":              0x0072,
+	"radical":        0x221A,
+	"registered":     0x00AE,
+	"ring":           0x02DA,
+	"s":              0x0073,
+	"scaron":         0x0161,
+	"section":        0x00A7,
+	"semicolon":      0x003B,
+	"seven":          0x0037,
+	"sfthyphen":      0x00AD,
+	"six":            0x0036,
+	"slash":          0x002F,
+	"space":          0x0020,
+	"sterling":       0x00A3,
+	"summation":      0x2211,
+	"t":              0x0074,
+	"thorn":          0x00FE,
+	"three":          0x0033,
+	"threequarters":  0x00BE,
+	"threesuperior":  0x00B3,
+	"tilde":          0x02DC,
+	"trademark":      0x2122,
+	"two":            0x0032,
+	"twosuperior":    0x00B2,
+	"u":              0x0075,
+	"uacute":         0x00FA,
+	"ucircumflex":    0x00FB,
+	"udieresis":      0x00FC,
+	"ugrave":         0x00F9,
+	"underscore":     0x005F,
+	"v":              0x0076,
+	"w":              0x0077,
+	"x":              0x0078,
+	"y":              0x0079,
+	"yacute":         0x00FD,
+	"ydieresis":      0x00FF,
+	"yen":            0x00A5,
+	"z":              0x007A,
+	"zcaron":         0x017E,
+	"zero":           0x0030,
+
+	// the Symbol set (Annex D.4)
+	"Alpha":          0x0391,
+	"Beta":           0x0392,
+	"Chi":            0x03A7,
+	"Epsilon":        0x0395,
+	"Eta":            0x0397,
+	"Gamma":          0x0393,
+	"Ifraktur":       0x2111,
+	"Iota":           0x0399,
+	"Kappa":          0x039A,
+	"Lambda":         0x039B,
+	"Mu":             0x039C,
+	"Nu":             0x039D,
+	"Omicron":        0x039F,
+	"Phi":            0x03A6,
+	"Pi":             0x03A0,
+	"Psi":            0x03A8,
+	"Rfraktur":       0x211C,
+	"Rho":            0x03A1,
+	"Sigma":          0x03A3,
+	"Tau":            0x03A4,
+	"Theta":          0x0398,
+	"Upsilon":        0x03A5,
+	"Upsilon1":       0x03D2,
+	"Xi":             0x039E,
+	"Zeta":           0x0396,
+	"aleph":          0x2135,
+	"alpha":          0x03B1,
+	"angle":          0x2220,
+	"angleleft":      0x2329,
+	"angleright":     0x232A,
+	"arrowboth":      0x2194,
+	"arrowdblboth":   0x21D4,
+	"arrowdbldown":   0x21D3,
+	"arrowdblleft":   0x21D0,
+	"arrowdblright":  0x21D2,
+	"arrowdblup":     0x21D1,
+	"arrowdown":      0x2193,
+	"arrowhorizex":   0xF8E7,
+	"arrowleft":      0x2190,
+	"arrowright":     0x2192,
+	"arrowup":        0x2191,
+	"arrowvertex":    0xF8E6,
+	"asteriskmath":   0x2217,
+	"beta":           0x03B2,
+	"braceex":        0xF8F4,
+	"braceleftbt":    0xF8F3,
+	"braceleftmid":   0xF8F2,
+	"bracelefttp":    0xF8F1,
+	"bracerightbt":   0xF8FE,
+	"bracerightmid":  0xF8FD,
+	"bracerighttp":   0xF8FC,
+	"bracketleftbt":  0xF8F0,
+	"bracketleftex":  0xF8EF,
+	"bracketlefttp":  0xF8EE,
+	"bracketrightbt": 0xF8FB,
+	"bracketrightex": 0xF8FA,
+	"bracketrighttp": 0xF8F9,
+	"carriagereturn": 0x21B5,
+	"chi":            0x03C7,
+	"circlemultiply": 0x2297,
+	"circleplus":     0x2295,
+	"club":           0x2663,
+	"congruent":      0x2245,
+	"copyrightsans":  0xF8E9,
+	"copyrightserif": 0xF6D9,
+	"delta":          0x03B4,
+	"diamond":        0x2666,
+	"dotmath":        0x22C5,
+	"element":        0x2208,
+	"emptyset":       0x2205,
+	"epsilon":        0x03B5,
+	"equivalence":    0x2261,
+	"eta":            0x03B7,
+	"existential":    0x2203,
+	"gamma":          0x03B3,
+	"gradient":       0x2207,
+	"heart":          0x2665,
+	"integralbt":     0x2321,
+	"integralex":     0xF8F5,
+	"integraltp":     0x2320,
+	"intersection":   0x2229,
+	"iota":           0x03B9,
+	"kappa":          0x03BA,
+	"lambda":         0x03BB,
+	"logicaland":     0x2227,
+	"logicalor":      0x2228,
+	"minute":         0x2032,
+	"notelement":     0x2209,
+	"notsubset":      0x2284,
+	"nu":             0x03BD,
+	"omega":          0x03C9,
+	"omega1":         0x03D6,
+	"omicron":        0x03BF,
+	"parenleftbt":    0xF8ED,
+	"parenleftex":    0xF8EC,
+	"parenlefttp":    0xF8EB,
+	"parenrightbt":   0xF8F8,
+	"parenrightex":   0xF8F7,
+	"parenrighttp":   0xF8F6,
+	"perpendicular":  0x22A5,
+	"phi":            0x03C6,
+	"phi1":           0x03D5,
+	"propersubset":   0x2282,
+	"propersuperset": 0x2283,
+	"proportional":   0x221D,
+	"psi":            0x03C8,
+	"radicalex":      0xF8E5,
+	"reflexsubset":   0x2286,
+	"reflexsuperset": 0x2287,
+	"registersans":   0xF8E8,
+	"registerserif":  0xF6DA,
+	"rho":            0x03C1,
+	"second":         0x2033,
+	"sigma":          0x03C3,
+	"sigma1":         0x03C2,
+	"similar":        0x223C,
+	"spade":          0x2660,
+	"suchthat":       0x220B,
+	"tau":            0x03C4,
+	"therefore":      0x2234,
+	"theta":          0x03B8,
+	"theta1":         0x03D1,
+	"trademarksans":  0xF8EA,
+	"trademarkserif": 0xF6DB,
+	"union":          0x222A,
+	"universal":      0x2200,
+	"upsilon":        0x03C5,
+	"weierstrass":    0x2118,
+	"xi":             0x03BE,
+	"zeta":           0x03B6,
+
+	// the expert set (Annex D.3)
+	"colonmonetary":      0x20A1,
+	"eightinferior":      0x2088,
+	"eightsuperior":      0x2078,
+	"figuredash":         0x2012,
+	"fiveeighths":        0x215D,
+	"fiveinferior":       0x2085,
+	"fivesuperior":       0x2075,
+	"fourinferior":       0x2084,
+	"foursuperior":       0x2074,
+	"nineinferior":       0x2089,
+	"ninesuperior":       0x2079,
+	"nsuperior":          0x207F,
+	"onedotenleader":     0x2024,
+	"oneeighth":          0x215B,
+	"oneinferior":        0x2081,
+	"onethird":           0x2153,
+	"parenleftinferior":  0x208D,
+	"parenleftsuperior":  0x207D,
+	"parenrightinferior": 0x208E,
+	"parenrightsuperior": 0x207E,
+	"seveneighths":       0x215E,
+	"seveninferior":      0x2087,
+	"sevensuperior":      0x2077,
+	"sixinferior":        0x2086,
+	"sixsuperior":        0x2076,
+	"threeeighths":       0x215C,
+	"threeinferior":      0x2083,
+	"twodotenleader":     0x2025,
+	"twoinferior":        0x2082,
+	"twothirds":          0x2154,
+	"zeroinferior":       0x2080,
+	"zerosuperior":       0x2070,
+}
+
+// the Unicode values of the ZapfDingbats glyph names (Annex D.5),
+// which are only meaningful in that font
+var zapfDingbatsGlyphNames = map[string]rune{
+	"a1":   0x2701,
+	"a2":   0x2702,
+	"a3":   0x2704,
+	"a4":   0x260E,
+	"a5":   0x2706,
+	"a6":   0x271D,
+	"a7":   0x271E,
+	"a8":   0x271F,
+	"a9":   0x2720,
+	"a10":  0x2721,
+	"a11":  0x261B,
+	"a12":  0x261E,
+	"a13":  0x270C,
+	"a14":  0x270D,
+	"a15":  0x270E,
+	"a16":  0x270F,
+	"a17":  0x2711,
+	"a18":  0x2712,
+	"a19":  0x2713,
+	"a20":  0x2714,
+	"a21":  0x2715,
+	"a22":  0x2716,
+	"a23":  0x2717,
+	"a24":  0x2718,
+	"a25":  0x2719,
+	"a26":  0x271A,
+	"a27":  0x271B,
+	"a28":  0x271C,
+	"a29":  0x2722,
+	"a30":  0x2723,
+	"a31":  0x2724,
+	"a32":  0x2725,
+	"a33":  0x2726,
+	"a34":  0x2727,
+	"a35":  0x2605,
+	"a36":  0x2729,
+	"a37":  0x272A,
+	"a38":  0x272B,
+	"a39":  0x272C,
+	"a40":  0x272D,
+	"a41":  0x272E,
+	"a42":  0x272F,
+	"a43":  0x2730,
+	"a44":  0x2731,
+	"a45":  0x2732,
+	"a46":  0x2733,
+	"a47":  0x2734,
+	"a48":  0x2735,
+	"a49":  0x2736,
+	"a50":  0x2737,
+	"a51":  0x2738,
+	"a52":  0x2739,
+	"a53":  0x273A,
+	"a54":  0x273B,
+	"a55":  0x273C,
+	"a56":  0x273D,
+	"a57":  0x273E,
+	"a58":  0x273F,
+	"a59":  0x2740,
+	"a60":  0x2741,
+	"a61":  0x2742,
+	"a62":  0x2743,
+	"a63":  0x2744,
+	"a64":  0x2745,
+	"a65":  0x2746,
+	"a66":  0x2747,
+	"a67":  0x2748,
+	"a68":  0x2749,
+	"a69":  0x274A,
+	"a70":  0x274B,
+	"a71":  0x25CF,
+	"a72":  0x274D,
+	"a73":  0x25A0,
+	"a74":  0x274F,
+	"a75":  0x2751,
+	"a76":  0x25B2,
+	"a77":  0x25BC,
+	"a78":  0x25C6,
+	"a79":  0x2756,
+	"a81":  0x25D7,
+	"a82":  0x2758,
+	"a83":  0x2759,
+	"a84":  0x275A,
+	"a85":  0x276F,
+	"a86":  0x2771,
+	"a87":  0x2772,
+	"a88":  0x2773,
+	"a89":  0x2768,
+	"a90":  0x2769,
+	"a91":  0x276C,
+	"a92":  0x276D,
+	"a93":  0x276A,
+	"a94":  0x276B,
+	"a95":  0x2774,
+	"a96":  0x2775,
+	"a97":  0x275B,
+	"a98":  0x275C,
+	"a99":  0x275D,
+	"a100": 0x275E,
+	"a101": 0x2761,
+	"a102": 0x2762,
+	"a103": 0x2763,
+	"a104": 0x2764,
+	"a105": 0x2710,
+	"a106": 0x2765,
+	"a107": 0x2766,
+	"a108": 0x2767,
+	"a109": 0x2660,
+	"a110": 0x2665,
+	"a111": 0x2666,
+	"a112": 0x2663,
+	"a117": 0x2709,
+	"a118": 0x2708,
+	"a119": 0x2707,
+	"a120": 0x2460,
+	"a121": 0x2461,
+	"a122": 0x2462,
+	"a123": 0x2463,
+	"a124": 0x2464,
+	"a125": 0x2465,
+	"a126": 0x2466,
+	"a127": 0x2467,
+	"a128": 0x2468,
+	"a129": 0x2469,
+	"a130": 0x2776,
+	"a131": 0x2777,
+	"a132": 0x2778,
+	"a133": 0x2779,
+	"a134": 0x277A,
+	"a135": 0x277B,
+	"a136": 0x277C,
+	"a137": 0x277D,
+	"a138": 0x277E,
+	"a139": 0x277F,
+	"a140": 0x2780,
+	"a141": 0x2781,
+	"a142": 0x2782,
+	"a143": 0x2783,
+	"a144": 0x2784,
+	"a145": 0x2785,
+	"a146": 0x2786,
+	"a147": 0x2787,
+	"a148": 0x2788,
+	"a149": 0x2789,
+	"a150": 0x278A,
+	"a151": 0x278B,
+	"a152": 0x278C,
+	"a153": 0x278D,
+	"a154": 0x278E,
+	"a155": 0x278F,
+	"a156": 0x2790,
+	"a157": 0x2791,
+	"a158": 0x2792,
+	"a159": 0x2793,
+	"a160": 0x2794,
+	"a161": 0x2192,
+	"a162": 0x27A3,
+	"a163": 0x2194,
+	"a164": 0x2195,
+	"a165": 0x2799,
+	"a166": 0x279B,
+	"a167": 0x279C,
+	"a168": 0x279D,
+	"a169": 0x279E,
+	"a170": 0x279F,
+	"a171": 0x27A0,
+	"a172": 0x27A1,
+	"a173": 0x27A2,
+	"a174": 0x27A4,
+	"a175": 0x27A5,
+	"a176": 0x27A6,
+	"a177": 0x27A7,
+	"a178": 0x27A8,
+	"a179": 0x27A9,
+	"a180": 0x27AB,
+	"a181": 0x27AD,
+	"a182": 0x27AF,
+	"a183": 0x27B2,
+	"a184": 0x27B3,
+	"a185": 0x27B5,
+	"a186": 0x27B8,
+	"a187": 0x27BA,
+	"a188": 0x27BB,
+	"a189": 0x27BC,
+	"a190": 0x27BD,
+	"a191": 0x27BE,
+	"a192": 0x279A,
+	"a193": 0x27AA,
+	"a194": 0x27B6,
+	"a195": 0x27B9,
+	"a196": 0x2798,
+	"a197": 0x27B4,
+	"a198": 0x27B7,
+	"a199": 0x27AC,
+	"a200": 0x27AE,
+	"a201": 0x27B1,
+	"a202": 0x2703,
+	"a203": 0x2750,
+	"a204": 0x2752,
+	"a205": 0x276E,
+	"a206": 0x2770,
+}
@@ -0,0 +1,347 @@
+package text
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/nathankerr/pdf"
+)
+
+// a character code and the number of bytes it was encoded with
+type code struct {
+	value  uint32
+	length int
+}
+
+// what is needed from a font (§9.5) to decode and position text
+type font struct {
+	name pdf.Name
+
+	// composite fonts (Type0 §9.7) use a CMap to split strings
+	// into codes and map codes to CIDs; simple fonts use single
+	// bytes and map them to glyph names
+	composite bool
+	encoding  *cmap
+	glyphs    [256]string
+	// glyph names such as a1 are those of ZapfDingbats (Annex D.5)
+	dingbats bool
+
+	// predefined CMaps whose codes are UCS-2 or UTF-16 (§9.7.5.2)
+	unicodeCodes bool
+
+	toUnicode *cmap
+
+	// widths are in glyph space and are keyed by code for
+	// simple fonts and by CID for composite fonts
+	widths       map[uint32]float64
+	defaultWidth float64
+	// glyph space to text space (§9.2.4)
+	glyphScale float64
+}
+
+func loadFont(file *pdf.File, dict pdf.Dictionary) *font {
+	f := &font{
+		widths:     map[uint32]float64{},
+		glyphScale: 0.001,
+	}
+	f.name, _ = resolve(file, dict["BaseFont"]).(pdf.Name)
+
+	if stream, ok := resolve(file, dict["ToUnicode"]).(pdf.Stream); ok {
+		if data, err := stream.Decode(); err == nil {
+			f.toUnicode = parseCMap(data)
+		}
+	}
+
+	switch subtype, _ := resolve(file, dict["Subtype"]).(pdf.Name); subtype {
+	case "Type0":
+		f.loadComposite(file, dict)
+	case "Type3":
+		f.name, _ = resolve(file, dict["Name"]).(pdf.Name)
+		if fontMatrix, ok := resolve(file, dict["FontMatrix"]).(pdf.Array); ok && len(fontMatrix) == 6 {
+			if scale, ok := number(fontMatrix[0]); ok {
+				f.glyphScale = scale
+			}
+		}
+		f.loadSimple(file, dict)
+	default:
+		f.loadSimple(file, dict)
+	}
+
+	return f
+}
+
+// simple fonts (§9.6)
+func (f *font) loadSimple(file *pdf.File, dict pdf.Dictionary) {
+	// §9.6.6 character encoding, starting from the font's built-in
+	// encoding, which is StandardEncoding for the Latin text fonts
+	f.glyphs = standardEncoding
+	if builtin, ok := builtinEncodings[f.name]; ok {
+		f.glyphs = *builtin
+	}
+	f.dingbats = strings.Contains(string(f.name), "Dingbats")
+	switch encoding := resolve(file, dict["Encoding"]).(type) {
+	case pdf.Name:
+		if base, ok := encodings[encoding]; ok {
+			f.glyphs = *base
+		}
+	case pdf.Dictionary:
+		if name, ok := resolve(file, encoding["BaseEncoding"]).(pdf.Name); ok {
+			if base, ok := encodings[name]; ok {
+				f.glyphs = *base
+			}
+		}
+
+		// [code /name /name ... code /name ...]
+		differences, _ := resolve(file, encoding["Differences"]).(pdf.Array)
+		next := -1
+		for _, difference := range differences {
+			switch difference := resolve(file, difference).(type) {
+			case pdf.Integer:
+				next = int(difference)
+			case pdf.Name:
+				if next >= 0 && next < len(f.glyphs) {
+					f.glyphs[next] = string(difference)
+					next++
+				}
+			}
+		}
+	}
+
+	// §9.6.2.1 Table 111
+	firstChar, _ := resolve(file, dict["FirstChar"]).(pdf.Integer)
+	widths, _ := resolve(file, dict["Widths"]).(pdf.Array)
+	for i, width := range widths {
+		if w, ok := number(resolve(file, width)); ok {
+			f.widths[uint32(firstChar)+uint32(i)] = w
+		}
+	}
+
+	descriptor, _ := resolve(file, dict["FontDescriptor"]).(pdf.Dictionary)
+	f.defaultWidth, _ = number(resolve(file, descriptor["MissingWidth"]))
+
+	// the standard 14 fonts may omit their widths (§9.6.2.2)
+	if len(widths) == 0 {
+		metrics := standardWidths[f.name]
+		for c, glyph := range f.glyphs {
+			if width, ok := metrics[glyph]; ok {
+				f.widths[uint32(c)] = float64(width)
+			}
+		}
+
+		// every Courier glyph is 600 wide; glyphs that are not in
+		// the metrics, and fonts that are not standard, get a
+		// typical width
+		if f.defaultWidth == 0 {
+			f.defaultWidth = 500
+			if strings.HasPrefix(string(f.name), "Courier") {
+				f.defaultWidth = 600
+			}
+		}
+	}
+}
+
+// composite fonts (§9.7)
+func (f *font) loadComposite(file *pdf.File, dict pdf.Dictionary) {
+	f.composite = true
+
+	// §9.7.5 CMaps
+	switch encoding := resolve(file, dict["Encoding"]).(type) {
+	case pdf.Name:
+		name := string(encoding)
+		f.unicodeCodes = strings.Contains(name, "UCS2") || strings.Contains(name, "UTF16")
+	case pdf.Stream:
+		if data, err := encoding.Decode(); err == nil {
+			f.encoding = parseCMap(data)
+		}
+	}
+
+	// §9.7.4.3 glyph metrics in CIDFonts
+	descendants, _ := resolve(file, dict["DescendantFonts"]).(pdf.Array)
+	if len(descendants) == 0 {
+		return
+	}
+	descendant, _ := resolve(file, descendants[0]).(pdf.Dictionary)
+
+	f.defaultWidth = 1000
+	if dw, ok := number(resolve(file, descendant["DW"])); ok {
+		f.defaultWidth = dw
+	}
+
+	// c [w1 w2 ... wn] or c_first c_last w
+	w, _ := resolve(file, descendant["W"]).(pdf.Array)
+	for i := 0; i+1 < len(w); {
+		first, ok := number(resolve(file, w[i]))
+		if !ok {
+			return
+		}
+
+		if widths, ok := resolve(file, w[i+1]).(pdf.Array); ok {
+			for j, width := range widths {
+				if width, ok := number(resolve(file, width)); ok {
+					f.widths[uint32(first)+uint32(j)] = width
+				}
+			}
+			i += 2
+			continue
+		}
+
+		if i+2 >= len(w) {
+			return
+		}
+		last, ok1 := number(resolve(file, w[i+1]))
+		width, ok2 := number(resolve(file, w[i+2]))
+		if !ok1 || !ok2 || last-first > 0xFFFF {
+			return
+		}
+		for cid := uint32(first); cid <= uint32(last); cid++ {
+			f.widths[cid] = width
+		}
+		i += 3
+	}
+}
+
+// splits a string into character codes
+func (f *font) codes(s pdf.String) []code {
+	codes := make([]code, 0, len(s))
+
+	if !f.composite {
+		for _, b := range s {
+			codes = append(codes, code{uint32(b), 1})
+		}
+		return codes
+	}
+
+	for len(s) > 0 {
+		var c code
+		if f.encoding != nil {
+			c = f.encoding.next(s)
+		} else {
+			// the predefined CMaps used here (Identity-H/V and
+			// Unicode CMaps) use two byte codes
+			c = code{uint32(s[0]), 1}
+			if len(s) > 1 {
+				c = code{uint32(s[0])<<8 | uint32(s[1]), 2}
+			}
+		}
+
+		codes = append(codes, c)
+		s = s[c.length:]
+	}
+
+	return codes
+}
+
+// the horizontal displacement of a code in text space
+// for a font size of 1
+func (f *font) width(c code) float64 {
+	key := c.value
+	if f.composite && f.encoding != nil {
+		key = f.encoding.cid(c)
+	}
+
+	width, ok := f.widths[key]
+	if !ok {
+		width = f.defaultWidth
+	}
+	return width * f.glyphScale
+}
+
+// the Unicode text for a code (§9.10.2)
+func (f *font) unicode(c code) string {
+	if f.toUnicode != nil {
+		if s, ok := f.toUnicode.unicode[c]; ok {
+			return s
+		}
+	}
+
+	switch {
+	case f.composite && f.unicodeCodes:
+		if utf16.IsSurrogate(rune(c.value)) {
+			return replacementCharacter
+		}
+		return string(rune(c.value))
+	case !f.composite:
+		glyph := f.glyphs[c.value&0xFF]
+		if r, ok := zapfDingbatsGlyphNames[glyph]; ok && f.dingbats {
+			return string(r)
+		}
+		if s := glyphUnicode(glyph); s != "" {
+			return s
+		}
+	}
+
+	return replacementCharacter
+}
+
+// encodings that can be named in a font's Encoding (§9.6.6.1)
+var encodings = map[pdf.Name]*[256]string{
+	"StandardEncoding":  &standardEncoding,
+	"WinAnsiEncoding":   &winAnsiEncoding,
+	"MacRomanEncoding":  &macRomanEncoding,
+	"MacExpertEncoding": &macExpertEncoding,
+}
+
+// the fonts whose built-in encoding is not StandardEncoding (§9.6.6.2)
+var builtinEncodings = map[pdf.Name]*[256]string{
+	"Symbol":       &symbolEncoding,
+	"ZapfDingbats": &zapfDingbatsEncoding,
+}
+
+// maps a glyph name to Unicode following the Adobe Glyph List
+// Specification's rules for glyph names that are not in the list
+func glyphUnicode(name string) string {
+	if r, ok := glyphNames[name]; ok {
+		return string(r)
+	}
+
+	// suffixes such as "a.sc"
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		return glyphUnicode(name[:i])
+	}
+
+	// the expert set's variants (Annex D.3), such as "Asmall" for
+	// a small capital a and "oneoldstyle" for an oldstyle 1
+	for _, suffix := range []string{"small", "oldstyle", "superior", "inferior"} {
+		base := strings.TrimSuffix(name, suffix)
+		if base == name || base == "" {
+			continue
+		}
+		if r, ok := glyphNames[strings.ToLower(base)]; ok {
+			return string(r)
+		}
+		return glyphUnicode(base)
+	}
+
+	// ligatures such as "f_f_i"
+	if strings.IndexByte(name, '_') > 0 {
+		s := ""
+		for _, component := range strings.Split(name, "_") {
+			s += glyphUnicode(component)
+		}
+		return s
+	}
+
+	// uniXXXX[XXXX...]
+	if digits := strings.TrimPrefix(name, "uni"); len(digits) != len(name) && len(digits) > 0 && len(digits)%4 == 0 {
+		s := ""
+		for i := 0; i < len(digits); i += 4 {
+			value, err := strconv.ParseUint(digits[i:i+4], 16, 16)
+			if err != nil || utf16.IsSurrogate(rune(value)) {
+				return ""
+			}
+			s += string(rune(value))
+		}
+		return s
+	}
+
+	// uXXXX to uXXXXXX
+	if digits := strings.TrimPrefix(name, "u"); len(digits) != len(name) && len(digits) >= 4 && len(digits) <= 6 {
+		value, err := strconv.ParseUint(digits, 16, 32)
+		if err == nil && value <= 0x10FFFF && !utf16.IsSurrogate(rune(value)) {
+			return string(rune(value))
+		}
+	}
+
+	return ""
+}
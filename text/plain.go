@@ -0,0 +1,87 @@
+package text
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// PlainText returns the text of the runs in reading order.
+//
+// Runs that share a baseline form a line and are ordered left
+// to right; lines are ordered top to bottom. Spaces are added
+// between runs separated by more than a fraction of the font size
+// and an empty line is added between lines that are far apart.
+// Only horizontal text is considered.
+func PlainText(runs []Run) string {
+	type line struct {
+		y, size float64
+		runs    []Run
+	}
+
+	lines := []*line{}
+	for _, run := range runs {
+		if run.Text == "" || run.Size == 0 {
+			continue
+		}
+
+		var found *line
+		for _, l := range lines {
+			tolerance := math.Min(l.size, run.Size) / 2
+			if math.Abs(l.y-run.Y) <= tolerance {
+				found = l
+				break
+			}
+		}
+		if found == nil {
+			found = &line{y: run.Y}
+			lines = append(lines, found)
+		}
+
+		found.runs = append(found.runs, run)
+		found.size = math.Max(found.size, run.Size)
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].y > lines[j].y
+	})
+
+	text := &strings.Builder{}
+	for i, l := range lines {
+		if i > 0 {
+			text.WriteByte('\n')
+
+			// paragraphs
+			if lines[i-1].y-l.y > 2*math.Max(l.size, lines[i-1].size) {
+				text.WriteByte('\n')
+			}
+		}
+
+		sort.SliceStable(l.runs, func(i, j int) bool {
+			return l.runs[i].X < l.runs[j].X
+		})
+
+		lineText := &strings.Builder{}
+		for j, run := range l.runs {
+			if j > 0 {
+				previous := l.runs[j-1]
+				gap := run.X - (previous.X + previous.Width)
+				if gap > wordSpace*math.Max(run.Size, previous.Size) &&
+					!endsWithSpace(lineText.String()) && !strings.HasPrefix(run.Text, " ") {
+					lineText.WriteByte(' ')
+				}
+			}
+			lineText.WriteString(run.Text)
+		}
+		text.WriteString(strings.TrimRight(lineText.String(), " "))
+	}
+
+	return text.String()
+}
+
+// gaps wider than this fraction of the font size separate words
+const wordSpace = 0.15
+
+func endsWithSpace(s string) bool {
+	return s == "" || strings.HasSuffix(s, " ")
+}
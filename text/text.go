@@ -0,0 +1,463 @@
+/*
+Package text extracts text from PDF pages.
+
+The page's content stream is interpreted for the text operators (§9.4)
+and the operators that change their coordinate system. Each string
+shown is decoded to Unicode using the font's ToUnicode CMap (§9.10.3)
+or, failing that, its character encoding (§9.6.6, Annex D).
+*/
+package text
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/nathankerr/pdf"
+	"github.com/nathankerr/pdf/content"
+)
+
+// A Run is the text shown by a single string of a text showing
+// operator (Tj, TJ, ' or ").
+//
+// Positions and sizes are in default user space (§8.3.2.3).
+type Run struct {
+	Text string
+
+	// the start of the baseline
+	X, Y float64
+
+	// the distance the text advanced along the baseline
+	Width float64
+
+	// the BaseFont of the font used to show the text
+	Font pdf.Name
+
+	// the font size scaled by the text and current transformation matrices
+	Size float64
+
+	// the text rendering mode (Tr §9.3.6)
+	RenderMode int
+}
+
+// Invisible reports whether the text is neither filled nor stroked,
+// such as text recognized from a scanned image that is drawn over it.
+func (r Run) Invisible() bool {
+	return r.RenderMode == 3 || r.RenderMode == 7
+}
+
+// Characters that could not be mapped to Unicode are
+// replaced with U+FFFD.
+const replacementCharacter = "\uFFFD"
+
+// maximum nesting of form XObjects
+const maxFormDepth = 32
+
+// Extract returns the text runs shown on page in the order
+// they are shown. Resources inherited from the page tree
+// (§7.7.3.4) and form XObjects (§8.10) are followed.
+//
+// On error, the runs extracted so far are returned.
+func Extract(file *pdf.File, page pdf.Dictionary) ([]Run, error) {
+	data, err := pageContents(file, page)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, _ := inherited(file, page, "Resources").(pdf.Dictionary)
+
+	e := &extractor{
+		file:  file,
+		fonts: map[pdf.ObjectReference]*font{},
+		state: graphicsState{ctm: identity, scale: 1},
+	}
+	err = e.interpret(data, resources)
+	return e.runs, err
+}
+
+// concatenates the page's content streams (§7.7.3.3)
+func pageContents(file *pdf.File, page pdf.Dictionary) ([]byte, error) {
+	var streams pdf.Array
+	switch contents := resolve(file, page["Contents"]).(type) {
+	case pdf.Stream:
+		streams = pdf.Array{contents}
+	case pdf.Array:
+		streams = contents
+	case nil, pdf.Null:
+		return nil, nil
+	default:
+		return nil, errors.New("page contents must be a stream or an array of streams")
+	}
+
+	data := []byte{}
+	for _, obj := range streams {
+		stream, ok := resolve(file, obj).(pdf.Stream)
+		if !ok {
+			return nil, errors.New("page contents must be a stream or an array of streams")
+		}
+
+		decoded, err := stream.Decode()
+		if err != nil {
+			return nil, err
+		}
+
+		// the division between streams is white-space
+		data = append(data, decoded...)
+		data = append(data, '\n')
+	}
+
+	return data, nil
+}
+
+// returns the value of an attribute that can be inherited from
+// the page's ancestors in the page tree
+func inherited(file *pdf.File, page pdf.Dictionary, key pdf.Name) pdf.Object {
+	for depth := 0; page != nil && depth < 64; depth++ {
+		if value, ok := page[key]; ok {
+			return resolve(file, value)
+		}
+		page, _ = resolve(file, page["Parent"]).(pdf.Dictionary)
+	}
+	return nil
+}
+
+// follows object references
+func resolve(file *pdf.File, obj pdf.Object) pdf.Object {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(pdf.ObjectReference)
+		if !ok {
+			return obj
+		}
+		if file == nil {
+			return nil
+		}
+		obj = file.Get(ref)
+	}
+	return nil
+}
+
+// returns the value of an Integer or Real
+func number(obj pdf.Object) (float64, bool) {
+	switch n := obj.(type) {
+	case pdf.Integer:
+		return float64(n), true
+	case pdf.Real:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func numbers(objs []pdf.Object) ([]float64, bool) {
+	values := make([]float64, len(objs))
+	for i, obj := range objs {
+		value, ok := number(obj)
+		if !ok {
+			return nil, false
+		}
+		values[i] = value
+	}
+	return values, true
+}
+
+// [a b c d e f] §8.3.4
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// m × n
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(tx, ty float64) matrix {
+	return matrix{1, 0, 0, 1, tx, ty}
+}
+
+// the parts of the graphics state (§8.4) and
+// text state (§9.3) used to position text
+type graphicsState struct {
+	ctm         matrix
+	charSpacing float64 // Tc
+	wordSpacing float64 // Tw
+	scale       float64 // Tz / 100
+	leading     float64 // TL
+	font        *font   // Tf
+	size        float64 // Tf
+	rise        float64 // Ts
+	renderMode  int     // Tr
+}
+
+type extractor struct {
+	file  *pdf.File
+	fonts map[pdf.ObjectReference]*font
+	runs  []Run
+
+	state      graphicsState
+	stack      []graphicsState
+	textMatrix matrix // Tm
+	lineMatrix matrix // Tlm
+	depth      int
+}
+
+func (e *extractor) interpret(data []byte, resources pdf.Dictionary) error {
+	// unknown operators are ignored rather than ending the extraction
+	operations, parseErr := content.ParseCompatible(data)
+
+	for _, operation := range operations {
+		e.operation(operation, resources)
+	}
+
+	return parseErr
+}
+
+func (e *extractor) operation(operation content.Operation, resources pdf.Dictionary) {
+	state := &e.state
+	operands := operation.Operands
+	values, isNumbers := numbers(operands)
+
+	switch operation.Operator {
+	// §8.4.4 graphics state operators
+	case "q":
+		e.stack = append(e.stack, e.state)
+	case "Q":
+		if len(e.stack) > 0 {
+			e.state = e.stack[len(e.stack)-1]
+			e.stack = e.stack[:len(e.stack)-1]
+		}
+	case "cm":
+		if isNumbers && len(values) == 6 {
+			state.ctm = matrix{values[0], values[1], values[2], values[3], values[4], values[5]}.multiply(state.ctm)
+		}
+
+	// §9.4.1 text objects
+	case "BT":
+		e.textMatrix = identity
+		e.lineMatrix = identity
+
+	// §9.3 text state operators
+	case "Tc":
+		if isNumbers && len(values) == 1 {
+			state.charSpacing = values[0]
+		}
+	case "Tw":
+		if isNumbers && len(values) == 1 {
+			state.wordSpacing = values[0]
+		}
+	case "Tz":
+		if isNumbers && len(values) == 1 {
+			state.scale = values[0] / 100
+		}
+	case "TL":
+		if isNumbers && len(values) == 1 {
+			state.leading = values[0]
+		}
+	case "Ts":
+		if isNumbers && len(values) == 1 {
+			state.rise = values[0]
+		}
+	case "Tr":
+		if isNumbers && len(values) == 1 && values[0] >= 0 && values[0] <= 7 {
+			state.renderMode = int(values[0])
+		}
+	case "Tf":
+		if len(operands) != 2 {
+			break
+		}
+		name, ok1 := operands[0].(pdf.Name)
+		size, ok2 := number(operands[1])
+		if ok1 && ok2 {
+			state.font = e.font(resources, name)
+			state.size = size
+		}
+
+	// §9.4.2 text positioning operators
+	case "Td":
+		if isNumbers && len(values) == 2 {
+			e.moveText(values[0], values[1])
+		}
+	case "TD":
+		if isNumbers && len(values) == 2 {
+			state.leading = -values[1]
+			e.moveText(values[0], values[1])
+		}
+	case "Tm":
+		if isNumbers && len(values) == 6 {
+			e.textMatrix = matrix{values[0], values[1], values[2], values[3], values[4], values[5]}
+			e.lineMatrix = e.textMatrix
+		}
+	case "T*":
+		e.moveText(0, -state.leading)
+
+	// §9.4.3 text showing operators
+	case "Tj":
+		if len(operands) == 1 {
+			if s, ok := operands[0].(pdf.String); ok {
+				e.show(s)
+			}
+		}
+	case "'":
+		e.moveText(0, -state.leading)
+		if len(operands) == 1 {
+			if s, ok := operands[0].(pdf.String); ok {
+				e.show(s)
+			}
+		}
+	case "\"":
+		if len(operands) != 3 {
+			break
+		}
+		wordSpacing, ok1 := number(operands[0])
+		charSpacing, ok2 := number(operands[1])
+		s, ok3 := operands[2].(pdf.String)
+		if ok1 && ok2 && ok3 {
+			state.wordSpacing = wordSpacing
+			state.charSpacing = charSpacing
+			e.moveText(0, -state.leading)
+			e.show(s)
+		}
+	case "TJ":
+		if len(operands) != 1 {
+			break
+		}
+		array, _ := operands[0].(pdf.Array)
+		for _, element := range array {
+			switch element := element.(type) {
+			case pdf.String:
+				e.show(element)
+			default:
+				if adjustment, ok := number(element); ok {
+					tx := -adjustment / 1000 * state.size * state.scale
+					e.textMatrix = translate(tx, 0).multiply(e.textMatrix)
+				}
+			}
+		}
+
+	// §8.10 form XObjects
+	case "Do":
+		if len(operands) == 1 {
+			if name, ok := operands[0].(pdf.Name); ok {
+				e.form(resources, name)
+			}
+		}
+	}
+}
+
+func (e *extractor) moveText(tx, ty float64) {
+	e.lineMatrix = translate(tx, ty).multiply(e.lineMatrix)
+	e.textMatrix = e.lineMatrix
+}
+
+// shows a string, advancing the text matrix (§9.4.4)
+func (e *extractor) show(s pdf.String) {
+	state := &e.state
+	if state.font == nil {
+		return
+	}
+
+	// text rendering matrix §9.4.2
+	renderingMatrix := func() matrix {
+		parameters := matrix{state.size * state.scale, 0, 0, state.size, 0, state.rise}
+		return parameters.multiply(e.textMatrix).multiply(state.ctm)
+	}
+
+	start := renderingMatrix()
+	space := e.textMatrix.multiply(state.ctm)
+	size := math.Abs(state.size) * math.Hypot(space[2], space[3])
+
+	text := &strings.Builder{}
+	for _, c := range state.font.codes(s) {
+		text.WriteString(state.font.unicode(c))
+
+		tx := state.font.width(c)*state.size + state.charSpacing
+		if c.length == 1 && c.value == ' ' {
+			tx += state.wordSpacing
+		}
+		tx *= state.scale
+
+		e.textMatrix = translate(tx, 0).multiply(e.textMatrix)
+	}
+	end := renderingMatrix()
+
+	e.runs = append(e.runs, Run{
+		Text:       text.String(),
+		X:          start[4],
+		Y:          start[5],
+		Width:      math.Hypot(end[4]-start[4], end[5]-start[5]),
+		Font:       state.font.name,
+		Size:       size,
+		RenderMode: state.renderMode,
+	})
+}
+
+// returns the font for a font resource name (§9.2.2)
+func (e *extractor) font(resources pdf.Dictionary, name pdf.Name) *font {
+	fonts, _ := resolve(e.file, resources["Font"]).(pdf.Dictionary)
+	obj := fonts[name]
+
+	ref, isRef := obj.(pdf.ObjectReference)
+	if isRef {
+		if f, ok := e.fonts[ref]; ok {
+			return f
+		}
+	}
+
+	dict, ok := resolve(e.file, obj).(pdf.Dictionary)
+	if !ok {
+		return nil
+	}
+
+	f := loadFont(e.file, dict)
+	if isRef {
+		e.fonts[ref] = f
+	}
+	return f
+}
+
+// interprets a form XObject (§8.10.1)
+func (e *extractor) form(resources pdf.Dictionary, name pdf.Name) {
+	xobjects, _ := resolve(e.file, resources["XObject"]).(pdf.Dictionary)
+	form, ok := resolve(e.file, xobjects[name]).(pdf.Stream)
+	if !ok || form.Dictionary["Subtype"] != pdf.Name("Form") {
+		return
+	}
+
+	if e.depth >= maxFormDepth {
+		return
+	}
+	e.depth++
+	defer func() { e.depth-- }()
+
+	data, err := form.Decode()
+	if err != nil {
+		return
+	}
+
+	// forms without resources use those of the page (§7.8.3)
+	formResources, ok := resolve(e.file, form.Dictionary["Resources"]).(pdf.Dictionary)
+	if !ok {
+		formResources = resources
+	}
+
+	saved, savedStack := e.state, e.stack
+	savedText, savedLine := e.textMatrix, e.lineMatrix
+	e.stack = nil
+
+	if array, ok := resolve(e.file, form.Dictionary["Matrix"]).(pdf.Array); ok {
+		if values, ok := numbers(array); ok && len(values) == 6 {
+			formMatrix := matrix{values[0], values[1], values[2], values[3], values[4], values[5]}
+			e.state.ctm = formMatrix.multiply(e.state.ctm)
+		}
+	}
+
+	e.interpret(data, formResources)
+
+	e.state, e.stack = saved, savedStack
+	e.textMatrix, e.lineMatrix = savedText, savedLine
+}
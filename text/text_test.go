@@ -0,0 +1,283 @@
+package text
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nathankerr/pdf"
+)
+
+// a page with the given content stream and fonts
+func testPage(stream string, fonts pdf.Dictionary) pdf.Dictionary {
+	return pdf.Dictionary{
+		"Type": pdf.Name("Page"),
+		"Parent": pdf.Dictionary{
+			"Type":      pdf.Name("Pages"),
+			"Resources": pdf.Dictionary{"Font": fonts},
+		},
+		"Contents": pdf.Stream{
+			Dictionary: pdf.Dictionary{"Length": pdf.Integer(len(stream))},
+			Stream:     []byte(stream),
+		},
+	}
+}
+
+func fixedWidthFont(name pdf.Name, width int) pdf.Dictionary {
+	widths := pdf.Array{}
+	for i := 0; i < 256; i++ {
+		widths = append(widths, pdf.Integer(width))
+	}
+	return pdf.Dictionary{
+		"Type":      pdf.Name("Font"),
+		"Subtype":   pdf.Name("Type1"),
+		"BaseFont":  name,
+		"Encoding":  pdf.Name("WinAnsiEncoding"),
+		"FirstChar": pdf.Integer(0),
+		"Widths":    widths,
+	}
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestExtractPositions(t *testing.T) {
+	page := testPage(`
+q 2 0 0 2 10 20 cm
+BT /F1 10 Tf 12 TL 5 6 Td (ab) Tj T* [(c) -1000 (d)] TJ
+1 0 0 1 100 0 Tm 3 Tc (e) ' 50 Tz 2 1 (f f) " ET
+Q
+BT /F1 10 Tf (g) Tj ET`, pdf.Dictionary{"F1": fixedWidthFont("Courier", 600)})
+
+	runs, err := Extract(nil, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Run{
+		{Text: "ab", X: 20, Y: 32, Width: 24, Size: 20},
+		{Text: "c", X: 20, Y: 8, Width: 12, Size: 20},
+		{Text: "d", X: 52, Y: 8, Width: 12, Size: 20},
+		{Text: "e", X: 210, Y: -4, Width: 18, Size: 20},
+		// (3 × (6 + 1 char spacing) + 2 word spacing) × 50% horizontal scaling × 2 cm
+		{Text: "f f", X: 210, Y: -28, Width: 23, Size: 20},
+		{Text: "g", X: 0, Y: 0, Width: 6, Size: 10},
+	}
+
+	if len(runs) != len(expected) {
+		t.Fatalf("expected %d runs, got %d: %v", len(expected), len(runs), runs)
+	}
+	for i, run := range runs {
+		e := expected[i]
+		if run.Text != e.Text || !closeTo(run.X, e.X) || !closeTo(run.Y, e.Y) ||
+			!closeTo(run.Width, e.Width) || !closeTo(run.Size, e.Size) || run.Font != "Courier" {
+			t.Errorf("%d: expected %+v, got %+v", i, e, run)
+		}
+	}
+}
+
+func TestExtractEncodings(t *testing.T) {
+	differences := fixedWidthFont("Custom", 500)
+	differences["Encoding"] = pdf.Dictionary{
+		"BaseEncoding": pdf.Name("MacRomanEncoding"),
+		"Differences":  pdf.Array{pdf.Integer(1), pdf.Name("fi"), pdf.Name("uni00E9"), pdf.Integer(0x41), pdf.Name("a.sc")},
+	}
+
+	toUnicode := []byte(`/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def
+/CMapName /Adobe-Identity-UCS def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0003> <0048>
+<0004> <D835DC00>
+endbfchar
+2 beginbfrange
+<0010> <0012> <0061>
+<0020> <0021> [<0078> <00660066>]
+endbfrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`)
+	composite := pdf.Dictionary{
+		"Type":     pdf.Name("Font"),
+		"Subtype":  pdf.Name("Type0"),
+		"BaseFont": pdf.Name("Composite"),
+		"Encoding": pdf.Name("Identity-H"),
+		"DescendantFonts": pdf.Array{pdf.Dictionary{
+			"Type":    pdf.Name("Font"),
+			"Subtype": pdf.Name("CIDFontType2"),
+			"DW":      pdf.Integer(1000),
+			"W":       pdf.Array{pdf.Integer(3), pdf.Array{pdf.Integer(250)}, pdf.Integer(16), pdf.Integer(18), pdf.Integer(500)},
+		}},
+		"ToUnicode": pdf.Stream{Dictionary: pdf.Dictionary{}, Stream: toUnicode},
+	}
+
+	page := testPage(`BT /F1 1 Tf (\001\002\200\047A) Tj /F2 1 Tf <0003000400100011001200200021ffff> Tj ET`,
+		pdf.Dictionary{"F1": differences, "F2": composite})
+
+	runs, err := Extract(nil, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %v", runs)
+	}
+
+	if runs[0].Text != "ﬁéÄ'a" {
+		t.Errorf("expected %q, got %q", "ﬁéÄ'a", runs[0].Text)
+	}
+	if runs[1].Text != "H\U0001d400abcxff�" {
+		t.Errorf("expected %q, got %q", "H\U0001d400abcxff�", runs[1].Text)
+	}
+	if width := 0.25 + 1 + 3*0.5 + 3*1; !closeTo(runs[1].Width, width) {
+		t.Errorf("expected width %v, got %v", width, runs[1].Width)
+	}
+}
+
+func TestExtractForm(t *testing.T) {
+	form := pdf.Stream{
+		Dictionary: pdf.Dictionary{
+			"Type":    pdf.Name("XObject"),
+			"Subtype": pdf.Name("Form"),
+			"BBox":    pdf.Array{pdf.Integer(0), pdf.Integer(0), pdf.Integer(100), pdf.Integer(100)},
+			"Matrix":  pdf.Array{pdf.Integer(1), pdf.Integer(0), pdf.Integer(0), pdf.Integer(1), pdf.Integer(0), pdf.Integer(50)},
+		},
+		Stream: []byte("BT /F1 10 Tf (in form) Tj ET"),
+	}
+
+	page := testPage("q 1 0 0 1 10 0 cm /Fm1 Do Q BT /F1 10 Tf (after) Tj ET",
+		pdf.Dictionary{"F1": fixedWidthFont("Helvetica", 500)})
+	page["Resources"] = pdf.Dictionary{
+		"Font":    page["Parent"].(pdf.Dictionary)["Resources"].(pdf.Dictionary)["Font"],
+		"XObject": pdf.Dictionary{"Fm1": form},
+	}
+
+	runs, err := Extract(nil, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runs) != 2 || runs[0].Text != "in form" || runs[0].X != 10 || runs[0].Y != 50 ||
+		runs[1].Text != "after" || runs[1].X != 0 || runs[1].Y != 0 {
+		t.Errorf("unexpected runs %+v", runs)
+	}
+}
+
+func TestExtractUnknownOperators(t *testing.T) {
+	page := testPage(`BT /F1 10 Tf (a) Tj 1 2 unknown (b) Tj ET`,
+		pdf.Dictionary{"F1": fixedWidthFont("Courier", 600)})
+	runs, err := Extract(nil, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].Text != "a" || runs[1].Text != "b" {
+		t.Errorf("unexpected runs %+v", runs)
+	}
+}
+
+func TestExtractRenderMode(t *testing.T) {
+	page := testPage(`BT /F1 10 Tf (a) Tj 3 Tr (b) Tj ET BT /F1 10 Tf (c) Tj ET`,
+		pdf.Dictionary{"F1": fixedWidthFont("Courier", 600)})
+	runs, err := Extract(nil, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("unexpected runs %+v", runs)
+	}
+	// the text state is kept between text objects (§9.3.1)
+	for i, invisible := range []bool{false, true, true} {
+		if runs[i].Invisible() != invisible {
+			t.Errorf("%d: %+v is invisible %v", i, runs[i], runs[i].Invisible())
+		}
+	}
+}
+
+func TestExtractStandardFonts(t *testing.T) {
+	for _, test := range []struct {
+		font     pdf.Name
+		encoding pdf.Object
+		text     string
+		expected string
+		width    float64
+	}{
+		{"Helvetica", nil, "Wi", "Wi", 944 + 222},
+		{"Helvetica-BoldOblique", nil, "Wi", "Wi", 944 + 278},
+		{"Times-Bold", pdf.Name("WinAnsiEncoding"), "W\351", "Wé", 1000 + 444},
+		{"Courier", nil, "Wi", "Wi", 600 + 600},
+		{"Symbol", nil, "abg\326", "αβγ√", 631 + 549 + 411 + 549},
+		{"ZapfDingbats", nil, "4\254", "✔①", 846 + 788},
+		{"Times-Roman", pdf.Name("MacExpertEncoding"), "\126\141\061\332", "ﬀa1¹", 0},
+	} {
+		font := pdf.Dictionary{
+			"Type":     pdf.Name("Font"),
+			"Subtype":  pdf.Name("Type1"),
+			"BaseFont": test.font,
+		}
+		if test.encoding != nil {
+			font["Encoding"] = test.encoding
+		}
+		page := testPage("BT /F1 10 Tf ("+test.text+") Tj ET", pdf.Dictionary{"F1": font})
+
+		runs, err := Extract(nil, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != 1 || runs[0].Text != test.expected {
+			t.Errorf("%s: expected %q, got %+v", test.font, test.expected, runs)
+			continue
+		}
+		if test.width != 0 && !closeTo(runs[0].Width, test.width/100) {
+			t.Errorf("%s: expected width %v, got %v", test.font, test.width/100, runs[0].Width)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	runs := []Run{
+		{Text: "second", X: 72, Y: 686, Width: 36, Size: 12},
+		{Text: "Hello", X: 72, Y: 700, Width: 30, Size: 12},
+		{Text: "world", X: 105, Y: 700.5, Width: 30, Size: 12},
+		{Text: "!", X: 135, Y: 700, Width: 3, Size: 12},
+		{Text: "line", X: 110, Y: 686, Width: 24, Size: 12},
+		{Text: "Next paragraph", X: 72, Y: 640, Width: 80, Size: 12},
+		{Text: "", X: 0, Y: 0, Size: 12},
+	}
+
+	expected := "Hello world!\nsecond line\n\nNext paragraph"
+	if got := PlainText(runs); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestGlyphUnicode(t *testing.T) {
+	for name, expected := range map[string]string{
+		"A":            "A",
+		"quoteright":   "’",
+		"Euro":         "€",
+		"f_f_i":        "ffi",
+		"uni20AC":      "€",
+		"uni00410042":  "AB",
+		"u1D400":       "\U0001d400",
+		"one.oldstyle": "1",
+		"alpha":        "α",
+		"Asmall":       "a",
+		"AEsmall":      "æ",
+		"zerooldstyle": "0",
+		"zeroinferior": "₀",
+		"asuperior":    "a",
+		"uniD800":      "",
+		"g123":         "",
+		".notdef":      "",
+	} {
+		if got := glyphUnicode(name); got != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RFC 3161 time-stamp tokens, for signature timestamps and
+// document timestamps (§12.8.5)
+
+// Timestamper gets time-stamp tokens from a time-stamping authority.
+type Timestamper interface {
+	// Timestamp returns a time-stamp token, the DER encoded
+	// content info of RFC 3161 section 2.4.2, for the digest
+	// made with the hash.
+	Timestamp(digest []byte, hash crypto.Hash) ([]byte, error)
+}
+
+// HTTPTimestamper is a Timestamper that requests tokens from a
+// time-stamping authority with the HTTP protocol of RFC 3161
+// section 3.4.
+type HTTPTimestamper struct {
+	// The URL requests are posted to.
+	URL string
+
+	// http.DefaultClient is used when nil.
+	Client *http.Client
+
+	// The TSA policy the token is requested under, if any.
+	Policy asn1.ObjectIdentifier
+}
+
+// the largest response that is read
+const maxTimestampResponse = 1 << 20
+
+// Timestamp requests a token for the digest. The token is checked
+// to be for the digest, but its certificate is not verified.
+func (h *HTTPTimestamper) Timestamp(digest []byte, hash crypto.Hash) ([]byte, error) {
+	algorithm, ok := digestAlgorithms[hash]
+	if !ok {
+		return nil, fmt.Errorf("hash %v is not supported", hash)
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	request, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: algorithm},
+			HashedMessage: digest,
+		},
+		Policy:  h.Policy,
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Post(h.URL, "application/timestamp-query", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("time-stamping authority responded with %s", response.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxTimestampResponse))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp timeStampResp
+	_, err = asn1.Unmarshal(body, &resp)
+	if err != nil {
+		return nil, err
+	}
+	// granted or granted with modifications
+	if resp.Status.Status != 0 && resp.Status.Status != 1 {
+		return nil, fmt.Errorf("time stamp was not granted (status %d): %s",
+			resp.Status.Status, strings.Join(resp.Status.StatusString, "; "))
+	}
+
+	token := resp.TimeStampToken.FullBytes
+	_, info, err := verifyTimestamp(token, nil, digest)
+	if err != nil {
+		return nil, err
+	}
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("time-stamp token does not have the request's nonce")
+	}
+	return token, nil
+}
+
+// AddDocumentTimestamp adds a signature field with a document
+// timestamp (§12.8.5) of the whole file, which is saved and then
+// timestamped as with Sign.
+func (f *File) AddDocumentTimestamp(t Timestamper) error {
+	sig := Dictionary{
+		"Type":      Name("DocTimeStamp"),
+		"Filter":    Name("Adobe.PPKLite"),
+		"SubFilter": Name("ETSI.RFC3161"),
+	}
+	return f.addSignature(sig, signing{
+		size: 16384,
+		hash: crypto.SHA256,
+		sign: func(digest []byte) ([]byte, error) {
+			return t.Timestamp(digest, crypto.SHA256)
+		},
+	})
+}
+
+// RFC 3161 section 2.4.1
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	Policy         asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+	Extensions     []pkix.Extension      `asn1:"optional,tag:0"`
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// RFC 3161 section 2.4.2
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional,utf8"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time     `asn1:"generalized"`
+	Accuracy       accuracy      `asn1:"optional"`
+	Ordering       bool          `asn1:"optional,default:false"`
+	Nonce          *big.Int      `asn1:"optional"`
+	TSA            asn1.RawValue `asn1:"optional,tag:0"`
+	Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// verifies the signature of a time-stamp token and that it is for the
+// data, or for the digest when there is no data
+func verifyTimestamp(token []byte, data [][]byte, digest []byte) (*cmsSignature, *tstInfo, error) {
+	cms, err := verifyCMS(token, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !cms.contentType.Equal(oidTSTInfo) {
+		return nil, nil, fmt.Errorf("time-stamp token content type %v is not TSTInfo", cms.contentType)
+	}
+	info := &tstInfo{}
+	_, err = asn1.Unmarshal(cms.content, info)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(data) != 0 {
+		hash, ok := hashes[info.MessageImprint.HashAlgorithm.Algorithm.String()]
+		if !ok || !hash.Available() {
+			return nil, nil, fmt.Errorf("time-stamp digest algorithm %v is not supported", info.MessageImprint.HashAlgorithm.Algorithm)
+		}
+		h := hash.New()
+		for _, part := range data {
+			h.Write(part)
+		}
+		digest = h.Sum(nil)
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, nil, errors.New("time-stamp token is not for the data")
+	}
+
+	return cms, info, nil
+}
+
+// verifies the certificate of a verified time-stamp token
+func (cms *cmsSignature) verifyTimestampCertificate(roots *x509.CertPool, genTime time.Time) ([][]*x509.Certificate, error) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range cms.certificates {
+		intermediates.AddCert(cert)
+	}
+	return cms.signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   genTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+}
@@ -0,0 +1,242 @@
+package pdf
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// a time-stamping authority answering RFC 3161 requests over HTTP
+type testTSA struct {
+	t           *testing.T
+	certificate *x509.Certificate
+	key         *rsa.PrivateKey
+	genTime     time.Time
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the extended key usage is critical (RFC 3161 section 2.3)
+	timeStamping, err := asn1.Marshal([]asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 8}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(100),
+		Subject:               pkix.Name{CommonName: "time-stamping authority"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		ExtraExtensions: []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{2, 5, 29, 37}, Critical: true, Value: timeStamping},
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testTSA{
+		t:           t,
+		certificate: cert,
+		key:         key,
+		genTime:     time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func (tsa *testTSA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		tsa.t.Error(err)
+		return
+	}
+	var request timeStampReq
+	_, err = asn1.Unmarshal(body, &request)
+	if err != nil {
+		tsa.t.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	info, err := asn1.Marshal(tstInfo{
+		Version:        1,
+		Policy:         asn1.ObjectIdentifier{1, 2, 3, 4},
+		MessageImprint: request.MessageImprint,
+		SerialNumber:   big.NewInt(1),
+		GenTime:        tsa.genTime,
+		Accuracy:       accuracy{Seconds: 1},
+		Nonce:          request.Nonce,
+	})
+	if err != nil {
+		tsa.t.Fatal(err)
+	}
+	digest := crypto.SHA256.New()
+	digest.Write(info)
+	attributes := []attribute{}
+	err = addSigningCertificate(&attributes, tsa.certificate)
+	if err != nil {
+		tsa.t.Fatal(err)
+	}
+	token, err := signData(oidTSTInfo, info, digest.Sum(nil), crypto.SHA256, attributes, tsa.key, []*x509.Certificate{tsa.certificate}, nil)
+	if err != nil {
+		tsa.t.Fatal(err)
+	}
+
+	response, err := asn1.Marshal(timeStampResp{
+		Status:         pkiStatusInfo{Status: 0},
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	})
+	if err != nil {
+		tsa.t.Fatal(err)
+	}
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	w.Write(response)
+}
+
+func TestHTTPTimestamper(t *testing.T) {
+	tsa := newTestTSA(t)
+	server := httptest.NewServer(tsa)
+	defer server.Close()
+
+	digest := crypto.SHA256.New()
+	digest.Write([]byte("data"))
+	timestamper := &HTTPTimestamper{URL: server.URL}
+	token, err := timestamper.Timestamp(digest.Sum(nil), crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cms, info, err := verifyTimestamp(token, [][]byte{[]byte("da"), []byte("ta")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.GenTime.Equal(tsa.genTime) || info.Accuracy.Seconds != 1 {
+		t.Errorf("unexpected token info %+v", info)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(tsa.certificate)
+	_, err = cms.verifyTimestampCertificate(roots, info.GenTime)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, _, err = verifyTimestamp(token, [][]byte{[]byte("other")}, nil)
+	if err == nil {
+		t.Error("expected an error for other data")
+	}
+}
+
+func TestSignWithTimestamp(t *testing.T) {
+	tsa := newTestTSA(t)
+	server := httptest.NewServer(tsa)
+	defer server.Close()
+	cert, key := testCertificate(t, "signer", 1)
+
+	filename := filepath.Join(t.TempDir(), "signed.pdf")
+	file := testDocument(t, filename)
+	err := file.Sign(&Signature{
+		Signer:      key,
+		Certificate: cert,
+		Format:      CAdESDetached,
+		Timestamper: &HTTPTimestamper{URL: server.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	roots.AddCert(tsa.certificate)
+	file, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	verifications, err := file.VerifySignatures(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := verifications[0]
+	if v.Err != nil {
+		t.Fatal(v.Err)
+	}
+	if !v.Timestamp.Equal(tsa.genTime) || !v.TimestampCertificate.Equal(tsa.certificate) {
+		t.Errorf("unexpected timestamp %v by %v", v.Timestamp, v.TimestampCertificate)
+	}
+}
+
+func TestAddDocumentTimestamp(t *testing.T) {
+	tsa := newTestTSA(t)
+	server := httptest.NewServer(tsa)
+	defer server.Close()
+	cert, key := testCertificate(t, "signer", 1)
+
+	filename := filepath.Join(t.TempDir(), "timestamped.pdf")
+	file := testDocument(t, filename)
+	err := file.Sign(&Signature{Signer: key, Certificate: cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.AddDocumentTimestamp(&HTTPTimestamper{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	roots.AddCert(tsa.certificate)
+	file, err = Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	catalog := file.Get(file.Root).(Dictionary)
+	fields := catalog["AcroForm"].(Dictionary)["Fields"].(Array)
+	widget := file.Get(fields[1].(ObjectReference)).(Dictionary)
+	sig := file.Get(widget["V"].(ObjectReference)).(Dictionary)
+	if sig["Type"] != Name("DocTimeStamp") || sig["SubFilter"] != Name("ETSI.RFC3161") {
+		t.Errorf("unexpected document timestamp dictionary %v", sig)
+	}
+
+	verifications, err := file.VerifySignatures(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verifications) != 2 {
+		t.Fatalf("expected 2 signatures, got %d", len(verifications))
+	}
+	signature, timestamp := verifications[0], verifications[1]
+	if signature.Err != nil || timestamp.Err != nil {
+		t.Fatal(signature.Err, timestamp.Err)
+	}
+	// the document timestamp is allowed after the signature
+	if len(signature.Changes) == 0 || !signature.ChangesAllowed() {
+		t.Errorf("unexpected changes %+v", signature.Changes)
+	}
+	if !timestamp.Certificate.Equal(tsa.certificate) || !timestamp.Timestamp.Equal(tsa.genTime) {
+		t.Errorf("unexpected document timestamp verification %+v", timestamp)
+	}
+	if timestamp.Revision != timestamp.Revisions || len(timestamp.Changes) != 0 {
+		t.Errorf("document timestamp covers revision %d of %d", timestamp.Revision, timestamp.Revisions)
+	}
+}
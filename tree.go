@@ -0,0 +1,71 @@
+package pdf
+
+import (
+	"bytes"
+	"sort"
+)
+
+// an entry in a name tree (§7.9.6) or number tree (§7.9.7)
+type treeEntry struct {
+	key   Object // String in name trees, Integer in number trees
+	value Object
+}
+
+// returns the entries of the name or number tree whose leaves have
+// the kind of entries, Names or Nums
+func (f *File) treeEntries(root Object, kind Name) []treeEntry {
+	entries := []treeEntry{}
+	visited := map[ObjectReference]bool{}
+
+	var walk func(node Object)
+	walk = func(node Object) {
+		if ref, ok := node.(ObjectReference); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict, ok := f.resolve(node).(Dictionary)
+		if !ok {
+			return
+		}
+		kids, _ := f.resolve(dict["Kids"]).(Array)
+		for _, kid := range kids {
+			walk(kid)
+		}
+		pairs, _ := f.resolve(dict[kind]).(Array)
+		for i := 0; i+1 < len(pairs); i += 2 {
+			entries = append(entries, treeEntry{f.resolve(pairs[i]), pairs[i+1]})
+		}
+	}
+	walk(root)
+
+	return entries
+}
+
+// returns a name or number tree of the entries in a single node
+func newTree(kind Name, entries []treeEntry) Dictionary {
+	sorted := append([]treeEntry{}, entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return treeKeyLess(sorted[i].key, sorted[j].key)
+	})
+
+	pairs := Array{}
+	for _, entry := range sorted {
+		pairs = append(pairs, entry.key, entry.value)
+	}
+	return Dictionary{kind: pairs}
+}
+
+// name tree keys are ordered by their bytes, number tree keys by value
+func treeKeyLess(a, b Object) bool {
+	switch a := a.(type) {
+	case String:
+		b, _ := b.(String)
+		return bytes.Compare(a, b) < 0
+	case Integer:
+		b, _ := b.(Integer)
+		return a < b
+	}
+	return false
+}
@@ -0,0 +1,1186 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha1" // SHA-1 digests of older signatures
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// signature validation (§12.8.1) and modification detection (§12.8.2)
+
+// SignatureVerification is the result of verifying the signature
+// of a signature field.
+type SignatureVerification struct {
+	// The fully qualified name of the signature field,
+	// and the field's signature dictionary.
+	FieldName string
+	Field     ObjectReference
+	Signature Dictionary
+
+	// The signer's certificate, and its chains to the trusted roots.
+	Certificate *x509.Certificate
+	Chains      [][]*x509.Certificate
+
+	// The signing time claimed by the signer, from the signed
+	// attributes or the signature dictionary.
+	SigningTime time.Time
+
+	// The time of the signature's time-stamp token, or of the document
+	// timestamp, and the time-stamping authority's certificate.
+	// The signer's certificate is verified at the Timestamp when
+	// there is one. For document timestamps, the Certificate is
+	// the time-stamping authority's.
+	Timestamp            time.Time
+	TimestampCertificate *x509.Certificate
+
+	// The revision covered by the signature, counting from 1,
+	// and the number of revisions in the file.
+	Revision  int
+	Revisions int
+
+	// The objects changed by the incremental updates after the
+	// signed revision, with whether the signature's DocMDP and
+	// FieldMDP permissions allow the changes.
+	Changes []Change
+
+	// Why the signature is not valid, nil when it is.
+	// Changes do not make a signature invalid.
+	Err error
+}
+
+// ChangesAllowed reports whether the changes after the signed
+// revision are allowed by the signature's permissions.
+func (v *SignatureVerification) ChangesAllowed() bool {
+	for _, change := range v.Changes {
+		if !change.Allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// Change is an object that was added, changed or freed by an
+// incremental update after a signed revision.
+type Change struct {
+	ObjectReference
+
+	// The revision of the update, counting from 1.
+	Revision int
+
+	// Whether the signature's permissions allow the change,
+	// and why not when they do not.
+	Allowed bool
+	Reason  string
+}
+
+// VerifySignatures verifies the signatures of the signature fields
+// in the catalog's interactive form, and the certification signature
+// in its permissions, in every revision of the file. Signatures that
+// are not in the last revision are reported as changes that are not
+// allowed. The signed bytes are checked
+// against the signature and the signer's certificate is verified
+// against the roots, using the certificates in the signature as
+// intermediates. The system roots are used when roots is nil.
+//
+// The file must have been opened, and is verified as it is on disk.
+func (f *File) VerifySignatures(roots *x509.CertPool) ([]*SignatureVerification, error) {
+	if f.mmap == nil {
+		return nil, errors.New("signatures can only be verified in opened files")
+	}
+
+	ends := f.revisionEnds()
+	if len(ends) == 0 {
+		return nil, errors.New("file does not have any revisions")
+	}
+	revisions := make([]*File, len(ends))
+	for i, end := range ends {
+		var err error
+		revisions[i], err = f.revision(end)
+		if err != nil {
+			return nil, err
+		}
+	}
+	current := revisions[len(revisions)-1]
+
+	// signatures removed by later updates are found in the revisions
+	// they were in, the last of which is in last
+	fields := []signatureField{}
+	last := []int{}
+	found := map[string]bool{}
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revisionFields, err := revisions[i].signatureFields()
+		if err != nil {
+			return nil, err
+		}
+		if certification, ok := revisions[i].certification(); ok {
+			revisionFields = append(revisionFields, certification)
+		}
+		for _, field := range revisionFields {
+			key := field.key()
+			if found[key] {
+				continue
+			}
+			found[key] = true
+			fields = append(fields, field)
+			last = append(last, i)
+		}
+	}
+
+	verifications := []*SignatureVerification{}
+	for fieldIndex, field := range fields {
+		v := &SignatureVerification{
+			FieldName: field.name,
+			Field:     field.ref,
+			Revisions: len(revisions),
+		}
+		verifications = append(verifications, v)
+
+		sig, ok := field.value.(Dictionary)
+		if !ok {
+			v.Err = errors.New("signature is not a dictionary")
+			continue
+		}
+		v.Signature = sig
+
+		end, err := v.verify(f.mmap, roots)
+		if err != nil {
+			v.Err = err
+			continue
+		}
+
+		for i, revisionEnd := range ends {
+			if end <= revisionEnd {
+				v.Revision = i + 1
+				break
+			}
+		}
+		if v.Revision == 0 || !bytes.HasSuffix(bytes.TrimRight(f.mmap[:end], "\r\n"), []byte("%%EOF")) {
+			v.Err = errors.New("signature does not cover a whole revision")
+			continue
+		}
+
+		// the permissions are those of the signed revision, so that
+		// they cannot be removed by later updates
+		signed := revisions[v.Revision-1]
+		permissions := &permissions{p: signed.docMDP(), signed: signed}
+		permissions.lock(signed, sig, field.dict)
+
+		v.Changes = permissions.changes(revisions, v.Revision, current)
+
+		if removed := last[fieldIndex] + 1; removed < len(revisions) {
+			ref := field.ref
+			if ref.ObjectNumber == 0 {
+				ref = field.valueRef
+			}
+			v.Changes = append(v.Changes, Change{
+				ObjectReference: ref,
+				Revision:        removed + 1,
+				Reason:          "the signature was removed",
+			})
+			sortChanges(v.Changes)
+		}
+	}
+
+	return verifications, nil
+}
+
+// checks the byte range, the signed digest and the certificate,
+// returning the end of the signed bytes
+func (v *SignatureVerification) verify(data []byte, roots *x509.CertPool) (int, error) {
+	sig := v.Signature
+	format, _ := sig["SubFilter"].(Name)
+	switch format {
+	case Name(PKCS7Detached), Name(CAdESDetached), "ETSI.RFC3161":
+	default:
+		return 0, fmt.Errorf("signature format %s is not supported", format)
+	}
+	// the byte range must be everything but the hexadecimal Contents
+	byteRange, ok := sig["ByteRange"].(Array)
+	if !ok || len(byteRange) != 4 {
+		return 0, errors.New("ByteRange must be an array of 4 integers")
+	}
+	offsets := [4]int{}
+	for i, obj := range byteRange {
+		integer, ok := obj.(Integer)
+		if !ok || integer < 0 || int64(integer) > int64(len(data)) {
+			return 0, errors.New("ByteRange must be an array of 4 integers in the file")
+		}
+		offsets[i] = int(integer)
+	}
+	start := offsets[0] + offsets[1]
+	end := offsets[2]
+	if offsets[0] != 0 || start >= end || end+offsets[3] > len(data) {
+		return 0, fmt.Errorf("ByteRange %v does not cover the file", byteRange)
+	}
+	if data[start] != '<' || data[end-1] != '>' {
+		return 0, fmt.Errorf("ByteRange %v does not exclude only the signature", byteRange)
+	}
+	contents, ok := sig["Contents"].(String)
+	if !ok {
+		return 0, errors.New("Contents must be a string")
+	}
+	// the excluded bytes are the Contents that is verified,
+	// padded with zeros
+	hole := make([]byte, (end-start-2)/2)
+	_, err := hex.Decode(hole, data[start+1:end-1])
+	if err != nil || len(hole) < len(contents) || !bytes.Equal(hole[:len(contents)], contents) ||
+		len(bytes.TrimLeft(hole[len(contents):], "\x00")) != 0 {
+		return 0, fmt.Errorf("ByteRange %v does not exclude only the signature", byteRange)
+	}
+
+	signed := [][]byte{data[:start], data[end : end+offsets[3]]}
+
+	// document timestamps are time-stamp tokens of the signed bytes
+	if format == "ETSI.RFC3161" {
+		cms, info, err := verifyTimestamp(contents, signed, nil)
+		if err != nil {
+			return 0, err
+		}
+		v.Certificate = cms.signer
+		v.TimestampCertificate = cms.signer
+		v.SigningTime = info.GenTime
+		v.Timestamp = info.GenTime
+		v.Chains, err = cms.verifyTimestampCertificate(roots, info.GenTime)
+		if err != nil {
+			return 0, err
+		}
+		return end + offsets[3], nil
+	}
+
+	cms, err := verifyCMS(contents, signed)
+	if err != nil {
+		return 0, err
+	}
+	v.Certificate = cms.signer
+
+	v.SigningTime = cms.signingTime
+	if v.SigningTime.IsZero() {
+		if m, ok := sig["M"].(String); ok {
+			v.SigningTime, _ = parseDate(m)
+		}
+	}
+	currentTime := v.SigningTime
+	if currentTime.IsZero() {
+		currentTime = time.Now()
+	}
+
+	// signature timestamps are time-stamp tokens of the
+	// signature value (RFC 3161 appendix A)
+	if len(cms.signerInfo.UnsignedAttributes.Bytes) != 0 {
+		attributes, err := parseAttributes(cms.signerInfo.UnsignedAttributes.Bytes)
+		if err != nil {
+			return 0, err
+		}
+		if token, ok := attributes[oidTimeStampToken.String()]; ok {
+			tsa, info, err := verifyTimestamp(token, [][]byte{cms.signerInfo.Signature}, nil)
+			if err != nil {
+				return 0, fmt.Errorf("signature timestamp: %v", err)
+			}
+			_, err = tsa.verifyTimestampCertificate(roots, info.GenTime)
+			if err != nil {
+				return 0, fmt.Errorf("signature timestamp: %v", err)
+			}
+			v.Timestamp = info.GenTime
+			v.TimestampCertificate = tsa.signer
+			currentTime = info.GenTime
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cms.certificates {
+		intermediates.AddCert(cert)
+	}
+	v.Chains, err = cms.signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   currentTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return end + offsets[3], nil
+}
+
+// parses dates like D:YYYYMMDDHHmmSSOHH'mm (§7.9.4),
+// where everything after the year is optional
+func parseDate(s String) (time.Time, error) {
+	date := strings.TrimPrefix(string(s), "D:")
+	date = strings.Replace(strings.TrimSuffix(date, "'"), "'", "", -1)
+	if strings.HasSuffix(date, "Z00") || strings.HasSuffix(date, "Z0000") {
+		date = date[:strings.LastIndexByte(date, 'Z')+1]
+	}
+
+	layout := "20060102150405"
+	if len(date) > len(layout) {
+		switch date[len(layout)] {
+		case 'Z':
+			layout += "Z"
+		case '+', '-':
+			layout += "-0700"
+			if len(date) == len("20060102150405-07") {
+				layout = layout[:len(layout)-2]
+			}
+		}
+	} else if len(date) >= 4 && len(date)%2 == 0 {
+		layout = layout[:len(date)]
+	}
+	return time.Parse(layout, date)
+}
+
+// a verified CMS signature
+type cmsSignature struct {
+	signer       *x509.Certificate
+	certificates []*x509.Certificate
+	signingTime  time.Time
+	signerInfo   signerInfo
+
+	// the encapsulated content, when the signature is not detached
+	contentType asn1.ObjectIdentifier
+	content     []byte
+}
+
+// verifies a CMS signed data content info (RFC 5652 section 5.6)
+// whose content is either encapsulated or the detached parts
+func verifyCMS(der []byte, detached [][]byte) (*cmsSignature, error) {
+	var info contentInfo
+	_, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, err
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("signature content type %v is not signed data", info.ContentType)
+	}
+	var sd signedData
+	_, err = asn1.Unmarshal(info.Content.Bytes, &sd)
+	if err != nil {
+		return nil, err
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("signature has %d signers, not 1", len(sd.SignerInfos))
+	}
+
+	cms := &cmsSignature{
+		signerInfo:  sd.SignerInfos[0],
+		contentType: sd.EncapContentInfo.ContentType,
+	}
+	cms.certificates, err = x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	for _, cert := range cms.certificates {
+		if identifies(cms.signerInfo.SignerIdentifier, cert) {
+			cms.signer = cert
+			break
+		}
+	}
+	if cms.signer == nil {
+		return nil, errors.New("signature does not have the signer's certificate")
+	}
+
+	hash, ok := hashes[cms.signerInfo.DigestAlgorithm.Algorithm.String()]
+	if !ok || !hash.Available() {
+		return nil, fmt.Errorf("digest algorithm %v is not supported", cms.signerInfo.DigestAlgorithm.Algorithm)
+	}
+	digest := hash.New()
+	if len(sd.EncapContentInfo.Content.Bytes) != 0 {
+		// the content is an octet string
+		_, err = asn1.Unmarshal(sd.EncapContentInfo.Content.Bytes, &cms.content)
+		if err != nil {
+			return nil, err
+		}
+		digest.Write(cms.content)
+	} else {
+		for _, part := range detached {
+			digest.Write(part)
+		}
+	}
+	contentDigest := digest.Sum(nil)
+
+	// the signature is of the signed attributes, with their SET tag,
+	// which have the digest of the content, or of the content itself
+	var message []byte
+	if len(cms.signerInfo.SignedAttributes.Bytes) != 0 {
+		message = append([]byte{}, cms.signerInfo.SignedAttributes.FullBytes...)
+		message[0] = 0x31
+
+		attributes, err := parseAttributes(cms.signerInfo.SignedAttributes.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		var contentType asn1.ObjectIdentifier
+		_, err = asn1.Unmarshal(attributes[oidContentType.String()], &contentType)
+		if err != nil || !contentType.Equal(sd.EncapContentInfo.ContentType) {
+			return nil, errors.New("signed content type does not match the content")
+		}
+		var messageDigest []byte
+		_, err = asn1.Unmarshal(attributes[oidMessageDigest.String()], &messageDigest)
+		if err != nil || !bytes.Equal(messageDigest, contentDigest) {
+			return nil, errors.New("signed digest does not match the content")
+		}
+		if signingTime, ok := attributes[oidSigningTime.String()]; ok {
+			asn1.Unmarshal(signingTime, &cms.signingTime)
+		}
+	} else {
+		if cms.content != nil {
+			message = cms.content
+		} else {
+			message = bytes.Join(detached, nil)
+		}
+	}
+
+	algorithm, ok := signatureAlgorithm(cms.signerInfo.SignatureAlgorithm.Algorithm, hash)
+	if !ok {
+		return nil, fmt.Errorf("signature algorithm %v is not supported with %v", cms.signerInfo.SignatureAlgorithm.Algorithm, hash)
+	}
+	err = cms.signer.CheckSignature(algorithm, message, cms.signerInfo.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return cms, nil
+}
+
+// returns the first value of each attribute by type
+func parseAttributes(der []byte) (map[string][]byte, error) {
+	attributes := map[string][]byte{}
+	for len(der) > 0 {
+		var a attribute
+		var err error
+		der, err = asn1.Unmarshal(der, &a)
+		if err != nil {
+			return nil, err
+		}
+		var value asn1.RawValue
+		_, err = asn1.Unmarshal(a.Values.Bytes, &value)
+		if err != nil {
+			return nil, err
+		}
+		attributes[a.Type.String()] = value.FullBytes
+	}
+	return attributes, nil
+}
+
+var (
+	hashes = map[string]crypto.Hash{
+		"1.3.14.3.2.26":          crypto.SHA1,
+		oidSHA256.String():       crypto.SHA256,
+		oidSHA384.String():       crypto.SHA384,
+		oidSHA512.String():       crypto.SHA512,
+		"2.16.840.1.101.3.4.2.4": crypto.SHA224,
+	}
+
+	rsaSignatureAlgorithms = map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA1:   x509.SHA1WithRSA,
+		crypto.SHA256: x509.SHA256WithRSA,
+		crypto.SHA384: x509.SHA384WithRSA,
+		crypto.SHA512: x509.SHA512WithRSA,
+	}
+	pssSignatureAlgorithms = map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA256: x509.SHA256WithRSAPSS,
+		crypto.SHA384: x509.SHA384WithRSAPSS,
+		crypto.SHA512: x509.SHA512WithRSAPSS,
+	}
+	ecdsaX509Algorithms = map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA1:   x509.ECDSAWithSHA1,
+		crypto.SHA256: x509.ECDSAWithSHA256,
+		crypto.SHA384: x509.ECDSAWithSHA384,
+		crypto.SHA512: x509.ECDSAWithSHA512,
+	}
+)
+
+// the x509 signature algorithm for a CMS signature algorithm,
+// which may only name the key's algorithm, and the digest
+func signatureAlgorithm(oid asn1.ObjectIdentifier, hash crypto.Hash) (x509.SignatureAlgorithm, bool) {
+	var algorithms map[crypto.Hash]x509.SignatureAlgorithm
+	switch oid.String() {
+	case oidRSAEncryption.String(),
+		"1.2.840.113549.1.1.5",  // sha1WithRSAEncryption
+		"1.2.840.113549.1.1.11", // sha256WithRSAEncryption
+		"1.2.840.113549.1.1.12", // sha384WithRSAEncryption
+		"1.2.840.113549.1.1.13": // sha512WithRSAEncryption
+		algorithms = rsaSignatureAlgorithms
+	case "1.2.840.113549.1.1.10": // RSASSA-PSS
+		algorithms = pssSignatureAlgorithms
+	case "1.2.840.10045.2.1", // id-ecPublicKey
+		"1.2.840.10045.4.1", // ecdsa-with-SHA1
+		oidECDSAWithSHA256.String(),
+		oidECDSAWithSHA384.String(),
+		oidECDSAWithSHA512.String():
+		algorithms = ecdsaX509Algorithms
+	case "1.3.101.112": // Ed25519
+		return x509.PureEd25519, true
+	}
+	algorithm, ok := algorithms[hash]
+	return algorithm, ok
+}
+
+// the end offsets of the file's revisions (§7.5.6), in order,
+// including the end of line markers after their %%EOF
+func (f *File) revisionEnds() []int {
+	ends := []int{}
+	eof := []byte("%%EOF")
+	for offset := 0; ; {
+		i := bytes.Index(f.mmap[offset:], eof)
+		if i == -1 {
+			break
+		}
+		i += offset
+		offset = i + len(eof)
+
+		// those not after a startxref are in stream data
+		before := f.mmap[:i]
+		if len(before) > 64 {
+			before = before[len(before)-64:]
+		}
+		startxref := bytes.LastIndex(before, []byte("startxref"))
+		if startxref == -1 || len(bytes.Trim(before[startxref+len("startxref"):], "0123456789 \t\r\n")) != 0 {
+			continue
+		}
+
+		end := offset
+		for end < len(f.mmap) && (f.mmap[end] == '\r' || f.mmap[end] == '\n') {
+			end++
+		}
+		ends = append(ends, end)
+	}
+	return ends
+}
+
+// the file as it was at the end of a revision, sharing the file's
+// memory map and security handler
+func (f *File) revision(end int) (*File, error) {
+	revision := &File{
+		filename: f.filename,
+		mmap:     f.mmap[:end],
+		version:  f.version,
+		security: f.security,
+	}
+	err := revision.loadReferences()
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// a terminal signature field
+type signatureField struct {
+	name     string
+	ref      ObjectReference
+	dict     Dictionary
+	value    Object
+	valueRef ObjectReference
+}
+
+// the signed signature fields of the interactive form (§12.7.3)
+func (f *File) signatureFields() ([]signatureField, error) {
+	catalog, ok := f.Get(f.Root).(Dictionary)
+	if !ok {
+		return nil, errors.New("the file does not have a catalog")
+	}
+	acroForm, ok := f.resolve(catalog["AcroForm"]).(Dictionary)
+	if !ok {
+		return nil, nil
+	}
+	fields, _, err := f.array(acroForm["Fields"])
+	if err != nil {
+		return nil, err
+	}
+
+	signatureFields := []signatureField{}
+	visited := map[ObjectReference]bool{}
+	var walk func(fields Array, parentName string, parentType Object) error
+	walk = func(fields Array, parentName string, parentType Object) error {
+		for _, obj := range fields {
+			ref, _ := obj.(ObjectReference)
+			if visited[ref] {
+				continue
+			}
+			visited[ref] = true
+			dict, ok := f.resolve(obj).(Dictionary)
+			if !ok {
+				continue
+			}
+
+			name := parentName
+			if t, ok := dict["T"].(String); ok {
+				name = qualifiedName(parentName, textString(t))
+			}
+			fieldType := dict["FT"]
+			if fieldType == nil {
+				fieldType = parentType
+			}
+
+			// kids without names are widget annotations
+			kids, _, err := f.array(dict["Kids"])
+			if err != nil {
+				return err
+			}
+			named := false
+			for _, kid := range kids {
+				if kidDict, ok := f.resolve(kid).(Dictionary); ok {
+					if _, ok := kidDict["T"]; ok {
+						named = true
+					}
+				}
+			}
+			if named {
+				err = walk(kids, name, fieldType)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			if fieldType != Name("Sig") {
+				continue
+			}
+			value, ok := dict["V"]
+			if !ok {
+				continue
+			}
+			valueRef, _ := value.(ObjectReference)
+			signatureFields = append(signatureFields, signatureField{
+				name:     name,
+				ref:      ref,
+				dict:     dict,
+				value:    f.resolve(value),
+				valueRef: valueRef,
+			})
+		}
+		return nil
+	}
+
+	err = walk(fields, "", nil)
+	return signatureFields, err
+}
+
+// the certification signature in the catalog's permissions (§12.8.4),
+// which is not in a field
+func (f *File) certification() (signatureField, bool) {
+	catalog, _ := f.Get(f.Root).(Dictionary)
+	perms, _ := f.resolve(catalog["Perms"]).(Dictionary)
+	valueRef, _ := perms["DocMDP"].(ObjectReference)
+	sig, ok := f.resolve(perms["DocMDP"]).(Dictionary)
+	if !ok {
+		return signatureField{}, false
+	}
+	return signatureField{value: sig, valueRef: valueRef}, true
+}
+
+// identifies a signature by its value,
+// which is the same in every revision it is in
+func (s signatureField) key() string {
+	if sig, ok := s.value.(Dictionary); ok {
+		if contents, ok := sig["Contents"].(String); ok {
+			return string(contents)
+		}
+	}
+	return fmt.Sprint(s.ref, s.valueRef)
+}
+
+func qualifiedName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// resolves an indirect object
+func (f *File) resolve(obj Object) Object {
+	if ref, ok := obj.(ObjectReference); ok {
+		return f.Get(ref)
+	}
+	return obj
+}
+
+// the fully qualified name of a field or widget annotation,
+// and whether it is a field
+func (f *File) fieldName(dict Dictionary) (string, bool) {
+	names := []string{}
+	isField := false
+	visited := map[ObjectReference]bool{}
+	// markup annotations have a T entry too
+	if subtype, ok := dict["Subtype"]; ok && subtype != Name("Widget") {
+		return "", false
+	}
+	for dict != nil {
+		if t, ok := dict["T"].(String); ok {
+			names = append([]string{textString(t)}, names...)
+			isField = true
+		}
+		if _, ok := dict["FT"]; ok {
+			isField = true
+		}
+
+		parentRef, ok := dict["Parent"].(ObjectReference)
+		if !ok || visited[parentRef] {
+			break
+		}
+		visited[parentRef] = true
+		dict, _ = f.Get(parentRef).(Dictionary)
+	}
+	return strings.Join(names, "."), isField
+}
+
+// the type of a field or of a widget annotation's field,
+// which may be inherited (§12.7.3.1)
+func (f *File) fieldType(dict Dictionary) Name {
+	visited := map[ObjectReference]bool{}
+	for dict != nil {
+		if ft, ok := f.resolve(dict["FT"]).(Name); ok {
+			return ft
+		}
+		parentRef, ok := dict["Parent"].(ObjectReference)
+		if !ok || visited[parentRef] {
+			break
+		}
+		visited[parentRef] = true
+		dict, _ = f.Get(parentRef).(Dictionary)
+	}
+	return ""
+}
+
+// the certification signature's DocMDP permission level (§12.8.2.2),
+// 0 when the document is not certified
+func (f *File) docMDP() int {
+	catalog, _ := f.Get(f.Root).(Dictionary)
+	perms, _ := f.resolve(catalog["Perms"]).(Dictionary)
+	sig, ok := f.resolve(perms["DocMDP"]).(Dictionary)
+	if !ok {
+		return 0
+	}
+	for _, params := range f.transformParams(sig, "DocMDP") {
+		if p, ok := params["P"].(Integer); ok && 1 <= p && p <= 3 {
+			return int(p)
+		}
+	}
+	return 2
+}
+
+// the parameters of the signature references with the transform method
+func (f *File) transformParams(sig Dictionary, method Name) []Dictionary {
+	params := []Dictionary{}
+	references, _ := f.resolve(sig["Reference"]).(Array)
+	for _, obj := range references {
+		reference, ok := f.resolve(obj).(Dictionary)
+		if !ok || reference["TransformMethod"] != method {
+			continue
+		}
+		if dict, ok := f.resolve(reference["TransformParams"]).(Dictionary); ok {
+			params = append(params, dict)
+		}
+	}
+	return params
+}
+
+// the changes allowed after a signed revision
+type permissions struct {
+	// the DocMDP permission level, 0 when there is none
+	p int
+
+	// field locks (§12.8.2.4), whose actions are
+	// All, Include or Exclude
+	locks []fieldLock
+
+	// the revision the signature covers
+	signed *File
+
+	// from the current catalog
+	acroForm ObjectReference
+
+	// the validation data that was added after the signed revision
+	validation map[ObjectReference]bool
+}
+
+type fieldLock struct {
+	action Name
+	fields map[string]bool
+}
+
+// adds the signature's FieldMDP locks and the field's Lock
+func (p *permissions) lock(f *File, sig, field Dictionary) {
+	add := func(params Dictionary) {
+		lock := fieldLock{fields: map[string]bool{}}
+		lock.action, _ = params["Action"].(Name)
+		fields, _ := f.resolve(params["Fields"]).(Array)
+		for _, name := range fields {
+			if s, ok := f.resolve(name).(String); ok {
+				lock.fields[textString(s)] = true
+			}
+		}
+		p.locks = append(p.locks, lock)
+	}
+
+	for _, params := range f.transformParams(sig, "FieldMDP") {
+		add(params)
+	}
+	if lock, ok := f.resolve(field["Lock"]).(Dictionary); ok {
+		add(lock)
+	}
+}
+
+func (p *permissions) locked(name string) bool {
+	for _, lock := range p.locks {
+		switch lock.action {
+		case "All":
+			return true
+		case "Include":
+			if lock.fields[name] {
+				return true
+			}
+		case "Exclude":
+			if !lock.fields[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compares the objects in each revision after the signed one with
+// those in the revision before, and checks the changed ones
+func (p *permissions) changes(revisions []*File, signed int, current *File) []Change {
+	catalog, _ := current.Get(current.Root).(Dictionary)
+	p.acroForm, _ = catalog["AcroForm"].(ObjectReference)
+
+	// validation data (ISO 32000-2 §12.8.4.3) may be added to
+	// documents whatever their permissions
+	p.validation = map[ObjectReference]bool{}
+	if p.addedDSS(current, catalog["DSS"]) {
+		current.references(catalog["DSS"], p.validation)
+	}
+
+	changes := []Change{}
+	for i := signed; i < len(revisions); i++ {
+		before, after := revisions[i-1], revisions[i]
+		for objectNumber, xref := range after.objects {
+			if existing, ok := before.objects[objectNumber]; ok && existing == xref {
+				continue
+			}
+
+			var ref ObjectReference
+			var previous, updated Object
+			if existing, ok := before.objects[objectNumber].(crossReference); ok && existing[0] != 0 {
+				ref = objectReference(objectNumber, existing)
+				previous = before.Get(ref)
+			}
+			if entry := xref.(crossReference); entry[0] != 0 {
+				ref = objectReference(objectNumber, entry)
+				updated = after.Get(ref)
+				if stream, ok := updated.(Stream); ok {
+					switch stream.Dictionary["Type"] {
+					case Name("XRef"), Name("ObjStm"):
+						continue
+					}
+				}
+				if previous != nil && equal(previous, updated) {
+					continue
+				}
+			} else if previous == nil {
+				// already free
+				continue
+			}
+
+			reason := p.check(after, ref, previous, updated)
+			changes = append(changes, Change{
+				ObjectReference: ref,
+				Revision:        i + 1,
+				Allowed:         reason == "",
+				Reason:          reason,
+			})
+		}
+	}
+
+	sortChanges(changes)
+	return changes
+}
+
+// sorts changes by revision and object number
+func sortChanges(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Revision != changes[j].Revision {
+			return changes[i].Revision < changes[j].Revision
+		}
+		return changes[i].ObjectNumber < changes[j].ObjectNumber
+	})
+}
+
+// returns why the change is not allowed, or the empty string
+func (p *permissions) check(file *File, ref ObjectReference, previous, updated Object) string {
+	if p.p == 0 && len(p.locks) == 0 {
+		return ""
+	}
+	if p.validation[ref] && !p.existed(ref) {
+		return ""
+	}
+
+	var dict Dictionary
+	switch typed := updated.(type) {
+	case Dictionary:
+		dict = typed
+	case Stream:
+		dict = typed.Dictionary
+	}
+	if dict["Type"] == Name("DocTimeStamp") {
+		return ""
+	}
+
+	// fields are checked against the locks and permissions
+	if dict != nil {
+		if name, isField := file.fieldName(dict); isField {
+			if previous != nil && p.locked(name) {
+				return fmt.Sprintf("field %s is locked", name)
+			}
+			switch {
+			case p.p == 1:
+				return "no changes are permitted"
+			case p.p == 0:
+			case previous == nil:
+				// signing adds signature fields and their widgets
+				if file.fieldType(dict) != "Sig" {
+					return fmt.Sprintf("field %s may not be added", name)
+				}
+			default:
+				// filling in forms changes values and appearance states
+				oldDict, _ := previous.(Dictionary)
+				if stream, ok := previous.(Stream); ok {
+					oldDict = stream.Dictionary
+				}
+				if !equalExcept(oldDict, dict, "V", "AS") {
+					return fmt.Sprintf("field %s may only have its value changed", name)
+				}
+			}
+			return ""
+		}
+	}
+
+	if p.p == 0 {
+		return ""
+	}
+	if updated == nil {
+		return "objects may not be freed"
+	}
+	if ref.ObjectNumber == file.Root.ObjectNumber {
+		return p.checkCatalog(file, previous, dict)
+	}
+	if p.p == 1 {
+		return "no changes are permitted"
+	}
+
+	switch {
+	case ref.ObjectNumber == p.acroForm.ObjectNumber:
+		oldForm, _ := previous.(Dictionary)
+		return formChange(oldForm, dict)
+	case dict["Type"] == Name("Sig"):
+	case dict["Type"] == Name("Page"):
+		oldPage, _ := previous.(Dictionary)
+		if !equalExcept(oldPage, dict, "Annots") {
+			return "page changed"
+		}
+	case dict["Subtype"] == Name("Form") && previous == nil:
+		// appearance streams
+	case dict["Type"] == Name("Annot") || dict["Subtype"] != nil && dict["Rect"] != nil:
+		if p.p < 3 {
+			return "annotations are not permitted"
+		}
+	default:
+		// indirect arrays of fields and annotations may grow
+		oldArray, _ := previous.(Array)
+		newArray, ok := updated.(Array)
+		if ok && len(newArray) >= len(oldArray) && equal(oldArray, newArray[:len(oldArray)]) {
+			return ""
+		}
+		return "changes to the object are not permitted"
+	}
+	return ""
+}
+
+// the catalog may have validation data added, and at P=2 and 3
+// the interactive form changed
+func (p *permissions) checkCatalog(file *File, previous Object, catalog Dictionary) string {
+	oldCatalog, _ := previous.(Dictionary)
+	except := []Name{}
+	if p.addedDSS(file, catalog["DSS"]) {
+		except = append(except, "DSS")
+	}
+	if p.p > 1 {
+		except = append(except, "AcroForm")
+		if reason := catalogForm(oldCatalog["AcroForm"], catalog["AcroForm"]); reason != "" {
+			return reason
+		}
+	}
+	if equalExcept(oldCatalog, catalog, except...) {
+		return ""
+	}
+	if p.p == 1 {
+		return "no changes are permitted"
+	}
+	return "catalog changed"
+}
+
+// checks the catalog's AcroForm entry, which may add an interactive
+// form or change a direct one
+func catalogForm(previous, updated Object) string {
+	if equal(previous, updated) {
+		return ""
+	}
+	_, indirect := updated.(ObjectReference)
+	if previous == nil && indirect {
+		// the form is checked as an object of its own
+		return ""
+	}
+	oldForm, ok := previous.(Dictionary)
+	if previous != nil && !ok {
+		return "interactive form changed"
+	}
+	form, ok := updated.(Dictionary)
+	if !ok {
+		return "interactive form changed"
+	}
+	return formChange(oldForm, form)
+}
+
+// signing may add fields to the interactive form and set its
+// signature flags and default resources, but not change it otherwise
+func formChange(previous, form Dictionary) string {
+	oldFlags, _ := previous["SigFlags"].(Integer)
+	flags, _ := form["SigFlags"].(Integer)
+	if form == nil || !equalExcept(previous, form, "Fields", "SigFlags", "DR") ||
+		!extends(previous["Fields"], form["Fields"]) || !extends(previous["DR"], form["DR"]) ||
+		flags&oldFlags != oldFlags {
+		return "interactive form changed"
+	}
+	return ""
+}
+
+// whether b is a with elements appended to arrays and entries
+// added to dictionaries
+func extends(a, b Object) bool {
+	switch a := a.(type) {
+	case nil:
+		return true
+	case Array:
+		b, ok := b.(Array)
+		return ok && len(b) >= len(a) && equal(a, b[:len(a)])
+	case Dictionary:
+		b, ok := b.(Dictionary)
+		if !ok {
+			return false
+		}
+		for key, value := range a {
+			if !extends(value, b[key]) {
+				return false
+			}
+		}
+		return true
+	}
+	return equal(a, b)
+}
+
+// whether obj refers to a document security store that was added
+// after the signed revision, so that objects that were signed
+// cannot be passed off as validation data
+func (p *permissions) addedDSS(file *File, obj Object) bool {
+	ref, ok := obj.(ObjectReference)
+	if !ok || p.existed(ref) {
+		return false
+	}
+	dss, ok := file.Get(ref).(Dictionary)
+	return ok && dss["Type"] == Name("DSS")
+}
+
+// whether the object was in use in the signed revision
+func (p *permissions) existed(ref ObjectReference) bool {
+	xref, ok := p.signed.objects[ref.ObjectNumber].(crossReference)
+	return ok && xref[0] != 0
+}
+
+// the reference to an object in use with the cross-reference entry
+func objectReference(objectNumber uint, xref crossReference) ObjectReference {
+	ref := ObjectReference{ObjectNumber: objectNumber}
+	if xref[0] == 1 {
+		ref.GenerationNumber = xref[2]
+	}
+	return ref
+}
+
+// adds the references in obj, recursively
+func (f *File) references(obj Object, refs map[ObjectReference]bool) {
+	switch typed := obj.(type) {
+	case ObjectReference:
+		if refs[typed] {
+			return
+		}
+		refs[typed] = true
+		f.references(f.Get(typed), refs)
+	case Array:
+		for _, element := range typed {
+			f.references(element, refs)
+		}
+	case Dictionary:
+		for _, value := range typed {
+			f.references(value, refs)
+		}
+	case Stream:
+		f.references(typed.Dictionary, refs)
+	}
+}
+
+// compares objects by value
+func equal(a, b Object) bool {
+	switch a := a.(type) {
+	case String:
+		b, ok := b.(String)
+		return ok && bytes.Equal(a, b)
+	case Array:
+		b, ok := b.(Array)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case Dictionary:
+		b, ok := b.(Dictionary)
+		return ok && equalExcept(a, b)
+	case Stream:
+		b, ok := b.(Stream)
+		return ok && equalExcept(a.Dictionary, b.Dictionary) && bytes.Equal(a.Stream, b.Stream)
+	case Null:
+		_, ok := b.(Null)
+		return ok
+	}
+	return a == b
+}
+
+// compares dictionaries, except for the keys
+func equalExcept(a, b Dictionary, keys ...Name) bool {
+	except := map[Name]bool{}
+	for _, key := range keys {
+		except[key] = true
+	}
+	for key, value := range a {
+		if !except[key] && !equal(value, b[key]) {
+			return false
+		}
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok && !except[key] {
+			return false
+		}
+	}
+	return true
+}
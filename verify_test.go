@@ -0,0 +1,507 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// opens a file and verifies its signatures with the certificate as root
+func testVerify(t *testing.T, filename string, root *x509.Certificate) []*SignatureVerification {
+	file, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	verifications, err := file.VerifySignatures(roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return verifications
+}
+
+func TestVerifySignatures(t *testing.T) {
+	cert, key := testCertificate(t, "signer", 1)
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "signed.pdf")
+
+	file := testDocument(t, filename)
+	err := file.Sign(&Signature{Signer: key, Certificate: cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infoRef, err := file.Add(Dictionary{"Title": String("Signed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Info = infoRef
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	verifications := testVerify(t, filename, cert)
+	if len(verifications) != 1 {
+		t.Fatalf("expected 1 signature, got %d", len(verifications))
+	}
+	v := verifications[0]
+	if v.Err != nil {
+		t.Fatal(v.Err)
+	}
+	if v.FieldName != "Signature1" || !v.Certificate.Equal(cert) || len(v.Chains) != 1 {
+		t.Errorf("unexpected verification %+v", v)
+	}
+	if time.Since(v.SigningTime) > time.Minute {
+		t.Errorf("signing time is %v", v.SigningTime)
+	}
+	if v.Revision != 2 || v.Revisions != 3 {
+		t.Errorf("signature covers revision %d of %d", v.Revision, v.Revisions)
+	}
+	if len(v.Changes) != 1 || v.Changes[0].ObjectReference != infoRef || v.Changes[0].Revision != 3 || !v.ChangesAllowed() {
+		t.Errorf("unexpected changes %+v", v.Changes)
+	}
+
+	// untrusted
+	other, _ := testCertificate(t, "other", 2)
+	v = testVerify(t, filename, other)[0]
+	if !errors.As(v.Err, &x509.UnknownAuthorityError{}) {
+		t.Errorf("expected an unknown authority error, got %v", v.Err)
+	}
+
+	// changed signed bytes
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := filepath.Join(dir, "tampered.pdf")
+	err = ioutil.WriteFile(tampered, bytes.Replace(data, []byte("612"), []byte("613"), 1), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v = testVerify(t, tampered, cert)[0]
+	if v.Err == nil {
+		t.Error("expected an error for the changed file")
+	}
+}
+
+// the Contents that is verified is the one excluded by the ByteRange
+func TestVerifyContents(t *testing.T) {
+	cert, key := testCertificate(t, "signer", 1)
+	filename := filepath.Join(t.TempDir(), "signed.pdf")
+	file := testDocument(t, filename)
+	signature := &Signature{Signer: key, Certificate: cert}
+	err := file.Sign(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a second signature of the same bytes replaces the signed one
+	// in a later revision
+	catalog := file.Get(file.Root).(Dictionary)
+	field := file.resolve(catalog["AcroForm"].(Dictionary)["Fields"].(Array)[0]).(Dictionary)
+	sigRef := field["V"].(ObjectReference)
+	sig := file.Get(sigRef).(Dictionary)
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byteRange := sig["ByteRange"].(Array)
+	digest := crypto.SHA256.New()
+	digest.Write(data[:byteRange[1].(Integer)])
+	digest.Write(data[byteRange[2].(Integer) : byteRange[2].(Integer)+byteRange[3].(Integer)])
+	contents, err := signCMS(digest.Sum(nil), crypto.SHA256, PKCS7Detached, signature, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig["Contents"] = String(contents)
+	_, err = file.Add(IndirectObject{sigRef, sig})
+	if err == nil {
+		err = file.Save()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	v := testVerify(t, filename, cert)[0]
+	if v.Err == nil || !strings.Contains(v.Err.Error(), "does not exclude only the signature") {
+		t.Errorf("expected a ByteRange error, got %v", v.Err)
+	}
+}
+
+// adds a text annotation to the first page
+func addAnnotation(t *testing.T, file *File) {
+	catalog := file.Get(file.Root).(Dictionary)
+	pageRef := file.firstPage(catalog)
+	page := file.Get(pageRef).(Dictionary)
+	annotRef, err := file.Add(Dictionary{
+		"Type":     Name("Annot"),
+		"Subtype":  Name("Text"),
+		"Rect":     Array{Integer(0), Integer(0), Integer(10), Integer(10)},
+		"Contents": String("Note"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	annots, _ := page["Annots"].(Array)
+	page["Annots"] = append(annots, annotRef)
+	_, err = file.Add(IndirectObject{pageRef, page})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// adds a text field with a widget annotation on the first page
+// and returns the widget
+func addTextField(t *testing.T, file *File, name string) ObjectReference {
+	catalog := file.Get(file.Root).(Dictionary)
+	pageRef := file.firstPage(catalog)
+	page := file.Get(pageRef).(Dictionary)
+	fieldRef, err := file.Add(Dictionary{"FT": Name("Tx"), "T": String(name)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	widgetRef, err := file.Add(Dictionary{
+		"Type":    Name("Annot"),
+		"Subtype": Name("Widget"),
+		"Rect":    Array{Integer(0), Integer(0), Integer(100), Integer(20)},
+		"P":       pageRef,
+		"Parent":  fieldRef,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := file.Get(fieldRef).(Dictionary)
+	field["Kids"] = Array{widgetRef}
+
+	form, _ := file.resolve(catalog["AcroForm"]).(Dictionary)
+	if form == nil {
+		form = Dictionary{}
+	}
+	fields, _ := form["Fields"].(Array)
+	form["Fields"] = append(fields, fieldRef)
+	if formRef, ok := catalog["AcroForm"].(ObjectReference); ok {
+		_, err = file.Add(IndirectObject{formRef, form})
+	} else {
+		catalog["AcroForm"] = form
+		_, err = file.Add(IndirectObject{file.Root, catalog})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	annots, _ := page["Annots"].(Array)
+	page["Annots"] = append(annots, widgetRef)
+	_, err = file.Add(IndirectObject{pageRef, page})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return widgetRef
+}
+
+func TestVerifyDocMDP(t *testing.T) {
+	cert, key := testCertificate(t, "certifier", 1)
+	approver, approverKey := testCertificate(t, "approver", 2)
+	approve := func(t *testing.T, file *File) {
+		err := file.Sign(&Signature{Signer: approverKey, Certificate: approver})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the widget of the text field added before certifying
+	var widgetRef ObjectReference
+	fill := func(t *testing.T, file *File) {
+		fieldRef := file.Get(widgetRef).(Dictionary)["Parent"].(ObjectReference)
+		field := file.Get(fieldRef).(Dictionary)
+		field["V"] = String("Alice")
+		_, err := file.Add(IndirectObject{fieldRef, field})
+		if err == nil {
+			err = file.Save()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	changeAction := func(t *testing.T, file *File) {
+		widget := file.Get(widgetRef).(Dictionary)
+		widget["AA"] = Dictionary{"K": Dictionary{"S": Name("JavaScript"), "JS": String("app.alert(1)")}}
+		_, err := file.Add(IndirectObject{widgetRef, widget})
+		if err == nil {
+			err = file.Save()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addField := func(t *testing.T, file *File) {
+		addTextField(t, file, "added")
+	}
+
+	// changes the catalog's interactive form and saves
+	changeForm := func(change func(catalog, form Dictionary)) func(*testing.T, *File) {
+		return func(t *testing.T, file *File) {
+			catalog := file.Get(file.Root).(Dictionary)
+			change(catalog, catalog["AcroForm"].(Dictionary))
+			_, err := file.Add(IndirectObject{file.Root, catalog})
+			if err == nil {
+				err = file.Save()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	removeForm := changeForm(func(catalog, form Dictionary) {
+		delete(catalog, "AcroForm")
+	})
+	removeField := changeForm(func(catalog, form Dictionary) {
+		form["Fields"] = form["Fields"].(Array)[1:]
+	})
+	needAppearances := changeForm(func(catalog, form Dictionary) {
+		form["NeedAppearances"] = Boolean(true)
+	})
+
+	// sets the catalog's DSS and saves
+	setDSS := func(t *testing.T, file *File, dss Object) {
+		catalog := file.Get(file.Root).(Dictionary)
+		catalog["DSS"] = dss
+		_, err := file.Add(IndirectObject{file.Root, catalog})
+		if err == nil {
+			err = file.Save()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addValidation := func(t *testing.T, file *File) {
+		certRef, err := file.Add(Stream{Dictionary: Dictionary{}, Stream: cert.Raw})
+		if err != nil {
+			t.Fatal(err)
+		}
+		dssRef, err := file.Add(Dictionary{"Type": Name("DSS"), "Certs": Array{certRef}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		setDSS(t, file, dssRef)
+	}
+	// signed objects are not validation data, even when they are
+	// the DSS or in a DSS that was added after signing
+	changePage := func(t *testing.T, file *File) *File {
+		pageRef := file.firstPage(file.Get(file.Root).(Dictionary))
+		page := file.Get(pageRef).(Dictionary)
+		page["MediaBox"] = Array{Integer(0), Integer(0), Integer(1), Integer(1)}
+		_, err := file.Add(IndirectObject{pageRef, page})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	pageAsDSS := func(t *testing.T, file *File) {
+		pageRef := file.firstPage(file.Get(file.Root).(Dictionary))
+		setDSS(t, changePage(t, file), pageRef)
+	}
+	pageInDSS := func(t *testing.T, file *File) {
+		pageRef := file.firstPage(file.Get(file.Root).(Dictionary))
+		dssRef, err := changePage(t, file).Add(Dictionary{"Type": Name("DSS"), "Certs": Array{pageRef}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		setDSS(t, file, dssRef)
+	}
+
+	for _, test := range []struct {
+		name    string
+		p       int
+		change  func(*testing.T, *File)
+		allowed bool
+	}{
+		{"no changes signing", 1, approve, false},
+		{"no changes adding validation data", 1, addValidation, true},
+		{"no changes removing the form", 1, removeForm, false},
+		{"form filling adding validation data", 2, addValidation, true},
+		{"form filling using a page as the DSS", 2, pageAsDSS, false},
+		{"form filling adding a page to the DSS", 2, pageInDSS, false},
+		{"form filling signing", 2, approve, true},
+		{"form filling filling", 2, fill, true},
+		{"form filling adding a text field", 2, addField, false},
+		{"form filling changing a widget's actions", 2, changeAction, false},
+		{"form filling removing a field", 2, removeField, false},
+		{"form filling setting NeedAppearances", 2, needAppearances, false},
+		{"form filling annotating", 2, addAnnotation, false},
+		{"annotating", 3, addAnnotation, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "certified.pdf")
+			file := testDocument(t, filename)
+			widgetRef = addTextField(t, file, "name")
+			err := file.Sign(&Signature{Signer: key, Certificate: cert, DocMDP: test.p})
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.change(t, file)
+			file.Close()
+
+			v := testVerify(t, filename, cert)[0]
+			if v.Err != nil {
+				t.Fatal(v.Err)
+			}
+			if len(v.Changes) == 0 {
+				t.Fatal("expected changes")
+			}
+			if v.ChangesAllowed() != test.allowed {
+				t.Errorf("expected allowed %v, got %+v", test.allowed, v.Changes)
+			}
+		})
+	}
+}
+
+// signatures removed by later updates are still verified
+func TestVerifyRemovedSignature(t *testing.T) {
+	cert, key := testCertificate(t, "signer", 1)
+	filename := filepath.Join(t.TempDir(), "removed.pdf")
+	file := testDocument(t, filename)
+	err := file.Sign(&Signature{Signer: key, Certificate: cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := file.Get(file.Root).(Dictionary)
+	form := catalog["AcroForm"].(Dictionary)
+	fieldRef := form["Fields"].(Array)[0].(ObjectReference)
+	form["Fields"] = Array{}
+	_, err = file.Add(IndirectObject{file.Root, catalog})
+	if err == nil {
+		err = file.Save()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	verifications := testVerify(t, filename, cert)
+	if len(verifications) != 1 {
+		t.Fatalf("expected 1 signature, got %d", len(verifications))
+	}
+	v := verifications[0]
+	if v.Err != nil || v.FieldName != "Signature1" || v.Revision != 2 {
+		t.Fatalf("unexpected verification %+v", v)
+	}
+	removed := v.Changes[len(v.Changes)-1]
+	if v.ChangesAllowed() || removed.ObjectReference != fieldRef || removed.Revision != 3 || removed.Reason != "the signature was removed" {
+		t.Errorf("unexpected changes %+v", v.Changes)
+	}
+}
+
+func TestVerifyFieldMDP(t *testing.T) {
+	cert, key := testCertificate(t, "signer", 1)
+	filename := filepath.Join(t.TempDir(), "form.pdf")
+
+	file := testDocument(t, filename)
+	nameRef, err := file.Add(Dictionary{"FT": Name("Tx"), "T": String("name"), "V": String("Alice")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dateRef, err := file.Add(Dictionary{"FT": Name("Tx"), "T": String("date")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := file.Get(file.Root).(Dictionary)
+	catalog["AcroForm"] = Dictionary{"Fields": Array{nameRef, dateRef}}
+	_, err = file.Add(IndirectObject{file.Root, catalog})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = file.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = file.Sign(&Signature{Signer: key, Certificate: cert, LockedFields: []string{"name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the FieldMDP reference has the signed document's catalog
+	form := file.resolve(file.Get(file.Root).(Dictionary)["AcroForm"]).(Dictionary)
+	fields := form["Fields"].(Array)
+	signature := file.resolve(file.resolve(fields[len(fields)-1]).(Dictionary)["V"]).(Dictionary)
+	reference := file.resolve(signature["Reference"].(Array)[0]).(Dictionary)
+	if reference["TransformMethod"] != Name("FieldMDP") || reference["Data"] != file.Root {
+		t.Errorf("unexpected signature reference %v", reference)
+	}
+
+	fill := func(ref ObjectReference, value string) {
+		field := file.Get(ref).(Dictionary)
+		field["V"] = String(value)
+		_, err := file.Add(IndirectObject{ref, field})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = file.Save()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fill(dateRef, "today")
+	v := testVerify(t, filename, cert)[0]
+	if v.FieldName != "Signature3" || v.Err != nil {
+		t.Fatalf("unexpected verification %+v", v)
+	}
+	if len(v.Changes) != 1 || !v.ChangesAllowed() {
+		t.Errorf("expected filling in an unlocked field to be allowed, got %+v", v.Changes)
+	}
+
+	fill(nameRef, "Mallory")
+	file.Close()
+	v = testVerify(t, filename, cert)[0]
+	if len(v.Changes) != 2 || v.ChangesAllowed() || v.Changes[1].Reason != "field name is locked" {
+		t.Errorf("expected changing a locked field to not be allowed, got %+v", v.Changes)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for _, test := range []struct {
+		date     string
+		expected time.Time
+	}{
+		{"D:20240102030405+01'30'", time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 90*60))},
+		{"D:20240102030405-05'00", time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", -5*60*60))},
+		{"D:20240102030405Z00'00'", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"D:20240102030405Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"D:202401", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"D:2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		date, err := parseDate(String(test.date))
+		if err != nil {
+			t.Errorf("%s: %v", test.date, err)
+			continue
+		}
+		if !date.Equal(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.date, test.expected, date)
+		}
+	}
+
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 2*60*60))
+	parsed, err := parseDate(dateString(date))
+	if err != nil || !parsed.Equal(date) {
+		t.Errorf("expected %v, got %v %v", date, parsed, err)
+	}
+}
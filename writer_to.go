@@ -1,9 +1,19 @@
 package pdf
 
 import (
+	"fmt"
 	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+// WriteObject serializes obj using the syntax in §7.3.
+func WriteObject(w io.Writer, obj Object) (int64, error) {
+	return obj.writeTo(w)
+}
+
 // WriteTo serializes the Boolean according to the rules in
 // §7.3.2
 func (b Boolean) writeTo(w io.Writer) (int64, error) {
@@ -23,7 +33,7 @@ func (b Boolean) writeTo(w io.Writer) (int64, error) {
 func (i Integer) writeTo(w io.Writer) (int64, error) {
 	buf := &buffer{}
 
-	buf.Printf("%d", int(i))
+	buf.WriteString(strconv.FormatInt(int64(i), 10))
 
 	return buf.WriteTo(w)
 }
@@ -33,7 +43,19 @@ func (i Integer) writeTo(w io.Writer) (int64, error) {
 func (r Real) writeTo(w io.Writer) (int64, error) {
 	buf := &buffer{}
 
-	buf.Printf("%v", float64(r))
+	if math.IsNaN(float64(r)) || math.IsInf(float64(r), 0) {
+		return 0, fmt.Errorf("%v cannot be represented as a Real", float64(r))
+	}
+
+	// exponential notation is not allowed
+	// the shortest representation that parses to the same value is used
+	str := strconv.FormatFloat(float64(r), 'f', -1, 64)
+	buf.WriteString(str)
+
+	// without a decimal point the Real would be read as an Integer
+	if !strings.ContainsRune(str, '.') {
+		buf.WriteString(".0")
+	}
 
 	return buf.WriteTo(w)
 }
@@ -50,6 +72,10 @@ func (s String) writeTo(w io.Writer) (int64, error) {
 			buf.WriteString("\\(")
 		case ')':
 			buf.WriteString("\\)")
+		case '\\':
+			buf.WriteString("\\\\")
+		case '\r':
+			buf.WriteString("\\r")
 		default:
 			buf.WriteByte(b)
 		}
@@ -95,8 +121,17 @@ func (a Array) writeTo(w io.Writer) (int64, error) {
 func (d Dictionary) writeTo(w io.Writer) (int64, error) {
 	buf := &buffer{}
 
+	// sorted so that the output is reproducible
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
 	buf.WriteString("<<")
-	for name, obj := range d {
+	for _, name := range names {
+		name := Name(name)
+		obj := d[name]
 		n, err := name.writeTo(buf)
 		if err != nil {
 			return n, err
@@ -0,0 +1,126 @@
+package pdf
+
+import (
+	"fmt"
+)
+
+// AsFormXObject returns a form XObject (§8.10) that draws the page as
+// it is displayed: the CropBox, rotated by Rotate, with its lower-left
+// corner at the origin. The XObject is DisplaySize wide and high.
+//
+// The page is not changed. The XObject uses the page's resources and,
+// when the page has a single content stream, its encoded data. Content
+// arrays are decoded, joined and encoded with FlateDecode. References
+// are to objects in the page's file, which must be copied along with
+// the XObject when it is added to another file.
+func (p Page) AsFormXObject() (Stream, error) {
+	cropBox, err := p.CropBox()
+	if err != nil {
+		return Stream{}, err
+	}
+
+	xobject, err := p.contents()
+	if err != nil {
+		return Stream{}, err
+	}
+	xobject.Dictionary["Type"] = Name("XObject")
+	xobject.Dictionary["Subtype"] = Name("Form")
+	xobject.Dictionary["BBox"] = cropBox.Array()
+	xobject.Dictionary["Matrix"] = numbers(p.matrix(cropBox)...)
+
+	if resources, ok := p.inherited("Resources"); ok {
+		xobject.Dictionary["Resources"] = resources
+	} else {
+		xobject.Dictionary["Resources"] = Dictionary{}
+	}
+	// the page's transparency group and metadata
+	// also apply to the XObject (§8.10.3, §14.3.2)
+	for _, key := range []Name{"Group", "Metadata"} {
+		if value, ok := p.Dictionary[key]; ok {
+			xobject.Dictionary[key] = value
+		}
+	}
+	return xobject, nil
+}
+
+// DisplaySize returns the width and height of the page's CropBox as it
+// is displayed, which are swapped when the page is rotated by 90 or 270
+// degrees.
+func (p Page) DisplaySize() (width, height float64, err error) {
+	cropBox, err := p.CropBox()
+	if err != nil {
+		return 0, 0, err
+	}
+	if p.Rotate()%180 != 0 {
+		return cropBox.Height(), cropBox.Width(), nil
+	}
+	return cropBox.Width(), cropBox.Height(), nil
+}
+
+// the transformation from the page's default user space to one with
+// the displayed crop box's lower-left corner at the origin
+func (p Page) matrix(cropBox Rectangle) []float64 {
+	switch p.Rotate() {
+	case 90:
+		// the left edge becomes the top edge
+		return []float64{0, -1, 1, 0, -cropBox.LLY, cropBox.URX}
+	case 180:
+		return []float64{-1, 0, 0, -1, cropBox.URX, cropBox.URY}
+	case 270:
+		// the left edge becomes the bottom edge
+		return []float64{0, 1, -1, 0, cropBox.URY, -cropBox.LLX}
+	default:
+		return []float64{1, 0, 0, 1, -cropBox.LLX, -cropBox.LLY}
+	}
+}
+
+// a stream of the page's contents (§7.7.3.3), which is the single
+// content stream's data as is or the data of the content array
+func (p Page) contents() (Stream, error) {
+	var streams []Stream
+	switch contents := p.file.resolve(p.Dictionary["Contents"]).(type) {
+	case nil, Null:
+	case Stream:
+		streams = append(streams, contents)
+	case Array:
+		for _, obj := range contents {
+			stream, ok := p.file.resolve(obj).(Stream)
+			if !ok {
+				return Stream{}, fmt.Errorf("page %v contents %v is not a stream", p.ObjectReference, obj)
+			}
+			streams = append(streams, stream)
+		}
+	default:
+		return Stream{}, fmt.Errorf("page %v contents is not a stream or an array", p.ObjectReference)
+	}
+
+	if len(streams) == 1 {
+		if _, external := streams[0].Dictionary["F"]; !external {
+			stream := Stream{Dictionary: Dictionary{}, Stream: streams[0].Stream, file: p.file}
+			for _, key := range []Name{"Filter", "DecodeParms"} {
+				if value, ok := streams[0].Dictionary[key]; ok {
+					stream.Dictionary[key] = value
+				}
+			}
+			return stream, nil
+		}
+	}
+
+	// the streams are joined as if they were one,
+	// separated at the token boundaries between them
+	data := []byte{}
+	for i, stream := range streams {
+		decoded, err := stream.Decode()
+		if err != nil {
+			return Stream{}, fmt.Errorf("page %v contents: %v", p.ObjectReference, err)
+		}
+		if i > 0 {
+			data = append(data, '\n')
+		}
+		data = append(data, decoded...)
+	}
+	if len(streams) == 0 {
+		return Stream{Dictionary: Dictionary{}}, nil
+	}
+	return EncodeStream(nil, data, []Name{"FlateDecode"}, nil)
+}
@@ -0,0 +1,131 @@
+package pdf
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAsFormXObject(t *testing.T) {
+	file, err := Create(filepath.Join(t.TempDir(), "xobject.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	add := func(obj Object) ObjectReference {
+		ref, err := file.Add(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ref
+	}
+	encode := func(data string, filters ...Name) ObjectReference {
+		stream, err := EncodeStream(nil, []byte(data), filters, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return add(stream)
+	}
+
+	// a 100 by 50 crop box inside the media box
+	pagesRef := add(Null{})
+	resourcesRef := add(Dictionary{"Font": Dictionary{}})
+	contents := []Object{
+		encode("0 0 m", "FlateDecode"),
+		Array{encode("0 0 m", "FlateDecode"), encode("10 10 l S", "ASCIIHexDecode")},
+		nil,
+		Array{encode("0 0 m")},
+	}
+	kids := Array{}
+	for i, rotate := range []int{0, 90, -180, 270} {
+		page := Dictionary{
+			"Type":   Name("Page"),
+			"Parent": pagesRef,
+			"Rotate": Integer(rotate),
+		}
+		if contents[i] != nil {
+			page["Contents"] = contents[i]
+		}
+		kids = append(kids, add(page))
+	}
+	_, err = file.Add(IndirectObject{pagesRef, Dictionary{
+		"Type":      Name("Pages"),
+		"Kids":      kids,
+		"Count":     Integer(len(kids)),
+		"MediaBox":  Array{Integer(0), Integer(0), Integer(200), Integer(100)},
+		"CropBox":   Array{Integer(10), Integer(20), Integer(110), Integer(70)},
+		"Resources": resourcesRef,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Root = add(Dictionary{"Type": Name("Catalog"), "Pages": pagesRef})
+
+	document, err := NewDocument(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := document.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		width, height float64
+		data          string
+	}{
+		{100, 50, "0 0 m"},
+		{50, 100, "0 0 m\n10 10 l S"},
+		{100, 50, ""},
+		{50, 100, "0 0 m"},
+	} {
+		page := pages[i]
+		original := copyDictionary(page.Dictionary)
+		xobject, err := page.AsFormXObject()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(page.Dictionary, original) {
+			t.Errorf("page %d changed to %v", i, page.Dictionary)
+		}
+
+		width, height, err := page.DisplaySize()
+		if err != nil || width != test.width || height != test.height {
+			t.Errorf("page %d is %vx%v instead of %vx%v %v", i, width, height, test.width, test.height, err)
+		}
+
+		dict := xobject.Dictionary
+		if dict["Subtype"] != Name("Form") || dict["Resources"] != resourcesRef ||
+			!reflect.DeepEqual(dict["BBox"], Array{Integer(10), Integer(20), Integer(110), Integer(70)}) {
+			t.Errorf("page %d has unexpected XObject %v", i, dict)
+		}
+
+		// the crop box's corners are mapped to those of the displayed page
+		m := [6]float64{}
+		for j, value := range dict["Matrix"].(Array) {
+			m[j] = float64(value.(Integer))
+		}
+		corners := map[[2]float64]bool{}
+		for _, corner := range [][2]float64{{10, 20}, {110, 20}, {10, 70}, {110, 70}} {
+			x, y := corner[0], corner[1]
+			corners[[2]float64{m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]}] = true
+		}
+		if !reflect.DeepEqual(corners, map[[2]float64]bool{
+			{0, 0}: true, {test.width, 0}: true, {0, test.height}: true, {test.width, test.height}: true,
+		}) {
+			t.Errorf("page %d matrix %v maps the crop box to %v", i, m, corners)
+		}
+
+		data, err := xobject.Decode()
+		if err != nil || string(data) != test.data {
+			t.Errorf("page %d has contents %q instead of %q %v", i, data, test.data, err)
+		}
+	}
+
+	// a single content stream is used as is
+	xobject, _ := pages[0].AsFormXObject()
+	stream := file.Get(contents[0].(ObjectReference)).(Stream)
+	if !reflect.DeepEqual(xobject.Stream, stream.Stream) || xobject.Dictionary["Filter"] != Name("FlateDecode") {
+		t.Errorf("contents were re-encoded")
+	}
+}